@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// config lists the notarization targets every committed digest is anchored
+// on.
+type config struct {
+	Targets []targetConfig `json:"targets"`
+	// Per-request timeout against a target; default 30.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// How long to wait for a pending anchor to confirm before giving up
+	// until the next attempt; default 3600.
+	ConfirmTimeoutSeconds int `json:"confirm_timeout_seconds,omitempty"`
+	// Delay between confirmation checks; default 15.
+	PollSeconds int `json:"poll_seconds,omitempty"`
+}
+
+// targetConfig is one notarization target. Name is what the ledger records
+// as the anchor's target.
+type targetConfig struct {
+	Name string `json:"name"`
+	// "ethereum" or "opentimestamps".
+	Type string `json:"type"`
+
+	// Ethereum: JSON-RPC endpoint, EIP-155 chain id, and the hex private
+	// key of the sending account, read from KeyEnv or KeyFile. The root is
+	// sent as the data of a zero-value transaction to To, by default the
+	// sender itself, and is confirmed after Confirmations blocks
+	// (default 12).
+	RPCURL        string `json:"rpc_url,omitempty"`
+	ChainID       uint64 `json:"chain_id,omitempty"`
+	KeyEnv        string `json:"key_env,omitempty"`
+	KeyFile       string `json:"key_file,omitempty"`
+	To            string `json:"to,omitempty"`
+	Confirmations uint64 `json:"confirmations,omitempty"`
+
+	// OpenTimestamps: calendar servers to submit the root to.
+	Calendars []string `json:"calendars,omitempty"`
+
+	key []byte
+}
+
+var (
+	targetNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._:-]{0,63}$`)
+	addressRe    = regexp.MustCompile(`^0x[0-9a-fA-F]{40}$`)
+)
+
+func loadConfig(path string) (*config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if c.TimeoutSeconds == 0 {
+		c.TimeoutSeconds = 30
+	}
+	if c.ConfirmTimeoutSeconds == 0 {
+		c.ConfirmTimeoutSeconds = 3600
+	}
+	if c.PollSeconds == 0 {
+		c.PollSeconds = 15
+	}
+	if len(c.Targets) == 0 {
+		return nil, fmt.Errorf("config: at least one target required")
+	}
+	if c.TimeoutSeconds < 1 || c.ConfirmTimeoutSeconds < 1 || c.PollSeconds < 1 {
+		return nil, fmt.Errorf("config: timeouts must be positive")
+	}
+	names := map[string]bool{}
+	for i := range c.Targets {
+		t := &c.Targets[i]
+		if !targetNameRe.MatchString(t.Name) || names[t.Name] {
+			return nil, fmt.Errorf("config: targets[%d]: name must be unique, 1-64 lowercase letters, digits or ._:-", i)
+		}
+		names[t.Name] = true
+		if err := t.validate(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("config: target %s: %w", t.Name, err)
+		}
+	}
+	return &c, nil
+}
+
+func (t *targetConfig) validate(dir string) error {
+	switch t.Type {
+	case "ethereum":
+		if !httpURL(t.RPCURL) {
+			return fmt.Errorf("rpc_url must be http(s)")
+		}
+		if t.ChainID == 0 {
+			return fmt.Errorf("chain_id required")
+		}
+		if t.To != "" && !addressRe.MatchString(t.To) {
+			return fmt.Errorf("to must be a 0x address")
+		}
+		if t.Confirmations == 0 {
+			t.Confirmations = 12
+		}
+		var err error
+		t.key, err = t.loadKey(dir)
+		return err
+	case "opentimestamps":
+		if len(t.Calendars) == 0 {
+			return fmt.Errorf("calendars required")
+		}
+		for _, u := range t.Calendars {
+			if !httpURL(u) {
+				return fmt.Errorf("calendar %q must be http(s)", u)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("type must be ethereum or opentimestamps")
+	}
+}
+
+func httpURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "https" || u.Scheme == "http") && u.Host != ""
+}
+
+func (t *targetConfig) loadKey(dir string) ([]byte, error) {
+	var v string
+	switch {
+	case t.KeyEnv != "" && t.KeyFile != "":
+		return nil, fmt.Errorf("use either key_env or key_file")
+	case t.KeyEnv != "":
+		if v = os.Getenv(t.KeyEnv); v == "" {
+			return nil, fmt.Errorf("%s is unset", t.KeyEnv)
+		}
+	case t.KeyFile != "":
+		p := t.KeyFile
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		v = string(b)
+	default:
+		return nil, fmt.Errorf("key_env or key_file required")
+	}
+	key, err := decodeHex(strings.TrimSpace(v))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("key must be a 32-byte hex private key")
+	}
+	return key, nil
+}
+
+func (c *config) timeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
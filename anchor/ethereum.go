@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"golang.org/x/crypto/sha3"
+
+	"payscope/client/auditlog"
+)
+
+// ethereumTarget anchors a root as the data of a zero-value legacy
+// (EIP-155) transaction. The anchor is recorded pending with the
+// transaction hash as soon as the node accepts it, and confirmed with its
+// block once Confirmations blocks are on top of it.
+type ethereumTarget struct {
+	cfg    *targetConfig
+	client *http.Client
+	key    *secp256k1.PrivateKey
+	from   string
+	to     string
+	poll   time.Duration
+	wait   time.Duration
+}
+
+func newEthereumTarget(cfg *targetConfig, c *config) *ethereumTarget {
+	key := secp256k1.PrivKeyFromBytes(cfg.key)
+	from := ethAddress(key.PubKey())
+	to := strings.ToLower(cfg.To)
+	if to == "" {
+		to = from
+	}
+	return &ethereumTarget{
+		cfg:    cfg,
+		client: &http.Client{Timeout: c.timeout()},
+		key:    key,
+		from:   from,
+		to:     to,
+		poll:   time.Duration(c.PollSeconds) * time.Second,
+		wait:   time.Duration(c.ConfirmTimeoutSeconds) * time.Second,
+	}
+}
+
+func (t *ethereumTarget) name() string { return t.cfg.Name }
+
+func (t *ethereumTarget) network() string {
+	return "eip155:" + strconv.FormatUint(t.cfg.ChainID, 10)
+}
+
+func (t *ethereumTarget) submit(ctx context.Context, d *auditlog.MerkleDigest) (*auditlog.DigestAnchor, error) {
+	data, err := decodeHex(d.Root)
+	if err != nil {
+		return nil, fmt.Errorf("merkle_root: %w", err)
+	}
+	toBytes, _ := decodeHex(t.to)
+
+	var nonce, gasPrice, gas hexUint
+	if err := t.call(ctx, &nonce, "eth_getTransactionCount", t.from, "pending"); err != nil {
+		return nil, err
+	}
+	if err := t.call(ctx, &gasPrice, "eth_gasPrice"); err != nil {
+		return nil, err
+	}
+	call := map[string]string{"from": t.from, "to": t.to, "data": "0x" + d.Root}
+	if err := t.call(ctx, &gas, "eth_estimateGas", call); err != nil {
+		return nil, err
+	}
+
+	// Signed over [nonce, gasPrice, gas, to, value, data, chainId, 0, 0].
+	fields := [][]byte{
+		rlpUint(nonce.Uint64()), rlpBig(gasPrice.big()), rlpUint(gas.Uint64()),
+		rlpBytes(toBytes), rlpUint(0), rlpBytes(data),
+	}
+	unsigned := rlpList(append(fields, rlpUint(t.cfg.ChainID), rlpUint(0), rlpUint(0))...)
+	sig := ecdsa.SignCompact(t.key, keccak256(unsigned), false)
+	// sig is [27+recovery id, r, s].
+	v := new(big.Int).SetUint64(t.cfg.ChainID)
+	v.Mul(v, big.NewInt(2)).Add(v, big.NewInt(int64(sig[0]-27)+35))
+	raw := rlpList(append(fields, rlpBig(v), rlpBig(new(big.Int).SetBytes(sig[1:33])), rlpBig(new(big.Int).SetBytes(sig[33:])))...)
+
+	var txHash string
+	if err := t.call(ctx, &txHash, "eth_sendRawTransaction", "0x"+hex.EncodeToString(raw)); err != nil {
+		return nil, err
+	}
+	return &auditlog.DigestAnchor{
+		MerkleRoot: d.Root,
+		Target:     t.cfg.Name,
+		Status:     auditlog.AnchorPending,
+		Network:    t.network(),
+		Reference:  strings.ToLower(txHash),
+	}, nil
+}
+
+type ethReceipt struct {
+	BlockNumber *hexUint `json:"blockNumber"`
+	Status      hexUint  `json:"status"`
+}
+
+type ethBlock struct {
+	Timestamp hexUint `json:"timestamp"`
+}
+
+// confirm waits until the transaction of a has Confirmations blocks on top.
+func (t *ethereumTarget) confirm(ctx context.Context, a *auditlog.DigestAnchor) (*auditlog.DigestAnchor, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.wait)
+	defer cancel()
+	for {
+		var receipt *ethReceipt
+		if err := t.call(ctx, &receipt, "eth_getTransactionReceipt", a.Reference); err != nil {
+			return nil, err
+		}
+		if receipt != nil && receipt.BlockNumber != nil {
+			if receipt.Status.Uint64() != 1 {
+				return nil, fmt.Errorf("transaction %s failed", a.Reference)
+			}
+			var head hexUint
+			if err := t.call(ctx, &head, "eth_blockNumber"); err != nil {
+				return nil, err
+			}
+			block := receipt.BlockNumber.Uint64()
+			if head.Uint64() >= block && head.Uint64()-block+1 >= t.cfg.Confirmations {
+				var b ethBlock
+				if err := t.call(ctx, &b, "eth_getBlockByNumber", receipt.BlockNumber.String(), false); err != nil {
+					return nil, err
+				}
+				confirmed := *a
+				confirmed.Status = auditlog.AnchorConfirmed
+				confirmed.BlockNumber = &block
+				confirmed.AnchoredAt = time.Unix(int64(b.Timestamp.Uint64()), 0).UTC().Format(time.RFC3339)
+				return &confirmed, nil
+			}
+		}
+		select {
+		case <-time.After(t.poll):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("transaction %s not confirmed: %w", a.Reference, ctx.Err())
+		}
+	}
+}
+
+type rpcResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (t *ethereumTarget) call(ctx context.Context, out any, method string, params ...any) error {
+	if params == nil {
+		params = []any{}
+	}
+	body, err := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": 1, "method": method, "params": params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.cfg.RPCURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", method, resp.Status)
+	}
+	var r rpcResponse
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	if r.Error != nil {
+		return fmt.Errorf("%s: %s (%d)", method, r.Error.Message, r.Error.Code)
+	}
+	if err := json.Unmarshal(r.Result, out); err != nil {
+		return fmt.Errorf("%s: %w", method, err)
+	}
+	return nil
+}
+
+// hexUint is a JSON-RPC quantity such as "0x1b4".
+type hexUint struct{ v big.Int }
+
+func (h *hexUint) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	if !strings.HasPrefix(s, "0x") {
+		return errors.New("quantity must be 0x-prefixed hex")
+	}
+	if _, ok := h.v.SetString(s[2:], 16); !ok {
+		return fmt.Errorf("invalid quantity %q", s)
+	}
+	return nil
+}
+
+func (h *hexUint) big() *big.Int  { return &h.v }
+func (h *hexUint) Uint64() uint64 { return h.v.Uint64() }
+func (h *hexUint) String() string { return "0x" + h.v.Text(16) }
+
+func keccak256(b []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+// ethAddress is the lowercase 0x address of pub.
+func ethAddress(pub *secp256k1.PublicKey) string {
+	return "0x" + hex.EncodeToString(keccak256(pub.SerializeUncompressed()[1:])[12:])
+}
+
+func decodeHex(s string) ([]byte, error) {
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X"))
+}
+
+// RLP, as far as legacy transactions need it.
+
+func rlpBytes(b []byte) []byte {
+	if len(b) == 1 && b[0] < 0x80 {
+		return b
+	}
+	return append(rlpHeader(0x80, len(b)), b...)
+}
+
+func rlpUint(v uint64) []byte {
+	return rlpBig(new(big.Int).SetUint64(v))
+}
+
+func rlpBig(v *big.Int) []byte {
+	return rlpBytes(v.Bytes())
+}
+
+func rlpList(items ...[]byte) []byte {
+	body := bytes.Join(items, nil)
+	return append(rlpHeader(0xc0, len(body)), body...)
+}
+
+func rlpHeader(base byte, n int) []byte {
+	if n < 56 {
+		return []byte{base + byte(n)}
+	}
+	size := new(big.Int).SetInt64(int64(n)).Bytes()
+	return append([]byte{base + 55 + byte(len(size))}, size...)
+}
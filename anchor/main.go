@@ -0,0 +1,171 @@
+// Command anchor notarizes committed Merkle digests outside the consortium.
+//
+// It follows the chaincode's DigestCommitted events, records each digest
+// root on every configured target, a public Ethereum network or
+// OpenTimestamps calendars, and stores the receipts back on the ledger
+// through AnchorDigest. Targets already holding a receipt for a digest are
+// skipped and pending receipts are confirmed where the target allows, so a
+// restart from the checkpoint never anchors a root twice once its receipt
+// is recorded.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+
+	"payscope/client/auditlog"
+)
+
+// target is one notarization target.
+type target interface {
+	name() string
+	// submit records the digest root on the target and returns its
+	// receipt, pending or confirmed.
+	submit(ctx context.Context, d *auditlog.MerkleDigest) (*auditlog.DigestAnchor, error)
+	// confirm waits until a pending receipt is final and returns the
+	// confirmed one, or nil when the target does not confirm on its own.
+	confirm(ctx context.Context, a *auditlog.DigestAnchor) (*auditlog.DigestAnchor, error)
+}
+
+func main() {
+	profilePath := flag.String("profile", os.Getenv("ANCHOR_PROFILE"), "audit log connection profile JSON")
+	configPath := flag.String("config", os.Getenv("ANCHOR_CONFIG"), "anchoring targets config JSON")
+	checkpointPath := flag.String("checkpoint", envOr("ANCHOR_CHECKPOINT", "anchor-checkpoint.json"), "file recording the last handled transaction")
+	fromBlock := flag.Uint64("from-block", 0, "block to start from when there is no checkpoint")
+	flag.Parse()
+
+	if *profilePath == "" || *configPath == "" {
+		log.Fatal("anchor: -profile and -config (or ANCHOR_PROFILE, ANCHOR_CONFIG) are required")
+	}
+	profile, err := auditlog.LoadProfile(*profilePath)
+	if err != nil {
+		log.Fatalf("anchor: %v", err)
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("anchor: %v", err)
+	}
+	checkpoint, err := client.NewFileCheckpointer(*checkpointPath)
+	if err != nil {
+		log.Fatalf("anchor: checkpoint: %v", err)
+	}
+	defer checkpoint.Close()
+
+	var targets []target
+	for i := range cfg.Targets {
+		t := &cfg.Targets[i]
+		switch t.Type {
+		case "ethereum":
+			targets = append(targets, newEthereumTarget(t, cfg))
+		case "opentimestamps":
+			targets = append(targets, newOpenTimestampsTarget(t, cfg))
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		handled, err := run(ctx, profile, targets, checkpoint, *fromBlock)
+		if ctx.Err() != nil {
+			break
+		}
+		if handled > 0 {
+			backoff = time.Second
+		}
+		log.Printf("anchor: stream ended after block %d: %v; reconnecting in %s", checkpoint.BlockNumber(), err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+	log.Printf("anchor: stopped at block %d", checkpoint.BlockNumber())
+}
+
+// run follows committed digests from the checkpoint and anchors them in
+// order. It returns how many digests were handled before the stream ended.
+func run(ctx context.Context, profile *auditlog.Profile, targets []target, checkpoint *client.FileCheckpointer, fromBlock uint64) (int, error) {
+	c, err := auditlog.Connect(profile)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	digests, err := c.CommittedDigests(streamCtx, fromBlock, checkpoint)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("anchor: following %s/%s for %d targets", profile.Channel, profile.Chaincode, len(targets))
+
+	handled := 0
+	for d := range digests {
+		if err := anchor(ctx, c, targets, &d.Digest); err != nil {
+			return handled, err
+		}
+		if err := checkpoint.CheckpointTransaction(d.BlockNumber, d.TxID); err != nil {
+			return handled, err
+		}
+		handled++
+	}
+	return handled, ctx.Err()
+}
+
+// anchor brings every target's receipt for d to its final status,
+// submitting the root to targets that have none yet.
+func anchor(ctx context.Context, c *auditlog.Client, targets []target, d *auditlog.MerkleDigest) error {
+	recorded, err := c.DigestAnchors(d.DigestID)
+	if err != nil {
+		return err
+	}
+	byTarget := map[string]*auditlog.DigestAnchor{}
+	for i := range recorded {
+		byTarget[recorded[i].Target] = &recorded[i]
+	}
+	for _, t := range targets {
+		a := byTarget[t.name()]
+		if a == nil {
+			if a, err = t.submit(ctx, d); err != nil {
+				return err
+			}
+			if a, err = c.AnchorDigest(d.DigestID, a); err != nil {
+				return err
+			}
+			log.Printf("anchor: digest %s %s on %s: %s", d.DigestID, a.Status, t.name(), a.Reference)
+		}
+		if a.Status != auditlog.AnchorPending {
+			continue
+		}
+		confirmed, err := t.confirm(ctx, a)
+		if err != nil {
+			return err
+		}
+		if confirmed == nil {
+			continue
+		}
+		if _, err := c.AnchorDigest(d.DigestID, confirmed); err != nil {
+			return err
+		}
+		log.Printf("anchor: digest %s confirmed on %s", d.DigestID, t.name())
+	}
+	return nil
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
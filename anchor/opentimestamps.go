@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"payscope/client/auditlog"
+)
+
+// otsHeader starts every OpenTimestamps proof file, followed by the format
+// version.
+var otsHeader = append([]byte("\x00OpenTimestamps\x00\x00Proof\x00\xbf\x89\xe2\xe8\x84\xe8\x92\x94"), 0x01)
+
+// Proof file opcodes.
+const (
+	otsOpSHA256 = 0x08
+	otsFork     = 0xff
+)
+
+// opentimestampsTarget submits a root to OpenTimestamps calendars. A
+// calendar answers at once with a pending timestamp and commits it to
+// Bitcoin within hours, so the anchor stays pending here: its proof is a
+// standard .ots file over the root, which `ots upgrade` completes and
+// `ots verify -d <merkle_root>` checks.
+type opentimestampsTarget struct {
+	cfg    *targetConfig
+	client *http.Client
+}
+
+func newOpenTimestampsTarget(cfg *targetConfig, c *config) *opentimestampsTarget {
+	return &opentimestampsTarget{cfg: cfg, client: &http.Client{Timeout: c.timeout()}}
+}
+
+func (t *opentimestampsTarget) name() string { return t.cfg.Name }
+
+// submit sends the root to every calendar and joins the timestamps of those
+// that answered into one proof. It fails only when none did.
+func (t *opentimestampsTarget) submit(ctx context.Context, d *auditlog.MerkleDigest) (*auditlog.DigestAnchor, error) {
+	root, err := decodeHex(d.Root)
+	if err != nil || len(root) != 32 {
+		return nil, fmt.Errorf("merkle_root must be 32 bytes of hex")
+	}
+	var stamps [][]byte
+	var answered []string
+	for _, cal := range t.cfg.Calendars {
+		stamp, err := t.stamp(ctx, cal, root)
+		if err != nil {
+			log.Printf("anchor: %s: calendar %s: %v", t.cfg.Name, cal, err)
+			continue
+		}
+		stamps = append(stamps, stamp)
+		answered = append(answered, cal)
+	}
+	if len(stamps) == 0 {
+		return nil, fmt.Errorf("no calendar accepted digest %s", d.DigestID)
+	}
+
+	// Several timestamps of one message are its branches: each but the
+	// last is preceded by a fork.
+	proof := append(append(append([]byte{}, otsHeader...), otsOpSHA256), root...)
+	for i, s := range stamps {
+		if i < len(stamps)-1 {
+			proof = append(proof, otsFork)
+		}
+		proof = append(proof, s...)
+	}
+	return &auditlog.DigestAnchor{
+		MerkleRoot: d.Root,
+		Target:     t.cfg.Name,
+		Status:     auditlog.AnchorPending,
+		Network:    "opentimestamps",
+		Reference:  strings.Join(answered, " "),
+		Proof:      base64.StdEncoding.EncodeToString(proof),
+	}, nil
+}
+
+// confirm returns nil: upgrading a pending timestamp is left to the ots
+// tools.
+func (t *opentimestampsTarget) confirm(context.Context, *auditlog.DigestAnchor) (*auditlog.DigestAnchor, error) {
+	return nil, nil
+}
+
+func (t *opentimestampsTarget) stamp(ctx context.Context, calendar string, digest []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(calendar, "/")+"/digest", bytes.NewReader(digest))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.opentimestamps.v1")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s", resp.Status)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty timestamp")
+	}
+	return b, nil
+}
@@ -0,0 +1,163 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// DigestAnchor mirrors the chaincode's receipt of a digest root recorded on
+// an external notarization target.
+type DigestAnchor struct {
+	DigestID      string  `json:"digest_id,omitempty"`
+	MerkleRoot    string  `json:"merkle_root"`
+	Target        string  `json:"target"`
+	Status        string  `json:"status"`
+	Network       string  `json:"network,omitempty"`
+	Reference     string  `json:"reference"`
+	BlockNumber   *uint64 `json:"block_number,omitempty"`
+	Proof         string  `json:"proof,omitempty"`
+	AnchoredAt    string  `json:"anchored_at,omitempty"`
+	RecordedByMSP string  `json:"recorded_by_msp,omitempty"`
+	TxID          string  `json:"tx_id,omitempty"`
+	RecordedAt    string  `json:"recorded_at,omitempty"`
+}
+
+// Anchor statuses. A pending anchor may later be replaced by a confirmed
+// one.
+const (
+	AnchorPending   = "pending"
+	AnchorConfirmed = "confirmed"
+)
+
+// GetDigest reads a committed Merkle digest.
+func (c *Client) GetDigest(digestID string) (*MerkleDigest, error) {
+	b, err := c.evaluate("GetDigest", digestID)
+	if err != nil {
+		return nil, err
+	}
+	var d MerkleDigest
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// AnchorDigest records a's receipt for digestID and returns the stored
+// record. The chaincode fills in DigestID and who recorded it when.
+func (c *Client) AnchorDigest(digestID string, a *DigestAnchor) (*DigestAnchor, error) {
+	in := *a
+	in.DigestID, in.RecordedByMSP, in.TxID, in.RecordedAt = "", "", "", ""
+	b, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.submit("AnchorDigest", client.WithArguments(digestID, string(b)))
+	if err != nil {
+		return nil, err
+	}
+	var stored DigestAnchor
+	if err := json.Unmarshal(out, &stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// DigestAnchors lists the receipts recorded for digestID.
+func (c *Client) DigestAnchors(digestID string) ([]DigestAnchor, error) {
+	b, err := c.evaluate("GetDigestAnchors", digestID)
+	if err != nil {
+		return nil, err
+	}
+	var anchors []DigestAnchor
+	if err := json.Unmarshal(b, &anchors); err != nil {
+		return nil, err
+	}
+	return anchors, nil
+}
+
+// ProofStep is one sibling on an inclusion proof's path to the root.
+type ProofStep struct {
+	Hash string `json:"hash"`
+	// "left" or "right".
+	Side string `json:"side"`
+}
+
+// InclusionProof mirrors the chaincode's Merkle path from an event's
+// payload hash to a digest root.
+type InclusionProof struct {
+	DigestID    string      `json:"digest_id"`
+	EventID     string      `json:"event_id"`
+	PayloadHash string      `json:"payload_hash_sha256"`
+	LeafIndex   int         `json:"leaf_index"`
+	LeafCount   int         `json:"leaf_count"`
+	Root        string      `json:"merkle_root"`
+	Proof       []ProofStep `json:"proof"`
+}
+
+// AnchorVerification mirrors the chaincode's VerifyEventAgainstAnchor
+// verdict. Reason is set when Verified is false.
+type AnchorVerification struct {
+	EventID  string          `json:"event_id"`
+	Target   string          `json:"target"`
+	Verified bool            `json:"verified"`
+	Reason   string          `json:"reason,omitempty"`
+	Digest   *MerkleDigest   `json:"digest"`
+	Anchor   *DigestAnchor   `json:"anchor"`
+	Proof    *InclusionProof `json:"proof,omitempty"`
+}
+
+// VerifyEventAgainstAnchor proves eventID is under a digest root anchored
+// on target.
+func (c *Client) VerifyEventAgainstAnchor(eventID, target string) (*AnchorVerification, error) {
+	b, err := c.evaluate("VerifyEventAgainstAnchor", eventID, target)
+	if err != nil {
+		return nil, err
+	}
+	var v AnchorVerification
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// CommittedDigest is a digest announced by a DigestCommitted chaincode
+// event.
+type CommittedDigest struct {
+	BlockNumber uint64
+	TxID        string
+	Digest      MerkleDigest
+}
+
+// CommittedDigests streams the digests committed by valid transactions, in
+// commit order, like WrittenEvents.
+func (c *Client) CommittedDigests(ctx context.Context, startBlock uint64, checkpoint client.Checkpoint) (<-chan *CommittedDigest, error) {
+	opts := []client.ChaincodeEventsOption{client.WithStartBlock(startBlock)}
+	if checkpoint != nil {
+		opts = append(opts, client.WithCheckpoint(checkpoint))
+	}
+	events, err := c.network.ChaincodeEvents(ctx, c.chaincode, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan *CommittedDigest)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			if ev.EventName != "DigestCommitted" {
+				continue
+			}
+			d := &CommittedDigest{BlockNumber: ev.BlockNumber, TxID: ev.TransactionID}
+			if err := json.Unmarshal(ev.Payload, &d.Digest); err != nil {
+				continue
+			}
+			select {
+			case out <- d:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,49 @@
+package auditlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+
+	"payscope/auditlog/jcs"
+)
+
+// RecordArchiveLocation records where an archived event's payload was moved
+// to in cold storage, with storageProof as evidence of the stored object,
+// such as its version id or digest. It is recorded once per event. Admins
+// only.
+func (c *Client) RecordArchiveLocation(eventID, storageURI, storageProof string) (*Archival, error) {
+	b, err := c.submit("RecordArchiveLocation", client.WithArguments(eventID, storageURI, storageProof))
+	if err != nil {
+		return nil, err
+	}
+	var a Archival
+	if err := json.Unmarshal(b, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// ArchiveLocationHash recomputes the location_hash receipt of an archived
+// event read in full: the hex SHA-256 of the JCS encoding of its event_id,
+// payload hash, storage_uri and storage_proof. Compare it with
+// Archival.LocationHash before trusting the recorded location.
+func ArchiveLocationHash(ev *StoredEvent) (string, error) {
+	if ev.Archival == nil || ev.Archival.StorageURI == "" {
+		return "", errors.New("archive: event has no recorded location")
+	}
+	b, err := jcs.Marshal(struct {
+		EventID      string `json:"event_id"`
+		PayloadHash  string `json:"payload_hash_sha256"`
+		StorageURI   string `json:"storage_uri"`
+		StorageProof string `json:"storage_proof"`
+	}{ev.Event.EventID, ev.PayloadHash, ev.Archival.StorageURI, ev.Archival.StorageProof})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
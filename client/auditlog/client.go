@@ -0,0 +1,749 @@
+// Package auditlog is a Go client for the audit log chaincode in
+// infra/fabric-chaincode/auditlog, built on the Fabric Gateway API.
+package auditlog
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-gateway/pkg/identity"
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Client submits and queries audit log transactions as one identity.
+type Client struct {
+	conn     *grpc.ClientConn
+	gateway  *client.Gateway
+	network  *client.Network
+	contract *client.Contract
+	// Chaincode name, for chaincode event subscriptions.
+	chaincode string
+
+	// Submitting identity, kept for client-side signatures.
+	mspID   string
+	certPEM []byte
+	key     crypto.PrivateKey
+
+	retry       RetryPolicy
+	submitHooks []func(SubmitAttempt)
+
+	// Set by WithContext; see trace.go.
+	ctx    context.Context
+	tracer trace.Tracer
+}
+
+type Option func(*Client)
+
+// Connect opens a gateway connection described by profile.
+func Connect(profile *Profile, opts ...Option) (*Client, error) {
+	if err := profile.validate(); err != nil {
+		return nil, err
+	}
+	id, key, err := loadIdentity(profile)
+	if err != nil {
+		return nil, err
+	}
+	sign, err := identity.NewPrivateKeySign(key)
+	if err != nil {
+		return nil, err
+	}
+	tlsCA, err := os.ReadFile(profile.TLSCACertPath)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(tlsCA) {
+		return nil, fmt.Errorf("no certificates in %s", profile.TLSCACertPath)
+	}
+	creds := credentials.NewClientTLSFromCert(pool, profile.PeerHostOverride)
+	conn, err := grpc.Dial(profile.PeerEndpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, err
+	}
+	gw, err := client.Connect(id, client.WithSign(sign), client.WithClientConnection(conn))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	network := gw.GetNetwork(profile.Channel)
+	c := &Client{
+		conn:      conn,
+		gateway:   gw,
+		network:   network,
+		contract:  network.GetContract(profile.Chaincode),
+		chaincode: profile.Chaincode,
+		mspID:     profile.MSPID,
+		certPEM:   id.Credentials(),
+		key:       key,
+		retry:     DefaultRetryPolicy(),
+		tracer:    defaultTracer(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+func loadIdentity(profile *Profile) (*identity.X509Identity, crypto.PrivateKey, error) {
+	certPEM, err := os.ReadFile(profile.CertPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := identity.CertificateFromPEM(certPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	id, err := identity.NewX509Identity(profile.MSPID, cert)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(profile.KeyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := identity.PrivateKeyFromPEM(keyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	return id, key, nil
+}
+
+func (c *Client) Close() error {
+	c.gateway.Close()
+	return c.conn.Close()
+}
+
+func (c *Client) evaluate(name string, args ...string) ([]byte, error) {
+	ctx, span := c.startSpan(c.baseContext(), "evaluate", name)
+	proposal, err := c.contract.NewProposal(name, client.WithArguments(args...))
+	var result []byte
+	if err == nil {
+		result, err = proposal.EvaluateWithContext(ctx)
+	}
+	endSpan(span, err)
+	return result, err
+}
+
+// Receipt mirrors the chaincode's response to event writes.
+type Receipt struct {
+	ChannelID   string `json:"channel_id"`
+	Chaincode   string `json:"chaincode,omitempty"`
+	TxID        string `json:"tx_id"`
+	TxTimestamp string `json:"tx_timestamp"`
+	EventID     string `json:"event_id,omitempty"`
+	PayloadHash string `json:"payload_hash_sha256,omitempty"`
+	Deduped     bool   `json:"deduped,omitempty"`
+}
+
+func (c *Client) submitReceipt(name string, opts ...client.ProposalOption) (*Receipt, error) {
+	return decodeReceipt(c.submit(name, opts...))
+}
+
+func decodeReceipt(b []byte, err error) (*Receipt, error) {
+	if err != nil {
+		return nil, err
+	}
+	var r Receipt
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// PutEvent records e and returns the receipt naming the channel and
+// transaction that hold it.
+func (c *Client) PutEvent(e *Event) (*Receipt, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return c.submitReceipt("PutEvent", client.WithArguments(string(b)))
+}
+
+// BatchResult mirrors the chaincode's response to PutEvents: one result
+// per event, in submission order, with its Outcome.
+type BatchResult struct {
+	TxID    string            `json:"tx_id"`
+	Receipt Receipt           `json:"receipt"`
+	Results []BatchItemResult `json:"results"`
+	Summary BatchSummary      `json:"summary"`
+}
+
+// Outcomes of a PutEvents item.
+const (
+	OutcomeCreated   = "CREATED"
+	OutcomeDuplicate = "DUPLICATE_NOOP"
+	OutcomeRejected  = "REJECTED"
+)
+
+// BatchItemResult is the receipt of one input event. PayloadHash is set
+// unless it was rejected, and Code and Reason only when it was. Status is
+// the outcome in its older form ("written", "deduped", "rejected"), still
+// the only one from chaincode builds predating Outcome.
+type BatchItemResult struct {
+	Index       int            `json:"index"`
+	EventID     string         `json:"event_id,omitempty"`
+	Outcome     string         `json:"outcome,omitempty"`
+	Status      string         `json:"status"`
+	PayloadHash string         `json:"payload_hash_sha256,omitempty"`
+	Code        string         `json:"code,omitempty"`
+	Reason      string         `json:"reason,omitempty"`
+	Details     map[string]any `json:"details,omitempty"`
+}
+
+type BatchSummary struct {
+	Written  int `json:"written"`
+	Deduped  int `json:"deduped"`
+	Rejected int `json:"rejected"`
+}
+
+// PutEvents records events in one transaction. Invalid events are
+// rejected individually in the result; the rest commit together. A retried
+// batch reports the events an earlier attempt committed as deduped.
+func (c *Client) PutEvents(events []*Event) (*BatchResult, error) {
+	b, err := json.Marshal(events)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.submit("PutEvents", client.WithArguments(string(b)))
+	if err != nil {
+		return nil, err
+	}
+	var res BatchResult
+	if err := json.Unmarshal(out, &res); err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+// ValidationResult reports whether PutEvent would accept an event. Status
+// is "valid", "deduped" or "rejected" with the error code and reason.
+type ValidationResult struct {
+	EventID               string         `json:"event_id,omitempty"`
+	Status                string         `json:"status"`
+	PayloadHash           string         `json:"payload_hash_sha256,omitempty"`
+	TimestampDriftFlagged bool           `json:"timestamp_drift_flagged,omitempty"`
+	Code                  string         `json:"code,omitempty"`
+	Reason                string         `json:"reason,omitempty"`
+	Details               map[string]any `json:"details,omitempty"`
+}
+
+// ValidateEvent dry-runs PutEvent for e on one peer without submitting a
+// transaction.
+func (c *Client) ValidateEvent(e *Event) (*ValidationResult, error) {
+	in, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.evaluate("ValidateEvent", string(in))
+	if err != nil {
+		return nil, err
+	}
+	var r ValidationResult
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// PutEventWithPayload records an INGEST event together with its full
+// payload, which the chaincode stores in the channel's private collection.
+// The payload travels in the transient map and never reaches the ledger.
+func (c *Client) PutEventWithPayload(e *Event, payload []byte) (*Receipt, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return decodeReceipt(c.submitTransient("PutEvent",
+		map[string][]byte{"payload": payload},
+		client.WithArguments(string(b)),
+	))
+}
+
+// AmendEvent records e as the correction of oldEventID and returns its
+// receipt. e.Supersedes may be left empty.
+func (c *Client) AmendEvent(oldEventID string, e *Event) (*Receipt, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return c.submitReceipt("AmendEvent", client.WithArguments(oldEventID, string(b)))
+}
+
+func (c *Client) GetEvent(eventID string) (*StoredEvent, error) {
+	b, err := c.evaluate("GetEvent", eventID, "full")
+	if err != nil {
+		return nil, err
+	}
+	var stored StoredEvent
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// GetEventOnRecord is GetEvent submitted as a transaction, so that a
+// channel with record_reads keeps an access record of the read.
+func (c *Client) GetEventOnRecord(eventID string) (*StoredEvent, error) {
+	b, err := c.submit("GetEvent", client.WithArguments(eventID, "full"))
+	if err != nil {
+		return nil, err
+	}
+	var stored StoredEvent
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+type AccessRecord struct {
+	EventID     string `json:"event_id"`
+	Producer    string `json:"producer,omitempty"`
+	ReaderMSP   string `json:"reader_msp"`
+	ReaderID    string `json:"reader_id"`
+	Transaction string `json:"transaction"`
+	TxID        string `json:"tx_id"`
+	AccessedAt  string `json:"accessed_at"`
+}
+
+type AccessPage struct {
+	Records      []AccessRecord `json:"records"`
+	FetchedCount int32          `json:"fetched_count"`
+	Bookmark     string         `json:"bookmark"`
+}
+
+// GetAccessLog returns one page of the recorded reads of an event. Empty
+// producerMSP means the client's own namespace. It needs an auditor
+// identity.
+func (c *Client) GetAccessLog(producerMSP, eventID, bookmark string, pageSize int32) (*AccessPage, error) {
+	return c.accessPage("GetAccessLog", producerMSP, eventID, bookmark, strconv.Itoa(int(pageSize)))
+}
+
+// GetAccessesByReader returns one page of the recorded reads made by
+// readerMSP. It needs an auditor identity.
+func (c *Client) GetAccessesByReader(readerMSP, bookmark string, pageSize int32) (*AccessPage, error) {
+	return c.accessPage("GetAccessesByReader", readerMSP, bookmark, strconv.Itoa(int(pageSize)))
+}
+
+func (c *Client) accessPage(name string, args ...string) (*AccessPage, error) {
+	b, err := c.evaluate(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	var page AccessPage
+	if err := json.Unmarshal(b, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// maxGetBatch is the chaincode's limit on ids per GetEventsBatch call.
+const maxGetBatch = 500
+
+type BatchGetResult struct {
+	EventID string       `json:"event_id"`
+	Found   bool         `json:"found"`
+	Event   *StoredEvent `json:"event,omitempty"`
+}
+
+// GetEvents fetches eventIDs in request order, reporting missing ones with
+// Found false. Long lists are split into GetEventsBatch calls of up to
+// maxGetBatch ids.
+func (c *Client) GetEvents(eventIDs []string) ([]BatchGetResult, error) {
+	results := make([]BatchGetResult, 0, len(eventIDs))
+	for start := 0; start < len(eventIDs); start += maxGetBatch {
+		end := start + maxGetBatch
+		if end > len(eventIDs) {
+			end = len(eventIDs)
+		}
+		ids, err := json.Marshal(eventIDs[start:end])
+		if err != nil {
+			return nil, err
+		}
+		b, err := c.evaluate("GetEventsBatch", string(ids), "full")
+		if err != nil {
+			return nil, err
+		}
+		var report struct {
+			Results []BatchGetResult `json:"results"`
+		}
+		if err := json.Unmarshal(b, &report); err != nil {
+			return nil, err
+		}
+		results = append(results, report.Results...)
+	}
+	return results, nil
+}
+
+// QueryEventsByTimeRange returns one page of events with timestamps in
+// [start, end). Pass the returned Bookmark to read the next page.
+func (c *Client) QueryEventsByTimeRange(start, end time.Time, bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("QueryEventsByTimeRange",
+		start.UTC().Format(time.RFC3339Nano),
+		end.UTC().Format(time.RFC3339Nano),
+		"full",
+		bookmark,
+		strconv.Itoa(int(pageSize)),
+	)
+}
+
+// QueryEventsByIDTime returns one page of UUIDv7 events whose ids were
+// created in [start, end), in id order. The channel must have
+// time_ordered_keys set.
+func (c *Client) QueryEventsByIDTime(start, end time.Time, bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("QueryEventsByIDTime",
+		start.UTC().Format(time.RFC3339Nano),
+		end.UTC().Format(time.RFC3339Nano),
+		"full",
+		bookmark,
+		strconv.Itoa(int(pageSize)),
+	)
+}
+
+// ListEventsByBucket returns one page of events of eventType with
+// timestamps on UTC days in [startDay, endDay), read through the
+// chaincode's bucket index. Days are YYYY-MM-DD.
+func (c *Client) ListEventsByBucket(eventType, startDay, endDay, bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("ListEventsByBucket",
+		eventType,
+		startDay,
+		endDay,
+		"full",
+		bookmark,
+		strconv.Itoa(int(pageSize)),
+	)
+}
+
+// QueryEventSummariesByTimeRange is QueryEventsByTimeRange under the
+// summary projection: event_id, event_type, timestamp and payload hash only.
+func (c *Client) QueryEventSummariesByTimeRange(start, end time.Time, bookmark string, pageSize int32) (*EventSummaryPage, error) {
+	b, err := c.evaluate("QueryEventsByTimeRange",
+		start.UTC().Format(time.RFC3339Nano),
+		end.UTC().Format(time.RFC3339Nano),
+		"summary",
+		bookmark,
+		strconv.Itoa(int(pageSize)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	var page EventSummaryPage
+	if err := json.Unmarshal(b, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// GetEventsByType returns one page of full events of eventType in timestamp
+// order.
+func (c *Client) GetEventsByType(eventType, bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("GetEventsByType", eventType, "full", bookmark, strconv.Itoa(int(pageSize)))
+}
+
+// QueryEventsBySelector runs a CouchDB selector over events. It fails on
+// channels whose peers use LevelDB.
+func (c *Client) QueryEventsBySelector(selectorJSON, bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("QueryEventsBySelector", selectorJSON, "full", bookmark, strconv.Itoa(int(pageSize)))
+}
+
+// QueryDecisionsByAgent returns one page of the AGENT_DECISION events
+// agentID made, in timestamp order.
+func (c *Client) QueryDecisionsByAgent(agentID, bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("QueryDecisionsByAgent", agentID, "full", bookmark, strconv.Itoa(int(pageSize)))
+}
+
+// QueryIngestsBySource returns one page of the INGEST events of
+// sourceSystem whose batch window starts in [start, end), in window order.
+// Pass zero times for every batch of the source.
+func (c *Client) QueryIngestsBySource(sourceSystem string, start, end time.Time, bookmark string, pageSize int32) (*EventPage, error) {
+	var from, to string
+	if !start.IsZero() || !end.IsZero() {
+		from, to = start.UTC().Format(time.RFC3339Nano), end.UTC().Format(time.RFC3339Nano)
+	}
+	return c.queryPage("QueryIngestsBySource", sourceSystem, from, to, "full", bookmark, strconv.Itoa(int(pageSize)))
+}
+
+// QueryEventsBySchemaVersion returns one page of the events written under
+// schemaVersion, in timestamp order.
+func (c *Client) QueryEventsBySchemaVersion(schemaVersion, bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("QueryEventsBySchemaVersion", schemaVersion, "full", bookmark, strconv.Itoa(int(pageSize)))
+}
+
+// GetEventsByCorrelation returns one page of the events of a pipeline run
+// in timestamp order.
+func (c *Client) GetEventsByCorrelation(correlationID, bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("GetEventsByCorrelation", correlationID, "full", bookmark, strconv.Itoa(int(pageSize)))
+}
+
+// GetEventsByArtifactRole returns one page of the events listing
+// artifactHash in their artifacts, with the given role unless it is empty.
+func (c *Client) GetEventsByArtifactRole(artifactHash, role, bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("GetEventsByArtifactRole", artifactHash, role, "full", bookmark, strconv.Itoa(int(pageSize)))
+}
+
+// ListOrgEvents returns one page of the events written by the client's MSP
+// in timestamp order.
+func (c *Client) ListOrgEvents(bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("ListOrgEvents", "full", bookmark, strconv.Itoa(int(pageSize)))
+}
+
+// ListEventsForOrg returns one page of the events written by mspID. It
+// needs an auditor identity.
+func (c *Client) ListEventsForOrg(mspID, bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("ListEventsForOrg", mspID, "full", bookmark, strconv.Itoa(int(pageSize)))
+}
+
+func (c *Client) queryPage(name string, args ...string) (*EventPage, error) {
+	b, err := c.evaluate(name, args...)
+	if err != nil {
+		return nil, err
+	}
+	var page EventPage
+	if err := json.Unmarshal(b, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// BlockEvents streams committed blocks of the channel from startBlock until
+// ctx is cancelled or the connection fails, when the channel is closed.
+func (c *Client) BlockEvents(ctx context.Context, startBlock uint64) (<-chan *common.Block, error) {
+	return c.network.BlockEvents(ctx, client.WithStartBlock(startBlock))
+}
+
+type HealthReport struct {
+	Status            string `json:"status"`
+	ChannelID         string `json:"channel_id"`
+	TxID              string `json:"tx_id"`
+	TxTime            string `json:"tx_time"`
+	ConfigInitialized bool   `json:"config_initialized"`
+}
+
+// HealthCheck evaluates the chaincode's HealthCheck transaction, failing if
+// the gateway, peer or chaincode container cannot serve it.
+func (c *Client) HealthCheck() (*HealthReport, error) {
+	b, err := c.evaluate("HealthCheck")
+	if err != nil {
+		return nil, err
+	}
+	var r HealthReport
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// ContractInfo mirrors the chaincode's GetContractInfo result. When
+// AnySchemaVersion is set, SchemaVersions is empty and any non-empty
+// schema_version is accepted.
+type ContractInfo struct {
+	ContractVersion          string          `json:"contract_version"`
+	StorageVersion           int             `json:"storage_version"`
+	ChannelID                string          `json:"channel_id"`
+	ConfigInitialized        bool            `json:"config_initialized"`
+	SchemaVersions           []string        `json:"schema_versions"`
+	AnySchemaVersion         bool            `json:"any_schema_version"`
+	DeprecatedSchemaVersions []string        `json:"deprecated_schema_versions"`
+	EventTypes               []EventType     `json:"event_types"`
+	ReservedEventTypes       []string        `json:"reserved_event_types"`
+	Collections              []string        `json:"collections"`
+	Features                 map[string]bool `json:"features"`
+	Limits                   ContractLimits  `json:"limits"`
+	Transactions             []string        `json:"transactions"`
+	// Namespaces lists the transactions of each named contract, such as
+	// "Digest", called as "Digest:CommitDigest".
+	Namespaces map[string][]string `json:"namespaces,omitempty"`
+	// StateDatabase is "leveldb" or "couchdb"; Features["rich_queries"]
+	// says whether QueryEventsBySelector can run.
+	StateDatabase string `json:"state_database,omitempty"`
+}
+
+// ContractLimits are the channel's effective limits; page sizes above
+// MaxPageSize are rejected.
+type ContractLimits struct {
+	MaxEventBytes   int   `json:"max_event_bytes"`
+	MaxBatchEvents  int   `json:"max_batch_events"`
+	MaxPageSize     int32 `json:"max_page_size"`
+	MaxQueryResults int   `json:"max_query_results"`
+	MaxDigestLeaves int   `json:"max_digest_leaves"`
+}
+
+// EventType is a built-in ("builtin") or registered ("active", "retired")
+// event type.
+type EventType struct {
+	Name           string   `json:"name"`
+	RequiredFields []string `json:"required_fields"`
+	Status         string   `json:"status"`
+	RegisteredAt   string   `json:"registered_at,omitempty"`
+	RetiredAt      string   `json:"retired_at,omitempty"`
+}
+
+// GetContractInfo reads the contract version, accepted schema versions,
+// event types and the features the channel config turns on.
+func (c *Client) GetContractInfo() (*ContractInfo, error) {
+	b, err := c.evaluate("GetContractInfo")
+	if err != nil {
+		return nil, err
+	}
+	var info ContractInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// MissingTransactions returns those of txs the chaincode does not serve.
+// Qualified names such as "Digest:GetDigest" are looked up in Namespaces.
+func (i *ContractInfo) MissingTransactions(txs ...string) []string {
+	served := make(map[string]bool, len(i.Transactions))
+	for _, t := range i.Transactions {
+		served[t] = true
+	}
+	for ns, names := range i.Namespaces {
+		for _, t := range names {
+			served[ns+":"+t] = true
+		}
+	}
+	var missing []string
+	for _, t := range txs {
+		if !served[t] {
+			missing = append(missing, t)
+		}
+	}
+	return missing
+}
+
+// Usage is one org's write count for one ledger day; Quota and Remaining
+// are 0 when the org has no quota.
+type Usage struct {
+	MSPID     string `json:"msp_id"`
+	Day       string `json:"day"`
+	Written   int    `json:"written"`
+	Quota     int    `json:"quota"`
+	Remaining int    `json:"remaining"`
+}
+
+// GetUsage returns mspID's writes on day (YYYY-MM-DD). Empty arguments mean
+// the client's own MSP and today.
+func (c *Client) GetUsage(mspID, day string) (*Usage, error) {
+	b, err := c.evaluate("GetUsage", mspID, day)
+	if err != nil {
+		return nil, err
+	}
+	var u Usage
+	if err := json.Unmarshal(b, &u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+type ReferenceCheck struct {
+	EventID     string `json:"event_id"`
+	ReferenceTx string `json:"reference_tx"`
+	Chaincode   string `json:"chaincode"`
+	Function    string `json:"function"`
+	Exists      bool   `json:"exists"`
+	Status      int32  `json:"status"`
+	Message     string `json:"message,omitempty"`
+	CheckedAt   string `json:"checked_at"`
+}
+
+// VerifyReference reports whether the record eventID names in reference_tx
+// exists in the channel's configured payments chaincode.
+func (c *Client) VerifyReference(eventID string) (*ReferenceCheck, error) {
+	b, err := c.evaluate("VerifyReference", eventID)
+	if err != nil {
+		return nil, err
+	}
+	var r ReferenceCheck
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetEventLineage returns the ancestors and descendants of eventID up to
+// depth parent_event_ids links away.
+func (c *Client) GetEventLineage(eventID string, depth int) (*LineageGraph, error) {
+	b, err := c.evaluate("GetEventLineage", eventID, strconv.Itoa(depth))
+	if err != nil {
+		return nil, err
+	}
+	var g LineageGraph
+	if err := json.Unmarshal(b, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// GetEventsByTypeWithLineage pages through events of eventType with each
+// one's parents embedded up to depth (at most 5) levels, saving a
+// GetEvent per parent.
+func (c *Client) GetEventsByTypeWithLineage(eventType string, depth int, bookmark string, pageSize int32) (*LineagePage, error) {
+	b, err := c.evaluate("GetEventsByTypeWithLineage", eventType, strconv.Itoa(depth), bookmark, strconv.Itoa(int(pageSize)))
+	if err != nil {
+		return nil, err
+	}
+	var p LineagePage
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// ArtifactRecord mirrors the chaincode's ArtifactRegistry entries. OwnerMSP,
+// RegisteredAt and TxID are set by the chaincode.
+type ArtifactRecord struct {
+	ArtifactHash  string `json:"artifact_hash"`
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	SizeBytes     int64  `json:"size_bytes"`
+	ContentType   string `json:"content_type"`
+	StorageURI    string `json:"storage_uri"`
+	OwnerMSP      string `json:"owner_msp,omitempty"`
+	RegisteredAt  string `json:"registered_at,omitempty"`
+	TxID          string `json:"tx_id,omitempty"`
+}
+
+// RegisterArtifact records a's metadata in the ArtifactRegistry contract,
+// owned by the client's MSP, and returns the stored record.
+func (c *Client) RegisterArtifact(a *ArtifactRecord) (*ArtifactRecord, error) {
+	in := *a
+	in.OwnerMSP, in.RegisteredAt, in.TxID = "", "", ""
+	b, err := json.Marshal(&in)
+	if err != nil {
+		return nil, err
+	}
+	out, err := c.submit("ArtifactRegistry:RegisterArtifact", client.WithArguments(string(b)))
+	if err != nil {
+		return nil, err
+	}
+	var rec ArtifactRecord
+	if err := json.Unmarshal(out, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// GetArtifact returns the registered metadata for artifactHash.
+func (c *Client) GetArtifact(artifactHash string) (*ArtifactRecord, error) {
+	b, err := c.evaluate("ArtifactRegistry:GetArtifact", artifactHash)
+	if err != nil {
+		return nil, err
+	}
+	var rec ArtifactRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
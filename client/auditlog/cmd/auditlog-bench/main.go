@@ -0,0 +1,109 @@
+// Command auditlog-bench drives a PutEvent or PutEvents workload against a
+// running network and reports what it sustained: committed events and
+// transactions per second, commit latency percentiles and the MVCC conflict
+// rate. Use it against a test network before changing ingest volume; the
+// chaincode's Go benchmarks measure the chaincode's CPU cost in-process
+// instead.
+//
+// Workers submit concurrently through one gateway connection until
+// -duration passes or -events are written. Every event is fresh, so a plain
+// run measures independent writes. With -chain all workers extend one
+// prev_event_hash chain, and every write races the others for the chain
+// head, which shows how the network behaves under key contention.
+//
+// Submits are not retried unless -attempts says so, so conflicts show up as
+// failed transactions. The report is JSON; the exit status is 1 when the run
+// could not start.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"payscope/client/auditlog"
+)
+
+func main() {
+	var w workload
+	profilePath := flag.String("profile", os.Getenv("AUDITLOG_BENCH_PROFILE"), "connection profile JSON")
+	flag.IntVar(&w.Workers, "workers", 8, "concurrent submitters")
+	flag.IntVar(&w.Batch, "batch", 1, "events per transaction: 1 submits PutEvent, more submit PutEvents (at most 500)")
+	flag.DurationVar(&w.Duration, "duration", 30*time.Second, "how long to run")
+	flag.IntVar(&w.Events, "events", 0, "stop after this many events are committed (0: run for -duration)")
+	flag.Float64Var(&w.Rate, "rate", 0, "transactions per second across all workers (0: as fast as they go)")
+	flag.StringVar(&w.EventType, "event-type", "INGEST", "event_type of the written events")
+	flag.StringVar(&w.SchemaVersion, "schema-version", "v1", "schema_version of the written events")
+	flag.IntVar(&w.ArtifactBytes, "artifact-bytes", 256, "size of the random artifact hashed into each event")
+	flag.BoolVar(&w.Chain, "chain", false, "chain every event to the last committed one, so writes contend (requires -batch 1)")
+	flag.IntVar(&w.Attempts, "attempts", 1, "submit attempts per transaction; more than 1 retries conflicts")
+	out := flag.String("out", "-", "report file, or - for stdout")
+	flag.Parse()
+
+	if *profilePath == "" {
+		log.Fatal("auditlog-bench: -profile (or AUDITLOG_BENCH_PROFILE) is required")
+	}
+	if err := w.validate(); err != nil {
+		log.Fatalf("auditlog-bench: %v", err)
+	}
+	profile, err := auditlog.LoadProfile(*profilePath)
+	if err != nil {
+		log.Fatalf("auditlog-bench: %v", err)
+	}
+	stats := newStats()
+	policy := auditlog.DefaultRetryPolicy()
+	policy.MaxAttempts = w.Attempts
+	c, err := auditlog.Connect(profile, auditlog.WithRetryPolicy(policy), auditlog.WithSubmitHook(stats.attempt))
+	if err != nil {
+		log.Fatalf("auditlog-bench: %v", err)
+	}
+	defer c.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	log.Printf("auditlog-bench: %d workers, %d events per transaction, for %s", w.Workers, w.Batch, w.Duration)
+	rep := w.run(ctx, c, stats)
+	rep.Channel, rep.Chaincode = profile.Channel, profile.Chaincode
+
+	dst := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("auditlog-bench: %v", err)
+		}
+		defer f.Close()
+		dst = f
+	}
+	enc := json.NewEncoder(dst)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rep); err != nil {
+		log.Fatalf("auditlog-bench: %v", err)
+	}
+}
+
+func (w *workload) validate() error {
+	switch {
+	case w.Workers < 1:
+		return fmt.Errorf("-workers must be positive")
+	case w.Batch < 1 || w.Batch > 500:
+		return fmt.Errorf("-batch must be within 1-500")
+	case w.Duration <= 0:
+		return fmt.Errorf("-duration must be positive")
+	case w.Events < 0 || w.Rate < 0:
+		return fmt.Errorf("-events and -rate must not be negative")
+	case w.ArtifactBytes < 1:
+		return fmt.Errorf("-artifact-bytes must be positive")
+	case w.Attempts < 1:
+		return fmt.Errorf("-attempts must be positive")
+	case w.Chain && w.Batch != 1:
+		return fmt.Errorf("-chain requires -batch 1")
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"payscope/client/auditlog"
+)
+
+// report is the machine-readable result of a run. Commit latency is the
+// duration of each successful submit attempt, from endorsement to the
+// commit status; rates are over the elapsed time of the whole run.
+type report struct {
+	Channel   string   `json:"channel"`
+	Chaincode string   `json:"chaincode"`
+	Workload  workload `json:"workload"`
+	// The -duration limit; a run stopped by -events or a signal is shorter.
+	Duration       string  `json:"duration"`
+	StartedAt      string  `json:"started_at"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+
+	Transactions          int     `json:"transactions_committed"`
+	FailedTransactions    int     `json:"transactions_failed"`
+	Events                int     `json:"events_committed"`
+	RejectedEvents        int     `json:"events_rejected"`
+	EventsPerSecond       float64 `json:"events_per_second"`
+	TransactionsPerSecond float64 `json:"transactions_per_second"`
+	CommitLatencyMS       latency `json:"commit_latency_ms"`
+
+	// Every submit attempt, retries included. Conflicts are the attempts
+	// that failed validation with MVCC_READ_CONFLICT or
+	// PHANTOM_READ_CONFLICT.
+	Attempts     int     `json:"attempts"`
+	Retries      int     `json:"retries"`
+	Conflicts    int     `json:"mvcc_conflicts"`
+	ConflictRate float64 `json:"mvcc_conflict_rate"`
+	// Failed transactions by kind: mvcc_conflict, unavailable,
+	// unknown_commit, the chaincode's error code, or other. ErrorSamples
+	// holds the first error of each kind.
+	Failures     map[string]int    `json:"failures"`
+	ErrorSamples map[string]string `json:"error_samples,omitempty"`
+}
+
+type latency struct {
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P90  float64 `json:"p90"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// stats collects the run's outcomes from the workers and from the
+// client's submit hook.
+type stats struct {
+	mu           sync.Mutex
+	attempts     int
+	retries      int
+	conflicts    int
+	txCommitted  int
+	txFailed     int
+	events       int
+	rejected     int
+	latencies    []time.Duration
+	failures     map[string]int
+	errorSamples map[string]string
+}
+
+func newStats() *stats {
+	return &stats{failures: map[string]int{}, errorSamples: map[string]string{}}
+}
+
+// attempt is the client's submit hook.
+func (s *stats) attempt(a auditlog.SubmitAttempt) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if a.Retrying {
+		s.retries++
+	}
+	if a.Failure == "conflict" {
+		s.conflicts++
+	}
+	switch {
+	case a.Err == nil:
+		s.latencies = append(s.latencies, a.Duration)
+	case !a.Retrying:
+		kind := failureKind(a)
+		s.failures[kind]++
+		if _, ok := s.errorSamples[kind]; !ok {
+			s.errorSamples[kind] = a.Err.Error()
+		}
+	}
+}
+
+func failureKind(a auditlog.SubmitAttempt) string {
+	switch {
+	case a.Failure == "conflict":
+		return "mvcc_conflict"
+	case a.Failure != "":
+		return a.Failure
+	}
+	if ce, ok := auditlog.AsContractError(a.Err); ok {
+		return ce.Code
+	}
+	return "other"
+}
+
+func (s *stats) committed(events, rejected int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txCommitted++
+	s.events += events
+	s.rejected += rejected
+}
+
+func (s *stats) failed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.txFailed++
+}
+
+func (s *stats) committedEvents() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.events
+}
+
+func (s *stats) report(w *workload, start time.Time, elapsed time.Duration) *report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	secs := elapsed.Seconds()
+	r := &report{
+		Workload:              *w,
+		Duration:              w.Duration.String(),
+		StartedAt:             start.UTC().Format(time.RFC3339),
+		ElapsedSeconds:        secs,
+		Transactions:          s.txCommitted,
+		FailedTransactions:    s.txFailed,
+		Events:                s.events,
+		RejectedEvents:        s.rejected,
+		EventsPerSecond:       float64(s.events) / secs,
+		TransactionsPerSecond: float64(s.txCommitted) / secs,
+		CommitLatencyMS:       summarize(s.latencies),
+		Attempts:              s.attempts,
+		Retries:               s.retries,
+		Conflicts:             s.conflicts,
+		Failures:              s.failures,
+		ErrorSamples:          s.errorSamples,
+	}
+	if s.attempts > 0 {
+		r.ConflictRate = float64(s.conflicts) / float64(s.attempts)
+	}
+	return r
+}
+
+// summarize returns the mean, nearest-rank percentiles and maximum of ds in
+// milliseconds.
+func summarize(ds []time.Duration) latency {
+	if len(ds) == 0 {
+		return latency{}
+	}
+	sorted := append([]time.Duration(nil), ds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	pct := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return ms(sorted[max(i, 0)])
+	}
+	var sum time.Duration
+	for _, d := range sorted {
+		sum += d
+	}
+	return latency{
+		Mean: ms(sum / time.Duration(len(sorted))),
+		P50:  pct(0.50),
+		P90:  pct(0.90),
+		P95:  pct(0.95),
+		P99:  pct(0.99),
+		Max:  ms(sorted[len(sorted)-1]),
+	}
+}
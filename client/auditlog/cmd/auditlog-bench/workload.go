@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"log"
+	"sync"
+	"time"
+
+	"payscope/client/auditlog"
+)
+
+// workload is what the run submits; it is echoed in the report.
+type workload struct {
+	Workers       int           `json:"workers"`
+	Batch         int           `json:"batch"`
+	Duration      time.Duration `json:"-"`
+	Events        int           `json:"events,omitempty"`
+	Rate          float64       `json:"rate,omitempty"`
+	EventType     string        `json:"event_type"`
+	SchemaVersion string        `json:"schema_version"`
+	ArtifactBytes int           `json:"artifact_bytes"`
+	Chain         bool          `json:"chain,omitempty"`
+	Attempts      int           `json:"attempts"`
+}
+
+// chainHead is the payload hash the next chained event links to.
+type chainHead struct {
+	mu   sync.Mutex
+	hash string
+}
+
+func (h *chainHead) get() string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.hash
+}
+
+func (h *chainHead) set(hash string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hash = hash
+}
+
+// run submits until ctx is done, the duration passes or enough events are
+// committed, then waits for in-flight transactions and reports.
+func (w *workload) run(ctx context.Context, c *auditlog.Client, stats *stats) *report {
+	ctx, cancel := context.WithTimeout(ctx, w.Duration)
+	defer cancel()
+	var tick <-chan time.Time
+	if w.Rate > 0 {
+		t := time.NewTicker(time.Duration(float64(time.Second) / w.Rate))
+		defer t.Stop()
+		tick = t.C
+	}
+
+	head := &chainHead{}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < w.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if tick != nil {
+					select {
+					case <-tick:
+					case <-ctx.Done():
+						return
+					}
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				if w.submit(c, stats, head) && w.Events > 0 && stats.committedEvents() >= w.Events {
+					cancel()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return stats.report(w, start, time.Since(start))
+}
+
+// submit writes one transaction of fresh events and reports whether it
+// committed. Events deduped on a retry count as committed: an earlier
+// attempt wrote them.
+func (w *workload) submit(c *auditlog.Client, stats *stats, head *chainHead) bool {
+	// Read the head before stamping the event, so its timestamp is not
+	// earlier than the head's.
+	prev := ""
+	if w.Chain {
+		prev = head.get()
+	}
+	events := make([]*auditlog.Event, w.Batch)
+	for i := range events {
+		e, err := w.newEvent()
+		if err != nil {
+			log.Fatalf("auditlog-bench: %v", err)
+		}
+		events[i] = e
+	}
+	events[0].PrevEventHash = prev
+	if w.Batch == 1 {
+		r, err := c.PutEvent(events[0])
+		if err != nil {
+			stats.failed()
+			return false
+		}
+		if w.Chain {
+			head.set(r.PayloadHash)
+		}
+		stats.committed(1, 0)
+		return true
+	}
+	res, err := c.PutEvents(events)
+	if err != nil {
+		stats.failed()
+		return false
+	}
+	stats.committed(res.Summary.Written+res.Summary.Deduped, res.Summary.Rejected)
+	return true
+}
+
+func (w *workload) newEvent() (*auditlog.Event, error) {
+	artifact := make([]byte, w.ArtifactBytes)
+	if _, err := rand.Read(artifact); err != nil {
+		return nil, err
+	}
+	return auditlog.NewEvent(w.EventType, w.SchemaVersion, artifact)
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// config is the gateway's JSON configuration. Profile paths are resolved
+// against the config file's directory.
+type config struct {
+	Listen string     `json:"listen"`
+	OIDC   oidcConfig `json:"oidc"`
+	// Connection profile of each Fabric identity the gateway submits as,
+	// by name.
+	Identities map[string]string `json:"identities"`
+	// Token claims to identities; the first mapping the token matches wins.
+	Mappings []identityMapping `json:"mappings"`
+	// Largest request body accepted; default 4 MiB.
+	MaxBodyBytes int64 `json:"max_body_bytes,omitempty"`
+	// Start without checking each identity's chaincode with
+	// GetContractInfo, e.g. against a build that predates it.
+	SkipContractCheck bool `json:"skip_contract_check,omitempty"`
+}
+
+type oidcConfig struct {
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+	// Allowed clock skew on exp and nbf; default 60 seconds.
+	LeewaySeconds int `json:"leeway_seconds,omitempty"`
+}
+
+// identityMapping selects Identity for tokens whose Claim equals Value, or
+// contains it when the claim is a list such as groups.
+type identityMapping struct {
+	Claim    string `json:"claim"`
+	Value    string `json:"value"`
+	Identity string `json:"identity"`
+}
+
+const defaultMaxBodyBytes = 4 << 20
+
+func loadConfig(path string) (*config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for name, p := range c.Identities {
+		if p != "" && !filepath.IsAbs(p) {
+			c.Identities[name] = filepath.Join(dir, p)
+		}
+	}
+	if c.Listen == "" {
+		c.Listen = ":8080"
+	}
+	if c.MaxBodyBytes == 0 {
+		c.MaxBodyBytes = defaultMaxBodyBytes
+	}
+	if c.OIDC.LeewaySeconds == 0 {
+		c.OIDC.LeewaySeconds = 60
+	}
+	return &c, c.validate()
+}
+
+func (c *config) validate() error {
+	if c.OIDC.Issuer == "" || c.OIDC.Audience == "" {
+		return fmt.Errorf("config: oidc.issuer and oidc.audience required")
+	}
+	if len(c.Identities) == 0 {
+		return fmt.Errorf("config: at least one identity required")
+	}
+	if len(c.Mappings) == 0 {
+		return fmt.Errorf("config: at least one mapping required")
+	}
+	for i, m := range c.Mappings {
+		if m.Claim == "" || m.Value == "" {
+			return fmt.Errorf("config: mappings[%d]: claim and value required", i)
+		}
+		if _, ok := c.Identities[m.Identity]; !ok {
+			return fmt.Errorf("config: mappings[%d]: unknown identity %q", i, m.Identity)
+		}
+	}
+	if c.MaxBodyBytes < 0 || c.OIDC.LeewaySeconds < 0 {
+		return fmt.Errorf("config: max_body_bytes and oidc.leeway_seconds must not be negative")
+	}
+	return nil
+}
+
+func (c *oidcConfig) leeway() time.Duration {
+	return time.Duration(c.LeewaySeconds) * time.Second
+}
+
+// identityFor returns the identity the first matching mapping names for
+// claims, or "" when none matches.
+func (c *config) identityFor(claims map[string]any) string {
+	for _, m := range c.Mappings {
+		switch v := claims[m.Claim].(type) {
+		case string:
+			if v == m.Value {
+				return m.Identity
+			}
+		case []any:
+			for _, item := range v {
+				if s, ok := item.(string); ok && s == m.Value {
+					return m.Identity
+				}
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,129 @@
+// Command auditlog-gateway serves the audit log chaincode over plain HTTP
+// for producers that are not Fabric-aware.
+//
+// Callers authenticate with an OIDC bearer token. The gateway verifies it
+// against the issuer's published keys and maps its claims to one of the
+// Fabric identities it holds, which then submits or evaluates the
+// transaction through the Fabric Gateway:
+//
+//	POST /events        one event object (PutEvent) or an array (PutEvents)
+//	GET  /events/{id}   GetEvent
+//	GET  /events        one page of events, by a single filter
+//	GET  /healthz       liveness, without authentication
+//
+// At startup each identity's chaincode is asked for GetContractInfo, and the
+// gateway refuses to start when it lacks a transaction a route calls.
+//
+// Requests are traced with OpenTelemetry, continuing a W3C traceparent
+// header when the caller sends one, through to the chaincode.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"payscope/client/auditlog"
+)
+
+func main() {
+	configPath := flag.String("config", os.Getenv("AUDITLOG_GATEWAY_CONFIG"), "gateway config JSON")
+	flag.Parse()
+
+	if *configPath == "" {
+		log.Fatal("auditlog-gateway: -config (or AUDITLOG_GATEWAY_CONFIG) is required")
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("auditlog-gateway: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := setupTracing(ctx)
+	if err != nil {
+		log.Fatalf("auditlog-gateway: tracing: %v", err)
+	}
+	defer func() {
+		flush, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flush); err != nil {
+			log.Printf("auditlog-gateway: tracing: %v", err)
+		}
+	}()
+
+	v, err := newVerifier(ctx, cfg.OIDC)
+	if err != nil {
+		log.Fatalf("auditlog-gateway: %v", err)
+	}
+	clients := make(map[string]*auditlog.Client, len(cfg.Identities))
+	for name, path := range cfg.Identities {
+		profile, err := auditlog.LoadProfile(path)
+		if err != nil {
+			log.Fatalf("auditlog-gateway: identity %s: %v", name, err)
+		}
+		c, err := auditlog.Connect(profile)
+		if err != nil {
+			log.Fatalf("auditlog-gateway: identity %s: %v", name, err)
+		}
+		defer c.Close()
+		if !cfg.SkipContractCheck {
+			if err := checkContract(c); err != nil {
+				log.Fatalf("auditlog-gateway: identity %s: %v", name, err)
+			}
+		}
+		clients[name] = c
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           &gateway{cfg: cfg, verifier: v, clients: clients},
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := srv.Shutdown(shutdown); err != nil {
+			log.Printf("auditlog-gateway: shutdown: %v", err)
+		}
+	}()
+	log.Printf("auditlog-gateway: listening on %s for %d identities", cfg.Listen, len(clients))
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("auditlog-gateway: %v", err)
+	}
+	log.Printf("auditlog-gateway: stopped")
+}
+
+// routedTransactions are the chaincode transactions the REST routes call.
+var routedTransactions = []string{
+	"PutEvent", "PutEvents", "GetEvent", "GetEventsByType", "QueryIngestsBySource",
+	"QueryDecisionsByAgent", "GetEventsByCorrelation", "QueryEventsBySchemaVersion",
+	"ListEventsForOrg", "QueryEventsByTimeRange", "ListOrgEvents",
+}
+
+// checkContract fails when the chaincode behind c cannot serve every route,
+// so a mismatched deployment stops at startup rather than on a request.
+func checkContract(c *auditlog.Client) error {
+	info, err := c.GetContractInfo()
+	if err != nil {
+		return fmt.Errorf("contract info: %w", err)
+	}
+	if missing := info.MissingTransactions(routedTransactions...); len(missing) > 0 {
+		return fmt.Errorf("chaincode %s lacks %s", info.ContractVersion, strings.Join(missing, ", "))
+	}
+	if !info.ConfigInitialized {
+		log.Printf("auditlog-gateway: channel %s has no contract config; Init has not run", info.ChannelID)
+	}
+	log.Printf("auditlog-gateway: chaincode %s on channel %s", info.ContractVersion, info.ChannelID)
+	return nil
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"payscope/client/auditlog"
+)
+
+// gateway serves the REST API over one audit log client per configured
+// Fabric identity.
+type gateway struct {
+	cfg      *config
+	verifier *verifier
+	clients  map[string]*auditlog.Client
+}
+
+const defaultPageSize = 100
+
+// errorBody is the chaincode's error envelope, which the gateway also uses
+// for its own errors so callers handle a single shape.
+type errorBody = auditlog.ContractError
+
+func (g *gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/healthz" {
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		return
+	}
+
+	var route func(http.ResponseWriter, *http.Request, *auditlog.Client)
+	var pattern string
+	switch path := r.URL.Path; {
+	case path == "/events":
+		pattern = "/events"
+		switch r.Method {
+		case http.MethodPost:
+			route = g.postEvents
+		case http.MethodGet:
+			route = g.listEvents
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+			return
+		}
+	case strings.HasPrefix(path, "/events/") && !strings.Contains(path[len("/events/"):], "/"):
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		route, pattern = g.getEvent, "/events/{id}"
+	default:
+		writeError(w, http.StatusNotFound, &errorBody{Code: "ERR_NOT_FOUND", Message: "no such endpoint"})
+		return
+	}
+
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := tracer.Start(ctx, r.Method+" "+pattern,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.method", r.Method),
+			attribute.String("http.route", pattern),
+		))
+	defer span.End()
+	r = r.WithContext(ctx)
+
+	subject, c, errBody, code := g.authenticate(r)
+	if errBody != nil {
+		if code == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="auditlog"`)
+		}
+		span.SetAttributes(attribute.Int("http.status_code", code))
+		writeError(w, code, errBody)
+		return
+	}
+	span.SetAttributes(attribute.String("enduser.id", subject))
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	start := time.Now()
+	route(rec, r, c.WithContext(ctx))
+	span.SetAttributes(attribute.Int("http.status_code", rec.status))
+	if rec.status >= 500 {
+		span.SetStatus(otelcodes.Error, http.StatusText(rec.status))
+	}
+	log.Printf("auditlog-gateway: %s %s sub=%s status=%d in %s", r.Method, r.URL.Path, subject, rec.status, time.Since(start).Round(time.Millisecond))
+}
+
+// authenticate verifies the request's bearer token and returns its subject
+// and the client of the identity it maps to, or the error to answer with.
+func (g *gateway) authenticate(r *http.Request) (string, *auditlog.Client, *errorBody, int) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", nil, &errorBody{Code: "ERR_UNAUTHENTICATED", Message: "bearer token required"}, http.StatusUnauthorized
+	}
+	claims, err := g.verifier.verify(r.Context(), token)
+	if err != nil {
+		if errors.Is(err, errUnauthenticated) {
+			return "", nil, &errorBody{Code: "ERR_UNAUTHENTICATED", Message: err.Error()}, http.StatusUnauthorized
+		}
+		log.Printf("auditlog-gateway: token verification: %v", err)
+		return "", nil, &errorBody{Code: "ERR_UNAVAILABLE", Message: "identity provider unavailable"}, http.StatusServiceUnavailable
+	}
+	subject, _ := claims["sub"].(string)
+	name := g.cfg.identityFor(claims)
+	if name == "" {
+		return subject, nil, &errorBody{Code: "ERR_FORBIDDEN", Message: "no Fabric identity is mapped to this token"}, http.StatusForbidden
+	}
+	return subject, g.clients[name], nil, 0
+}
+
+// postEvents records one event, given as a JSON object, or a batch of up to
+// 500, given as an array.
+func (g *gateway) postEvents(w http.ResponseWriter, r *http.Request, c *auditlog.Client) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, g.cfg.MaxBodyBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeError(w, http.StatusRequestEntityTooLarge, &errorBody{Code: "ERR_TOO_LARGE", Message: fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit)})
+			return
+		}
+		writeError(w, http.StatusBadRequest, &errorBody{Code: "ERR_BAD_REQUEST", Message: err.Error()})
+		return
+	}
+
+	// Unknown fields are refused rather than dropped: the chaincode hashes
+	// the event as submitted here, which must be the event the producer sent.
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		var events []*auditlog.Event
+		if err := dec.Decode(&events); err != nil {
+			writeError(w, http.StatusBadRequest, &errorBody{Code: "ERR_VALIDATION_JSON", Message: "invalid json: " + err.Error()})
+			return
+		}
+		res, err := c.PutEvents(events)
+		if err != nil {
+			writeUpstreamError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, res)
+		return
+	}
+	var e auditlog.Event
+	if err := dec.Decode(&e); err != nil {
+		writeError(w, http.StatusBadRequest, &errorBody{Code: "ERR_VALIDATION_JSON", Message: "invalid json: " + err.Error()})
+		return
+	}
+	receipt, err := c.PutEvent(&e)
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	code := http.StatusCreated
+	if receipt.Deduped {
+		code = http.StatusOK
+	}
+	w.Header().Set("Location", "/events/"+e.EventID)
+	writeJSON(w, code, receipt)
+}
+
+func (g *gateway) getEvent(w http.ResponseWriter, r *http.Request, c *auditlog.Client) {
+	stored, err := c.GetEvent(strings.TrimPrefix(r.URL.Path, "/events/"))
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stored)
+}
+
+// listEvents returns one page of events chosen by at most one filter:
+// type, source (optionally with start and end on the batch window), agent,
+// correlation_id, schema_version or org, or start and end on the event
+// timestamp. With none it lists the identity's own organization's events.
+func (g *gateway) listEvents(w http.ResponseWriter, r *http.Request, c *auditlog.Client) {
+	q := r.URL.Query()
+	bookmark := q.Get("bookmark")
+	pageSize := int32(defaultPageSize)
+	if s := q.Get("page_size"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 32)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, &errorBody{Code: "ERR_BAD_REQUEST", Message: "page_size must be an integer", Field: "page_size"})
+			return
+		}
+		pageSize = int32(n)
+	}
+	start, err := queryTime(q.Get("start"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, &errorBody{Code: "ERR_BAD_REQUEST", Message: err.Error(), Field: "start"})
+		return
+	}
+	end, err := queryTime(q.Get("end"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, &errorBody{Code: "ERR_BAD_REQUEST", Message: err.Error(), Field: "end"})
+		return
+	}
+	timed := q.Has("start") || q.Has("end")
+
+	var filters []string
+	for _, f := range []string{"type", "source", "agent", "correlation_id", "schema_version", "org"} {
+		if q.Has(f) {
+			filters = append(filters, f)
+		}
+	}
+	if len(filters) > 1 || (timed && len(filters) == 1 && filters[0] != "source") {
+		writeError(w, http.StatusBadRequest, &errorBody{Code: "ERR_BAD_REQUEST", Message: "use one filter per query; start and end combine only with source"})
+		return
+	}
+
+	var page *auditlog.EventPage
+	switch {
+	case q.Has("type"):
+		page, err = c.GetEventsByType(q.Get("type"), bookmark, pageSize)
+	case q.Has("source"):
+		page, err = c.QueryIngestsBySource(q.Get("source"), start, end, bookmark, pageSize)
+	case q.Has("agent"):
+		page, err = c.QueryDecisionsByAgent(q.Get("agent"), bookmark, pageSize)
+	case q.Has("correlation_id"):
+		page, err = c.GetEventsByCorrelation(q.Get("correlation_id"), bookmark, pageSize)
+	case q.Has("schema_version"):
+		page, err = c.QueryEventsBySchemaVersion(q.Get("schema_version"), bookmark, pageSize)
+	case q.Has("org"):
+		page, err = c.ListEventsForOrg(q.Get("org"), bookmark, pageSize)
+	case timed:
+		if start.IsZero() || end.IsZero() {
+			writeError(w, http.StatusBadRequest, &errorBody{Code: "ERR_BAD_REQUEST", Message: "start and end are both required"})
+			return
+		}
+		page, err = c.QueryEventsByTimeRange(start, end, bookmark, pageSize)
+	default:
+		page, err = c.ListOrgEvents(bookmark, pageSize)
+	}
+	if err != nil {
+		writeUpstreamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func queryTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not an RFC3339 time", s)
+	}
+	return t, nil
+}
+
+// contractStatus maps the chaincode's stable error codes to HTTP statuses.
+var contractStatus = map[string]int{
+	"ERR_NOT_FOUND":      http.StatusNotFound,
+	"ERR_IDEMPOTENCY":    http.StatusConflict,
+	"ERR_FORBIDDEN":      http.StatusForbidden,
+	"ERR_CONFLICT":       http.StatusConflict,
+	"ERR_PRECONDITION":   http.StatusPreconditionFailed,
+	"ERR_QUOTA_EXCEEDED": http.StatusTooManyRequests,
+	"ERR_TOO_LARGE":      http.StatusRequestEntityTooLarge,
+	"ERR_UNSUPPORTED":    http.StatusNotImplemented,
+	"ERR_INTERNAL":       http.StatusBadGateway,
+}
+
+// writeUpstreamError answers with the chaincode's error envelope when the
+// transaction failed in the contract, and with a gateway error when Fabric
+// could not be reached.
+func writeUpstreamError(w http.ResponseWriter, err error) {
+	if ce, ok := auditlog.AsContractError(err); ok {
+		code, ok := contractStatus[ce.Code]
+		if !ok {
+			// ERR_BAD_REQUEST and the ERR_VALIDATION_* family.
+			code = http.StatusBadRequest
+		}
+		writeError(w, code, ce)
+		return
+	}
+	log.Printf("auditlog-gateway: fabric: %v", err)
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		writeError(w, http.StatusServiceUnavailable, &errorBody{Code: "ERR_UNAVAILABLE", Message: "ledger unavailable"})
+	default:
+		writeError(w, http.StatusBadGateway, &errorBody{Code: "ERR_UPSTREAM", Message: "ledger request failed"})
+	}
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, &errorBody{Code: "ERR_BAD_REQUEST", Message: "method not allowed"})
+}
+
+func writeError(w http.ResponseWriter, code int, body *errorBody) {
+	writeJSON(w, code, body)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("auditlog-gateway: write response: %v", err)
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
@@ -0,0 +1,253 @@
+// Command auditlog-verify replays the channel's blocks and checks that the
+// audit log they record is intact, independently of the chaincode.
+//
+// It checks each block's header chain and data hash, recomputes the
+// payload hash of every event record written by a valid transaction,
+// follows prev_event_hash chains and recomputes the Merkle root of every
+// committed digest. It then compares the event keyspace of the state
+// database, from a live ExportSnapshot or a file written by payscope-audit
+// snapshot, against the last write of each key, which exposes records
+// changed in the state database behind the peer's back. Blocks come from a
+// peer or, offline, from files fetched with `peer channel fetch`.
+//
+// The report is JSON. The exit status is 0 when the log is intact, 2 when
+// the run found issues and 1 when it could not complete.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"google.golang.org/protobuf/proto"
+
+	"payscope/client/auditlog"
+)
+
+func main() {
+	profilePath := flag.String("profile", os.Getenv("AUDITLOG_VERIFY_PROFILE"), "connection profile JSON, to read blocks and state from a peer")
+	blocksDir := flag.String("blocks", "", "directory of .block files to replay instead of reading from a peer")
+	chaincode := flag.String("chaincode", "", "chaincode name (default: the profile's, or auditlog)")
+	snapshotPath := flag.String("snapshot", "", "payscope-audit snapshot file to compare the state against (default: a live snapshot from the peer)")
+	noState := flag.Bool("no-state", false, "skip the state database comparison")
+	fromBlock := flag.Uint64("from-block", 0, "first block to replay")
+	toBlock := flag.Int64("to-block", -1, "last block to replay (default: the peer's last block)")
+	out := flag.String("out", "-", "report file, or - for stdout")
+	flag.IntVar(&maxListed, "max-issues", maxListed, "most issues and warnings to list")
+	flag.Parse()
+
+	if (*profilePath == "") == (*blocksDir == "") {
+		log.Fatal("auditlog-verify: one of -profile (or AUDITLOG_VERIFY_PROFILE) and -blocks is required")
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	rep := &report{Chaincode: *chaincode, FromBlock: *fromBlock, Intact: true, IssueCounts: map[string]int{}, Issues: []issue{}, Warnings: []issue{}}
+	var err error
+	if *blocksDir != "" {
+		err = verifyFiles(rep, *blocksDir, *snapshotPath, *noState, *toBlock)
+	} else {
+		err = verifyPeer(ctx, rep, *profilePath, *snapshotPath, *noState, *toBlock)
+	}
+	if err != nil {
+		log.Fatalf("auditlog-verify: %v", err)
+	}
+	rep.GeneratedAt = time.Now().UTC().Format(time.RFC3339)
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatalf("auditlog-verify: %v", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rep); err != nil {
+		log.Fatalf("auditlog-verify: %v", err)
+	}
+	if !rep.Intact {
+		fmt.Fprintf(os.Stderr, "auditlog-verify: %d issues found\n", len(rep.Issues))
+		os.Exit(2)
+	}
+}
+
+// verifyPeer replays blocks streamed from the peer and compares the state
+// from a live snapshot, or the snapshot file when one is given. The height
+// is read before and after a live snapshot; keys written in between are
+// left out of the comparison.
+func verifyPeer(ctx context.Context, rep *report, profilePath, snapshotPath string, noState bool, toBlock int64) error {
+	profile, err := auditlog.LoadProfile(profilePath)
+	if err != nil {
+		return err
+	}
+	if rep.Chaincode == "" {
+		rep.Chaincode = profile.Chaincode
+	}
+	rep.Channel, rep.Source = profile.Channel, "peer"
+	c, err := auditlog.Connect(profile)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	height, err := c.BlockHeight()
+	if err != nil {
+		return err
+	}
+	changedFrom := height
+	var snapshot []auditlog.SnapshotRecord
+	sum := &stateSummary{Source: snapshotPath}
+	skip := ""
+	switch {
+	case noState:
+		skip = "disabled with -no-state"
+	case snapshotPath != "":
+		if snapshot, err = readSnapshot(snapshotPath); err != nil {
+			return err
+		}
+	case toBlock >= 0:
+		skip = "a live snapshot needs the replay to reach the last block; pass -snapshot"
+	default:
+		sum.Source = "peer"
+		if _, err := c.ExportSnapshot(500, func(page *auditlog.SnapshotPage) error {
+			snapshot = append(snapshot, page.Records...)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+		if height, err = c.BlockHeight(); err != nil {
+			return err
+		}
+	}
+
+	last := height - 1
+	if toBlock >= 0 {
+		last = uint64(toBlock)
+	}
+	if height == 0 || last < rep.FromBlock {
+		return fmt.Errorf("no blocks to replay from %d", rep.FromBlock)
+	}
+	r := newReplayer(rep.Chaincode, rep)
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	blocks, err := c.BlockEvents(streamCtx, rep.FromBlock)
+	if err != nil {
+		return err
+	}
+	for block := range blocks {
+		if err := r.block(block); err != nil {
+			return err
+		}
+		if block.GetHeader().GetNumber() >= last {
+			break
+		}
+	}
+	if !r.started || rep.ToBlock < last {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("block stream ended before block %d", last)
+	}
+	if snapshotPath != "" {
+		// A snapshot file is taken to be of the replayed height.
+		changedFrom = last + 1
+	}
+	r.finish(sum, snapshot, skip, changedFrom)
+	return nil
+}
+
+// verifyFiles replays the .block files in dir, ordered by block number,
+// and compares the state from the snapshot file when one is given.
+func verifyFiles(rep *report, dir, snapshotPath string, noState bool, toBlock int64) error {
+	if rep.Chaincode == "" {
+		rep.Chaincode = "auditlog"
+	}
+	rep.Source = dir
+	paths, err := filepath.Glob(filepath.Join(dir, "*.block"))
+	if err != nil {
+		return err
+	}
+	blocks := make([]*common.Block, 0, len(paths))
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		block := &common.Block{}
+		if err := proto.Unmarshal(b, block); err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		n := block.GetHeader().GetNumber()
+		if n < rep.FromBlock || (toBlock >= 0 && n > uint64(toBlock)) {
+			continue
+		}
+		blocks = append(blocks, block)
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("no blocks in %s from block %d", dir, rep.FromBlock)
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].GetHeader().GetNumber() < blocks[j].GetHeader().GetNumber() })
+	rep.Channel = blockChannel(blocks[0])
+
+	r := newReplayer(rep.Chaincode, rep)
+	for _, block := range blocks {
+		if err := r.block(block); err != nil {
+			return err
+		}
+	}
+	var snapshot []auditlog.SnapshotRecord
+	skip := ""
+	switch {
+	case noState:
+		skip = "disabled with -no-state"
+	case snapshotPath == "":
+		skip = "no -snapshot given"
+	default:
+		if snapshot, err = readSnapshot(snapshotPath); err != nil {
+			return err
+		}
+	}
+	r.finish(&stateSummary{Source: snapshotPath}, snapshot, skip, rep.ToBlock+1)
+	return nil
+}
+
+// finish runs the state comparison unless skip says why not. It needs the
+// whole ledger replayed.
+func (r *replayer) finish(sum *stateSummary, snapshot []auditlog.SnapshotRecord, skip string, changedFrom uint64) {
+	switch {
+	case skip != "":
+		r.rep.warn(issue{Kind: issueStateSkipped, Detail: skip})
+	case !r.fromGenesis():
+		r.rep.warn(issue{Kind: issueStateSkipped, Detail: "state comparison needs a replay from block 0"})
+	default:
+		r.compareState(sum, snapshot, changedFrom)
+	}
+}
+
+func blockChannel(block *common.Block) string {
+	data := block.GetData().GetData()
+	if len(data) == 0 {
+		return ""
+	}
+	env := &common.Envelope{}
+	payload := &common.Payload{}
+	chdr := &common.ChannelHeader{}
+	if proto.Unmarshal(data[0], env) != nil || proto.Unmarshal(env.GetPayload(), payload) != nil ||
+		proto.Unmarshal(payload.GetHeader().GetChannelHeader(), chdr) != nil {
+		return ""
+	}
+	return chdr.GetChannelId()
+}
@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+
+	"payscope/auditlog/jcs"
+	"payscope/client/auditlog"
+)
+
+// World state key prefixes of the chaincode; see namespace.go, salt.go and
+// merkle.go there.
+const (
+	eventKeyPrefix        = "event:"
+	saltKeyPrefix         = "salt:"
+	digestKeyPrefix       = "digest:"
+	digestLeavesKeyPrefix = "digest_leaves:"
+)
+
+// record is the part of a stored event record the checks read. Event stays
+// raw: its hash is recomputed from the bytes on the ledger, not from a
+// decoded copy, so the check does not depend on this tool's view of the
+// event schema.
+type record struct {
+	Event            json.RawMessage `json:"event"`
+	PayloadHash      string          `json:"payload_hash_sha256"`
+	SaltID           string          `json:"salt_id"`
+	Canonicalization string          `json:"canonicalization"`
+	// Set on tombstones, which no longer hold what was hashed.
+	Deletion json.RawMessage `json:"deletion"`
+}
+
+type eventFields struct {
+	EventID       string `json:"event_id"`
+	Timestamp     string `json:"timestamp"`
+	PrevEventHash string `json:"prev_event_hash"`
+}
+
+// written is the first record of an event key, where its chain link is
+// checked.
+type written struct {
+	key   string
+	block uint64
+	txID  string
+	hash  string
+	event eventFields
+}
+
+// replayer checks blocks in order and keeps what later checks need: the
+// salts, the payload hash each event key was first written with, and the
+// last value of every event key.
+type replayer struct {
+	chaincode string
+	rep       *report
+
+	started    bool
+	next       uint64
+	prevHeader []byte
+
+	salts     map[string]string
+	hashes    map[string]string   // event key -> payload hash
+	byHash    map[string]*written // payload hash -> first event written with it
+	successor map[string]string   // payload hash -> key of the event linking to it
+	// Last value hash of every event key, and the block that wrote it; a
+	// delete removes the key.
+	state     map[string][sha256.Size]byte
+	writtenAt map[string]uint64
+}
+
+func newReplayer(chaincode string, rep *report) *replayer {
+	return &replayer{
+		chaincode: chaincode,
+		rep:       rep,
+		salts:     map[string]string{},
+		hashes:    map[string]string{},
+		byHash:    map[string]*written{},
+		successor: map[string]string{},
+		state:     map[string][sha256.Size]byte{},
+		writtenAt: map[string]uint64{},
+	}
+}
+
+// fromGenesis reports whether the replay covers the whole ledger, so that
+// anything not seen in it does not exist.
+func (r *replayer) fromGenesis() bool { return r.rep.FromBlock == 0 }
+
+// block checks block's place in the chain and the writes of its valid
+// transactions.
+func (r *replayer) block(block *common.Block) error {
+	h := block.GetHeader()
+	number := h.GetNumber()
+	if !r.started {
+		r.started, r.next = true, number
+		r.rep.FromBlock = number
+	}
+	if number != r.next {
+		r.rep.fail(issue{Kind: issueBlockGap, Block: blockRef(number), Detail: fmt.Sprintf("expected block %d", r.next)})
+		r.prevHeader = nil
+	}
+	if r.prevHeader != nil && !bytes.Equal(h.GetPreviousHash(), r.prevHeader) {
+		r.rep.fail(issue{Kind: issueBlockHashChain, Block: blockRef(number),
+			Detail: fmt.Sprintf("previous_hash %x, header hash of block %d is %x", h.GetPreviousHash(), number-1, r.prevHeader)})
+	}
+	dataHash := sha256.Sum256(bytes.Join(block.GetData().GetData(), nil))
+	if !bytes.Equal(dataHash[:], h.GetDataHash()) {
+		r.rep.fail(issue{Kind: issueBlockDataHash, Block: blockRef(number), Detail: fmt.Sprintf("data_hash %x, data hashes to %x", h.GetDataHash(), dataHash)})
+	}
+	headerHash, err := auditlog.BlockHeaderHash(h)
+	if err != nil {
+		return err
+	}
+	r.prevHeader, r.next = headerHash, number+1
+	r.rep.ToBlock = number
+	r.rep.Blocks++
+
+	var filter []byte
+	if md := block.GetMetadata().GetMetadata(); len(md) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		filter = md[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+	for i, envBytes := range block.GetData().GetData() {
+		txID, writes, err := r.txWrites(envBytes)
+		if err != nil {
+			r.rep.fail(issue{Kind: issueTxCorrupt, Block: blockRef(number), Detail: fmt.Sprintf("transaction %d: %v", i, err)})
+			continue
+		}
+		if txID == "" {
+			continue
+		}
+		if i >= len(filter) || filter[i] != byte(peer.TxValidationCode_VALID) {
+			r.rep.InvalidTxs++
+			continue
+		}
+		r.rep.ValidTxs++
+		r.apply(number, txID, writes)
+	}
+	return nil
+}
+
+// txWrites returns the id of an endorser transaction and its writes to
+// the chaincode's namespace; the id is empty for other transactions.
+func (r *replayer) txWrites(envBytes []byte) (string, []*kvrwset.KVWrite, error) {
+	env := &common.Envelope{}
+	if err := proto.Unmarshal(envBytes, env); err != nil {
+		return "", nil, fmt.Errorf("envelope: %w", err)
+	}
+	payload := &common.Payload{}
+	if err := proto.Unmarshal(env.GetPayload(), payload); err != nil {
+		return "", nil, fmt.Errorf("payload: %w", err)
+	}
+	chdr := &common.ChannelHeader{}
+	if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), chdr); err != nil {
+		return "", nil, fmt.Errorf("channel header: %w", err)
+	}
+	if chdr.GetType() != int32(common.HeaderType_ENDORSER_TRANSACTION) {
+		return "", nil, nil
+	}
+	tx := &peer.Transaction{}
+	if err := proto.Unmarshal(payload.GetData(), tx); err != nil {
+		return "", nil, fmt.Errorf("tx %s: %w", chdr.GetTxId(), err)
+	}
+	var writes []*kvrwset.KVWrite
+	for _, action := range tx.GetActions() {
+		kvs, err := namespaceWrites(action, r.chaincode)
+		if err != nil {
+			return "", nil, fmt.Errorf("tx %s: %w", chdr.GetTxId(), err)
+		}
+		writes = append(writes, kvs...)
+	}
+	return chdr.GetTxId(), writes, nil
+}
+
+func namespaceWrites(action *peer.TransactionAction, chaincode string) ([]*kvrwset.KVWrite, error) {
+	actionPayload := &peer.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(action.GetPayload(), actionPayload); err != nil {
+		return nil, err
+	}
+	prp := &peer.ProposalResponsePayload{}
+	if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), prp); err != nil {
+		return nil, err
+	}
+	cca := &peer.ChaincodeAction{}
+	if err := proto.Unmarshal(prp.GetExtension(), cca); err != nil {
+		return nil, err
+	}
+	txrw := &rwset.TxReadWriteSet{}
+	if err := proto.Unmarshal(cca.GetResults(), txrw); err != nil {
+		return nil, err
+	}
+	var writes []*kvrwset.KVWrite
+	for _, ns := range txrw.GetNsRwset() {
+		if ns.GetNamespace() != chaincode {
+			continue
+		}
+		kv := &kvrwset.KVRWSet{}
+		if err := proto.Unmarshal(ns.GetRwset(), kv); err != nil {
+			return nil, err
+		}
+		writes = append(writes, kv.GetWrites()...)
+	}
+	return writes, nil
+}
+
+// apply checks one valid transaction's writes. A write set lists keys in
+// sorted order, not the order they were written in, so salts and new
+// events are taken in before the records, links and digests that may
+// refer to them.
+func (r *replayer) apply(block uint64, txID string, writes []*kvrwset.KVWrite) {
+	for _, kv := range writes {
+		if id, ok := strings.CutPrefix(kv.GetKey(), saltKeyPrefix); ok && !kv.GetIsDelete() {
+			r.salts[id] = string(kv.GetValue())
+		}
+	}
+
+	var fresh []*written
+	digests := map[string]*auditlog.MerkleDigest{}
+	leaves := map[string][]string{}
+	for _, kv := range writes {
+		key := kv.GetKey()
+		switch {
+		case strings.HasPrefix(key, eventKeyPrefix):
+			if kv.GetIsDelete() {
+				delete(r.state, key)
+				r.writtenAt[key] = block
+				continue
+			}
+			r.state[key] = sha256.Sum256(kv.GetValue())
+			r.writtenAt[key] = block
+			if w := r.eventRecord(block, txID, key, kv.GetValue()); w != nil {
+				fresh = append(fresh, w)
+			}
+		case strings.HasPrefix(key, digestLeavesKeyPrefix):
+			var hashes []string
+			if err := json.Unmarshal(kv.GetValue(), &hashes); err != nil {
+				r.rep.fail(issue{Kind: issueDigestCorrupt, Block: blockRef(block), TxID: txID, Key: key, Detail: err.Error()})
+				continue
+			}
+			leaves[strings.TrimPrefix(key, digestLeavesKeyPrefix)] = hashes
+		case strings.HasPrefix(key, digestKeyPrefix):
+			var d auditlog.MerkleDigest
+			if err := json.Unmarshal(kv.GetValue(), &d); err != nil {
+				r.rep.fail(issue{Kind: issueDigestCorrupt, Block: blockRef(block), TxID: txID, Key: key, Detail: err.Error()})
+				continue
+			}
+			digests[strings.TrimPrefix(key, digestKeyPrefix)] = &d
+		}
+	}
+
+	for _, w := range fresh {
+		r.checkLink(w)
+	}
+	ids := make([]string, 0, len(digests))
+	for id := range digests {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		r.checkDigest(block, txID, id, digests[id], leaves[id])
+	}
+}
+
+// eventRecord recomputes a record's payload hash and checks it against
+// the hash the key was first written with. It returns the key's first
+// record, whose chain link is then checked, and nil for later ones.
+func (r *replayer) eventRecord(block uint64, txID, key string, value []byte) *written {
+	r.rep.Records++
+	at := issue{Block: blockRef(block), TxID: txID, Key: key}
+	var rec record
+	var ev eventFields
+	err := json.Unmarshal(value, &rec)
+	if err == nil && (len(rec.Event) == 0 || rec.PayloadHash == "") {
+		err = fmt.Errorf("not an event record")
+	}
+	if err == nil {
+		err = json.Unmarshal(rec.Event, &ev)
+	}
+	if err != nil {
+		at.Kind, at.Detail = issueRecordCorrupt, err.Error()
+		r.rep.fail(at)
+		return nil
+	}
+	at.EventID = ev.EventID
+
+	salt, known := "", true
+	if rec.SaltID != "" {
+		salt, known = r.salts[rec.SaltID]
+	}
+	switch {
+	case len(rec.Deletion) > 0:
+		at.Kind, at.Detail = issueEventDeleted, "tombstoned by a governed deletion; its payload hash cannot be recomputed"
+		r.rep.warn(at)
+	case !known:
+		at.Kind, at.Detail = issueSaltUnknown, "salt "+rec.SaltID+" was registered before the replayed blocks"
+		if r.fromGenesis() {
+			r.rep.fail(at)
+		} else {
+			r.rep.warn(at)
+		}
+	default:
+		computed, err := recordHash(&rec, salt)
+		if err != nil {
+			at.Kind, at.Detail = issueRecordCorrupt, err.Error()
+			r.rep.fail(at)
+		} else if computed != rec.PayloadHash {
+			at.Kind, at.Detail = issuePayloadHash, fmt.Sprintf("stored %s, computed %s", rec.PayloadHash, computed)
+			r.rep.fail(at)
+		}
+	}
+
+	if first, seen := r.hashes[key]; seen {
+		if first != rec.PayloadHash {
+			at.Kind, at.Detail = issueHashChanged, fmt.Sprintf("first written with %s, now %s", first, rec.PayloadHash)
+			r.rep.fail(at)
+		}
+		return nil
+	}
+	r.hashes[key] = rec.PayloadHash
+	r.rep.Events++
+	w := &written{key: key, block: block, txID: txID, hash: rec.PayloadHash, event: ev}
+	if _, dup := r.byHash[rec.PayloadHash]; !dup {
+		r.byHash[rec.PayloadHash] = w
+	}
+	return w
+}
+
+// recordHash is the chaincode's payload hash of a record: SHA-256 over the
+// salt and the event in the record's canonicalization. Legacy records were
+// hashed over encoding/json's output, which is what the record holds.
+func recordHash(rec *record, salt string) (string, error) {
+	var canon []byte
+	switch rec.Canonicalization {
+	case "":
+		canon = rec.Event
+	case "jcs":
+		var err error
+		if canon, err = jcs.Marshal(rec.Event); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unknown canonicalization %q", rec.Canonicalization)
+	}
+	h := sha256.New()
+	h.Write([]byte(salt))
+	h.Write(canon)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// checkLink checks a new event's prev_event_hash: the predecessor exists,
+// has no other successor and is not later than the event.
+func (r *replayer) checkLink(w *written) {
+	prevHash := w.event.PrevEventHash
+	if prevHash == "" {
+		return
+	}
+	r.rep.ChainLinks++
+	at := issue{Block: blockRef(w.block), TxID: w.txID, Key: w.key, EventID: w.event.EventID}
+	prev := r.byHash[prevHash]
+	if prev == nil {
+		at.Kind, at.Detail = issueChainLinkMissing, "no event with payload hash "+prevHash
+		if r.fromGenesis() {
+			r.rep.fail(at)
+		} else {
+			at.Detail += " in the replayed blocks"
+			r.rep.warn(at)
+		}
+		return
+	}
+	if other, taken := r.successor[prevHash]; taken && other != w.key {
+		at.Kind, at.Detail = issueChainFork, prev.key+" is already followed by "+other
+		r.rep.fail(at)
+	} else {
+		r.successor[prevHash] = w.key
+	}
+	prevTs, err1 := time.Parse(time.RFC3339, prev.event.Timestamp)
+	ts, err2 := time.Parse(time.RFC3339, w.event.Timestamp)
+	if err1 == nil && err2 == nil && ts.Before(prevTs) {
+		at.Kind, at.Detail = issueChainTimestamp, fmt.Sprintf("%s is earlier than its predecessor's %s", w.event.Timestamp, prev.event.Timestamp)
+		r.rep.fail(at)
+	}
+}
+
+// checkDigest recomputes a committed digest's Merkle root from the leaves
+// stored with it and checks every leaf is the payload hash of an event.
+func (r *replayer) checkDigest(block uint64, txID, id string, d *auditlog.MerkleDigest, leaves []string) {
+	r.rep.Digests++
+	at := issue{Block: blockRef(block), TxID: txID, Key: digestKeyPrefix + id}
+	if leaves == nil {
+		at.Kind, at.Detail = issueDigestCorrupt, "no leaves written with the digest"
+		r.rep.fail(at)
+		return
+	}
+	if len(leaves) != d.LeafCount {
+		at.Kind, at.Detail = issueDigestLeafCount, fmt.Sprintf("leaf_count %d, %d leaves stored", d.LeafCount, len(leaves))
+		r.rep.fail(at)
+	}
+	root, err := auditlog.MerkleRoot(leaves)
+	if err != nil {
+		at.Kind, at.Detail = issueDigestCorrupt, err.Error()
+		r.rep.fail(at)
+		return
+	}
+	if root != d.Root {
+		at.Kind, at.Detail = issueDigestRoot, fmt.Sprintf("merkle_root %s, leaves give %s", d.Root, root)
+		r.rep.fail(at)
+	}
+	unknown := 0
+	for _, leaf := range leaves {
+		if r.byHash[leaf] == nil {
+			unknown++
+		}
+	}
+	if unknown > 0 {
+		at.Kind, at.Detail = issueDigestLeafUnknown, fmt.Sprintf("%d of %d leaves match no event", unknown, len(leaves))
+		if r.fromGenesis() {
+			r.rep.fail(at)
+		} else {
+			at.Detail += " in the replayed blocks"
+			r.rep.warn(at)
+		}
+	}
+}
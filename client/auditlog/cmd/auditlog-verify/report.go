@@ -0,0 +1,90 @@
+package main
+
+// report is the machine-readable result of a verification run. Intact is
+// true only when the run found no issues; warnings name what it could not
+// check, such as links into blocks before the replayed range.
+type report struct {
+	Channel   string `json:"channel,omitempty"`
+	Chaincode string `json:"chaincode"`
+	Source    string `json:"source"`
+	FromBlock uint64 `json:"from_block"`
+	// Last block replayed.
+	ToBlock     uint64         `json:"to_block"`
+	Blocks      int            `json:"blocks"`
+	ValidTxs    int            `json:"valid_transactions"`
+	InvalidTxs  int            `json:"invalid_transactions"`
+	Records     int            `json:"event_records_checked"`
+	Events      int            `json:"events"`
+	ChainLinks  int            `json:"chain_links_checked"`
+	Digests     int            `json:"digests_checked"`
+	State       *stateSummary  `json:"state,omitempty"`
+	Intact      bool           `json:"intact"`
+	IssueCounts map[string]int `json:"issue_counts"`
+	Issues      []issue        `json:"issues"`
+	Warnings    []issue        `json:"warnings"`
+	GeneratedAt string         `json:"generated_at"`
+}
+
+type stateSummary struct {
+	// "peer" for a live ExportSnapshot, otherwise the snapshot file.
+	Source   string `json:"source"`
+	Records  int    `json:"records"`
+	Compared int    `json:"compared"`
+	// Keys written while the snapshot was taken, which cannot be told
+	// apart from tampering and are left out.
+	Skipped int `json:"skipped"`
+}
+
+// issue is one finding. Block is unset for state comparison findings.
+type issue struct {
+	Kind    string  `json:"kind"`
+	Block   *uint64 `json:"block,omitempty"`
+	TxID    string  `json:"tx_id,omitempty"`
+	Key     string  `json:"key,omitempty"`
+	EventID string  `json:"event_id,omitempty"`
+	Detail  string  `json:"detail,omitempty"`
+}
+
+// Issue kinds.
+const (
+	issueBlockGap          = "block_gap"
+	issueBlockHashChain    = "block_previous_hash_mismatch"
+	issueBlockDataHash     = "block_data_hash_mismatch"
+	issueTxCorrupt         = "transaction_corrupt"
+	issueRecordCorrupt     = "record_corrupt"
+	issuePayloadHash       = "payload_hash_mismatch"
+	issueHashChanged       = "payload_hash_changed"
+	issueSaltUnknown       = "salt_unknown"
+	issueEventDeleted      = "event_deleted"
+	issueChainLinkMissing  = "chain_link_missing"
+	issueChainFork         = "chain_fork"
+	issueChainTimestamp    = "chain_timestamp_regression"
+	issueDigestCorrupt     = "digest_corrupt"
+	issueDigestRoot        = "digest_root_mismatch"
+	issueDigestLeafCount   = "digest_leaf_count_mismatch"
+	issueDigestLeafUnknown = "digest_leaf_unknown"
+	issueStateMismatch     = "state_value_mismatch"
+	issueStateUnexpected   = "state_record_unexpected"
+	issueStateMissing      = "state_record_missing"
+	issueStateSkipped      = "state_not_compared"
+)
+
+// maxListed caps the issues and warnings listed; IssueCounts still counts
+// every one.
+var maxListed = 1000
+
+func (r *report) fail(i issue) {
+	r.Intact = false
+	r.IssueCounts[i.Kind]++
+	if len(r.Issues) < maxListed {
+		r.Issues = append(r.Issues, i)
+	}
+}
+
+func (r *report) warn(i issue) {
+	if len(r.Warnings) < maxListed {
+		r.Warnings = append(r.Warnings, i)
+	}
+}
+
+func blockRef(n uint64) *uint64 { return &n }
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"payscope/client/auditlog"
+)
+
+// compareState checks the event keyspace of the state database against
+// what the replayed blocks wrote: a value differing from the last write of
+// its key, a key no valid transaction wrote or one the blocks still hold
+// but the state lacks all mean the state was changed outside the chaincode.
+// Keys last written at or after changedFrom are skipped, since the snapshot
+// may predate those writes.
+func (r *replayer) compareState(sum *stateSummary, records []auditlog.SnapshotRecord, changedFrom uint64) {
+	r.rep.State = sum
+	sum.Records = len(records)
+	seen := make(map[string]bool, len(records))
+	for _, rec := range records {
+		seen[rec.Key] = true
+		if at, ok := r.writtenAt[rec.Key]; ok && at >= changedFrom {
+			sum.Skipped++
+			continue
+		}
+		sum.Compared++
+		want, ok := r.state[rec.Key]
+		switch {
+		case !ok:
+			r.rep.fail(issue{Kind: issueStateUnexpected, Key: rec.Key, Detail: "no valid transaction in the replayed blocks left this key"})
+		case sha256.Sum256(rec.Value) != want:
+			r.rep.fail(issue{Kind: issueStateMismatch, Key: rec.Key, Detail: "value differs from the last write to the key"})
+		}
+	}
+
+	missing := []string{}
+	for key := range r.state {
+		if !seen[key] && r.writtenAt[key] < changedFrom {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	for _, key := range missing {
+		r.rep.fail(issue{Kind: issueStateMissing, Key: key, Detail: "written by the blocks but absent from the state"})
+	}
+}
+
+// readSnapshot reads the JSON lines written by payscope-audit snapshot.
+func readSnapshot(path string) ([]auditlog.SnapshotRecord, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var records []auditlog.SnapshotRecord
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for dec.More() {
+		var rec auditlog.SnapshotRecord
+		if err := dec.Decode(&rec); err != nil {
+			return nil, fmt.Errorf("%s: record %d: %w", path, len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
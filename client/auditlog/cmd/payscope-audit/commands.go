@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"payscope/client/auditlog"
+)
+
+func submitCmd() *cobra.Command {
+	var eventFile, artifactFile, eventType, schemaVersion, tsaURL, keyringPath string
+	var withPayload bool
+	var payloadKeys []string
+	cmd := &cobra.Command{
+		Use:   "submit",
+		Short: "Submit an event from JSON, or build one from an artifact file",
+		Example: "  payscope-audit submit --event event.json\n" +
+			"  payscope-audit submit --artifact report.csv --type INGEST --schema v1",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var e *auditlog.Event
+			var payload []byte
+			switch {
+			case eventFile != "" && artifactFile != "":
+				return fmt.Errorf("use either --event or --artifact")
+			case eventFile != "":
+				b, err := readInput(eventFile)
+				if err != nil {
+					return err
+				}
+				e = &auditlog.Event{}
+				if err := json.Unmarshal(b, e); err != nil {
+					return fmt.Errorf("parse %s: %w", eventFile, err)
+				}
+			case artifactFile != "":
+				if eventType == "" || schemaVersion == "" {
+					return fmt.Errorf("--type and --schema are required with --artifact")
+				}
+				b, err := os.ReadFile(artifactFile)
+				if err != nil {
+					return err
+				}
+				if e, err = auditlog.NewEvent(eventType, schemaVersion, b); err != nil {
+					return err
+				}
+				if withPayload {
+					payload = b
+				}
+			default:
+				return fmt.Errorf("--event or --artifact required")
+			}
+			if tsaURL != "" {
+				if err := auditlog.RequestTimestamp(cmd.Context(), tsaURL, e); err != nil {
+					return err
+				}
+			}
+
+			var keyring *auditlog.Keyring
+			if len(payloadKeys) > 0 {
+				if payload == nil || keyringPath == "" {
+					return fmt.Errorf("--payload-key needs --private-payload and --keyring")
+				}
+				var err error
+				if keyring, err = auditlog.LoadKeyring(keyringPath); err != nil {
+					return err
+				}
+			}
+
+			c, err := connect()
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			var receipt *auditlog.Receipt
+			switch {
+			case keyring != nil:
+				receipt, err = c.PutEventWithEncryptedPayload(e, payload, keyring, payloadKeys...)
+			case payload != nil:
+				receipt, err = c.PutEventWithPayload(e, payload)
+			default:
+				receipt, err = c.PutEvent(e)
+			}
+			if err != nil {
+				return err
+			}
+			return printJSON(receipt)
+		},
+	}
+	cmd.Flags().StringVar(&eventFile, "event", "", "event JSON file, or - for stdin")
+	cmd.Flags().StringVar(&artifactFile, "artifact", "", "artifact file to hash into a new event")
+	cmd.Flags().StringVar(&eventType, "type", "", "event_type for --artifact")
+	cmd.Flags().StringVar(&schemaVersion, "schema", "", "schema_version for --artifact")
+	cmd.Flags().BoolVar(&withPayload, "private-payload", false, "also store the artifact in the private collection")
+	cmd.Flags().StringVar(&tsaURL, "tsa-url", "", "RFC 3161 timestamp authority to stamp the event with before submitting")
+	cmd.Flags().StringVar(&keyringPath, "keyring", os.Getenv("PAYSCOPE_AUDIT_KEYRING"), "data keys JSON for encrypted payloads (default $PAYSCOPE_AUDIT_KEYRING)")
+	cmd.Flags().StringSliceVar(&payloadKeys, "payload-key", nil, "encrypt the private payload under these keyring key ids")
+	return cmd
+}
+
+func payloadCmd() *cobra.Command {
+	var keyringPath string
+	cmd := &cobra.Command{
+		Use:   "payload EVENT_ID",
+		Short: "Write an event's private payload to stdout, decrypting it with --keyring",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := connect()
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			stored, err := c.GetEvent(args[0])
+			if err != nil {
+				return err
+			}
+			payload, err := c.GetPrivatePayload(args[0])
+			if err != nil {
+				return err
+			}
+			if len(stored.PayloadKeyIDs) > 0 {
+				if keyringPath == "" {
+					return fmt.Errorf("payload is encrypted under %v; --keyring required", stored.PayloadKeyIDs)
+				}
+				keyring, err := auditlog.LoadKeyring(keyringPath)
+				if err != nil {
+					return err
+				}
+				if payload, err = auditlog.DecryptPayload(stored, payload, keyring); err != nil {
+					return err
+				}
+			}
+			_, err = os.Stdout.Write(payload)
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&keyringPath, "keyring", os.Getenv("PAYSCOPE_AUDIT_KEYRING"), "data keys JSON (default $PAYSCOPE_AUDIT_KEYRING)")
+	return cmd
+}
+
+func getCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get EVENT_ID",
+		Short: "Print a stored event",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := connect()
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			stored, err := c.GetEvent(args[0])
+			if err != nil {
+				return err
+			}
+			return printJSON(stored)
+		},
+	}
+}
+
+func proofCmd() *cobra.Command {
+	var noBlock bool
+	cmd := &cobra.Command{
+		Use:   "proof EVENT_ID",
+		Short: "Print the write receipt of an event with its block and validation code",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := connect()
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			proof, err := c.GetEventProof(args[0], !noBlock)
+			if err != nil {
+				return err
+			}
+			return printJSON(proof)
+		},
+	}
+	cmd.Flags().BoolVar(&noBlock, "no-block", false, "skip fetching the committing block")
+	return cmd
+}
+
+// pageFlags selects which listing a command pages through.
+type pageFlags struct {
+	eventType  string
+	start, end string
+	bookmark   string
+	pageSize   int32
+}
+
+func (f *pageFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.eventType, "type", "", "only events of this event_type")
+	cmd.Flags().StringVar(&f.start, "start", "", "RFC3339 start of a time range (inclusive)")
+	cmd.Flags().StringVar(&f.end, "end", "", "RFC3339 end of a time range (exclusive)")
+	cmd.Flags().StringVar(&f.bookmark, "bookmark", "", "bookmark from a previous page")
+	cmd.Flags().Int32Var(&f.pageSize, "page-size", 100, "records per page")
+}
+
+func (f *pageFlags) fetch(c *auditlog.Client, bookmark string) (*auditlog.EventPage, error) {
+	switch {
+	case f.eventType != "" && (f.start != "" || f.end != ""):
+		return nil, fmt.Errorf("--type cannot be combined with --start/--end")
+	case f.eventType != "":
+		return c.GetEventsByType(f.eventType, bookmark, f.pageSize)
+	case f.start != "" || f.end != "":
+		start, err := time.Parse(time.RFC3339, f.start)
+		if err != nil {
+			return nil, fmt.Errorf("--start must be RFC3339")
+		}
+		end, err := time.Parse(time.RFC3339, f.end)
+		if err != nil {
+			return nil, fmt.Errorf("--end must be RFC3339")
+		}
+		return c.QueryEventsByTimeRange(start, end, bookmark, f.pageSize)
+	default:
+		return c.ListEvents(bookmark, f.pageSize)
+	}
+}
+
+func listCmd() *cobra.Command {
+	var f pageFlags
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Print one page of events",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := connect()
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			page, err := f.fetch(c, f.bookmark)
+			if err != nil {
+				return err
+			}
+			return printJSON(page)
+		},
+	}
+	f.register(cmd)
+	return cmd
+}
+
+func verifyCmd() *cobra.Command {
+	var chain bool
+	cmd := &cobra.Command{
+		Use:   "verify EVENT_ID... | verify --chain START_ID END_ID",
+		Short: "Recompute payload hashes on-chain; exits 2 on any mismatch",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			c, err := connect()
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			if chain {
+				if len(args) != 2 {
+					return fmt.Errorf("--chain takes START_ID END_ID")
+				}
+				report, err := c.VerifyChain(args[0], args[1])
+				if err != nil {
+					return err
+				}
+				if err := printJSON(report); err != nil {
+					return err
+				}
+				if len(report.Breaks) > 0 {
+					return errVerifyFailed
+				}
+				return nil
+			}
+			report, err := c.VerifyEvents(args)
+			if err != nil {
+				return err
+			}
+			if err := printJSON(report); err != nil {
+				return err
+			}
+			if report.Summary.Failures > 0 {
+				return errVerifyFailed
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolVar(&chain, "chain", false, "verify the prev_event_hash chain between two events")
+	return cmd
+}
+
+func exportCmd() *cobra.Command {
+	var f pageFlags
+	var out string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Write every matching event as JSON lines",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w := io.Writer(os.Stdout)
+			if out != "" && out != "-" {
+				file, err := os.Create(out)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+			bw := bufio.NewWriter(w)
+			enc := json.NewEncoder(bw)
+
+			c, err := connect()
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			it := auditlog.NewEventIterator(f.bookmark, func(bookmark string) (*auditlog.EventPage, error) {
+				return f.fetch(c, bookmark)
+			})
+			n := 0
+			for it.Next() {
+				if err := enc.Encode(it.Event()); err != nil {
+					return err
+				}
+				n++
+			}
+			if err := it.Err(); err != nil {
+				return fmt.Errorf("after %d events (resume with --bookmark %q): %w", n, it.Bookmark(), err)
+			}
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "exported %d events\n", n)
+			return nil
+		},
+	}
+	f.register(cmd)
+	cmd.Flags().StringVar(&out, "out", "-", "output file, or - for stdout")
+	return cmd
+}
+
+func snapshotCmd() *cobra.Command {
+	var out string
+	var pageSize int32
+	cmd := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Back up every stored event record as JSON lines and print the running digest",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w := io.Writer(os.Stdout)
+			if out != "" && out != "-" {
+				file, err := os.Create(out)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				w = file
+			}
+			bw := bufio.NewWriter(w)
+			enc := json.NewEncoder(bw)
+
+			c, err := connect()
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			n := 0
+			digest, err := c.ExportSnapshot(pageSize, func(page *auditlog.SnapshotPage) error {
+				for _, rec := range page.Records {
+					if err := enc.Encode(rec); err != nil {
+						return err
+					}
+					n++
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if err := bw.Flush(); err != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "snapshot of %d records, running digest %s\n", n, digest)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&out, "out", "-", "output file, or - for stdout")
+	cmd.Flags().Int32Var(&pageSize, "page-size", 500, "records per ExportSnapshot call")
+	return cmd
+}
+
+// readInput reads path, or stdin when path is "-".
+func readInput(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+func reportCmd() *cobra.Command {
+	var start, end, out string
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Export a signed audit report for a period",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := time.Parse(time.RFC3339, start)
+			if err != nil {
+				return fmt.Errorf("--start must be RFC3339")
+			}
+			to, err := time.Parse(time.RFC3339, end)
+			if err != nil {
+				return fmt.Errorf("--end must be RFC3339")
+			}
+			c, err := connect()
+			if err != nil {
+				return err
+			}
+			defer c.Close()
+			report, err := c.ExportAuditReport(from, to)
+			if err != nil {
+				return err
+			}
+			if out == "" || out == "-" {
+				return printJSON(report)
+			}
+			b, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				return err
+			}
+			return os.WriteFile(out, append(b, '\n'), 0o644)
+		},
+	}
+	cmd.Flags().StringVar(&start, "start", "", "RFC3339 start of the period (inclusive)")
+	cmd.Flags().StringVar(&end, "end", "", "RFC3339 end of the period (exclusive)")
+	cmd.Flags().StringVar(&out, "out", "-", "output file, or - for stdout")
+	return cmd
+}
+
+func verifyReportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify-report FILE",
+		Short: "Check a signed audit report's signature offline; exits 2 if invalid",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := readInput(args[0])
+			if err != nil {
+				return err
+			}
+			var report auditlog.SignedAuditReport
+			if err := json.Unmarshal(b, &report); err != nil {
+				return fmt.Errorf("parse %s: %w", args[0], err)
+			}
+			if err := auditlog.VerifyAuditReport(&report); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return errVerifyFailed
+			}
+			fmt.Printf("signature valid: signed by %s at %s\n", report.SignerMSP, report.GeneratedAt)
+			return nil
+		},
+	}
+}
@@ -0,0 +1,59 @@
+// Command payscope-audit submits, reads, verifies and exports audit log
+// events from the command line.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"payscope/client/auditlog"
+)
+
+var profilePath string
+
+// errVerifyFailed makes verify exit 2 after printing its report.
+var errVerifyFailed = errors.New("verification failed")
+
+func main() {
+	root := &cobra.Command{
+		Use:           "payscope-audit",
+		Short:         "Work with the PayScope audit log chaincode",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	root.PersistentFlags().StringVar(&profilePath, "profile", os.Getenv("PAYSCOPE_AUDIT_PROFILE"),
+		"connection profile JSON (default $PAYSCOPE_AUDIT_PROFILE)")
+	root.AddCommand(submitCmd(), payloadCmd(), getCmd(), proofCmd(), listCmd(), verifyCmd(), exportCmd(), snapshotCmd(), reportCmd(), verifyReportCmd())
+
+	if err := root.Execute(); err != nil {
+		if ce, ok := auditlog.AsContractError(err); ok {
+			err = ce
+		}
+		fmt.Fprintln(os.Stderr, "error:", err)
+		if errors.Is(err, errVerifyFailed) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+func connect() (*auditlog.Client, error) {
+	if profilePath == "" {
+		return nil, fmt.Errorf("--profile or PAYSCOPE_AUDIT_PROFILE required")
+	}
+	profile, err := auditlog.LoadProfile(profilePath)
+	if err != nil {
+		return nil, err
+	}
+	return auditlog.Connect(profile)
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,74 @@
+package auditlog
+
+import (
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+)
+
+// Deletion is set on an event tombstoned by a governed deletion.
+type Deletion struct {
+	ProposalID     string   `json:"proposal_id"`
+	Reason         string   `json:"reason"`
+	LegalReference string   `json:"legal_reference"`
+	ProposedByMSP  string   `json:"proposed_by_msp"`
+	ApprovedByMSPs []string `json:"approved_by_msps"`
+	MarkerEventID  string   `json:"marker_event_id"`
+	TxID           string   `json:"tx_id"`
+	DeletedAt      string   `json:"deleted_at"`
+}
+
+// DeletionProposal mirrors the chaincode's record of a governed deletion.
+// Status is "proposed" until the last required approval, then "executed".
+type DeletionProposal struct {
+	ProposalID        string             `json:"proposal_id"`
+	EventID           string             `json:"event_id"`
+	Producer          string             `json:"producer,omitempty"`
+	Reason            string             `json:"reason"`
+	LegalReference    string             `json:"legal_reference"`
+	ProposedByMSP     string             `json:"proposed_by_msp"`
+	ApproverMSPs      []string           `json:"approver_msps"`
+	ApprovalsRequired int                `json:"approvals_required"`
+	Approvals         []DeletionApproval `json:"approvals"`
+	Status            string             `json:"status"`
+	TxID              string             `json:"tx_id"`
+	ProposedAt        string             `json:"proposed_at"`
+	TombstoneEventID  string             `json:"tombstone_event_id,omitempty"`
+	ExecutedAt        string             `json:"executed_at,omitempty"`
+}
+
+type DeletionApproval struct {
+	MSPID         string `json:"msp_id"`
+	MarkerEventID string `json:"marker_event_id"`
+	TxID          string `json:"tx_id"`
+	ApprovedAt    string `json:"approved_at"`
+}
+
+// ProposeDeletion opens the governed deletion of an event. It needs an
+// admin identity carrying auditlog.deletion_approver=true.
+func (c *Client) ProposeDeletion(eventID, reason, legalReference string) (*DeletionProposal, error) {
+	return decodeDeletionProposal(c.submit("ProposeDeletion", client.WithArguments(eventID, reason, legalReference)))
+}
+
+// ApproveDeletion approves a proposal for the client's org. The approval
+// completing the quorum tombstones the event; the returned proposal is
+// then "executed".
+func (c *Client) ApproveDeletion(proposalID string) (*DeletionProposal, error) {
+	return decodeDeletionProposal(c.submit("ApproveDeletion", client.WithArguments(proposalID)))
+}
+
+// GetDeletionProposal reads a proposal and its approvals.
+func (c *Client) GetDeletionProposal(proposalID string) (*DeletionProposal, error) {
+	return decodeDeletionProposal(c.evaluate("GetDeletionProposal", proposalID))
+}
+
+func decodeDeletionProposal(b []byte, err error) (*DeletionProposal, error) {
+	if err != nil {
+		return nil, err
+	}
+	var p DeletionProposal
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
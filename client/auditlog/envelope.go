@@ -0,0 +1,234 @@
+package auditlog
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+// Envelope encryption for private payloads. SealPayload encrypts a payload
+// with a fresh AES-256-GCM data encryption key and wraps that key under
+// each named data key of a Keyring. Orgs keep their data keys off-chain;
+// the ledger records only their ids (StoredEvent.PayloadKeyIDs), so the
+// peers of collection members hold ciphertext at rest. The chaincode's
+// payload_keys config names the key each org's payloads must be wrapped
+// under.
+
+// PayloadEnvelope is the sealed form of a private payload, as the chaincode
+// stores it. Binary fields are base64.
+type PayloadEnvelope struct {
+	Version    int           `json:"version"`
+	Alg        string        `json:"alg"`
+	Keys       []EnvelopeKey `json:"keys"`
+	Nonce      string        `json:"nonce"`
+	Ciphertext string        `json:"ciphertext"`
+}
+
+// EnvelopeKey is the payload's data encryption key wrapped under one data
+// key.
+type EnvelopeKey struct {
+	KeyID      string `json:"key_id"`
+	Nonce      string `json:"nonce"`
+	WrappedKey string `json:"wrapped_key"`
+}
+
+const envelopeAlg = "AES-256-GCM"
+
+// Keyring holds AES-256 data keys by key id.
+type Keyring struct {
+	keys map[string][]byte
+}
+
+// NewKeyring returns a keyring of 32-byte keys by id.
+func NewKeyring(keys map[string][]byte) (*Keyring, error) {
+	kr := &Keyring{keys: map[string][]byte{}}
+	for id, k := range keys {
+		if id == "" || len(k) != 32 {
+			return nil, fmt.Errorf("keyring: key %q must be 32 bytes", id)
+		}
+		kr.keys[id] = append([]byte(nil), k...)
+	}
+	return kr, nil
+}
+
+// LoadKeyring reads a JSON object of base64 data keys by key id, e.g.
+// {"org1-2026-10": "<base64 of 32 random bytes>"}.
+func LoadKeyring(path string) (*Keyring, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var encoded map[string]string
+	if err := json.Unmarshal(b, &encoded); err != nil {
+		return nil, fmt.Errorf("parse keyring %s: %w", path, err)
+	}
+	keys := make(map[string][]byte, len(encoded))
+	for id, v := range encoded {
+		if keys[id], err = base64.StdEncoding.DecodeString(v); err != nil {
+			return nil, fmt.Errorf("keyring: key %q must be base64", id)
+		}
+	}
+	return NewKeyring(keys)
+}
+
+// SealPayload encrypts payload for e, wrapping its key under each of keyIDs,
+// and returns the envelope JSON. The ciphertext is bound to e's event_id.
+func SealPayload(e *Event, payload []byte, kr *Keyring, keyIDs ...string) ([]byte, error) {
+	if e.EventID == "" {
+		return nil, errors.New("envelope: event_id required")
+	}
+	if len(keyIDs) == 0 {
+		return nil, errors.New("envelope: at least one key id required")
+	}
+	dek := make([]byte, 32)
+	if _, err := rand.Read(dek); err != nil {
+		return nil, err
+	}
+	env := PayloadEnvelope{Version: 1, Alg: envelopeAlg}
+	var err error
+	if env.Nonce, env.Ciphertext, err = seal(dek, payload, []byte(e.EventID)); err != nil {
+		return nil, err
+	}
+	for _, id := range keyIDs {
+		kek, ok := kr.keys[id]
+		if !ok {
+			return nil, fmt.Errorf("envelope: no key %q in keyring", id)
+		}
+		k := EnvelopeKey{KeyID: id}
+		if k.Nonce, k.WrappedKey, err = seal(kek, dek, []byte(id)); err != nil {
+			return nil, err
+		}
+		env.Keys = append(env.Keys, k)
+	}
+	return json.Marshal(env)
+}
+
+// DecryptPayload opens the envelope of stored's private payload with the
+// first of its keys kr holds, and checks the plaintext against the event's
+// artifact_hash.
+func DecryptPayload(stored *StoredEvent, envelope []byte, kr *Keyring) ([]byte, error) {
+	var env PayloadEnvelope
+	if err := json.Unmarshal(envelope, &env); err != nil {
+		return nil, fmt.Errorf("envelope: %w", err)
+	}
+	if env.Version != 1 || env.Alg != envelopeAlg {
+		return nil, fmt.Errorf("envelope: unsupported version %d with %s", env.Version, env.Alg)
+	}
+	for _, k := range env.Keys {
+		kek, ok := kr.keys[k.KeyID]
+		if !ok {
+			continue
+		}
+		dek, err := open(kek, k.Nonce, k.WrappedKey, []byte(k.KeyID))
+		if err != nil {
+			return nil, fmt.Errorf("envelope: unwrap with %s: %w", k.KeyID, err)
+		}
+		payload, err := open(dek, env.Nonce, env.Ciphertext, []byte(stored.Event.EventID))
+		if err != nil {
+			return nil, fmt.Errorf("envelope: decrypt: %w", err)
+		}
+		digest, err := artifactDigest(&stored.Event, payload)
+		if err != nil {
+			return nil, err
+		}
+		if digest != stored.Event.ArtifactHash {
+			return nil, errors.New("envelope: payload does not match artifact_hash")
+		}
+		return payload, nil
+	}
+	return nil, errors.New("envelope: no key in keyring for this payload")
+}
+
+// PutEventWithEncryptedPayload is PutEventWithPayload with the payload
+// sealed under keyIDs, so the collection stores only the envelope.
+func (c *Client) PutEventWithEncryptedPayload(e *Event, payload []byte, kr *Keyring, keyIDs ...string) (*Receipt, error) {
+	env, err := SealPayload(e, payload, kr, keyIDs...)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return decodeReceipt(c.submitTransient("PutEvent",
+		map[string][]byte{"payload_envelope": env},
+		client.WithArguments(string(b)),
+	))
+}
+
+// GetPrivatePayload reads an event's private payload as stored: the
+// envelope JSON for one written encrypted.
+func (c *Client) GetPrivatePayload(eventID string) ([]byte, error) {
+	b, err := c.evaluate("GetPrivatePayload", eventID)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(string(b))
+}
+
+func seal(key, plaintext, aad []byte) (nonce, ciphertext string, err error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", "", err
+	}
+	n := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(n); err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(n),
+		base64.StdEncoding.EncodeToString(aead.Seal(nil, n, plaintext, aad)), nil
+}
+
+func open(key []byte, nonce, ciphertext string, aad []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	n, err := base64.StdEncoding.DecodeString(nonce)
+	if err != nil || len(n) != aead.NonceSize() {
+		return nil, errors.New("bad nonce")
+	}
+	ct, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, errors.New("bad ciphertext")
+	}
+	return aead.Open(nil, n, ct, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// artifactDigest hashes b with e's hash_algorithm, as the chaincode does.
+func artifactDigest(e *Event, b []byte) (string, error) {
+	var sum []byte
+	switch e.HashAlgorithm {
+	case "", "sha256":
+		s := sha256.Sum256(b)
+		sum = s[:]
+	case "sha3-256":
+		s := sha3.Sum256(b)
+		sum = s[:]
+	case "blake2b-256":
+		s := blake2b.Sum256(b)
+		sum = s[:]
+	default:
+		return "", fmt.Errorf("unsupported hash_algorithm %q", e.HashAlgorithm)
+	}
+	return hex.EncodeToString(sum), nil
+}
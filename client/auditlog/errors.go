@@ -0,0 +1,87 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/gateway"
+	"google.golang.org/grpc/status"
+)
+
+// ContractError is the structured error the chaincode fails transactions
+// with. Code is one of the stable ERR_* codes listed in the chaincode's
+// errors.go; Message is the original error text.
+type ContractError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Field   string         `json:"field,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+func (e *ContractError) Error() string {
+	if e.Field != "" {
+		return e.Code + " (" + e.Field + "): " + e.Message
+	}
+	return e.Code + ": " + e.Message
+}
+
+// AsContractError extracts the chaincode's error from an Evaluate or
+// Submit failure. The gateway reports it inside the error text and the
+// per-peer error details.
+func AsContractError(err error) (*ContractError, bool) {
+	if err == nil {
+		return nil, false
+	}
+	texts := []string{err.Error()}
+	if st, ok := status.FromError(err); ok {
+		for _, d := range st.Details() {
+			if detail, ok := d.(*gateway.ErrorDetail); ok {
+				texts = append(texts, detail.GetMessage())
+			}
+		}
+	}
+	for _, text := range texts {
+		i := strings.Index(text, `{"code":`)
+		if i < 0 {
+			continue
+		}
+		var ce ContractError
+		if err := json.NewDecoder(strings.NewReader(text[i:])).Decode(&ce); err == nil && ce.Code != "" {
+			return &ce, true
+		}
+	}
+	return nil, false
+}
+
+// IdempotencyConflict is the diff an idempotency_violation carries: the
+// payload hashes of the stored and the resubmitted event, and the fields
+// that differ between them.
+type IdempotencyConflict struct {
+	StoredPayloadHash    string      `json:"stored_payload_hash"`
+	SubmittedPayloadHash string      `json:"submitted_payload_hash"`
+	DifferingFields      []FieldDiff `json:"differing_fields"`
+}
+
+// FieldDiff is one differing field; a nil side means the field is absent.
+type FieldDiff struct {
+	Field     string `json:"field"`
+	Stored    any    `json:"stored"`
+	Submitted any    `json:"submitted"`
+}
+
+// AsIdempotencyConflict decodes the conflict from the Details of a
+// ContractError, BatchItemResult or ValidationResult.
+func AsIdempotencyConflict(details map[string]any) (*IdempotencyConflict, bool) {
+	if _, ok := details["differing_fields"]; !ok {
+		return nil, false
+	}
+	b, err := json.Marshal(details)
+	if err != nil {
+		return nil, false
+	}
+	var c IdempotencyConflict
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, false
+	}
+	return &c, true
+}
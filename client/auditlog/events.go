@@ -0,0 +1,285 @@
+package auditlog
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"payscope/auditlog/jcs"
+)
+
+// Event mirrors the chaincode's LedgerEvent.
+type Event struct {
+	EventID        string   `json:"event_id"`
+	EventType      string   `json:"event_type"`
+	ArtifactHash   string   `json:"artifact_hash"`
+	SchemaVersion  string   `json:"schema_version"`
+	Timestamp      string   `json:"timestamp"`
+	TSATokenHash   string   `json:"tsa_token_hash,omitempty"`
+	TSAToken       string   `json:"tsa_token,omitempty"`
+	TTLSeconds     *int64   `json:"ttl_seconds,omitempty"`
+	Confidence     *float64 `json:"confidence,omitempty"`
+	PrevEventHash  string   `json:"prev_event_hash,omitempty"`
+	HashAlgorithm  string   `json:"hash_algorithm,omitempty"`
+	Signature      string   `json:"signature,omitempty"`
+	SignerCert     string   `json:"signer_cert,omitempty"`
+	SignerKeyID    string   `json:"signer_key_id,omitempty"`
+	Supersedes     string   `json:"supersedes,omitempty"`
+	ReferenceTx    string   `json:"reference_tx,omitempty"`
+	CorrelationID  string   `json:"correlation_id,omitempty"`
+	ParentEventIDs []string `json:"parent_event_ids,omitempty"`
+	// Free-form labels; the chaincode accepts them from schema_version v2.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Every artifact involved, with its role, next to the primary
+	// ArtifactHash.
+	Artifacts []EventArtifact `json:"artifacts,omitempty"`
+	// Required on FORECAST events from schema_version v2.
+	Forecast *ForecastDetails `json:"forecast,omitempty"`
+	// Required on AGENT_DECISION events from schema_version v2.
+	Decision *AgentDecision `json:"decision,omitempty"`
+	// Required on INGEST events from schema_version v2.
+	Ingest *IngestDetails `json:"ingest,omitempty"`
+}
+
+// IngestDetails records where an INGEST event's batch came from, how many
+// records it held and the RFC3339 window it covers.
+type IngestDetails struct {
+	SourceSystem     string `json:"source_system"`
+	RecordCount      *int64 `json:"record_count"`
+	BatchWindowStart string `json:"batch_window_start"`
+	BatchWindowEnd   string `json:"batch_window_end"`
+}
+
+// AgentDecision records which agent made an AGENT_DECISION event's
+// decision, its outcome, its confidence in [0,1] and the policy it applied.
+type AgentDecision struct {
+	AgentID       string   `json:"agent_id"`
+	DecisionCode  string   `json:"decision_code"`
+	Confidence    *float64 `json:"confidence"`
+	PolicyVersion string   `json:"policy_version"`
+}
+
+// ForecastDetails records what produced a FORECAST event: the model, how
+// far ahead it forecasts as an ISO 8601 duration such as "P30D", and the
+// SHA-256 hex of its inputs.
+type ForecastDetails struct {
+	ModelID     string `json:"model_id"`
+	Horizon     string `json:"forecast_horizon"`
+	InputDigest string `json:"input_digest"`
+}
+
+// EventArtifact is one artifact of an event and the part it played, such
+// as "input", "model" or "output".
+type EventArtifact struct {
+	ArtifactHash  string `json:"artifact_hash"`
+	Role          string `json:"role"`
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+}
+
+// StoredEvent mirrors the record the chaincode returns for an event.
+type StoredEvent struct {
+	Event                 Event           `json:"event"`
+	PayloadHash           string          `json:"payload_hash_sha256"`
+	SaltID                string          `json:"salt_id,omitempty"`
+	Producer              string          `json:"producer,omitempty"`
+	CreatorMSP            string          `json:"creator_msp,omitempty"`
+	RequiredOrgs          []string        `json:"required_orgs,omitempty"`
+	OriginalEventType     string          `json:"original_event_type,omitempty"`
+	PrivateCollection     string          `json:"private_collection,omitempty"`
+	PayloadKeyIDs         []string        `json:"payload_key_ids,omitempty"`
+	SourceFormat          string          `json:"source_format,omitempty"`
+	Canonicalization      string          `json:"canonicalization,omitempty"`
+	LedgerTimestamp       string          `json:"ledger_timestamp,omitempty"`
+	TimestampDriftFlagged bool            `json:"timestamp_drift_flagged,omitempty"`
+	ContentHash           string          `json:"content_hash,omitempty"`
+	DuplicateOf           string          `json:"duplicate_of,omitempty"`
+	TSAStamp              *TSAStamp       `json:"tsa_stamp,omitempty"`
+	Expired               *bool           `json:"expired,omitempty"`
+	SupersededBy          string          `json:"superseded_by,omitempty"`
+	Revocation            *Revocation     `json:"revocation,omitempty"`
+	Archival              *Archival       `json:"archival,omitempty"`
+	Resolution            *Resolution     `json:"resolution,omitempty"`
+	Purge                 *Purge          `json:"purge,omitempty"`
+	Artifact              *ArtifactRecord `json:"artifact,omitempty"`
+	ConfigChange          *ConfigChange   `json:"config_change,omitempty"`
+	Deletion              *Deletion       `json:"deletion,omitempty"`
+	TxID                  string          `json:"tx_id,omitempty"`
+	StorageVersion        int             `json:"storage_version,omitempty"`
+	ChannelID             string          `json:"channel_id,omitempty"`
+	Chaincode             string          `json:"chaincode,omitempty"`
+
+	// Artifact hashes as submitted, by field, where the chaincode
+	// normalized them.
+	OriginalArtifactHashes map[string]string `json:"original_artifact_hashes,omitempty"`
+	TimeOrderedKeys        bool              `json:"time_ordered_keys,omitempty"`
+}
+
+// ConfigChange is set on the CONFIG_CHANGE events the contract writes for
+// every config update.
+type ConfigChange struct {
+	BeforeHash    string   `json:"before_hash,omitempty"`
+	AfterHash     string   `json:"after_hash"`
+	ChangedFields []string `json:"changed_fields"`
+	Transaction   string   `json:"transaction"`
+	ChangedBy     string   `json:"changed_by_msp"`
+}
+
+type Revocation struct {
+	ReasonCode    string `json:"reason_code"`
+	Justification string `json:"justification"`
+	RevokedByMSP  string `json:"revoked_by_msp"`
+	TxID          string `json:"tx_id"`
+	RevokedAt     string `json:"revoked_at"`
+}
+
+type Purge struct {
+	PurgedByMSP   string `json:"purged_by_msp"`
+	MarkerEventID string `json:"marker_event_id"`
+	TxID          string `json:"tx_id"`
+	PurgedAt      string `json:"purged_at"`
+}
+
+type Archival struct {
+	RetentionSeconds int64  `json:"retention_seconds"`
+	TxID             string `json:"tx_id"`
+	ArchivedAt       string `json:"archived_at"`
+	StorageURI       string `json:"storage_uri,omitempty"`
+	StorageProof     string `json:"storage_proof,omitempty"`
+	LocationHash     string `json:"location_hash,omitempty"`
+	LocatedByMSP     string `json:"located_by_msp,omitempty"`
+	LocationTxID     string `json:"location_tx_id,omitempty"`
+	LocatedAt        string `json:"located_at,omitempty"`
+}
+
+// Resolution is set on archived events whose cold-storage location was
+// recorded: where to fetch the original and what it must match.
+type Resolution struct {
+	StorageURI        string   `json:"storage_uri"`
+	PrivateCollection string   `json:"private_collection,omitempty"`
+	ArtifactHash      string   `json:"artifact_hash"`
+	HashAlgorithm     string   `json:"hash_algorithm,omitempty"`
+	PayloadKeyIDs     []string `json:"payload_key_ids,omitempty"`
+	LocationHash      string   `json:"location_hash"`
+}
+
+type EventSummary struct {
+	EventID     string `json:"event_id"`
+	EventType   string `json:"event_type"`
+	Timestamp   string `json:"timestamp"`
+	PayloadHash string `json:"payload_hash_sha256"`
+	Revoked     bool   `json:"revoked,omitempty"`
+	Archived    bool   `json:"archived,omitempty"`
+	Deleted     bool   `json:"deleted,omitempty"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+}
+
+type EventSummaryPage struct {
+	Records      []EventSummary `json:"records"`
+	FetchedCount int32          `json:"fetched_count"`
+	Bookmark     string         `json:"bookmark"`
+}
+
+type EventPage struct {
+	Records      []StoredEvent `json:"records"`
+	FetchedCount int32         `json:"fetched_count"`
+	Bookmark     string        `json:"bookmark"`
+}
+
+// NewEvent builds an event for artifact with a fresh event_id, the current
+// UTC time and artifact_hash set to the artifact's SHA-256.
+func NewEvent(eventType, schemaVersion string, artifact []byte) (*Event, error) {
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+	return &Event{
+		EventID:       id,
+		EventType:     eventType,
+		ArtifactHash:  HashArtifact(artifact),
+		SchemaVersion: schemaVersion,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339Nano),
+	}, nil
+}
+
+// HashArtifact returns the lowercase hex SHA-256 of artifact.
+func HashArtifact(artifact []byte) string {
+	sum := sha256.Sum256(artifact)
+	return hex.EncodeToString(sum[:])
+}
+
+// PayloadHash computes the payload_hash_sha256 the chaincode stores for e:
+// SHA-256 over salt followed by e's RFC 8785 canonical JSON. Pass an empty
+// salt when the channel has none. The chaincode hashes the event after its
+// own normalization (event_type case, timestamp_offsets,
+// timestamp_precision), so compare against a StoredEvent's Event rather
+// than the event as submitted.
+func PayloadHash(e *Event, salt string) (string, error) {
+	canon, err := jcs.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(salt), canon...))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func newUUID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// NewUUIDv7 returns a random version 7 UUID stamped with t, for event ids
+// that the chaincode's time_ordered_keys mode keys in creation order.
+func NewUUIDv7(t time.Time) (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", err
+	}
+	ms := uint64(t.UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	b[6] = b[6]&0x0f | 0x70
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+type LineageNode struct {
+	EventSummary
+	Depth int `json:"depth"`
+}
+
+type LineageEdge struct {
+	Parent string `json:"parent"`
+	Child  string `json:"child"`
+}
+
+// LineageGraph is the DAG GetEventLineage returns. Depth is negative for
+// ancestors of Root and positive for descendants.
+type LineageGraph struct {
+	Root      string        `json:"root"`
+	Nodes     []LineageNode `json:"nodes"`
+	Edges     []LineageEdge `json:"edges"`
+	Truncated bool          `json:"truncated"`
+}
+
+// LineageEvent is an event with its parents embedded, each with its own.
+type LineageEvent struct {
+	StoredEvent
+	Parents []LineageEvent `json:"parents"`
+}
+
+// LineagePage is what GetEventsByTypeWithLineage returns. Truncated is
+// set when the chaincode stopped embedding parents before depth.
+type LineagePage struct {
+	Records      []LineageEvent `json:"records"`
+	FetchedCount int32          `json:"fetched_count"`
+	Bookmark     string         `json:"bookmark"`
+	Truncated    bool           `json:"truncated"`
+}
@@ -0,0 +1,109 @@
+package auditlog
+
+import "time"
+
+// PageFunc fetches the page of a listing that starts at bookmark, such as
+// a method value of ListEvents with its page size bound.
+type PageFunc func(bookmark string) (*EventPage, error)
+
+// EventIterator walks a paged listing one event at a time, holding only
+// the current page in memory. Use it like a bufio.Scanner:
+//
+//	it := c.IterateEvents(500)
+//	for it.Next() {
+//		process(it.Event())
+//	}
+//	if err := it.Err(); err != nil { ... }
+//
+// Each page is its own evaluate, so a listing that changes while it is
+// walked is not read at a single point in time.
+type EventIterator struct {
+	fetch PageFunc
+
+	page     []StoredEvent
+	pos      int
+	bookmark string // of the current page
+	next     string // of the page after it
+	done     bool
+	err      error
+	cur      *StoredEvent
+}
+
+// NewEventIterator walks the listing fetch pages through, starting at
+// bookmark ("" for the first page).
+func NewEventIterator(bookmark string, fetch PageFunc) *EventIterator {
+	return &EventIterator{fetch: fetch, bookmark: bookmark, next: bookmark}
+}
+
+// Next advances to the next event, fetching the next page when the current
+// one is used up. It returns false at the end of the listing or on error.
+func (it *EventIterator) Next() bool {
+	for it.pos == len(it.page) {
+		if it.done || it.err != nil {
+			it.cur = nil
+			return false
+		}
+		page, err := it.fetch(it.next)
+		if err != nil {
+			// Every earlier page was consumed; resume at the failed one.
+			it.err, it.cur, it.bookmark = err, nil, it.next
+			return false
+		}
+		it.page, it.pos, it.bookmark = page.Records, 0, it.next
+		// Stop on the last page, and on a bookmark that does not advance,
+		// which would otherwise return the same page forever.
+		if page.Bookmark == "" || page.FetchedCount == 0 || page.Bookmark == it.bookmark {
+			it.done = true
+		}
+		it.next = page.Bookmark
+	}
+	it.cur = &it.page[it.pos]
+	it.pos++
+	return true
+}
+
+// Event returns the event Next advanced to.
+func (it *EventIterator) Event() *StoredEvent {
+	return it.cur
+}
+
+// Err returns the error that stopped the iterator, if any.
+func (it *EventIterator) Err() error {
+	return it.err
+}
+
+// Bookmark returns the bookmark of the page holding the current event, or
+// of the page that failed to load after Err. A walk resumed from it repeats
+// at most that page's earlier events.
+func (it *EventIterator) Bookmark() string {
+	return it.bookmark
+}
+
+// IterateEvents walks all events in key order.
+func (c *Client) IterateEvents(pageSize int32) *EventIterator {
+	return NewEventIterator("", func(bookmark string) (*EventPage, error) {
+		return c.ListEvents(bookmark, pageSize)
+	})
+}
+
+// IterateEventsByType walks the events of eventType in timestamp order.
+func (c *Client) IterateEventsByType(eventType string, pageSize int32) *EventIterator {
+	return NewEventIterator("", func(bookmark string) (*EventPage, error) {
+		return c.GetEventsByType(eventType, bookmark, pageSize)
+	})
+}
+
+// IterateEventsByTimeRange walks the events with timestamps in [start, end).
+func (c *Client) IterateEventsByTimeRange(start, end time.Time, pageSize int32) *EventIterator {
+	return NewEventIterator("", func(bookmark string) (*EventPage, error) {
+		return c.QueryEventsByTimeRange(start, end, bookmark, pageSize)
+	})
+}
+
+// IterateOrgEvents walks the events written by the client's MSP in
+// timestamp order.
+func (c *Client) IterateOrgEvents(pageSize int32) *EventIterator {
+	return NewEventIterator("", func(bookmark string) (*EventPage, error) {
+		return c.ListOrgEvents(bookmark, pageSize)
+	})
+}
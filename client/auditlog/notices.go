@@ -0,0 +1,94 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WrittenNotice mirrors the chaincode event payload for one newly written
+// event.
+type WrittenNotice struct {
+	EventID     string `json:"event_id"`
+	EventType   string `json:"event_type"`
+	PayloadHash string `json:"payload_hash_sha256"`
+	TxID        string `json:"tx_id"`
+	// Trace and span of the writing transaction, when its writer sent a
+	// trace context; see TraceContext.
+	TraceID string `json:"trace_id,omitempty"`
+	SpanID  string `json:"span_id,omitempty"`
+}
+
+// TraceContext returns the writing transaction's span context, marked
+// remote and sampled, to parent spans that handle the written event; it is
+// invalid when the notice carries no trace.
+func (n *WrittenNotice) TraceContext() trace.SpanContext {
+	traceID, err := trace.TraceIDFromHex(n.TraceID)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+	spanID, err := trace.SpanIDFromHex(n.SpanID)
+	if err != nil {
+		return trace.SpanContext{}
+	}
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+}
+
+// WrittenBatch is what one committed transaction wrote: the single event of
+// an EventWritten chaincode event, or every event of an EventsWritten one.
+type WrittenBatch struct {
+	BlockNumber uint64
+	TxID        string
+	Events      []WrittenNotice
+}
+
+// WrittenEvents streams the events written by valid transactions, in commit
+// order, from startBlock or from where checkpoint left off when it holds a
+// position. Pass each batch's BlockNumber and TxID to the checkpoint's
+// CheckpointTransaction once it is handled to resume after it later. The
+// channel is closed when ctx is cancelled or the connection fails.
+func (c *Client) WrittenEvents(ctx context.Context, startBlock uint64, checkpoint client.Checkpoint) (<-chan *WrittenBatch, error) {
+	opts := []client.ChaincodeEventsOption{client.WithStartBlock(startBlock)}
+	if checkpoint != nil {
+		opts = append(opts, client.WithCheckpoint(checkpoint))
+	}
+	events, err := c.network.ChaincodeEvents(ctx, c.chaincode, opts...)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan *WrittenBatch)
+	go func() {
+		defer close(out)
+		for ev := range events {
+			batch := &WrittenBatch{BlockNumber: ev.BlockNumber, TxID: ev.TransactionID}
+			switch ev.EventName {
+			case "EventWritten":
+				var n WrittenNotice
+				if err := json.Unmarshal(ev.Payload, &n); err != nil {
+					continue
+				}
+				batch.Events = []WrittenNotice{n}
+			case "EventsWritten":
+				if err := json.Unmarshal(ev.Payload, &batch.Events); err != nil {
+					continue
+				}
+			default:
+				// Other chaincode events, such as EventsArchived.
+				continue
+			}
+			select {
+			case out <- batch:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
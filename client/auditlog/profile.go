@@ -0,0 +1,61 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Profile describes how to reach the audit log chaincode and which identity
+// to submit as. Relative paths are resolved against the profile's directory.
+type Profile struct {
+	MSPID            string `json:"msp_id"`
+	CertPath         string `json:"cert_path"`
+	KeyPath          string `json:"key_path"`
+	PeerEndpoint     string `json:"peer_endpoint"`
+	PeerHostOverride string `json:"peer_host_override,omitempty"`
+	TLSCACertPath    string `json:"tls_ca_cert_path"`
+	Channel          string `json:"channel"`
+	Chaincode        string `json:"chaincode"`
+}
+
+// LoadProfile reads a JSON connection profile from path.
+func LoadProfile(path string) (*Profile, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Profile
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("parse profile %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for _, f := range []*string{&p.CertPath, &p.KeyPath, &p.TLSCACertPath} {
+		if *f != "" && !filepath.IsAbs(*f) {
+			*f = filepath.Join(dir, *f)
+		}
+	}
+	if p.Channel == "" {
+		p.Channel = "payscopechannel"
+	}
+	if p.Chaincode == "" {
+		p.Chaincode = "auditlog"
+	}
+	return &p, p.validate()
+}
+
+func (p *Profile) validate() error {
+	for name, v := range map[string]string{
+		"msp_id":           p.MSPID,
+		"cert_path":        p.CertPath,
+		"key_path":         p.KeyPath,
+		"peer_endpoint":    p.PeerEndpoint,
+		"tls_ca_cert_path": p.TLSCACertPath,
+	} {
+		if v == "" {
+			return fmt.Errorf("profile: %s required", name)
+		}
+	}
+	return nil
+}
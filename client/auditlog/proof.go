@@ -0,0 +1,148 @@
+package auditlog
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// EventProof is the chaincode's record of the transaction that wrote an
+// event. Block is filled in by GetEventProof when asked to fetch the block.
+type EventProof struct {
+	Receipt
+	LedgerKey string      `json:"ledger_key"`
+	Block     *BlockProof `json:"block,omitempty"`
+}
+
+// BlockProof ties a transaction to a committed block. HeaderHash is what
+// the next block records as its previous_hash, so anyone holding the
+// following blocks, e.g. from another org's peer, can check the block is
+// on the chain. The transaction only took effect if ValidationCode is
+// "VALID".
+type BlockProof struct {
+	BlockNumber    uint64 `json:"block_number"`
+	HeaderHash     string `json:"header_hash"`
+	PreviousHash   string `json:"previous_hash"`
+	DataHash       string `json:"data_hash"`
+	DataHashValid  bool   `json:"data_hash_valid"`
+	TxIndex        int    `json:"tx_index"`
+	ValidationCode string `json:"validation_code"`
+}
+
+// GetEventProof returns the write receipt of eventID. With withBlock it
+// also fetches the committing block from the peer's qscc system chaincode
+// and checks the transaction's place in it.
+func (c *Client) GetEventProof(eventID string, withBlock bool) (*EventProof, error) {
+	b, err := c.evaluate("GetEventProof", eventID)
+	if err != nil {
+		return nil, err
+	}
+	var p EventProof
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	if withBlock {
+		if p.Block, err = c.blockProof(p.TxID); err != nil {
+			return nil, err
+		}
+	}
+	return &p, nil
+}
+
+func (c *Client) blockProof(txID string) (*BlockProof, error) {
+	b, err := c.network.GetContract("qscc").EvaluateTransaction("GetBlockByTxID", c.network.Name(), txID)
+	if err != nil {
+		return nil, err
+	}
+	var block common.Block
+	if err := proto.Unmarshal(b, &block); err != nil {
+		return nil, fmt.Errorf("decode block: %w", err)
+	}
+	h := block.GetHeader()
+	dataHash := sha256.Sum256(bytes.Join(block.GetData().GetData(), nil))
+	headerHash, err := BlockHeaderHash(h)
+	if err != nil {
+		return nil, err
+	}
+	p := &BlockProof{
+		BlockNumber:   h.GetNumber(),
+		HeaderHash:    hex.EncodeToString(headerHash),
+		PreviousHash:  hex.EncodeToString(h.GetPreviousHash()),
+		DataHash:      hex.EncodeToString(h.GetDataHash()),
+		DataHashValid: bytes.Equal(dataHash[:], h.GetDataHash()),
+		TxIndex:       -1,
+	}
+	for i, data := range block.GetData().GetData() {
+		id, err := envelopeTxID(data)
+		if err != nil {
+			return nil, fmt.Errorf("block %d transaction %d: %w", p.BlockNumber, i, err)
+		}
+		if id == txID {
+			p.TxIndex = i
+			break
+		}
+	}
+	if p.TxIndex < 0 {
+		return nil, fmt.Errorf("transaction %s not in block %d", txID, p.BlockNumber)
+	}
+	filter := block.GetMetadata().GetMetadata()
+	if int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) >= len(filter) ||
+		p.TxIndex >= len(filter[common.BlockMetadataIndex_TRANSACTIONS_FILTER]) {
+		return nil, fmt.Errorf("block %d has no validation code for transaction %d", p.BlockNumber, p.TxIndex)
+	}
+	p.ValidationCode = peer.TxValidationCode(filter[common.BlockMetadataIndex_TRANSACTIONS_FILTER][p.TxIndex]).String()
+	return p, nil
+}
+
+// BlockHeight returns the number of blocks on the channel, from the peer's
+// qscc system chaincode.
+func (c *Client) BlockHeight() (uint64, error) {
+	b, err := c.network.GetContract("qscc").EvaluateTransaction("GetChainInfo", c.network.Name())
+	if err != nil {
+		return 0, err
+	}
+	var info common.BlockchainInfo
+	if err := proto.Unmarshal(b, &info); err != nil {
+		return 0, fmt.Errorf("decode chain info: %w", err)
+	}
+	return info.GetHeight(), nil
+}
+
+// BlockHeaderHash hashes a block header the way the orderer chains blocks:
+// SHA-256 over the ASN.1 encoding of number, previous hash and data hash.
+func BlockHeaderHash(h *common.BlockHeader) ([]byte, error) {
+	der, err := asn1.Marshal(struct {
+		Number       *big.Int
+		PreviousHash []byte
+		DataHash     []byte
+	}{new(big.Int).SetUint64(h.GetNumber()), h.GetPreviousHash(), h.GetDataHash()})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return sum[:], nil
+}
+
+func envelopeTxID(data []byte) (string, error) {
+	var env common.Envelope
+	if err := proto.Unmarshal(data, &env); err != nil {
+		return "", err
+	}
+	var payload common.Payload
+	if err := proto.Unmarshal(env.GetPayload(), &payload); err != nil {
+		return "", err
+	}
+	var ch common.ChannelHeader
+	if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), &ch); err != nil {
+		return "", err
+	}
+	return ch.GetTxId(), nil
+}
@@ -0,0 +1,138 @@
+package auditlog
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// MarshalEventProto encodes e as the LedgerEvent message of the chaincode's
+// proto/ledger_event.proto. Empty fields are omitted, as proto3 does.
+func MarshalEventProto(e *Event) ([]byte, error) {
+	var b []byte
+	str := func(num protowire.Number, v string) {
+		if v != "" {
+			b = protowire.AppendTag(b, num, protowire.BytesType)
+			b = protowire.AppendString(b, v)
+		}
+	}
+	str(1, e.EventID)
+	str(2, e.EventType)
+	str(3, e.ArtifactHash)
+	str(4, e.SchemaVersion)
+	str(5, e.Timestamp)
+	str(6, e.TSATokenHash)
+	if e.TTLSeconds != nil {
+		b = protowire.AppendTag(b, 7, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(*e.TTLSeconds))
+	}
+	if e.Confidence != nil {
+		b = protowire.AppendTag(b, 8, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(*e.Confidence))
+	}
+	str(9, e.PrevEventHash)
+	str(10, e.HashAlgorithm)
+	if e.Signature != "" {
+		sig, err := base64.StdEncoding.DecodeString(e.Signature)
+		if err != nil {
+			return nil, fmt.Errorf("signature must be base64: %w", err)
+		}
+		b = protowire.AppendTag(b, 11, protowire.BytesType)
+		b = protowire.AppendBytes(b, sig)
+	}
+	str(12, e.SignerCert)
+	str(13, e.Supersedes)
+	str(14, e.ReferenceTx)
+	str(15, e.CorrelationID)
+	for _, p := range e.ParentEventIDs {
+		str(16, p)
+	}
+	// Map entries in key order, so an event always encodes the same way.
+	keys := make([]string, 0, len(e.Metadata))
+	for k := range e.Metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = protowire.AppendTag(entry, 1, protowire.BytesType)
+		entry = protowire.AppendString(entry, k)
+		entry = protowire.AppendTag(entry, 2, protowire.BytesType)
+		entry = protowire.AppendString(entry, e.Metadata[k])
+		b = protowire.AppendTag(b, 17, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	for _, a := range e.Artifacts {
+		b = protowire.AppendTag(b, 18, protowire.BytesType)
+		b = protowire.AppendBytes(b, stringsMessage(a.ArtifactHash, a.Role, a.HashAlgorithm))
+	}
+	if f := e.Forecast; f != nil {
+		b = protowire.AppendTag(b, 19, protowire.BytesType)
+		b = protowire.AppendBytes(b, stringsMessage(f.ModelID, f.Horizon, f.InputDigest))
+	}
+	if d := e.Decision; d != nil {
+		msg := stringsMessage(d.AgentID, d.DecisionCode)
+		if d.Confidence != nil {
+			msg = protowire.AppendTag(msg, 3, protowire.Fixed64Type)
+			msg = protowire.AppendFixed64(msg, math.Float64bits(*d.Confidence))
+		}
+		if d.PolicyVersion != "" {
+			msg = protowire.AppendTag(msg, 4, protowire.BytesType)
+			msg = protowire.AppendString(msg, d.PolicyVersion)
+		}
+		b = protowire.AppendTag(b, 20, protowire.BytesType)
+		b = protowire.AppendBytes(b, msg)
+	}
+	if in := e.Ingest; in != nil {
+		msg := stringsMessage(in.SourceSystem)
+		if in.RecordCount != nil {
+			msg = protowire.AppendTag(msg, 2, protowire.VarintType)
+			msg = protowire.AppendVarint(msg, uint64(*in.RecordCount))
+		}
+		for i, v := range []string{in.BatchWindowStart, in.BatchWindowEnd} {
+			if v != "" {
+				msg = protowire.AppendTag(msg, protowire.Number(i+3), protowire.BytesType)
+				msg = protowire.AppendString(msg, v)
+			}
+		}
+		b = protowire.AppendTag(b, 21, protowire.BytesType)
+		b = protowire.AppendBytes(b, msg)
+	}
+	if e.TSAToken != "" {
+		token, err := base64.StdEncoding.DecodeString(e.TSAToken)
+		if err != nil {
+			return nil, fmt.Errorf("tsa_token must be base64: %w", err)
+		}
+		b = protowire.AppendTag(b, 22, protowire.BytesType)
+		b = protowire.AppendBytes(b, token)
+	}
+	str(23, e.SignerKeyID)
+	return b, nil
+}
+
+// stringsMessage encodes a nested message whose fields 1, 2, ... are the
+// string values, leaving out empty ones.
+func stringsMessage(values ...string) []byte {
+	var msg []byte
+	for i, v := range values {
+		if v != "" {
+			msg = protowire.AppendTag(msg, protowire.Number(i+1), protowire.BytesType)
+			msg = protowire.AppendString(msg, v)
+		}
+	}
+	return msg
+}
+
+// PutEventProto records e through PutEventProto, the protobuf counterpart
+// of PutEvent, and returns its receipt.
+func (c *Client) PutEventProto(e *Event) (*Receipt, error) {
+	b, err := MarshalEventProto(e)
+	if err != nil {
+		return nil, err
+	}
+	return c.submitReceipt("PutEventProto", client.WithArguments(base64.StdEncoding.EncodeToString(b)))
+}
@@ -0,0 +1,139 @@
+package auditlog
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	"payscope/auditlog/jcs"
+)
+
+type MerkleDigest struct {
+	DigestID    string `json:"digest_id"`
+	Start       string `json:"start"`
+	End         string `json:"end"`
+	Root        string `json:"merkle_root"`
+	LeafCount   int    `json:"leaf_count"`
+	CommittedAt string `json:"committed_at"`
+}
+
+// AuditReport mirrors the chaincode's ExportAuditReport result.
+type AuditReport struct {
+	Start         string         `json:"start"`
+	End           string         `json:"end"`
+	AsOf          string         `json:"as_of"`
+	Total         int            `json:"total"`
+	TypeCounts    map[string]int `json:"type_counts"`
+	EventSetHash  string         `json:"event_set_hash"`
+	MerkleRoot    string         `json:"merkle_root,omitempty"`
+	Digest        *MerkleDigest  `json:"digest,omitempty"`
+	DigestMatches bool           `json:"digest_matches"`
+}
+
+// SignedAuditReport is an AuditReport signed by the exporting identity.
+// Signature is over the RFC 8785 form of the report with Signature empty,
+// using the same schemes as event signatures: ECDSA ASN.1 or RSA PKCS#1
+// v1.5 over SHA-256, or Ed25519 over the message.
+type SignedAuditReport struct {
+	Report      AuditReport `json:"report"`
+	GeneratedAt string      `json:"generated_at"`
+	SignerMSP   string      `json:"signer_msp"`
+	SignerCert  string      `json:"signer_cert"`
+	Signature   string      `json:"signature,omitempty"`
+}
+
+func (r *SignedAuditReport) signedMessage() ([]byte, error) {
+	unsigned := *r
+	unsigned.Signature = ""
+	return jcs.Marshal(unsigned)
+}
+
+// ExportAuditReport fetches the report for [start, end) and signs it with
+// the client's identity.
+func (c *Client) ExportAuditReport(start, end time.Time) (*SignedAuditReport, error) {
+	b, err := c.evaluate("ExportAuditReport", start.UTC().Format(time.RFC3339Nano), end.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return nil, err
+	}
+	signed := &SignedAuditReport{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339Nano),
+		SignerMSP:   c.mspID,
+		SignerCert:  string(c.certPEM),
+	}
+	if err := json.Unmarshal(b, &signed.Report); err != nil {
+		return nil, err
+	}
+	msg, err := signed.signedMessage()
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signMessage(c.key, msg)
+	if err != nil {
+		return nil, err
+	}
+	signed.Signature = base64.StdEncoding.EncodeToString(sig)
+	return signed, nil
+}
+
+// VerifyAuditReport checks r's signature against its embedded certificate.
+// It does not check that certificate against the signer's MSP; auditors
+// should validate it with the organisation's CA.
+func VerifyAuditReport(r *SignedAuditReport) error {
+	block, _ := pem.Decode([]byte(r.SignerCert))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return errors.New("signer_cert must be a PEM certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return errors.New("signature must be base64")
+	}
+	msg, err := r.signedMessage()
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(msg)
+
+	ok := false
+	switch pub := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(pub, digest[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, msg, sig)
+	default:
+		return fmt.Errorf("unsupported signer key type %T", pub)
+	}
+	if !ok {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+func signMessage(key crypto.PrivateKey, msg []byte) ([]byte, error) {
+	digest := sha256.Sum256(msg)
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return ecdsa.SignASN1(rand.Reader, k, digest[:])
+	case *rsa.PrivateKey:
+		return rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case ed25519.PrivateKey:
+		return ed25519.Sign(k, msg), nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+}
@@ -0,0 +1,240 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryPolicy controls how submits are retried.
+//
+// A transaction that fails validation with MVCC_READ_CONFLICT or
+// PHANTOM_READ_CONFLICT did not commit, and neither did one whose
+// endorsement could not reach a peer, so those are retried for every
+// transaction. When a submit fails after the transaction went to the
+// orderer, or its commit status cannot be read, it may have committed.
+// Such failures are retried only for the idempotent writes (PutEvent,
+// PutEventProto, PutEvents and RegisterArtifact) and only with
+// RetryUnknownCommit: the chaincode recognises the resubmitted event by its
+// event_id and payload hash, and a Receipt with Deduped set then means an
+// earlier attempt committed it.
+type RetryPolicy struct {
+	// Attempts in total, including the first; 1 disables retries.
+	MaxAttempts int
+	// Delay before the first retry, multiplied by Multiplier (2 when 0)
+	// before each further one and capped at MaxBackoff when that is set.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	Multiplier     float64
+	// Spreads each delay randomly by up to this fraction, e.g. 0.2 for
+	// ±20%, so clients that conflicted together do not retry together.
+	Jitter float64
+	// Resubmit idempotent writes whose outcome is unknown.
+	RetryUnknownCommit bool
+}
+
+// DefaultRetryPolicy is the policy Connect starts from: 3 attempts from
+// 100ms, doubling up to 2s with 20% jitter, and idempotent writes
+// resubmitted when their outcome is unknown.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:        3,
+		InitialBackoff:     100 * time.Millisecond,
+		MaxBackoff:         2 * time.Second,
+		Multiplier:         2,
+		Jitter:             0.2,
+		RetryUnknownCommit: true,
+	}
+}
+
+// WithRetryPolicy replaces the retry policy.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+// WithRetry sets how many times a submit is attempted and the delay before
+// the first retry, keeping the rest of the policy.
+func WithRetry(maxAttempts int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retry.MaxAttempts = maxAttempts
+		c.retry.InitialBackoff = backoff
+	}
+}
+
+// SubmitAttempt describes one submit attempt to the hooks registered with
+// WithSubmitHook, e.g. to count conflicts and retries per transaction.
+type SubmitAttempt struct {
+	Transaction string
+	// 1 for the first attempt.
+	Attempt  int
+	Duration time.Duration
+	// Nil when the attempt succeeded.
+	Err error
+	// "conflict", "unavailable" or "unknown_commit" when Err is one of the
+	// failures RetryPolicy describes, otherwise empty.
+	Failure string
+	// Whether the submit is retried, and after how long.
+	Retrying   bool
+	RetryDelay time.Duration
+}
+
+// WithSubmitHook calls fn after every submit attempt. Hooks run on the
+// submitting goroutine and should return quickly.
+func WithSubmitHook(fn func(SubmitAttempt)) Option {
+	return func(c *Client) { c.submitHooks = append(c.submitHooks, fn) }
+}
+
+// idempotentWrites are the transactions that are safe to resubmit when it
+// is unknown whether an earlier attempt committed.
+var idempotentWrites = map[string]bool{
+	"PutEvent":                          true,
+	"PutEventProto":                     true,
+	"PutEvents":                         true,
+	"ArtifactRegistry:RegisterArtifact": true,
+}
+
+// classifyFailure names the kind of a submit failure the retry policy
+// handles, or returns "" for any other error.
+func classifyFailure(err error) string {
+	var commitErr *client.CommitError
+	if errors.As(err, &commitErr) {
+		if commitErr.Code == peer.TxValidationCode_MVCC_READ_CONFLICT ||
+			commitErr.Code == peer.TxValidationCode_PHANTOM_READ_CONFLICT {
+			return "conflict"
+		}
+		return ""
+	}
+	var endorseErr *client.EndorseError
+	if errors.As(err, &endorseErr) {
+		if status.Code(err) == codes.Unavailable {
+			return "unavailable"
+		}
+		return ""
+	}
+	var submitErr *client.SubmitError
+	var statusErr *client.CommitStatusError
+	if errors.As(err, &submitErr) || errors.As(err, &statusErr) {
+		return "unknown_commit"
+	}
+	return ""
+}
+
+func (p *RetryPolicy) retries(name, failure string) bool {
+	switch failure {
+	case "conflict", "unavailable":
+		return true
+	case "unknown_commit":
+		return p.RetryUnknownCommit && idempotentWrites[name]
+	}
+	return false
+}
+
+// delay is the wait before retry n, counting from 1.
+func (p *RetryPolicy) delay(n int) time.Duration {
+	mult := p.Multiplier
+	if mult == 0 {
+		mult = 2
+	}
+	d := float64(p.InitialBackoff)
+	for i := 1; i < n; i++ {
+		d *= mult
+		if p.MaxBackoff > 0 && d >= float64(p.MaxBackoff) {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > float64(p.MaxBackoff) {
+		d = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+func (c *Client) submit(name string, opts ...client.ProposalOption) ([]byte, error) {
+	return c.submitTransient(name, nil, opts...)
+}
+
+// submitTransient submits name with transient data, to which it adds the
+// transaction's trace context.
+func (c *Client) submitTransient(name string, transient map[string][]byte, opts ...client.ProposalOption) ([]byte, error) {
+	ctx, span := c.startSpan(c.baseContext(), "submit", name)
+	if transient = withTraceContext(ctx, transient); transient != nil {
+		opts = append(opts, client.WithTransient(transient))
+	}
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		result, err := c.submitAttempt(ctx, name, attempt, opts)
+		a := SubmitAttempt{Transaction: name, Attempt: attempt, Duration: time.Since(start), Err: err}
+		if err != nil {
+			a.Failure = classifyFailure(err)
+			if attempt < c.retry.MaxAttempts && c.retry.retries(name, a.Failure) {
+				a.Retrying, a.RetryDelay = true, c.retry.delay(attempt)
+			}
+		}
+		for _, hook := range c.submitHooks {
+			hook(a)
+		}
+		if !a.Retrying {
+			endSpan(span, err)
+			return result, err
+		}
+		span.AddEvent("retry", trace.WithAttributes(
+			attribute.Int("auditlog.attempt", attempt),
+			attribute.String("auditlog.failure", a.Failure),
+		))
+		time.Sleep(a.RetryDelay)
+	}
+}
+
+// submitAttempt is Contract.Submit with a span for each phase: endorsement
+// by the peers, submission to the orderer and waiting for the commit.
+func (c *Client) submitAttempt(ctx context.Context, name string, attempt int, opts []client.ProposalOption) ([]byte, error) {
+	proposal, err := c.contract.NewProposal(name, opts...)
+	if err != nil {
+		return nil, err
+	}
+	attrs := trace.WithAttributes(
+		attribute.String("fabric.tx_id", proposal.TransactionID()),
+		attribute.Int("auditlog.attempt", attempt),
+	)
+
+	phaseCtx, span := c.tracer.Start(ctx, name+" endorse", attrs)
+	tx, err := proposal.EndorseWithContext(phaseCtx)
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+
+	phaseCtx, span = c.tracer.Start(ctx, name+" submit", attrs)
+	commit, err := tx.SubmitWithContext(phaseCtx)
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+
+	phaseCtx, span = c.tracer.Start(ctx, name+" commit", attrs)
+	status, err := commit.StatusWithContext(phaseCtx)
+	if err == nil {
+		span.SetAttributes(
+			attribute.Int64("fabric.block_number", int64(status.BlockNumber)),
+			attribute.String("fabric.validation_code", status.Code.String()),
+		)
+		if !status.Successful {
+			err = &commitFailure{&client.CommitError{TransactionID: status.TransactionID, Code: status.Code}}
+		}
+	}
+	endSpan(span, err)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Result(), nil
+}
@@ -0,0 +1,147 @@
+package auditlog
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+
+	"payscope/auditlog/jcs"
+)
+
+// SignerKey mirrors the chaincode's registry record of an org's event
+// signing key.
+type SignerKey struct {
+	KeyID           string `json:"key_id"`
+	MSPID           string `json:"msp_id"`
+	PublicKey       string `json:"public_key"`
+	Certificate     string `json:"certificate,omitempty"`
+	Label           string `json:"label,omitempty"`
+	ValidFrom       string `json:"valid_from"`
+	ValidUntil      string `json:"valid_until,omitempty"`
+	Status          string `json:"status"`
+	RevokedAt       string `json:"revoked_at,omitempty"`
+	RevokedReason   string `json:"revoked_reason,omitempty"`
+	RevokedByMSP    string `json:"revoked_by_msp,omitempty"`
+	RegisteredByMSP string `json:"registered_by_msp"`
+	TxID            string `json:"tx_id"`
+	RegisteredAt    string `json:"registered_at"`
+}
+
+// KeyRegistration is RegisterKey's input: MSPID and either a PEM PUBLIC KEY
+// or a PEM certificate. Empty validity bounds take the chaincode defaults.
+type KeyRegistration struct {
+	MSPID       string `json:"msp_id"`
+	PublicKey   string `json:"public_key,omitempty"`
+	Certificate string `json:"certificate,omitempty"`
+	Label       string `json:"label,omitempty"`
+	ValidFrom   string `json:"valid_from,omitempty"`
+	ValidUntil  string `json:"valid_until,omitempty"`
+}
+
+// SignatureCheck mirrors VerifyEventSignature's result.
+type SignatureCheck struct {
+	EventID     string `json:"event_id"`
+	Signed      bool   `json:"signed"`
+	SignerKeyID string `json:"signer_key_id,omitempty"`
+	KeyStatus   string `json:"key_status,omitempty"`
+	Valid       bool   `json:"valid"`
+	Detail      string `json:"detail,omitempty"`
+}
+
+// SignerKeyID returns the registry id of pub: the hex SHA-256 of its DER
+// SubjectPublicKeyInfo.
+func SignerKeyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// SignEvent signs e with key, registered under keyID, setting Signature and
+// SignerKeyID. Sign last: any later change to e invalidates the signature.
+func SignEvent(e *Event, key crypto.PrivateKey, keyID string) error {
+	e.SignerKeyID, e.SignerCert = keyID, ""
+	unsigned := *e
+	unsigned.Signature = ""
+	msg, err := jcs.Marshal(unsigned)
+	if err != nil {
+		return err
+	}
+	sig, err := signMessage(key, msg)
+	if err != nil {
+		return err
+	}
+	e.Signature = base64.StdEncoding.EncodeToString(sig)
+	return nil
+}
+
+// RegisterKey adds a signing key to the registry. Admins only.
+func (c *Client) RegisterKey(r *KeyRegistration) (*SignerKey, error) {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeSignerKey(c.submit("RegisterKey", client.WithArguments(string(b))))
+}
+
+// RevokeKey revokes a signing key as of effective, or now when effective is
+// zero. Events written with it before then stay valid. Admins only.
+func (c *Client) RevokeKey(keyID, reason string, effective time.Time) (*SignerKey, error) {
+	at := ""
+	if !effective.IsZero() {
+		at = effective.UTC().Format(time.RFC3339Nano)
+	}
+	return decodeSignerKey(c.submit("RevokeKey", client.WithArguments(keyID, reason, at)))
+}
+
+// GetSignerKey reads one registered signing key.
+func (c *Client) GetSignerKey(keyID string) (*SignerKey, error) {
+	return decodeSignerKey(c.evaluate("GetSignerKey", keyID))
+}
+
+// SignerKeys lists the keys registered for mspID, or for all orgs when it
+// is empty.
+func (c *Client) SignerKeys(mspID string) ([]SignerKey, error) {
+	b, err := c.evaluate("ListSignerKeys", mspID)
+	if err != nil {
+		return nil, err
+	}
+	var keys []SignerKey
+	if err := json.Unmarshal(b, &keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// VerifyEventSignature has the chaincode re-check a stored event's
+// signature against the registry.
+func (c *Client) VerifyEventSignature(eventID string) (*SignatureCheck, error) {
+	b, err := c.evaluate("VerifyEventSignature", eventID)
+	if err != nil {
+		return nil, err
+	}
+	var r SignatureCheck
+	if err := json.Unmarshal(b, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func decodeSignerKey(b []byte, err error) (*SignerKey, error) {
+	if err != nil {
+		return nil, err
+	}
+	var k SignerKey
+	if err := json.Unmarshal(b, &k); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
@@ -0,0 +1,78 @@
+package auditlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SnapshotRecord is one event record as stored on the ledger.
+type SnapshotRecord struct {
+	Key         string          `json:"key"`
+	Value       json.RawMessage `json:"value"`
+	PayloadHash string          `json:"payload_hash_sha256"`
+	ValueHash   string          `json:"value_sha256"`
+}
+
+type SnapshotPage struct {
+	Records       []SnapshotRecord `json:"records"`
+	FetchedCount  int32            `json:"fetched_count"`
+	RunningDigest string           `json:"running_digest"`
+	Bookmark      string           `json:"bookmark"`
+	Complete      bool             `json:"complete"`
+}
+
+// SnapshotDigestStart is the running digest before the first record.
+var SnapshotDigestStart = strings.Repeat("0", 64)
+
+// ExtendSnapshotDigest folds records into the running digest prev the way
+// the chaincode's ExportSnapshot does, so a stored backup can be checked
+// against the digest the ledger reported.
+func ExtendSnapshotDigest(prev string, records []SnapshotRecord) (string, error) {
+	d, err := hex.DecodeString(prev)
+	if err != nil || len(d) != sha256.Size {
+		return "", fmt.Errorf("invalid running digest %q", prev)
+	}
+	for _, r := range records {
+		k, v := sha256.Sum256([]byte(r.Key)), sha256.Sum256(r.Value)
+		h := sha256.New()
+		h.Write(d)
+		h.Write(k[:])
+		h.Write(v[:])
+		d = h.Sum(nil)
+	}
+	return hex.EncodeToString(d), nil
+}
+
+// ExportSnapshot pages through the whole event keyspace, passing each page
+// to fn after checking its records against the page's running digest. It
+// returns the final digest.
+func (c *Client) ExportSnapshot(pageSize int32, fn func(*SnapshotPage) error) (string, error) {
+	bookmark, digest := "", SnapshotDigestStart
+	for {
+		b, err := c.evaluate("ExportSnapshot", bookmark, strconv.Itoa(int(pageSize)))
+		if err != nil {
+			return "", err
+		}
+		var page SnapshotPage
+		if err := json.Unmarshal(b, &page); err != nil {
+			return "", err
+		}
+		if digest, err = ExtendSnapshotDigest(digest, page.Records); err != nil {
+			return "", err
+		}
+		if digest != page.RunningDigest {
+			return "", fmt.Errorf("snapshot page digest mismatch: computed %s, chaincode reported %s", digest, page.RunningDigest)
+		}
+		if err := fn(&page); err != nil {
+			return "", err
+		}
+		if page.Complete || page.Bookmark == "" {
+			return digest, nil
+		}
+		bookmark = page.Bookmark
+	}
+}
@@ -0,0 +1,98 @@
+package auditlog
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Every evaluate and submit is an OpenTelemetry client span named after its
+// transaction, a child of the span in the context given to WithContext. A
+// submit adds one endorse, submit and commit span per attempt. The
+// transaction span's W3C trace context goes to the chaincode in the
+// transient map under "traceparent"; the chaincode copies its trace and
+// span IDs into the EventWritten and EventsWritten notices.
+const (
+	tracerName        = "payscope/client/auditlog"
+	transientTraceKey = "traceparent"
+)
+
+// WithTracerProvider sets where spans go; the global provider by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *Client) { c.tracer = tp.Tracer(tracerName) }
+}
+
+// WithContext returns a shallow copy of c whose calls use ctx: they end
+// when ctx does and their spans are children of its span. The copy shares
+// c's connection, which only c should close.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	c2 := *c
+	c2.ctx = ctx
+	return &c2
+}
+
+func (c *Client) baseContext() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
+func (c *Client) startSpan(ctx context.Context, op, name string) (context.Context, trace.Span) {
+	return c.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("fabric.channel", c.network.Name()),
+			attribute.String("fabric.chaincode", c.chaincode),
+			attribute.String("fabric.operation", op),
+			attribute.String("fabric.transaction", name),
+		))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// withTraceContext adds the trace context of ctx to transient. It returns
+// transient itself when there is no trace to propagate.
+func withTraceContext(ctx context.Context, transient map[string][]byte) map[string][]byte {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	tp := carrier.Get(transientTraceKey)
+	if tp == "" {
+		return transient
+	}
+	out := make(map[string][]byte, len(transient)+1)
+	for k, v := range transient {
+		out[k] = v
+	}
+	out[transientTraceKey] = []byte(tp)
+	return out
+}
+
+func defaultTracer() trace.Tracer {
+	return otel.GetTracerProvider().Tracer(tracerName)
+}
+
+// commitFailure is the error Contract.Submit returns for a transaction that
+// fails validation, which the gateway package offers no way to build.
+// errors.As finds the *client.CommitError it wraps.
+type commitFailure struct {
+	err *client.CommitError
+}
+
+func (f *commitFailure) Error() string {
+	return fmt.Sprintf("transaction %s failed to commit with status code %d (%s)", f.err.TransactionID, int32(f.err.Code), f.err.Code)
+}
+
+func (f *commitFailure) Unwrap() error { return f.err }
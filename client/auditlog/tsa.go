@@ -0,0 +1,114 @@
+package auditlog
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+
+	"payscope/auditlog/jcs"
+)
+
+// TSAStamp is what the chaincode read from a verified tsa_token.
+type TSAStamp struct {
+	GenTime   string `json:"gen_time"`
+	Authority string `json:"authority"`
+	Serial    string `json:"serial"`
+	Policy    string `json:"policy"`
+}
+
+// TimestampedMessage returns the message an event's tsa_token must cover:
+// the RFC 8785 form of e with TSAToken, TSATokenHash, Signature and
+// SignerCert left out.
+// Timestamp an event before signing it, since the signature covers the
+// token. Like PayloadHash, it is taken over the event after the chaincode's
+// normalization, so submit normalized fields.
+func TimestampedMessage(e *Event) ([]byte, error) {
+	unstamped := *e
+	unstamped.TSAToken, unstamped.TSATokenHash = "", ""
+	unstamped.Signature, unstamped.SignerCert = "", ""
+	return jcs.Marshal(unstamped)
+}
+
+var oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		HashedMessage []byte
+	}
+	Nonce   *big.Int
+	CertReq bool
+}
+
+type timeStampResp struct {
+	Status struct {
+		Status int
+	}
+	Token asn1.RawValue `asn1:"optional"`
+}
+
+// RequestTimestamp asks the RFC 3161 authority at tsaURL to timestamp e and
+// sets e.TSAToken to the token it returns. The request asks for the
+// authority's certificate to be embedded, so the chaincode's
+// tsa_certificates may hold just its CA.
+func RequestTimestamp(ctx context.Context, tsaURL string, e *Event) error {
+	msg, err := TimestampedMessage(e)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(msg)
+	nonce, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 64))
+	if err != nil {
+		return err
+	}
+	req := timeStampReq{Version: 1, Nonce: nonce, CertReq: true}
+	req.MessageImprint.HashAlgorithm.Algorithm = oidSHA256
+	req.MessageImprint.HashedMessage = digest[:]
+	body, err := asn1.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, tsaURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/timestamp-query")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return fmt.Errorf("tsa: %w", err)
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("tsa: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("tsa: %s", resp.Status)
+	}
+	var tsr timeStampResp
+	if _, err := asn1.Unmarshal(b, &tsr); err != nil {
+		return fmt.Errorf("tsa: malformed response: %w", err)
+	}
+	// 0 is granted, 1 granted with modifications.
+	if tsr.Status.Status > 1 || len(tsr.Token.FullBytes) == 0 {
+		return fmt.Errorf("tsa: request rejected with status %d", tsr.Status.Status)
+	}
+	// A cheap check that the token is for this event; the chaincode
+	// verifies it in full.
+	if !bytes.Contains(tsr.Token.FullBytes, digest[:]) {
+		return errors.New("tsa: token does not cover the event")
+	}
+	e.TSAToken = base64.StdEncoding.EncodeToString(tsr.Token.FullBytes)
+	return nil
+}
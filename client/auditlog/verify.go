@@ -0,0 +1,115 @@
+package auditlog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+type VerifyResult struct {
+	EventID      string `json:"event_id"`
+	Found        bool   `json:"found"`
+	Consistent   bool   `json:"consistent"`
+	StoredHash   string `json:"stored_hash,omitempty"`
+	ComputedHash string `json:"computed_hash,omitempty"`
+	// Tombstoned by a governed deletion; not a failure.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+type VerifyBatchReport struct {
+	Results []VerifyResult `json:"results"`
+	Summary struct {
+		Total    int `json:"total"`
+		Failures int `json:"failures"`
+	} `json:"summary"`
+}
+
+type ChainBreak struct {
+	EventID string `json:"event_id"`
+	Kind    string `json:"kind"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+type ChainReport struct {
+	StartEventID  string       `json:"start_event_id"`
+	EndEventID    string       `json:"end_event_id"`
+	VerifiedCount int          `json:"verified_count"`
+	ReachedStart  bool         `json:"reached_start"`
+	Intact        bool         `json:"intact"`
+	Breaks        []ChainBreak `json:"breaks"`
+	ResumeFrom    string       `json:"resume_from,omitempty"`
+}
+
+// ListEvents returns one page of all events in key order.
+func (c *Client) ListEvents(bookmark string, pageSize int32) (*EventPage, error) {
+	return c.queryPage("ListEvents", "full", bookmark, strconv.Itoa(int(pageSize)))
+}
+
+// VerifyEvents has the chaincode recompute and compare the payload hashes of
+// eventIDs.
+func (c *Client) VerifyEvents(eventIDs []string) (*VerifyBatchReport, error) {
+	ids, err := json.Marshal(eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	b, err := c.evaluate("VerifyEventsBatch", string(ids))
+	if err != nil {
+		return nil, err
+	}
+	var report VerifyBatchReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// VerifyChain checks the prev_event_hash chain from endEventID back to
+// startEventID. Follow ResumeFrom for chains longer than one call covers.
+func (c *Client) VerifyChain(startEventID, endEventID string) (*ChainReport, error) {
+	b, err := c.evaluate("VerifyChain", startEventID, endEventID)
+	if err != nil {
+		return nil, err
+	}
+	var report ChainReport
+	if err := json.Unmarshal(b, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// MerkleRoot computes the root the chaincode's CommitDigest stores over
+// payloadHashes, in the order given:
+//
+//	leaf = sha256(0x00 || payload_hash)
+//	node = sha256(0x01 || left || right)
+//
+// with hashes as raw bytes and an unpaired node moving up unchanged.
+func MerkleRoot(payloadHashes []string) (string, error) {
+	if len(payloadHashes) == 0 {
+		return "", errors.New("no leaves")
+	}
+	level := make([][]byte, len(payloadHashes))
+	for i, h := range payloadHashes {
+		b, err := hex.DecodeString(h)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(append([]byte{0x00}, b...))
+		level[i] = sum[:]
+	}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			sum := sha256.Sum256(append(append([]byte{0x01}, level[i]...), level[i+1]...))
+			next = append(next, sum[:])
+		}
+		level = next
+	}
+	return hex.EncodeToString(level[0]), nil
+}
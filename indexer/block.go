@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go-apiv2/common"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/ledger/rwset/kvrwset"
+	"github.com/hyperledger/fabric-protos-go-apiv2/peer"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+
+	"payscope/client/auditlog"
+)
+
+// Primary event keys in the chaincode's world state; see namespace.go there.
+const eventKeyPrefix = "event:"
+
+// eventWrite is one StoredEvent written by a valid transaction.
+type eventWrite struct {
+	Ref    string
+	TxID   string
+	Stored auditlog.StoredEvent
+	Raw    []byte
+	// The writer's span, from the transaction's chaincode event, when it
+	// was traced.
+	Trace trace.SpanContext
+}
+
+// eventWrites extracts the event records written to chaincode's namespace by
+// the valid transactions of block. Fabric blocks are final once delivered,
+// so replaying a block always yields the same writes.
+func eventWrites(block *common.Block, chaincode string) ([]eventWrite, error) {
+	var filter []byte
+	if md := block.GetMetadata().GetMetadata(); len(md) > int(common.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+		filter = md[common.BlockMetadataIndex_TRANSACTIONS_FILTER]
+	}
+
+	var writes []eventWrite
+	for i, envBytes := range block.GetData().GetData() {
+		if i >= len(filter) || filter[i] != byte(peer.TxValidationCode_VALID) {
+			continue
+		}
+		env := &common.Envelope{}
+		if err := proto.Unmarshal(envBytes, env); err != nil {
+			return nil, fmt.Errorf("tx %d: envelope: %w", i, err)
+		}
+		payload := &common.Payload{}
+		if err := proto.Unmarshal(env.GetPayload(), payload); err != nil {
+			return nil, fmt.Errorf("tx %d: payload: %w", i, err)
+		}
+		chdr := &common.ChannelHeader{}
+		if err := proto.Unmarshal(payload.GetHeader().GetChannelHeader(), chdr); err != nil {
+			return nil, fmt.Errorf("tx %d: channel header: %w", i, err)
+		}
+		if chdr.GetType() != int32(common.HeaderType_ENDORSER_TRANSACTION) {
+			continue
+		}
+		tx := &peer.Transaction{}
+		if err := proto.Unmarshal(payload.GetData(), tx); err != nil {
+			return nil, fmt.Errorf("tx %s: %w", chdr.GetTxId(), err)
+		}
+		for _, action := range tx.GetActions() {
+			kvs, ccEvent, err := namespaceWrites(action, chaincode)
+			if err != nil {
+				return nil, fmt.Errorf("tx %s: %w", chdr.GetTxId(), err)
+			}
+			traces := noticeTraces(ccEvent, chaincode)
+			for _, kv := range kvs {
+				if kv.GetIsDelete() || !strings.HasPrefix(kv.GetKey(), eventKeyPrefix) {
+					continue
+				}
+				w := eventWrite{Ref: strings.TrimPrefix(kv.GetKey(), eventKeyPrefix), TxID: chdr.GetTxId(), Raw: kv.GetValue()}
+				if err := json.Unmarshal(kv.GetValue(), &w.Stored); err != nil {
+					return nil, fmt.Errorf("tx %s: key %s: %w", chdr.GetTxId(), kv.GetKey(), err)
+				}
+				w.Trace = traces[w.Stored.Event.EventID]
+				writes = append(writes, w)
+			}
+		}
+	}
+	return writes, nil
+}
+
+// namespaceWrites returns the action's writes to chaincode's namespace and
+// the chaincode event it set, if any.
+func namespaceWrites(action *peer.TransactionAction, chaincode string) ([]*kvrwset.KVWrite, *peer.ChaincodeEvent, error) {
+	actionPayload := &peer.ChaincodeActionPayload{}
+	if err := proto.Unmarshal(action.GetPayload(), actionPayload); err != nil {
+		return nil, nil, err
+	}
+	prp := &peer.ProposalResponsePayload{}
+	if err := proto.Unmarshal(actionPayload.GetAction().GetProposalResponsePayload(), prp); err != nil {
+		return nil, nil, err
+	}
+	cca := &peer.ChaincodeAction{}
+	if err := proto.Unmarshal(prp.GetExtension(), cca); err != nil {
+		return nil, nil, err
+	}
+	var ccEvent *peer.ChaincodeEvent
+	if len(cca.GetEvents()) > 0 {
+		ccEvent = &peer.ChaincodeEvent{}
+		if err := proto.Unmarshal(cca.GetEvents(), ccEvent); err != nil {
+			return nil, nil, err
+		}
+	}
+	txrw := &rwset.TxReadWriteSet{}
+	if err := proto.Unmarshal(cca.GetResults(), txrw); err != nil {
+		return nil, nil, err
+	}
+	var writes []*kvrwset.KVWrite
+	for _, ns := range txrw.GetNsRwset() {
+		if ns.GetNamespace() != chaincode {
+			continue
+		}
+		kv := &kvrwset.KVRWSet{}
+		if err := proto.Unmarshal(ns.GetRwset(), kv); err != nil {
+			return nil, nil, err
+		}
+		writes = append(writes, kv.GetWrites()...)
+	}
+	return writes, ccEvent, nil
+}
+
+// noticeTraces maps event IDs to the writer spans named by the notices of
+// an EventWritten or EventsWritten chaincode event. Notices are advisory,
+// so one that cannot be read just leaves its events untraced.
+func noticeTraces(ev *peer.ChaincodeEvent, chaincode string) map[string]trace.SpanContext {
+	if ev == nil || ev.GetChaincodeId() != chaincode {
+		return nil
+	}
+	var notices []auditlog.WrittenNotice
+	switch ev.GetEventName() {
+	case "EventWritten":
+		var n auditlog.WrittenNotice
+		if json.Unmarshal(ev.GetPayload(), &n) != nil {
+			return nil
+		}
+		notices = []auditlog.WrittenNotice{n}
+	case "EventsWritten":
+		if json.Unmarshal(ev.GetPayload(), &notices) != nil {
+			return nil
+		}
+	}
+	traces := map[string]trace.SpanContext{}
+	for i := range notices {
+		if sc := notices[i].TraceContext(); sc.IsValid() {
+			traces[notices[i].EventID] = sc
+		}
+	}
+	return traces
+}
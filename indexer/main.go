@@ -0,0 +1,123 @@
+// Command indexer mirrors audit log events from the ledger into PostgreSQL.
+//
+// It reads committed blocks, keeps the event records written by valid
+// transactions and upserts them into audit_events. Progress is checkpointed
+// per block, so a restart resumes where it stopped; -from-block replays from
+// an earlier height, which is safe because every write is an upsert.
+//
+// Each applied block is an OpenTelemetry span, and each indexed event a span
+// in the trace of the client that wrote it, when the writer sent its trace
+// context to the chaincode.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"payscope/client/auditlog"
+)
+
+func main() {
+	profilePath := flag.String("profile", os.Getenv("INDEXER_PROFILE"), "audit log connection profile JSON")
+	databaseURL := flag.String("database-url", os.Getenv("INDEXER_DATABASE_URL"), "PostgreSQL connection string")
+	fromBlock := flag.Int64("from-block", -1, "replay from this block instead of the stored checkpoint")
+	flag.Parse()
+
+	if *profilePath == "" || *databaseURL == "" {
+		log.Fatal("indexer: -profile and -database-url (or INDEXER_PROFILE, INDEXER_DATABASE_URL) are required")
+	}
+	profile, err := auditlog.LoadProfile(*profilePath)
+	if err != nil {
+		log.Fatalf("indexer: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	shutdownTracing, err := setupTracing(ctx)
+	if err != nil {
+		log.Fatalf("indexer: tracing: %v", err)
+	}
+	defer func() {
+		flush, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flush); err != nil {
+			log.Printf("indexer: tracing: %v", err)
+		}
+	}()
+
+	pool, err := pgxpool.New(ctx, *databaseURL)
+	if err != nil {
+		log.Fatalf("indexer: database: %v", err)
+	}
+	defer pool.Close()
+	st := &store{pool: pool, channel: profile.Channel, chaincode: profile.Chaincode}
+
+	next, err := st.checkpoint(ctx)
+	if err != nil {
+		log.Fatalf("indexer: checkpoint: %v", err)
+	}
+	if *fromBlock >= 0 {
+		next = uint64(*fromBlock)
+	}
+
+	backoff := time.Second
+	for ctx.Err() == nil {
+		processed, err := run(ctx, profile, st, next)
+		next += processed
+		if ctx.Err() != nil {
+			break
+		}
+		if processed > 0 {
+			backoff = time.Second
+		}
+		log.Printf("indexer: stream ended at block %d: %v; reconnecting in %s", next, err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+	log.Printf("indexer: stopped; next block %d", next)
+}
+
+// run streams blocks from start and applies them in order. It returns how
+// many blocks were applied before the stream ended.
+func run(ctx context.Context, profile *auditlog.Profile, st *store, start uint64) (uint64, error) {
+	c, err := auditlog.Connect(profile)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	blocks, err := c.BlockEvents(streamCtx, start)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("indexer: streaming %s/%s from block %d", profile.Channel, profile.Chaincode, start)
+
+	var applied uint64
+	for block := range blocks {
+		number := block.GetHeader().GetNumber()
+		writes, err := eventWrites(block, profile.Chaincode)
+		if err != nil {
+			return applied, err
+		}
+		if err := st.applyBlock(ctx, number, writes); err != nil {
+			return applied, err
+		}
+		applied++
+	}
+	return applied, ctx.Err()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// store writes the mirror tables from infra/postgres/004_audit_event_mirror.sql.
+type store struct {
+	pool      *pgxpool.Pool
+	channel   string
+	chaincode string
+}
+
+// checkpoint returns the next block to process, or 0 for a fresh mirror.
+func (s *store) checkpoint(ctx context.Context) (uint64, error) {
+	var next int64
+	err := s.pool.QueryRow(ctx,
+		`SELECT next_block FROM audit_indexer_checkpoints WHERE channel = $1 AND chaincode = $2`,
+		s.channel, s.chaincode).Scan(&next)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return uint64(next), err
+}
+
+// applyBlock upserts the block's event writes and advances the checkpoint in
+// one database transaction, so a crash never skips or half-applies a block.
+// The block gets a span, and each write a span in the trace of the
+// transaction that wrote it, linked to the block's.
+func (s *store) applyBlock(ctx context.Context, number uint64, writes []eventWrite) (err error) {
+	ctx, span := tracer.Start(ctx, "apply block", trace.WithAttributes(
+		attribute.Int64("fabric.block_number", int64(number)),
+		attribute.Int("auditlog.event_count", len(writes)),
+	))
+	defer func() { endSpan(span, err) }()
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for _, w := range writes {
+		if err := s.upsert(ctx, tx, number, w); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+INSERT INTO audit_indexer_checkpoints (channel, chaincode, next_block, updated_at)
+VALUES ($1, $2, $3, now())
+ON CONFLICT (channel, chaincode) DO UPDATE SET next_block = EXCLUDED.next_block, updated_at = now()`,
+		s.channel, s.chaincode, int64(number+1))
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+func (s *store) upsert(ctx context.Context, tx pgx.Tx, number uint64, w eventWrite) (err error) {
+	parent := ctx
+	if w.Trace.IsValid() {
+		parent = trace.ContextWithRemoteSpanContext(ctx, w.Trace)
+	}
+	ctx, span := tracer.Start(parent, "index event",
+		trace.WithLinks(trace.LinkFromContext(ctx)),
+		trace.WithAttributes(
+			attribute.String("auditlog.event_id", w.Stored.Event.EventID),
+			attribute.String("fabric.tx_id", w.TxID),
+		))
+	defer func() { endSpan(span, err) }()
+
+	e := w.Stored.Event
+	ts, err := time.Parse(time.RFC3339, e.Timestamp)
+	if err != nil {
+		return fmt.Errorf("event %s: timestamp: %w", w.Ref, err)
+	}
+	_, err = tx.Exec(ctx, `
+INSERT INTO audit_events (ref, event_id, event_type, artifact_hash, schema_version, event_ts,
+  payload_hash_sha256, producer, creator_msp, stored, block_number, tx_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10, $11, $12)
+ON CONFLICT (ref) DO UPDATE SET
+  event_type = EXCLUDED.event_type,
+  stored = EXCLUDED.stored,
+  block_number = EXCLUDED.block_number,
+  tx_id = EXCLUDED.tx_id`,
+		w.Ref, e.EventID, e.EventType, e.ArtifactHash, e.SchemaVersion, ts,
+		w.Stored.PayloadHash, w.Stored.Producer, w.Stored.CreatorMSP, w.Raw, int64(number), w.TxID)
+	if err != nil {
+		return fmt.Errorf("event %s: %w", w.Ref, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package auditlogtest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"payscope/auditlog/contract"
+)
+
+// Event returns the n-th deterministic schema v1 event of eventType: the
+// same n always gives the same event_id, artifact_hash and timestamp, so
+// tests can assert on payload hashes and keys. Timestamps are n seconds
+// after Epoch.
+func Event(eventType string, n int) *contract.LedgerEvent {
+	return &contract.LedgerEvent{
+		EventID:      ID(eventType, n),
+		EventType:    eventType,
+		ArtifactHash: digest("artifact", eventType, n),
+		SchemaVer:    "v1",
+		TimestampUTC: Timestamp(n),
+	}
+}
+
+// IngestEvent returns the n-th deterministic schema v2 INGEST event, a
+// batch of n*100 records from "core-banking" covering the hour before its
+// timestamp.
+func IngestEvent(n int) *contract.LedgerEvent {
+	e := Event("INGEST", n)
+	e.SchemaVer = "v2"
+	count := int64(n * 100)
+	at := Epoch.Add(time.Duration(n) * time.Second)
+	e.Ingest = &contract.IngestDetails{
+		SourceSystem:     "core-banking",
+		RecordCount:      &count,
+		BatchWindowStart: at.Add(-time.Hour).Format(time.RFC3339),
+		BatchWindowEnd:   at.Format(time.RFC3339),
+	}
+	return e
+}
+
+// ForecastEvent returns the n-th deterministic schema v2 FORECAST event,
+// a 30 day forecast by "cashflow-model@1".
+func ForecastEvent(n int) *contract.LedgerEvent {
+	e := Event("FORECAST", n)
+	e.SchemaVer = "v2"
+	e.Forecast = &contract.ForecastDetails{
+		ModelID:     "cashflow-model@1",
+		Horizon:     "P30D",
+		InputDigest: digest("input", "FORECAST", n),
+	}
+	return e
+}
+
+// DecisionEvent returns the n-th deterministic schema v2 AGENT_DECISION
+// event, an "APPROVE" by "agent-1" under policy "2024.1" with confidence
+// 0.9.
+func DecisionEvent(n int) *contract.LedgerEvent {
+	e := Event("AGENT_DECISION", n)
+	e.SchemaVer = "v2"
+	confidence := 0.9
+	e.Decision = &contract.AgentDecision{
+		AgentID:       "agent-1",
+		DecisionCode:  "APPROVE",
+		Confidence:    &confidence,
+		PolicyVersion: "2024.1",
+	}
+	return e
+}
+
+// ID returns a deterministic version 4 UUID for the n-th event of
+// eventType.
+func ID(eventType string, n int) string {
+	b := sha256.Sum256([]byte(fmt.Sprintf("auditlogtest/id/%s/%d", eventType, n)))
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
+
+// Timestamp returns the RFC3339 UTC time n seconds after Epoch.
+func Timestamp(n int) string {
+	return Epoch.Add(time.Duration(n) * time.Second).Format(time.RFC3339)
+}
+
+// MustJSON returns the JSON of v, the form PutEvent and the other
+// transactions take, and panics if v cannot be encoded.
+func MustJSON(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func digest(kind, eventType string, n int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("auditlogtest/%s/%s/%d", kind, eventType, n)))
+	return hex.EncodeToString(sum[:])
+}
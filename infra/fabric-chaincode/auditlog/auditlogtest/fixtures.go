@@ -0,0 +1,60 @@
+package auditlogtest
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+//go:embed fixtures/*.json
+var fixtureFiles embed.FS
+
+// Fixture is a golden event: the JSON as submitted, its RFC 8785 canonical
+// form and the payload_hash_sha256 the chaincode stores for it on a channel
+// without a salt. Clients that canonicalize events themselves can check
+// their output against Canonical and PayloadHash byte for byte.
+type Fixture struct {
+	Name        string          `json:"name"`
+	Event       json.RawMessage `json:"event"`
+	Canonical   string          `json:"canonical"`
+	PayloadHash string          `json:"payload_hash_sha256"`
+}
+
+// Fixtures returns every golden fixture, ordered by name.
+func Fixtures() ([]Fixture, error) {
+	entries, err := fixtureFiles.ReadDir("fixtures")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(names)
+	out := make([]Fixture, 0, len(names))
+	for _, name := range names {
+		f, err := LoadFixture(name)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// LoadFixture returns the golden fixture called name, such as
+// "forecast_v2".
+func LoadFixture(name string) (Fixture, error) {
+	b, err := fixtureFiles.ReadFile(path.Join("fixtures", name+".json"))
+	if err != nil {
+		return Fixture{}, fmt.Errorf("fixture %s not found", name)
+	}
+	var f Fixture
+	if err := json.Unmarshal(b, &f); err != nil {
+		return Fixture{}, fmt.Errorf("fixture %s: %w", name, err)
+	}
+	return f, nil
+}
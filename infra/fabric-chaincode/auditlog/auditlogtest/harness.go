@@ -0,0 +1,332 @@
+// Package auditlogtest runs the audit log chaincode in-process so teams
+// consuming it can test against real contract behaviour without a Fabric
+// network: a Harness drives transactions through an in-memory stub with
+// peer-like commit semantics, builders make deterministic events, and
+// Fixtures holds golden canonical JSON and payload hashes.
+package auditlogtest
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"payscope/auditlog/contract"
+)
+
+// Epoch is where a harness's clock starts unless WithClock says otherwise.
+var Epoch = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Harness is an in-memory channel running the audit log chaincode.
+// Submitted transactions commit their writes in order; evaluated ones and
+// failed ones leave the ledger unchanged. A Harness is not safe for
+// concurrent use.
+type Harness struct {
+	cc        shim.Chaincode
+	w         *world
+	channel   string
+	chaincode string
+	now       time.Time
+	seq       uint64
+	peers     map[string]*peerChaincode
+	creators  map[string][]byte
+	events    []*peer.ChaincodeEvent
+}
+
+type peerChaincode struct {
+	cc shim.Chaincode
+	w  *world
+}
+
+// Option configures a Harness.
+type Option func(*Harness)
+
+// WithChannel sets the channel id transactions report; the default is
+// "auditchannel".
+func WithChannel(name string) Option {
+	return func(h *Harness) { h.channel = name }
+}
+
+// WithChaincodeName sets the chaincode name transactions report; the
+// default is "auditlog".
+func WithChaincodeName(name string) Option {
+	return func(h *Harness) { h.chaincode = name }
+}
+
+// WithClock starts the harness clock at t.
+func WithClock(t time.Time) Option {
+	return func(h *Harness) { h.now = t.UTC() }
+}
+
+// WithChaincode makes cc reachable through InvokeChaincode under name, with
+// a namespace of its own, for example a stand-in for reference_chaincode.
+func WithChaincode(name string, cc shim.Chaincode) Option {
+	return func(h *Harness) { h.peers[name] = &peerChaincode{cc: cc, w: newWorld()} }
+}
+
+// New returns a harness over an empty ledger. The contract is not
+// initialized; submit Init or InitLedger first, as on a new channel.
+func New(opts ...Option) (*Harness, error) {
+	cc, err := contract.NewChaincode()
+	if err != nil {
+		return nil, err
+	}
+	h := &Harness{
+		cc:        cc,
+		w:         newWorld(),
+		channel:   "auditchannel",
+		chaincode: "auditlog",
+		now:       Epoch,
+		peers:     map[string]*peerChaincode{},
+		creators:  map[string][]byte{},
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
+}
+
+// TxError is a failed transaction's error, decoded from the chaincode's
+// JSON error envelope.
+type TxError struct {
+	Status int32
+	contract.ErrorEnvelope
+}
+
+func (e *TxError) Error() string {
+	if e.Field != "" {
+		return e.Code + " (" + e.Field + "): " + e.Message
+	}
+	return e.Code + ": " + e.Message
+}
+
+// Submit runs fn as id and commits its writes when it succeeds. fn may be
+// qualified with a contract name, such as "ArtifactRegistry:GetArtifact".
+func (h *Harness) Submit(id Identity, fn string, args ...string) (string, error) {
+	return h.SubmitTransient(id, nil, fn, args...)
+}
+
+// SubmitTransient is Submit with transient data, for private payloads.
+func (h *Harness) SubmitTransient(id Identity, transient map[string][]byte, fn string, args ...string) (string, error) {
+	stub, err := h.invoke(id, transient, fn, args)
+	if err != nil {
+		return "", err
+	}
+	h.Commit(stub)
+	return string(stub.result), nil
+}
+
+// Endorse runs fn as id like Submit but leaves the result uncommitted, for
+// CommitBlock.
+func (h *Harness) Endorse(id Identity, fn string, args ...string) (*Stub, error) {
+	return h.invoke(id, nil, fn, args)
+}
+
+// CommitBlock commits endorsed transactions as one block, in order,
+// validating each against the state left by those before it as a peer
+// does: a transaction whose reads of the chaincode's state have changed
+// since it was endorsed is invalid, and its writes are dropped. It returns
+// each transaction's validation error, nil for those committed. Submit
+// does not validate.
+func (h *Harness) CommitBlock(stubs ...*Stub) []error {
+	errs := make([]error, len(stubs))
+	for i, stub := range stubs {
+		if errs[i] = stub.validate(); errs[i] == nil {
+			h.Commit(stub)
+		}
+	}
+	return errs
+}
+
+// Evaluate runs fn as id without committing anything.
+func (h *Harness) Evaluate(id Identity, fn string, args ...string) (string, error) {
+	stub, err := h.invoke(id, nil, fn, args)
+	if err != nil {
+		return "", err
+	}
+	return string(stub.result), nil
+}
+
+// Context returns a transaction context for calling contract or helper
+// code directly, bypassing the router and BeforeTransaction. Pass the stub
+// to Commit to keep its writes.
+func (h *Harness) Context(id Identity, fn string, args ...string) (*contractapi.TransactionContext, *Stub, error) {
+	stub, err := h.NewStub(id, nil, fn, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	ci, err := cid.New(stub)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx := &contractapi.TransactionContext{}
+	ctx.SetStub(stub)
+	ctx.SetClientIdentity(ci)
+	return ctx, stub, nil
+}
+
+// NewStub returns the stub of a new transaction by id invoking fn.
+func (h *Harness) NewStub(id Identity, transient map[string][]byte, fn string, args ...string) (*Stub, error) {
+	creator, err := h.creator(id)
+	if err != nil {
+		return nil, err
+	}
+	h.seq++
+	sum := sha256.Sum256([]byte(fmt.Sprintf("auditlogtest/%s/%d", h.channel, h.seq)))
+	raw := make([][]byte, 0, len(args)+1)
+	raw = append(raw, []byte(fn))
+	for _, a := range args {
+		raw = append(raw, []byte(a))
+	}
+	return h.newStub(h.w, h.chaincode, hex.EncodeToString(sum[:]), creator, transient, raw), nil
+}
+
+// Commit applies stub's writes and records its chaincode event.
+func (h *Harness) Commit(stub *Stub) {
+	stub.commit()
+	if stub.event != nil {
+		h.events = append(h.events, stub.event)
+	}
+}
+
+// Advance moves the harness clock, which stamps every transaction, by d.
+func (h *Harness) Advance(d time.Duration) {
+	h.now = h.now.Add(d)
+}
+
+// Now returns the timestamp the next transaction will carry.
+func (h *Harness) Now() time.Time {
+	return h.now
+}
+
+// State returns the committed value of key, or nil.
+func (h *Harness) State(key string) []byte {
+	return clone(h.w.state[key])
+}
+
+// PrivateData returns the committed value of key in collection, or nil.
+func (h *Harness) PrivateData(collection, key string) []byte {
+	return clone(h.w.private[collection][key])
+}
+
+// ValidationParameter returns the committed key-level endorsement policy of
+// key, or nil.
+func (h *Harness) ValidationParameter(key string) []byte {
+	return clone(h.w.params[key])
+}
+
+// Events returns the chaincode events of committed transactions, oldest
+// first.
+func (h *Harness) Events() []*peer.ChaincodeEvent {
+	return append([]*peer.ChaincodeEvent{}, h.events...)
+}
+
+// Unmarshal decodes a transaction result into v.
+func Unmarshal(result string, v any) error {
+	return json.Unmarshal([]byte(result), v)
+}
+
+func (h *Harness) invoke(id Identity, transient map[string][]byte, fn string, args []string) (*Stub, error) {
+	stub, err := h.NewStub(id, transient, fn, args...)
+	if err != nil {
+		return nil, err
+	}
+	resp := h.cc.Invoke(stub)
+	if resp.Status >= shim.ERRORTHRESHOLD {
+		txErr := &TxError{Status: resp.Status}
+		if err := json.Unmarshal([]byte(resp.Message), &txErr.ErrorEnvelope); err != nil || txErr.Code == "" {
+			txErr.ErrorEnvelope = contract.ErrorEnvelope{Code: "ERR_INTERNAL", Message: resp.Message}
+		}
+		return nil, txErr
+	}
+	stub.result = resp.Payload
+	return stub, nil
+}
+
+func (h *Harness) newStub(w *world, chaincode, txID string, creator []byte, transient map[string][]byte, args [][]byte) *Stub {
+	nonce := make([]byte, 8)
+	binary.BigEndian.PutUint64(nonce, h.seq)
+	if transient == nil {
+		transient = map[string][]byte{}
+	}
+	return &Stub{
+		h:         h,
+		w:         w,
+		chaincode: chaincode,
+		args:      args,
+		txID:      txID,
+		creator:   creator,
+		nonce:     nonce,
+		transient: transient,
+		ts:        timestamppb.New(h.now),
+		reads:     map[string]int{},
+		writes:    map[string]write{},
+		private:   map[string]map[string]write{},
+		params:    map[string][]byte{},
+	}
+}
+
+func (h *Harness) creator(id Identity) ([]byte, error) {
+	if id.MSPID == "" {
+		return nil, fmt.Errorf("identity must have an MSPID")
+	}
+	key := id.key()
+	if c, ok := h.creators[key]; ok {
+		return c, nil
+	}
+	c, err := id.creator()
+	if err != nil {
+		return nil, err
+	}
+	h.creators[key] = c
+	return c, nil
+}
+
+// signedProposal builds the proposal a client would have sent for s, so
+// code reading the chaincode name or channel header from it works.
+func signedProposal(s *Stub) (*peer.SignedProposal, error) {
+	channelHeader, err := proto.Marshal(&common.ChannelHeader{
+		Type:      int32(common.HeaderType_ENDORSER_TRANSACTION),
+		ChannelId: s.h.channel,
+		TxId:      s.txID,
+		Timestamp: s.ts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	signatureHeader, err := proto.Marshal(&common.SignatureHeader{Creator: s.creator, Nonce: s.nonce})
+	if err != nil {
+		return nil, err
+	}
+	header, err := proto.Marshal(&common.Header{ChannelHeader: channelHeader, SignatureHeader: signatureHeader})
+	if err != nil {
+		return nil, err
+	}
+	input, err := proto.Marshal(&peer.ChaincodeInvocationSpec{ChaincodeSpec: &peer.ChaincodeSpec{
+		Type:        peer.ChaincodeSpec_GOLANG,
+		ChaincodeId: &peer.ChaincodeID{Name: s.chaincode},
+		Input:       &peer.ChaincodeInput{Args: s.args},
+	}})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(&peer.ChaincodeProposalPayload{Input: input})
+	if err != nil {
+		return nil, err
+	}
+	prop, err := proto.Marshal(&peer.Proposal{Header: header, Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	return &peer.SignedProposal{ProposalBytes: prop}, nil
+}
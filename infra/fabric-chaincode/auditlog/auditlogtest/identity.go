@@ -0,0 +1,105 @@
+package auditlogtest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/attrmgr"
+	"github.com/hyperledger/fabric-protos-go/msp"
+)
+
+// Identity is a transaction submitter. The harness issues it a throwaway
+// X.509 certificate carrying OUs as organizational units, so an "admin" OU
+// makes an org admin for InitLedger, and Attrs as Fabric CA attributes, the
+// way access control and type_writer_attrs read them.
+type Identity struct {
+	MSPID string
+	Name  string
+	OUs   []string
+	Attrs map[string]string
+}
+
+// Client returns a plain client identity of mspID.
+func Client(mspID string) Identity {
+	return Identity{MSPID: mspID, Name: "client"}
+}
+
+// Admin returns an org admin identity of mspID.
+func Admin(mspID string) Identity {
+	return Identity{MSPID: mspID, Name: "admin", OUs: []string{"admin"}}
+}
+
+// WithAttrs returns a copy of id that also carries attrs.
+func (id Identity) WithAttrs(attrs map[string]string) Identity {
+	merged := map[string]string{}
+	for k, v := range id.Attrs {
+		merged[k] = v
+	}
+	for k, v := range attrs {
+		merged[k] = v
+	}
+	id.Attrs = merged
+	return id
+}
+
+// key identifies id for the harness's certificate cache.
+func (id Identity) key() string {
+	names := make([]string, 0, len(id.Attrs))
+	for k := range id.Attrs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(id.MSPID + "\x00" + id.Name + "\x00" + strings.Join(id.OUs, ",") + "\x00")
+	for _, k := range names {
+		b.WriteString(k + "=" + id.Attrs[k] + "\x00")
+	}
+	return b.String()
+}
+
+// creator returns the serialized identity a peer would hand the chaincode
+// for id: its MSP id beside a self-signed PEM certificate.
+func (id Identity) creator() ([]byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	name := id.Name
+	if name == "" {
+		name = "client"
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject: pkix.Name{
+			CommonName:         name,
+			Organization:       []string{id.MSPID},
+			OrganizationalUnit: id.OUs,
+		},
+		NotBefore: time.Unix(0, 0),
+		NotAfter:  time.Now().AddDate(100, 0, 0),
+	}
+	if len(id.Attrs) > 0 {
+		if err := attrmgr.New().AddAttributesToCert(&attrmgr.Attributes{Attrs: id.Attrs}, tmpl); err != nil {
+			return nil, err
+		}
+		// attrmgr fills Extensions, which CreateCertificate ignores.
+		tmpl.ExtraExtensions, tmpl.Extensions = tmpl.Extensions, nil
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&msp.SerializedIdentity{
+		Mspid:   id.MSPID,
+		IdBytes: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+	})
+}
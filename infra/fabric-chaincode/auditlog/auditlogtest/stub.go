@@ -0,0 +1,599 @@
+package auditlogtest
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// world is the committed state of one chaincode namespace.
+type world struct {
+	state   map[string][]byte
+	history map[string][]*queryresult.KeyModification
+	private map[string]map[string][]byte
+	params  map[string][]byte
+	// Sorted keys of state, rebuilt on the first range read after a
+	// commit changes the key set.
+	stateKeys []string
+}
+
+// version of key counts its committed writes.
+func (w *world) version(key string) int {
+	return len(w.history[key])
+}
+
+// sortedStateKeys returns the keys of state in order.
+func (w *world) sortedStateKeys() []string {
+	if w.stateKeys == nil {
+		w.stateKeys = sortedKeys(w.state)
+	}
+	return w.stateKeys
+}
+
+func newWorld() *world {
+	return &world{
+		state:   map[string][]byte{},
+		history: map[string][]*queryresult.KeyModification{},
+		private: map[string]map[string][]byte{},
+		params:  map[string][]byte{},
+	}
+}
+
+// write is a pending update of one key; a nil value deletes it.
+type write struct {
+	value []byte
+}
+
+// Stub is the shim.ChaincodeStubInterface of one harness transaction.
+// Like a peer's simulator it reads committed state only: its writes,
+// private data, validation parameters and event take effect when the
+// harness commits the transaction, and are dropped when the transaction
+// fails or is only evaluated. Paginated queries are refused once the
+// transaction has written, and writes once it has paged, as on a peer.
+type Stub struct {
+	h         *Harness
+	w         *world
+	chaincode string
+	args      [][]byte
+	txID      string
+	creator   []byte
+	nonce     []byte
+	transient map[string][]byte
+	ts        *timestamp.Timestamp
+
+	// Versions of the keys and ranges read, for CommitBlock.
+	reads  map[string]int
+	ranges []*rangeRead
+
+	writes    map[string]write
+	private   map[string]map[string]write
+	params    map[string][]byte
+	event     *peer.ChaincodeEvent
+	invoked   []*Stub
+	result    []byte
+	wrote     bool
+	paginated bool
+}
+
+// TxID returns the transaction's id.
+func (s *Stub) TxID() string {
+	return s.txID
+}
+
+// Event returns the chaincode event the transaction set, or nil.
+func (s *Stub) Event() *peer.ChaincodeEvent {
+	return s.event
+}
+
+func (s *Stub) GetArgs() [][]byte {
+	return s.args
+}
+
+func (s *Stub) GetStringArgs() []string {
+	out := make([]string, len(s.args))
+	for i, a := range s.args {
+		out[i] = string(a)
+	}
+	return out
+}
+
+func (s *Stub) GetFunctionAndParameters() (string, []string) {
+	args := s.GetStringArgs()
+	if len(args) == 0 {
+		return "", []string{}
+	}
+	return args[0], args[1:]
+}
+
+func (s *Stub) GetArgsSlice() ([]byte, error) {
+	var out []byte
+	for _, a := range s.args {
+		out = append(out, a...)
+	}
+	return out, nil
+}
+
+func (s *Stub) GetTxID() string {
+	return s.txID
+}
+
+func (s *Stub) GetChannelID() string {
+	return s.h.channel
+}
+
+// InvokeChaincode runs a chaincode registered with WithChaincode in the
+// same transaction, over its own namespace.
+func (s *Stub) InvokeChaincode(chaincodeName string, args [][]byte, channel string) peer.Response {
+	if channel != "" && channel != s.h.channel {
+		return shim.Error(fmt.Sprintf("channel %s not found", channel))
+	}
+	name := chaincodeName
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	cc, ok := s.h.peers[name]
+	if !ok {
+		return shim.Error(fmt.Sprintf("chaincode %s not found", chaincodeName))
+	}
+	nested := s.h.newStub(cc.w, name, s.txID, s.creator, s.transient, args)
+	resp := cc.cc.Invoke(nested)
+	if resp.Status < shim.ERRORTHRESHOLD {
+		s.invoked = append(s.invoked, nested)
+	}
+	return resp
+}
+
+func (s *Stub) GetState(key string) ([]byte, error) {
+	s.reads[key] = s.w.version(key)
+	return clone(s.w.state[key]), nil
+}
+
+func (s *Stub) PutState(key string, value []byte) error {
+	if key == "" {
+		return errors.New("key must not be an empty string")
+	}
+	if err := s.beforeWrite(); err != nil {
+		return err
+	}
+	if len(value) == 0 {
+		// A peer treats an empty value as a delete.
+		s.writes[key] = write{}
+		return nil
+	}
+	s.writes[key] = write{value: clone(value)}
+	return nil
+}
+
+func (s *Stub) DelState(key string) error {
+	if err := s.beforeWrite(); err != nil {
+		return err
+	}
+	s.writes[key] = write{}
+	return nil
+}
+
+func (s *Stub) SetStateValidationParameter(key string, ep []byte) error {
+	if err := s.beforeWrite(); err != nil {
+		return err
+	}
+	s.params[key] = clone(ep)
+	return nil
+}
+
+func (s *Stub) GetStateValidationParameter(key string) ([]byte, error) {
+	return clone(s.w.params[key]), nil
+}
+
+func (s *Stub) GetStateByRange(startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if startKey == "" {
+		startKey = "\x01"
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	kvs, _ := scanRange(s.w.state, s.w.sortedStateKeys(), startKey, endKey, 0)
+	return s.readRange(startKey, endKey, kvs), nil
+}
+
+func (s *Stub) GetStateByRangeWithPagination(startKey, endKey string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if startKey == "" {
+		startKey = "\x01"
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, nil, err
+	}
+	return s.page(startKey, endKey, pageSize, bookmark)
+}
+
+func (s *Stub) GetStateByPartialCompositeKey(objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	start, end, err := partialRange(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	kvs, _ := scanRange(s.w.state, s.w.sortedStateKeys(), start, end, 0)
+	return s.readRange(start, end, kvs), nil
+}
+
+func (s *Stub) GetStateByPartialCompositeKeyWithPagination(objectType string, keys []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	start, end, err := partialRange(objectType, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.page(start, end, pageSize, bookmark)
+}
+
+func (s *Stub) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+// SplitCompositeKey parses compositeKey as the shim does, ignoring its
+// first byte.
+func (s *Stub) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if len(compositeKey) < 2 {
+		return "", nil, fmt.Errorf("not a composite key: %q", compositeKey)
+	}
+	parts := strings.Split(strings.TrimSuffix(compositeKey[1:], "\x00"), "\x00")
+	return parts[0], parts[1:], nil
+}
+
+// GetQueryResult fails: the harness models LevelDB state, which has no
+// rich queries.
+func (s *Stub) GetQueryResult(query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errRichQuery
+}
+
+func (s *Stub) GetQueryResultWithPagination(query string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	return nil, nil, errRichQuery
+}
+
+// GetHistoryForKey returns the committed modifications of key, newest
+// first.
+func (s *Stub) GetHistoryForKey(key string) (shim.HistoryQueryIteratorInterface, error) {
+	mods := s.w.history[key]
+	out := make([]*queryresult.KeyModification, len(mods))
+	for i, m := range mods {
+		out[len(mods)-1-i] = m
+	}
+	return &historyIterator{mods: out}, nil
+}
+
+func (s *Stub) GetPrivateData(collection, key string) ([]byte, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	return clone(s.w.private[collection][key]), nil
+}
+
+func (s *Stub) GetPrivateDataHash(collection, key string) ([]byte, error) {
+	v, err := s.GetPrivateData(collection, key)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(v)
+	return sum[:], nil
+}
+
+func (s *Stub) PutPrivateData(collection string, key string, value []byte) error {
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+	if len(value) == 0 {
+		return errors.New("value must not be empty")
+	}
+	return s.putPrivate(collection, key, write{value: clone(value)})
+}
+
+func (s *Stub) DelPrivateData(collection, key string) error {
+	if collection == "" {
+		return errors.New("collection must not be an empty string")
+	}
+	return s.putPrivate(collection, key, write{})
+}
+
+// PurgePrivateData deletes key; the harness keeps no private data history
+// for a purge to remove.
+func (s *Stub) PurgePrivateData(collection, key string) error {
+	return s.DelPrivateData(collection, key)
+}
+
+func (s *Stub) SetPrivateDataValidationParameter(collection, key string, ep []byte) error {
+	if err := s.beforeWrite(); err != nil {
+		return err
+	}
+	s.params[privateParamKey(collection, key)] = clone(ep)
+	return nil
+}
+
+func (s *Stub) GetPrivateDataValidationParameter(collection, key string) ([]byte, error) {
+	return clone(s.w.params[privateParamKey(collection, key)]), nil
+}
+
+func (s *Stub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	if startKey == "" {
+		startKey = "\x01"
+	}
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	kvs, _ := scanRange(s.w.private[collection], sortedKeys(s.w.private[collection]), startKey, endKey, 0)
+	return &kvIterator{kvs: kvs}, nil
+}
+
+func (s *Stub) GetPrivateDataByPartialCompositeKey(collection, objectType string, keys []string) (shim.StateQueryIteratorInterface, error) {
+	if collection == "" {
+		return nil, errors.New("collection must not be an empty string")
+	}
+	start, end, err := partialRange(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	kvs, _ := scanRange(s.w.private[collection], sortedKeys(s.w.private[collection]), start, end, 0)
+	return &kvIterator{kvs: kvs}, nil
+}
+
+func (s *Stub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
+	return nil, errRichQuery
+}
+
+func (s *Stub) GetCreator() ([]byte, error) {
+	return s.creator, nil
+}
+
+func (s *Stub) GetTransient() (map[string][]byte, error) {
+	return s.transient, nil
+}
+
+func (s *Stub) GetBinding() ([]byte, error) {
+	sum := sha256.Sum256(append(append([]byte{}, s.nonce...), s.creator...))
+	return sum[:], nil
+}
+
+func (s *Stub) GetDecorations() map[string][]byte {
+	return map[string][]byte{}
+}
+
+func (s *Stub) GetSignedProposal() (*peer.SignedProposal, error) {
+	return signedProposal(s)
+}
+
+func (s *Stub) GetTxTimestamp() (*timestamp.Timestamp, error) {
+	return s.ts, nil
+}
+
+// SetEvent replaces the transaction's chaincode event; a transaction
+// carries at most one.
+func (s *Stub) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return errors.New("event name can not be empty string")
+	}
+	s.event = &peer.ChaincodeEvent{ChaincodeId: s.chaincode, TxId: s.txID, EventName: name, Payload: clone(payload)}
+	return nil
+}
+
+var errRichQuery = errors.New("rich queries are not supported by the auditlogtest stub, which models LevelDB state")
+
+func (s *Stub) beforeWrite() error {
+	if s.paginated {
+		return fmt.Errorf("txid [%s]: unsupported transaction. Queries with pagination supported only in read-only transactions", s.txID)
+	}
+	s.wrote = true
+	return nil
+}
+
+func (s *Stub) putPrivate(collection, key string, w write) error {
+	if err := s.beforeWrite(); err != nil {
+		return err
+	}
+	if s.private[collection] == nil {
+		s.private[collection] = map[string]write{}
+	}
+	s.private[collection][key] = w
+	return nil
+}
+
+func (s *Stub) page(start, end string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if s.wrote {
+		return nil, nil, fmt.Errorf("txid [%s]: paginated queries are not supported in transactions that have written state", s.txID)
+	}
+	s.paginated = true
+	if bookmark != "" {
+		start = bookmark
+	}
+	kvs, next := scanRange(s.w.state, s.w.sortedStateKeys(), start, end, int(pageSize))
+	if next != "" {
+		end = next
+	}
+	return s.readRange(start, end, kvs), &peer.QueryResponseMetadata{FetchedRecordsCount: int32(len(kvs)), Bookmark: next}, nil
+}
+
+// rangeRead is a range query over state: the versions of the keys it
+// returned, and how far its iterator was read.
+type rangeRead struct {
+	start, end string
+	versions   []int
+	it         *kvIterator
+}
+
+func (s *Stub) readRange(start, end string, kvs []*queryresult.KV) *kvIterator {
+	r := &rangeRead{start: start, end: end, versions: make([]int, len(kvs)), it: &kvIterator{kvs: kvs}}
+	for i, kv := range kvs {
+		r.versions[i] = s.w.version(kv.Key)
+	}
+	s.ranges = append(s.ranges, r)
+	return r.it
+}
+
+// validate fails as a peer's MVCC validation would if state changed under
+// what the transaction read: a key it read has a new version, or a range
+// it read, up to where its iterator stopped, now returns other keys.
+func (s *Stub) validate() error {
+	for key, v := range s.reads {
+		if s.w.version(key) != v {
+			return fmt.Errorf("txid [%s]: MVCC_READ_CONFLICT on %q", s.txID, key)
+		}
+	}
+	for _, r := range s.ranges {
+		read := r.it.kvs[:r.it.i]
+		end := r.end
+		if r.it.HasNext() {
+			if len(read) == 0 {
+				continue
+			}
+			end = read[len(read)-1].Key + "\x00"
+		}
+		now, _ := scanRange(s.w.state, s.w.sortedStateKeys(), r.start, end, 0)
+		if len(now) != len(read) {
+			return fmt.Errorf("txid [%s]: PHANTOM_READ_CONFLICT in [%q, %q)", s.txID, r.start, end)
+		}
+		for i, kv := range now {
+			if kv.Key != read[i].Key || s.w.version(kv.Key) != r.versions[i] {
+				return fmt.Errorf("txid [%s]: PHANTOM_READ_CONFLICT in [%q, %q)", s.txID, r.start, end)
+			}
+		}
+	}
+	return nil
+}
+
+// commit applies the transaction, and those it invoked, to their worlds.
+func (s *Stub) commit() {
+	for _, nested := range s.invoked {
+		nested.commit()
+	}
+	for key, w := range s.writes {
+		mod := &queryresult.KeyModification{TxId: s.txID, Value: w.value, Timestamp: s.ts, IsDelete: w.value == nil}
+		s.w.history[key] = append(s.w.history[key], mod)
+		if _, ok := s.w.state[key]; ok == (w.value == nil) {
+			s.w.stateKeys = nil
+		}
+		if w.value == nil {
+			delete(s.w.state, key)
+			continue
+		}
+		s.w.state[key] = w.value
+	}
+	for collection, writes := range s.private {
+		if s.w.private[collection] == nil {
+			s.w.private[collection] = map[string][]byte{}
+		}
+		for key, w := range writes {
+			if w.value == nil {
+				delete(s.w.private[collection], key)
+				continue
+			}
+			s.w.private[collection][key] = w.value
+		}
+	}
+	for key, ep := range s.params {
+		s.w.params[key] = ep
+	}
+}
+
+// scanRange returns the entries of m in [start, end), an empty end meaning
+// no upper bound, up to limit entries when limit is positive, and the key
+// to resume from when more remain. keys are m's keys in order.
+func scanRange(m map[string][]byte, keys []string, start, end string, limit int) ([]*queryresult.KV, string) {
+	keys = keys[sort.SearchStrings(keys, start):]
+	if end != "" {
+		keys = keys[:sort.SearchStrings(keys, end)]
+	}
+	next := ""
+	if limit > 0 && len(keys) > limit {
+		next = keys[limit]
+		keys = keys[:limit]
+	}
+	kvs := make([]*queryresult.KV, len(keys))
+	for i, k := range keys {
+		kvs[i] = &queryresult.KV{Key: k, Value: clone(m[k])}
+	}
+	return kvs, next
+}
+
+func sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func partialRange(objectType string, keys []string) (string, string, error) {
+	start, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return "", "", err
+	}
+	return start, start + string(utf8.MaxRune), nil
+}
+
+func validateSimpleKeys(keys ...string) error {
+	for _, k := range keys {
+		if strings.HasPrefix(k, "\x00") {
+			return fmt.Errorf("first character of the key [%s] contains a null character which is not allowed", k)
+		}
+	}
+	return nil
+}
+
+func privateParamKey(collection, key string) string {
+	return "\x00private\x00" + collection + "\x00" + key
+}
+
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte{}, b...)
+}
+
+type kvIterator struct {
+	kvs []*queryresult.KV
+	i   int
+}
+
+func (it *kvIterator) HasNext() bool {
+	return it.i < len(it.kvs)
+}
+
+func (it *kvIterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	it.i++
+	return it.kvs[it.i-1], nil
+}
+
+func (it *kvIterator) Close() error {
+	return nil
+}
+
+type historyIterator struct {
+	mods []*queryresult.KeyModification
+	i    int
+}
+
+func (it *historyIterator) HasNext() bool {
+	return it.i < len(it.mods)
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, errors.New("no more results")
+	}
+	it.i++
+	return it.mods[it.i-1], nil
+}
+
+func (it *historyIterator) Close() error {
+	return nil
+}
@@ -0,0 +1,17 @@
+#!/usr/bin/env bash
+set -euo pipefail
+
+# Builds the ccaas chaincode package for `peer lifecycle chaincode install`.
+# Edit connection.json first if the chaincode service is reachable at a
+# different address or requires TLS.
+
+DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
+OUT="${1:-$DIR/auditlog-ccaas.tgz}"
+WORK="$(mktemp -d)"
+trap 'rm -rf "$WORK"' EXIT
+
+tar -C "$DIR" -czf "$WORK/code.tar.gz" connection.json
+cp "$DIR/metadata.json" "$WORK/"
+tar -C "$WORK" -czf "$OUT" metadata.json code.tar.gz
+
+echo "Wrote $OUT. After install, set CHAINCODE_ID on the service to the package ID."
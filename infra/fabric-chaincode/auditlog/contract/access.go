@@ -0,0 +1,357 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/identity"
+)
+
+// Identity attributes that grant access independently of the MSP lists.
+const (
+	writerAttr   = "auditlog.writer"
+	readerAttr   = "auditlog.reader"
+	digesterAttr = "auditlog.digester"
+	anchorerAttr = "auditlog.anchorer"
+)
+
+type txRole int
+
+const (
+	roleOpen txRole = iota
+	roleReader
+	roleWriter
+	roleAdmin
+	// Readers that enumerate every org's events; auditors only once
+	// org_scoped_reads or auditor_listings is on. See orgscope.go.
+	roleCrossOrg
+	// Readers, or auditors only once auditor_listings is on.
+	roleHistory
+	roleAuditor
+	// Admins, digest_msps and auditlog.digester identities.
+	roleDigest
+	// Admins, anchor_msps and auditlog.anchorer identities.
+	roleAnchor
+)
+
+// txRoles declares the role every transaction requires. Transactions missing
+// from the table are refused, so new ones must be classified here. Names
+// are unqualified and shared by all contracts of the chaincode; a
+// transaction served under several contracts has one role.
+var txRoles = map[string]txRole{
+	"HealthCheck": roleOpen,
+	// Init and InitLedger check the org admin role themselves; see
+	// bootstrap.go.
+	"Init":       roleOpen,
+	"InitLedger": roleOpen,
+	// Approvers need not be admins; ApproveDeletion checks them itself.
+	"ApproveDeletion": roleOpen,
+
+	"PutEvent":                 roleWriter,
+	"PutEvents":                roleWriter,
+	"PutEventProto":            roleWriter,
+	"RegisterTSAToken":         roleWriter,
+	"SetStateBasedEndorsement": roleWriter,
+	"AmendEvent":               roleWriter,
+	"RegisterArtifact":         roleWriter,
+	"ValidateEvent":            roleWriter,
+
+	"GetEvent":                        roleReader,
+	"ListEvents":                      roleCrossOrg,
+	"GetEventsByType":                 roleCrossOrg,
+	"GetEventsByTypeWithLineage":      roleCrossOrg,
+	"GetActiveEvents":                 roleCrossOrg,
+	"GetDistinctArtifactsByType":      roleCrossOrg,
+	"GetDistinctValues":               roleCrossOrg,
+	"GetEventsByArtifactHash":         roleCrossOrg,
+	"GetEventsByArtifactRole":         roleCrossOrg,
+	"QueryEventsByTimeRange":          roleCrossOrg,
+	"QueryEventsByIDTime":             roleCrossOrg,
+	"ListEventsByBucket":              roleCrossOrg,
+	"QueryEventsBySchemaVersion":      roleCrossOrg,
+	"QueryDecisionsByAgent":           roleCrossOrg,
+	"QueryIngestsBySource":            roleCrossOrg,
+	"QueryEventsBySelector":           roleCrossOrg,
+	"GetForecastsByConfidenceRange":   roleCrossOrg,
+	"GetEventsWithoutTSA":             roleCrossOrg,
+	"QueryExpiredEvents":              roleCrossOrg,
+	"GetEventHistory":                 roleHistory,
+	"GetEventProof":                   roleReader,
+	"GetEventEndorsementRequirements": roleReader,
+	"VerifyEventsBatch":               roleReader,
+	"GetArrivalStats":                 roleReader,
+	"AssertRangeEmpty":                roleCrossOrg,
+	"FindTimestampClusters":           roleCrossOrg,
+	"GenerateComplianceReport":        roleCrossOrg,
+	"ExportEventsMsgpack":             roleCrossOrg,
+	"ExportSnapshot":                  roleCrossOrg,
+	"AckEventDelivery":                roleReader,
+	"GetUndeliveredEvents":            roleCrossOrg,
+	"GetPrivatePayload":               roleReader,
+	"GetSignerKey":                    roleReader,
+	"ListSignerKeys":                  roleReader,
+	"VerifyEventSignature":            roleReader,
+	"ListSchemas":                     roleReader,
+	"ListEventTypes":                  roleReader,
+	"GetContractInfo":                 roleReader,
+	"VerifyChain":                     roleReader,
+	"GetDigest":                       roleReader,
+	"GetInclusionProof":               roleReader,
+	"GetDigestAnchors":                roleReader,
+	"VerifyEventAgainstAnchor":        roleReader,
+	"GetDeletionProposal":             roleReader,
+	"ExportAuditReport":               roleCrossOrg,
+	"GetLegalHold":                    roleReader,
+	"ListRetentionPolicies":           roleReader,
+	"GetEventStats":                   roleReader,
+	"GetEventStatsForDay":             roleReader,
+	"GetDaySummary":                   roleReader,
+	"GetEventsBatch":                  roleReader,
+	"GetEventSchema":                  roleReader,
+	"VerifyReference":                 roleReader,
+	"GetEventsByCorrelation":          roleCrossOrg,
+	"GetEventLineage":                 roleReader,
+	"GetArtifact":                     roleReader,
+	"GetEventsWithoutArtifactRecord":  roleCrossOrg,
+	"ListOrgEvents":                   roleReader,
+	"ListEventsForOrg":                roleAuditor,
+	"GetAccessLog":                    roleAuditor,
+	"GetAccessesByReader":             roleAuditor,
+	"GetUsage":                        roleReader,
+
+	"GetEventForProducer":   roleAdmin,
+	"SetHashingSalt":        roleAdmin,
+	"SetAccessControl":      roleAdmin,
+	"RevokeEvent":           roleAdmin,
+	"RegisterSchema":        roleAdmin,
+	"DeprecateSchema":       roleAdmin,
+	"RegisterEventType":     roleAdmin,
+	"RetireEventType":       roleAdmin,
+	"RegisterKey":           roleAdmin,
+	"RevokeKey":             roleAdmin,
+	"PlaceLegalHold":        roleAdmin,
+	"ReleaseLegalHold":      roleAdmin,
+	"SetRetentionPolicy":    roleAdmin,
+	"ArchiveExpiredEvents":  roleAdmin,
+	"CommitDigest":          roleDigest,
+	"AnchorDigest":          roleAnchor,
+	"CompactEventStats":     roleAdmin,
+	"RecordArchiveLocation": roleAdmin,
+	"CloseDay":              roleAdmin,
+	"RegisterEventSchema":   roleAdmin,
+	"PurgePrivatePayload":   roleAdmin,
+	"ProposeDeletion":       roleAdmin,
+	"UpdateConfig":          roleAdmin,
+	"MigrateState":          roleAdmin,
+}
+
+// AccessControl gates reads and writes when Enforce is set. An identity may
+// write if its MSP is in WriterMSPs or it carries auditlog.writer=true, and
+// read if its MSP is in ReaderMSPs or it carries auditlog.reader=true.
+// Admins pass both checks, and auditors pass the reader check.
+type AccessControl struct {
+	Enforce    bool     `json:"enforce"`
+	WriterMSPs []string `json:"writer_msps,omitempty"`
+	ReaderMSPs []string `json:"reader_msps,omitempty"`
+	// EnforceReads applies the reader check without Enforce, for channels
+	// whose writers are managed by the channel policy alone.
+	EnforceReads bool `json:"enforce_reads,omitempty"`
+	// AuditorListings reserves listings, history and exports for auditors,
+	// leaving readers the reads of single events by id.
+	AuditorListings bool `json:"auditor_listings,omitempty"`
+	// TypeWriterAttrs maps an event type to the identity attribute its
+	// writers must carry set to "true", e.g. {"FORECAST":
+	// "auditlog.forecaster"}. It applies whether or not Enforce is set,
+	// and admins are not exempt.
+	TypeWriterAttrs map[string]string `json:"type_writer_attrs,omitempty"`
+}
+
+func (ac *AccessControl) validate() error {
+	for _, m := range append(append([]string{}, ac.WriterMSPs...), ac.ReaderMSPs...) {
+		if m == "" {
+			return fmt.Errorf("access_control MSP entries must be non-empty")
+		}
+	}
+	for t, attr := range ac.TypeWriterAttrs {
+		if !eventTypeNameRe.MatchString(t) {
+			return fmt.Errorf("type_writer_attrs keys must be upper-case event types")
+		}
+		if reservedTypes[t] {
+			return fmt.Errorf("type_writer_attrs may not name %s, it is written by the contract", t)
+		}
+		if attr == "" {
+			return fmt.Errorf("type_writer_attrs entries must be non-empty")
+		}
+	}
+	return nil
+}
+
+// checkTypeWriter refuses e unless the caller carries the attribute
+// type_writer_attrs requires for its event type.
+func checkTypeWriter(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, e *LedgerEvent) error {
+	attr, ok := cfg.AccessControl.TypeWriterAttrs[e.EventType]
+	if !ok {
+		return nil
+	}
+	ok, err := identity.HasAttr(ctx.GetClientIdentity(), attr)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("forbidden: event_type %s requires attribute %s", e.EventType, attr)
+	}
+	return nil
+}
+
+// requireAdmin allows the call only for identities from a configured admin
+// MSP. With no admin_msps configured, admin transactions are disabled.
+func requireAdmin(ctx contractapi.TransactionContextInterface, cfg *ContractConfig) error {
+	if len(cfg.AdminMSPs) == 0 {
+		return errors.New("forbidden: no admin_msps configured")
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return err
+	}
+	if !identity.Contains(cfg.AdminMSPs, mspID) {
+		return errors.New("forbidden: admin identity required")
+	}
+	return nil
+}
+
+// requireCreatorOrAdmin allows the MSP that wrote stored, or an admin, to
+// perform action on it.
+func requireCreatorOrAdmin(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, stored *StoredEvent, action string) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return err
+	}
+	if stored.CreatorMSP != "" && mspID == stored.CreatorMSP {
+		return nil
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return fmt.Errorf("forbidden: only the creator MSP or an admin may %s", action)
+	}
+	return nil
+}
+
+func mspIn(ctx contractapi.TransactionContextInterface, msps []string) (bool, error) {
+	return identity.MSPIn(ctx.GetClientIdentity(), msps)
+}
+
+// hasAccess reports whether the caller's MSP is listed or it carries attr=true.
+func hasAccess(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, msps []string, attr string) (bool, error) {
+	return identity.Granted(ctx.GetClientIdentity(), append(append([]string{}, msps...), cfg.AdminMSPs...), attr)
+}
+
+// txName returns the invoked transaction without its contract prefix.
+func txName(ctx contractapi.TransactionContextInterface) string {
+	fn, _ := ctx.GetStub().GetFunctionAndParameters()
+	if i := strings.LastIndex(fn, ":"); i >= 0 {
+		fn = fn[i+1:]
+	}
+	return fn
+}
+
+// authorize runs before every transaction and enforces txRoles.
+func authorize(ctx contractapi.TransactionContextInterface) error {
+	fn := txName(ctx)
+	role, ok := txRoles[fn]
+	if !ok {
+		return fmt.Errorf("forbidden: transaction %s has no access role", fn)
+	}
+	if role == roleOpen {
+		return nil
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	switch role {
+	case roleCrossOrg:
+		if cfg.OrgScopedReads || cfg.AccessControl.AuditorListings {
+			return requireAuditor(ctx, cfg)
+		}
+		role = roleReader
+	case roleHistory:
+		if cfg.AccessControl.AuditorListings {
+			return requireAuditor(ctx, cfg)
+		}
+		role = roleReader
+	}
+	switch role {
+	case roleAuditor:
+		return requireAuditor(ctx, cfg)
+	case roleAdmin:
+		return requireAdmin(ctx, cfg)
+	case roleDigest:
+		ok, err := hasAccess(ctx, cfg, cfg.DigestMSPs, digesterAttr)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("forbidden: digest identity required")
+		}
+	case roleAnchor:
+		ok, err := hasAccess(ctx, cfg, cfg.AnchorMSPs, anchorerAttr)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("forbidden: anchoring identity required")
+		}
+	case roleWriter:
+		if !cfg.AccessControl.Enforce {
+			return nil
+		}
+		ok, err := hasAccess(ctx, cfg, cfg.AccessControl.WriterMSPs, writerAttr)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("forbidden: writer identity required")
+		}
+	case roleReader:
+		if !cfg.AccessControl.Enforce && !cfg.AccessControl.EnforceReads {
+			return nil
+		}
+		ok, err := hasAccess(ctx, cfg, cfg.AccessControl.ReaderMSPs, readerAttr)
+		if err == nil && !ok {
+			ok, err = hasAccess(ctx, cfg, cfg.AuditorMSPs, auditorAttr)
+		}
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("forbidden: reader identity required")
+		}
+	}
+	return nil
+}
+
+// SetAccessControl replaces the channel's access control settings.
+func (c *AuditLogContract) SetAccessControl(ctx contractapi.TransactionContextInterface, aclJSON string) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return err
+	}
+	var ac AccessControl
+	dec := json.NewDecoder(bytes.NewReader([]byte(aclJSON)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&ac); err != nil {
+		return fmt.Errorf("invalid access_control json: %w", err)
+	}
+	if err := ac.validate(); err != nil {
+		return err
+	}
+	cfg.AccessControl = ac
+	return saveConfig(ctx, cfg)
+}
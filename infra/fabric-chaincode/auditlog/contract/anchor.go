@@ -0,0 +1,307 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// Committed digests can be anchored on notarization targets outside the
+// consortium, such as a public Ethereum network or OpenTimestamps, so their
+// existence can be proven without trusting any member. The anchoring
+// service follows DigestCommitted events, records each root on its targets
+// and stores the receipts back through AnchorDigest, one per digest and
+// target.
+const (
+	digestCommittedName = "DigestCommitted"
+	// Composite index digest_id~target holding the DigestAnchor.
+	anchorIndex = "anchor~digest"
+
+	anchorPending   = "pending"
+	anchorConfirmed = "confirmed"
+)
+
+var anchorTargetRe = regexp.MustCompile(`^[a-z0-9][a-z0-9._:-]{0,63}$`)
+
+// DigestAnchor is the receipt of one digest root recorded on one external
+// target. Reference locates the anchor there, e.g. an Ethereum transaction
+// hash; Proof carries what the target returned when a verifier needs it,
+// e.g. an OpenTimestamps file. A pending anchor, one not yet final on its
+// target, may be replaced once by the confirmed one.
+type DigestAnchor struct {
+	DigestID   string `json:"digest_id"`
+	MerkleRoot string `json:"merkle_root"`
+	// Configured name of the target, e.g. "ethereum:sepolia".
+	Target string `json:"target"`
+	Status string `json:"status"`
+	// Chain id, calendar URL or similar, as the target defines it.
+	Network   string `json:"network,omitempty"`
+	Reference string `json:"reference"`
+	// Block on the target holding the anchor, once confirmed.
+	BlockNumber *uint64 `json:"block_number,omitempty"`
+	// Base64.
+	Proof string `json:"proof,omitempty"`
+	// RFC3339 time the target attests, when it attests one.
+	AnchoredAt    string `json:"anchored_at,omitempty"`
+	RecordedByMSP string `json:"recorded_by_msp"`
+	TxID          string `json:"tx_id"`
+	RecordedAt    string `json:"recorded_at"`
+}
+
+func (a *DigestAnchor) validate() error {
+	if !anchorTargetRe.MatchString(a.Target) {
+		return fmt.Errorf("target must be 1-64 lowercase letters, digits or ._:-")
+	}
+	if a.Status != anchorPending && a.Status != anchorConfirmed {
+		return fmt.Errorf("status must be pending or confirmed")
+	}
+	if a.Reference == "" {
+		return fmt.Errorf("reference required")
+	}
+	if a.Proof != "" {
+		if _, err := base64.StdEncoding.DecodeString(a.Proof); err != nil {
+			return fmt.Errorf("proof must be base64")
+		}
+	}
+	if a.AnchoredAt != "" {
+		if _, err := time.Parse(time.RFC3339, a.AnchoredAt); err != nil {
+			return fmt.Errorf("anchored_at must be RFC3339")
+		}
+	}
+	return nil
+}
+
+// emitDigestCommitted announces a new digest for the anchoring service.
+func emitDigestCommitted(ctx contractapi.TransactionContextInterface, d []byte) error {
+	return ctx.GetStub().SetEvent(digestCommittedName, d)
+}
+
+// AnchorDigest stores the receipt of digestID anchored on an external
+// target. Only anchoring identities may store receipts, since a confirmed
+// one is what VerifyEventAgainstAnchor trusts. The receipt must name the
+// digest's merkle_root. A target holds one receipt per digest; only a
+// pending one may be replaced, by a confirmed receipt.
+func (c *AuditLogContract) AnchorDigest(ctx contractapi.TransactionContextInterface, digestID string, anchorJSON string) (string, error) {
+	d, err := getDigest(ctx, digestID)
+	if err != nil {
+		return "", err
+	}
+	var a DigestAnchor
+	dec := json.NewDecoder(bytes.NewReader([]byte(anchorJSON)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&a); err != nil {
+		return "", fmt.Errorf("invalid anchor json: %w", err)
+	}
+	if err := a.validate(); err != nil {
+		return "", err
+	}
+	if a.MerkleRoot != d.Root {
+		return "", fmt.Errorf("anchor_root_mismatch: digest %s has merkle_root %s", digestID, d.Root)
+	}
+
+	key, err := ctx.GetStub().CreateCompositeKey(anchorIndex, []string{digestID, a.Target})
+	if err != nil {
+		return "", err
+	}
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		var prior DigestAnchor
+		if err := json.Unmarshal(existing, &prior); err != nil {
+			return "", fmt.Errorf("corrupt anchor record")
+		}
+		if prior.Status != anchorPending || a.Status != anchorConfirmed {
+			return "", fmt.Errorf("anchor_already_recorded: %s is %s on %s", digestID, prior.Status, a.Target)
+		}
+	}
+
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	a.DigestID = digestID
+	a.RecordedByMSP = mspID
+	a.TxID = ctx.GetStub().GetTxID()
+	a.RecordedAt = now.Format(time.RFC3339Nano)
+	out, err := json.Marshal(a)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(key, out); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// GetDigestAnchors lists the receipts of digestID by target.
+func (c *AuditLogContract) GetDigestAnchors(ctx contractapi.TransactionContextInterface, digestID string) (string, error) {
+	if _, err := getDigest(ctx, digestID); err != nil {
+		return "", err
+	}
+	it, err := ctx.GetStub().GetStateByPartialCompositeKey(anchorIndex, []string{digestID})
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+	anchors := []DigestAnchor{}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		var a DigestAnchor
+		if err := json.Unmarshal(kv.Value, &a); err != nil {
+			return "", fmt.Errorf("corrupt anchor record")
+		}
+		anchors = append(anchors, a)
+	}
+	return marshalString(anchors)
+}
+
+// AnchorVerification is VerifyEventAgainstAnchor's verdict. Verified means
+// the proof leads from the event's payload hash to the merkle_root the
+// anchor recorded on Target; a pending anchor may still change on the
+// target. Failing that, Reason says why: not_in_digest when every digest
+// anchored there was committed before the event landed in its window.
+type AnchorVerification struct {
+	EventID  string          `json:"event_id"`
+	Target   string          `json:"target"`
+	Verified bool            `json:"verified"`
+	Reason   string          `json:"reason,omitempty"`
+	Digest   *MerkleDigest   `json:"digest"`
+	Anchor   *DigestAnchor   `json:"anchor"`
+	Proof    *InclusionProof `json:"proof,omitempty"`
+}
+
+// VerifyEventAgainstAnchor checks eventID against its anchor on target:
+// among the digests whose window covers the event's timestamp and that
+// are anchored there, it takes one holding the event, confirmed anchors
+// and later digests first, and returns the inclusion proof with the
+// anchor's external reference.
+func (c *AuditLogContract) VerifyEventAgainstAnchor(ctx contractapi.TransactionContextInterface, eventID string, target string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	if !anchorTargetRe.MatchString(target) {
+		return "", fmt.Errorf("target must be 1-64 lowercase letters, digits or ._:-")
+	}
+	ref, err := resolveRef(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	ts, err := indexTimestamp(stored.Event.TimestampUTC)
+	if err != nil {
+		return "", err
+	}
+
+	stub := ctx.GetStub()
+	it, err := stub.GetStateByPartialCompositeKey(digestWindowIndex, []string{})
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+	var best, fallback *AnchorVerification
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		_, attrs, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return "", err
+		}
+		if len(attrs) != 3 {
+			return "", fmt.Errorf("corrupt digest window key")
+		}
+		// Windows are listed by start.
+		if attrs[0] > ts {
+			break
+		}
+		if ts >= attrs[1] {
+			continue
+		}
+		anchor, err := getAnchor(ctx, attrs[2], target)
+		if err != nil {
+			return "", err
+		}
+		if anchor == nil {
+			continue
+		}
+		d, err := getDigest(ctx, attrs[2])
+		if err != nil {
+			return "", err
+		}
+		proof, err := inclusionProof(ctx, d, stored)
+		if err != nil {
+			return "", err
+		}
+		v := &AnchorVerification{EventID: stored.Event.EventID, Target: target, Digest: d, Anchor: anchor, Proof: proof}
+		if proof == nil {
+			if fallback == nil || committedAfter(d, fallback.Digest) {
+				fallback = v
+			}
+			continue
+		}
+		if best == nil || betterAnchor(v, best) {
+			best = v
+		}
+	}
+	switch {
+	case best != nil:
+		root, err := proofRoot(best.Proof)
+		if err != nil {
+			return "", err
+		}
+		best.Verified = root == best.Anchor.MerkleRoot
+		if !best.Verified {
+			best.Reason = "anchor_root_mismatch"
+		}
+		return marshalString(best)
+	case fallback != nil:
+		fallback.Reason = "not_in_digest"
+		return marshalString(fallback)
+	}
+	return "", fmt.Errorf("anchor_not_found: no digest covering %s is anchored on %s", eventID, target)
+}
+
+// betterAnchor prefers confirmed anchors, then later digests.
+func betterAnchor(a, b *AnchorVerification) bool {
+	if (a.Anchor.Status == anchorConfirmed) != (b.Anchor.Status == anchorConfirmed) {
+		return a.Anchor.Status == anchorConfirmed
+	}
+	return committedAfter(a.Digest, b.Digest)
+}
+
+// getAnchor returns digestID's receipt on target, or nil.
+func getAnchor(ctx contractapi.TransactionContextInterface, digestID, target string) (*DigestAnchor, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(anchorIndex, []string{digestID, target})
+	if err != nil {
+		return nil, err
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil || b == nil {
+		return nil, err
+	}
+	var a DigestAnchor
+	if err := json.Unmarshal(b, &a); err != nil {
+		return nil, fmt.Errorf("corrupt anchor record")
+	}
+	return &a, nil
+}
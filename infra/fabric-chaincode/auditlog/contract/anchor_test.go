@@ -0,0 +1,163 @@
+package contract_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+func TestVerifyEventAgainstAnchor(t *testing.T) {
+	h, err := auditlogtest.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := auditlogtest.Admin("Org1MSP")
+	if _, err := h.Submit(admin, "Init", `{"admin_msps":["Org1MSP"]}`); err != nil {
+		t.Fatal(err)
+	}
+	client := auditlogtest.Client("Org1MSP")
+	events := []*contract.LedgerEvent{}
+	for n := 1; n <= 4; n++ {
+		e := auditlogtest.Event("INGEST", n)
+		put(t, h, client, e)
+		events = append(events, e)
+	}
+	first := commitAndAnchor(t, h, client, "confirmed", "0xabc")
+
+	v := verify(t, h, client, events[1].EventID, "ethereum:sepolia")
+	if !v.Verified || v.Proof == nil || v.Proof.Root != v.Anchor.MerkleRoot || v.Digest.DigestID != first || v.Anchor.Reference != "0xabc" {
+		t.Fatalf("verification %+v, want a proof to %s anchored at 0xabc", v, first)
+	}
+
+	// Written into the window after the anchored digest.
+	late := auditlogtest.Event("INGEST", 5)
+	put(t, h, client, late)
+	v = verify(t, h, client, late.EventID, "ethereum:sepolia")
+	if v.Verified || v.Reason != "not_in_digest" || v.Proof != nil {
+		t.Fatalf("late event: %+v, want not_in_digest", v)
+	}
+
+	// A later digest anchored only as pending proves the late event, but
+	// the confirmed anchor still wins for events under both.
+	h.Advance(time.Minute)
+	second := commitAndAnchor(t, h, client, "pending", "0xdef")
+	if v = verify(t, h, client, late.EventID, "ethereum:sepolia"); !v.Verified || v.Digest.DigestID != second {
+		t.Fatalf("late event: %+v, want verified under %s", v, second)
+	}
+	if v = verify(t, h, client, events[1].EventID, "ethereum:sepolia"); !v.Verified || v.Digest.DigestID != first {
+		t.Fatalf("early event: %+v, want verified under the confirmed %s", v, first)
+	}
+
+	for _, tc := range []struct {
+		eventID, target, reason string
+	}{
+		{events[0].EventID, "opentimestamps", "anchor_not_found"},
+		{"not-a-uuid", "ethereum:sepolia", ""},
+		{events[0].EventID, "Bad Target", ""},
+	} {
+		_, err := h.Evaluate(client, "VerifyEventAgainstAnchor", tc.eventID, tc.target)
+		var txErr *auditlogtest.TxError
+		if !errors.As(err, &txErr) || tc.reason != "" && txErr.Details["reason"] != tc.reason {
+			t.Errorf("%s on %q: err = %v, want rejection %q", tc.eventID, tc.target, err, tc.reason)
+		}
+	}
+}
+
+func TestCommitDigestRequiresDigestIdentity(t *testing.T) {
+	h := initHarness(t, map[string]any{"digest_msps": []string{"Org3MSP"}})
+	put(t, h, auditlogtest.Client("Org2MSP"), auditlogtest.Event("INGEST", 1))
+
+	for _, tc := range []struct {
+		name string
+		id   auditlogtest.Identity
+		ok   bool
+	}{
+		{"plain writer", auditlogtest.Client("Org2MSP"), false},
+		{"admin msp", auditlogtest.Client("Org1MSP"), true},
+		{"digest_msps", auditlogtest.Client("Org3MSP"), true},
+		{"digester attribute", auditlogtest.Client("Org2MSP").WithAttrs(map[string]string{"auditlog.digester": "true"}), true},
+	} {
+		_, err := h.Submit(tc.id, "CommitDigest", auditlogtest.Timestamp(1), auditlogtest.Timestamp(10))
+		var txErr *auditlogtest.TxError
+		if tc.ok && err != nil || !tc.ok && (!errors.As(err, &txErr) || txErr.Code != "ERR_FORBIDDEN") {
+			t.Errorf("%s: err = %v, want ok = %v", tc.name, err, tc.ok)
+		}
+	}
+}
+
+func TestAnchorDigestRequiresAnchoringIdentity(t *testing.T) {
+	h := initHarness(t, map[string]any{"anchor_msps": []string{"Org3MSP"}})
+	put(t, h, auditlogtest.Client("Org2MSP"), auditlogtest.Event("INGEST", 1))
+	out, err := h.Submit(auditlogtest.Client("Org1MSP"), "CommitDigest", auditlogtest.Timestamp(1), auditlogtest.Timestamp(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d contract.MerkleDigest
+	if err := auditlogtest.Unmarshal(out, &d); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name, target string
+		id           auditlogtest.Identity
+		ok           bool
+	}{
+		{"plain writer", "ethereum:sepolia", auditlogtest.Client("Org2MSP"), false},
+		{"admin msp", "ethereum:sepolia", auditlogtest.Client("Org1MSP"), true},
+		{"anchor_msps", "ethereum:mainnet", auditlogtest.Client("Org3MSP"), true},
+		{"anchorer attribute", "opentimestamps", auditlogtest.Client("Org2MSP").WithAttrs(map[string]string{"auditlog.anchorer": "true"}), true},
+	} {
+		anchor := map[string]string{"target": tc.target, "status": "confirmed", "reference": "0xabc", "merkle_root": d.Root}
+		_, err := h.Submit(tc.id, "AnchorDigest", d.DigestID, auditlogtest.MustJSON(anchor))
+		var txErr *auditlogtest.TxError
+		if tc.ok && err != nil || !tc.ok && (!errors.As(err, &txErr) || txErr.Code != "ERR_FORBIDDEN") {
+			t.Errorf("%s: err = %v, want ok = %v", tc.name, err, tc.ok)
+		}
+	}
+	out, err = h.Evaluate(auditlogtest.Client("Org2MSP"), "GetDigestAnchors", d.DigestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var anchors []contract.DigestAnchor
+	if err := auditlogtest.Unmarshal(out, &anchors); err != nil {
+		t.Fatal(err)
+	}
+	if len(anchors) != 3 {
+		t.Fatalf("anchors %+v, want the three authorized receipts", anchors)
+	}
+}
+
+// commitAndAnchor digests the first ten seconds and anchors the root on
+// ethereum:sepolia, returning the digest id.
+func commitAndAnchor(t *testing.T, h *auditlogtest.Harness, id auditlogtest.Identity, status, reference string) string {
+	t.Helper()
+	out, err := h.Submit(id, "CommitDigest", auditlogtest.Timestamp(1), auditlogtest.Timestamp(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d contract.MerkleDigest
+	if err := auditlogtest.Unmarshal(out, &d); err != nil {
+		t.Fatal(err)
+	}
+	anchor := map[string]string{"target": "ethereum:sepolia", "status": status, "reference": reference, "merkle_root": d.Root}
+	if _, err := h.Submit(id, "AnchorDigest", d.DigestID, auditlogtest.MustJSON(anchor)); err != nil {
+		t.Fatal(err)
+	}
+	return d.DigestID
+}
+
+func verify(t *testing.T, h *auditlogtest.Harness, id auditlogtest.Identity, eventID, target string) contract.AnchorVerification {
+	t.Helper()
+	out, err := h.Evaluate(id, "VerifyEventAgainstAnchor", eventID, target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v contract.AnchorVerification
+	if err := auditlogtest.Unmarshal(out, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
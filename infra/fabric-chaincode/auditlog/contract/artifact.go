@@ -0,0 +1,191 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// ArtifactRegistry is the chaincode's second contract. It records what an
+// artifact_hash points to: size, content type, where the bytes are stored
+// and which org owns them. Call its transactions as
+// "ArtifactRegistry:<name>". Events link to a registered artifact through
+// their artifact_hash and hash_algorithm; with require_registered_artifacts
+// set, writes of unregistered artifacts are rejected.
+type ArtifactRegistry struct {
+	contractapi.Contract
+}
+
+const artifactRecordPrefix = "artifact_record:"
+
+type ArtifactRecord struct {
+	ArtifactHash  string `json:"artifact_hash"`
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	SizeBytes     int64  `json:"size_bytes"`
+	ContentType   string `json:"content_type"`
+	StorageURI    string `json:"storage_uri"`
+	// Set from the registering identity, not the submission.
+	OwnerMSP     string `json:"owner_msp"`
+	RegisteredAt string `json:"registered_at"`
+	TxID         string `json:"tx_id"`
+}
+
+func (a *ArtifactRecord) validate() error {
+	if err := validateArtifactHash(&LedgerEvent{ArtifactHash: a.ArtifactHash, HashAlgorithm: a.HashAlgorithm}); err != nil {
+		return err
+	}
+	if a.SizeBytes < 0 {
+		return fmt.Errorf("size_bytes must not be negative")
+	}
+	if _, _, err := mime.ParseMediaType(a.ContentType); err != nil {
+		return fmt.Errorf("content_type must be a media type, e.g. application/pdf")
+	}
+	return validate.StorageURI(a.StorageURI)
+}
+
+func getArtifactRecord(ctx contractapi.TransactionContextInterface, artifactHash string) (*ArtifactRecord, error) {
+	b, err := ctx.GetStub().GetState(artifactRecordPrefix + artifactHash)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var rec ArtifactRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("corrupt artifact record")
+	}
+	return &rec, nil
+}
+
+// checkArtifactRegistered enforces require_registered_artifacts on a write.
+func checkArtifactRegistered(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, e *LedgerEvent) error {
+	if !cfg.RequireRegisteredArtifacts {
+		return nil
+	}
+	if err := checkRegistered(ctx, e.ArtifactHash, e.HashAlgorithm, "artifact_hash"); err != nil {
+		return err
+	}
+	for i, a := range e.Artifacts {
+		if err := checkRegistered(ctx, a.ArtifactHash, a.HashAlgorithm, fmt.Sprintf("artifacts[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRegistered checks hash is registered with algorithm alg; field names
+// it in errors.
+func checkRegistered(ctx contractapi.TransactionContextInterface, hash, alg, field string) error {
+	rec, err := getArtifactRecord(ctx, hash)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return fmt.Errorf("artifact_not_registered: register %s with ArtifactRegistry first", field)
+	}
+	want := artifactAlgorithm(&LedgerEvent{HashAlgorithm: rec.HashAlgorithm})
+	if artifactAlgorithm(&LedgerEvent{HashAlgorithm: alg}) != want {
+		return fmt.Errorf("hash_algorithm must match the registered artifact's %s", want)
+	}
+	return nil
+}
+
+// RegisterArtifact records an artifact's metadata, owned by the caller's
+// MSP. Records are write-once; resubmitting identical metadata is a no-op.
+func (r *ArtifactRegistry) RegisterArtifact(ctx contractapi.TransactionContextInterface, artifactJSON string) (string, error) {
+	var rec ArtifactRecord
+	dec := json.NewDecoder(bytes.NewReader([]byte(artifactJSON)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&rec); err != nil {
+		return "", fmt.Errorf("invalid artifact json: %w", err)
+	}
+	var err error
+	if rec.ArtifactHash, err = artifactHashArg(ctx, rec.ArtifactHash); err != nil {
+		return "", err
+	}
+	if err := rec.validate(); err != nil {
+		return "", err
+	}
+	owner, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	existing, err := getArtifactRecord(ctx, rec.ArtifactHash)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		if existing.HashAlgorithm == rec.HashAlgorithm && existing.SizeBytes == rec.SizeBytes &&
+			existing.ContentType == rec.ContentType && existing.StorageURI == rec.StorageURI && existing.OwnerMSP == owner {
+			return marshalString(existing)
+		}
+		return "", errors.New("artifact_already_registered: artifact_hash registered with different metadata")
+	}
+
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	rec.OwnerMSP = owner
+	rec.RegisteredAt = now.Format(time.RFC3339Nano)
+	rec.TxID = ctx.GetStub().GetTxID()
+	out, err := json.Marshal(&rec)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(artifactRecordPrefix+rec.ArtifactHash, out); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// GetArtifact returns the registered metadata for artifactHash.
+func (r *ArtifactRegistry) GetArtifact(ctx contractapi.TransactionContextInterface, artifactHash string) (string, error) {
+	artifactHash, err := artifactHashArg(ctx, artifactHash)
+	if err != nil {
+		return "", err
+	}
+	if !validate.SHA256.MatchString(artifactHash) {
+		return "", fmt.Errorf("artifact_hash must be lowercase hex")
+	}
+	rec, err := getArtifactRecord(ctx, artifactHash)
+	if err != nil {
+		return "", err
+	}
+	if rec == nil {
+		return "", fmt.Errorf("not_found")
+	}
+	return marshalString(rec)
+}
+
+// GetEventsWithoutArtifactRecord pages through events whose
+// artifact_hash has no ArtifactRegistry record, so they can be registered
+// before require_registered_artifacts is switched on. CONFIG_CHANGE events
+// point at config hashes, not artifacts, and are skipped.
+func (c *AuditLogContract) GetEventsWithoutArtifactRecord(ctx contractapi.TransactionContextInterface, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	page, err := scanEvents(ctx, bookmark, pageSize, func(stored *StoredEvent) (bool, error) {
+		if stored.Event.EventType == configChangeType {
+			return false, nil
+		}
+		rec, err := getArtifactRecord(ctx, stored.Event.ArtifactHash)
+		if err != nil {
+			return false, err
+		}
+		return rec == nil, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return marshalPage(page, projection)
+}
@@ -0,0 +1,110 @@
+package contract_test
+
+import (
+	"strings"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+)
+
+// The write path benchmarks run on the harness's in-memory stub, so they
+// measure chaincode CPU and allocations, not peer throughput; the client's
+// cmd/auditlog-bench measures a network. Each writes fresh schema v2 INGEST
+// events, so nothing is deduped.
+
+const benchEvents = 1000
+
+// BenchmarkPutEvents writes benchEvents events per operation in PutEvents
+// batches of the chaincode's maximum, 500.
+func BenchmarkPutEvents(b *testing.B) {
+	h, writer := benchHarness(b)
+	seq := 0
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for written := 0; written < benchEvents; written += 500 {
+			b.StopTimer()
+			docs := make([]string, 500)
+			for j := range docs {
+				seq++
+				docs[j] = auditlogtest.MustJSON(auditlogtest.IngestEvent(seq))
+			}
+			b.StartTimer()
+			out, err := h.Submit(writer, "PutEvents", "["+strings.Join(docs, ",")+"]")
+			if err != nil {
+				b.Fatal(err)
+			}
+			var res struct {
+				Summary struct{ Written int } `json:"summary"`
+			}
+			if err := auditlogtest.Unmarshal(out, &res); err != nil {
+				b.Fatal(err)
+			}
+			if res.Summary.Written != len(docs) {
+				b.Fatalf("PutEvents wrote %d of %d events: %s", res.Summary.Written, len(docs), out)
+			}
+		}
+	}
+	reportEvents(b, benchEvents)
+}
+
+// BenchmarkPutEvent writes one event per operation.
+func BenchmarkPutEvent(b *testing.B) {
+	h, writer := benchHarness(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		doc := auditlogtest.MustJSON(auditlogtest.IngestEvent(i + 1))
+		b.StartTimer()
+		if _, err := h.Submit(writer, "PutEvent", doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportEvents(b, 1)
+}
+
+// BenchmarkValidateEvent dry-runs one event per operation through
+// ValidateEvent, which validates and hashes without writing.
+func BenchmarkValidateEvent(b *testing.B) {
+	h, writer := benchHarness(b)
+	docs := make([]string, benchEvents)
+	for j := range docs {
+		docs[j] = auditlogtest.MustJSON(auditlogtest.IngestEvent(j + 1))
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		out, err := h.Evaluate(writer, "ValidateEvent", docs[i%len(docs)])
+		if err != nil {
+			b.Fatal(err)
+		}
+		var res struct{ Status string }
+		if err := auditlogtest.Unmarshal(out, &res); err != nil {
+			b.Fatal(err)
+		}
+		if res.Status != "valid" {
+			b.Fatalf("ValidateEvent: %s", out)
+		}
+	}
+	reportEvents(b, 1)
+}
+
+// benchHarness returns an initialized harness and a writer identity.
+func benchHarness(b *testing.B) (*auditlogtest.Harness, auditlogtest.Identity) {
+	b.Helper()
+	h, err := auditlogtest.New()
+	if err != nil {
+		b.Fatal(err)
+	}
+	if _, err := h.Submit(auditlogtest.Admin("Org1MSP"), "InitLedger", `{"config":{"admin_msps":["Org1MSP"]}}`); err != nil {
+		b.Fatal(err)
+	}
+	return h, auditlogtest.Client("Org1MSP")
+}
+
+// reportEvents adds events per second to a benchmark writing perOp events
+// per operation.
+func reportEvents(b *testing.B, perOp int) {
+	b.ReportMetric(float64(b.N*perOp)/b.Elapsed().Seconds(), "events/s")
+}
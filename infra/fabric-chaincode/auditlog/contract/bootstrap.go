@@ -0,0 +1,120 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/identity"
+)
+
+// LedgerBootstrap is everything InitLedger seeds in one transaction.
+type LedgerBootstrap struct {
+	Config     ContractConfig       `json:"config"`
+	EventTypes []EventTypeBootstrap `json:"event_types,omitempty"`
+	Schemas    []SchemaBootstrap    `json:"schemas,omitempty"`
+}
+
+type EventTypeBootstrap struct {
+	Name           string   `json:"name"`
+	RequiredFields []string `json:"required_fields,omitempty"`
+}
+
+type SchemaBootstrap struct {
+	Version    string          `json:"version"`
+	JSONSchema json.RawMessage `json:"json_schema,omitempty"`
+}
+
+// requireOrgAdmin allows only identities whose certificate carries the
+// Fabric NodeOU "admin" role, i.e. the org admins that run channel
+// configuration. It needs NodeOUs enabled in the MSP.
+func requireOrgAdmin(ctx contractapi.TransactionContextInterface) error {
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil {
+		return err
+	}
+	if cert != nil {
+		for _, ou := range cert.Subject.OrganizationalUnit {
+			if strings.EqualFold(ou, "admin") {
+				return nil
+			}
+		}
+	}
+	return errors.New("forbidden: org admin identity required")
+}
+
+// requireSeededAdmin checks that the bootstrapper's MSP is in the
+// admin_msps it seeds.
+func requireSeededAdmin(ctx contractapi.TransactionContextInterface, cfg *ContractConfig) error {
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return err
+	}
+	if !identity.Contains(cfg.AdminMSPs, mspID) {
+		return fmt.Errorf("admin_msps must include the caller's MSP %s", mspID)
+	}
+	return nil
+}
+
+// InitLedger seeds the contract configuration together with the channel's
+// custom event types and schema versions, replacing the Init plus
+// RegisterEventType/RegisterSchema sequence with one atomic transaction.
+// The caller must be an org admin whose MSP is listed in the seeded
+// admin_msps, so the bootstrapper cannot lock itself out. It runs once per
+// channel, like Init.
+func (c *AuditLogContract) InitLedger(ctx contractapi.TransactionContextInterface, bootstrapJSON string) error {
+	if err := requireOrgAdmin(ctx); err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(configKey)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.New("config_already_initialized")
+	}
+
+	var boot LedgerBootstrap
+	dec := json.NewDecoder(bytes.NewReader([]byte(bootstrapJSON)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&boot); err != nil {
+		return fmt.Errorf("invalid bootstrap json: %w", err)
+	}
+	if err := boot.Config.validate(); err != nil {
+		return err
+	}
+	if err := requireSeededAdmin(ctx, &boot.Config); err != nil {
+		return err
+	}
+
+	// Reads in this transaction do not see its writes, so duplicates within
+	// the bootstrap must be caught here rather than by the registries.
+	seen := map[string]bool{}
+	for i, t := range boot.EventTypes {
+		if seen[t.Name] {
+			return fmt.Errorf("event_types[%d]: duplicate event type %q", i, t.Name)
+		}
+		seen[t.Name] = true
+		if t.RequiredFields == nil {
+			t.RequiredFields = []string{}
+		}
+		if err := registerEventType(ctx, t.Name, t.RequiredFields); err != nil {
+			return fmt.Errorf("%w: event_types[%d]", err, i)
+		}
+	}
+	seen = map[string]bool{}
+	for i, s := range boot.Schemas {
+		if seen[s.Version] {
+			return fmt.Errorf("schemas[%d]: duplicate schema version %q", i, s.Version)
+		}
+		seen[s.Version] = true
+		if err := registerSchema(ctx, s.Version, string(s.JSONSchema)); err != nil {
+			return fmt.Errorf("%w: schemas[%d]", err, i)
+		}
+	}
+	return saveConfig(ctx, &boot.Config)
+}
@@ -0,0 +1,40 @@
+package contract_test
+
+import (
+	"errors"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+)
+
+// Init guards a fresh channel like InitLedger: only an org admin from one
+// of the seeded admin_msps may configure it.
+func TestInitRequiresSeededOrgAdmin(t *testing.T) {
+	for _, tc := range []struct {
+		fn, arg string
+	}{
+		{"Init", `{"admin_msps":["Org1MSP"]}`},
+		{"InitLedger", `{"config":{"admin_msps":["Org1MSP"]}}`},
+	} {
+		h, err := auditlogtest.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range []auditlogtest.Identity{
+			auditlogtest.Client("Org1MSP"),
+			auditlogtest.Admin("Org2MSP"),
+		} {
+			_, err := h.Submit(id, tc.fn, tc.arg)
+			var txErr *auditlogtest.TxError
+			if !errors.As(err, &txErr) {
+				t.Errorf("%s by %s %s: err = %v, want a rejection", tc.fn, id.MSPID, id.Name, err)
+			}
+		}
+		if h.State("config") != nil {
+			t.Fatalf("%s: a rejected caller stored a config", tc.fn)
+		}
+		if _, err := h.Submit(auditlogtest.Admin("Org1MSP"), tc.fn, tc.arg); err != nil {
+			t.Fatalf("%s by the Org1MSP admin: %v", tc.fn, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// The primary "event:<ref>" key is what id lookups, key history and
+// state-based endorsement work on, so it stays. Every record also has an
+// entry under bucket~type~day~ref, clustered by event_type and UTC
+// timestamp day, which like the other indexes holds the ref. A type- and
+// day-scoped scan reads one contiguous key range and touches no entry
+// outside it, however large the ledger. putStoredEvent writes both keys.
+// Records from before storage_version 2 have no bucket entry, and ones from
+// before storage_version 6 a whole copy of the record instead of the ref,
+// until MigrateState rewrites them.
+const (
+	bucketIndex  = "bucket~type~day~ref"
+	bucketLayout = "2006-01-02"
+)
+
+// bucketAttrs are stored's attributes in the bucket index.
+func bucketAttrs(stored *StoredEvent) ([]string, error) {
+	ts, err := time.Parse(time.RFC3339, stored.Event.TimestampUTC)
+	if err != nil {
+		return nil, fmt.Errorf("corrupt stored event %q", stored.ref())
+	}
+	return []string{stored.Event.EventType, ts.UTC().Format(bucketLayout), stored.ref()}, nil
+}
+
+// bucketKey is stored's key in the bucketed layout.
+func bucketKey(ctx contractapi.TransactionContextInterface, stored *StoredEvent) (string, error) {
+	attrs, err := bucketAttrs(stored)
+	if err != nil {
+		return "", err
+	}
+	return indexKey(ctx.GetStub(), bucketIndex, attrs)
+}
+
+// putStoredEvent writes out, the encoding of stored, under its primary key
+// with policy, when non-nil, and points its bucket entry at it.
+func putStoredEvent(ctx contractapi.TransactionContextInterface, stored *StoredEvent, out []byte, policy []byte) error {
+	stub := ctx.GetStub()
+	key := eventKey(stored.ref())
+	if err := stub.PutState(key, out); err != nil {
+		return err
+	}
+	if policy != nil {
+		if err := stub.SetStateValidationParameter(key, policy); err != nil {
+			return err
+		}
+	}
+	bucket, err := bucketKey(ctx, stored)
+	if err != nil {
+		return err
+	}
+	return stub.PutState(bucket, []byte(stored.ref()))
+}
+
+// entryRef is the ref an index entry points at. Bucket entries from before
+// storage_version 6 hold the whole record instead.
+func entryRef(value []byte) (string, error) {
+	if len(value) == 0 || value[0] != '{' {
+		return string(value), nil
+	}
+	var stored StoredEvent
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return "", fmt.Errorf("corrupt stored event")
+	}
+	return stored.ref(), nil
+}
+
+// ListEventsByBucket pages through the events of eventType whose timestamps
+// fall on UTC days in [startDay, endDay), given as YYYY-MM-DD, in day order
+// and by ref within a day. It reads the bucket index, so events not yet
+// migrated to storage_version 2 are missing; use GetEventsByType or
+// QueryEventsByTimeRange until MigrateState has run.
+func (c *AuditLogContract) ListEventsByBucket(ctx contractapi.TransactionContextInterface, eventType string, startDay string, endDay string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if err := requireEventType(ctx, eventType); err != nil {
+		return "", err
+	}
+	start, err := time.Parse(bucketLayout, startDay)
+	if err != nil {
+		return "", fmt.Errorf("start_day must be YYYY-MM-DD")
+	}
+	end, err := time.Parse(bucketLayout, endDay)
+	if err != nil {
+		return "", fmt.Errorf("end_day must be YYYY-MM-DD")
+	}
+	if !start.Before(end) {
+		return "", fmt.Errorf("start_day must be before end_day")
+	}
+	startKey, endKey, err := timeRangeKeys(ctx, bucketIndex, []string{eventType}, startDay, endDay)
+	if err != nil {
+		return "", err
+	}
+	page, err := pageIndexRange(ctx, startKey, endKey, bookmark, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return marshalPage(page, projection)
+}
@@ -0,0 +1,46 @@
+package contract_test
+
+import (
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// Bucket entries hold the ref, like the other indexes, and listings load
+// each record from its primary key.
+func TestListEventsByBucket(t *testing.T) {
+	h := initHarness(t, nil)
+	client := auditlogtest.Client("Org1MSP")
+	ingests := []*contract.LedgerEvent{auditlogtest.Event("INGEST", 1), auditlogtest.Event("INGEST", 2)}
+	for _, e := range ingests {
+		put(t, h, client, e)
+	}
+	put(t, h, client, auditlogtest.Event("FORECAST", 3))
+
+	for _, e := range ingests {
+		key := "\x01bucket~type~day~ref\x00INGEST\x002024-01-01\x00" + e.EventID + "\x00"
+		if got := string(h.State(key)); got != e.EventID {
+			t.Errorf("bucket entry of %s = %q, want its ref", e.EventID, got)
+		}
+	}
+
+	out, err := h.Evaluate(client, "ListEventsByBucket", "INGEST", "2024-01-01", "2024-01-02", "full", "", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page contract.EventPage
+	if err := auditlogtest.Unmarshal(out, &page); err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, v := range page.Records {
+		if v.Event.EventType != "INGEST" {
+			t.Fatalf("record %+v, want an INGEST event", v)
+		}
+		got[v.Event.EventID] = true
+	}
+	if len(got) != 2 || !got[ingests[0].EventID] || !got[ingests[1].EventID] {
+		t.Errorf("listed %v, want the two INGEST events", got)
+	}
+}
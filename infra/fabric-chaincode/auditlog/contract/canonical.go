@@ -0,0 +1,62 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"payscope/auditlog/jcs"
+)
+
+// Payload hashes of new events are taken over the RFC 8785 (JCS) form of the
+// event so clients in any language can reproduce them. That form is of the
+// decoded LedgerEvent as encoding/json writes it, with empty omitempty fields
+// and unknown fields dropped, not of the bytes the client submitted: clients
+// hash the event GetEvent returns, not their request body. Events stored
+// before JCS carry no canonicalization marker and keep verifying against the
+// legacy Go encoding they were hashed with; nothing is rewritten.
+const (
+	canonLegacy = ""
+	canonJCS    = "jcs"
+)
+
+// canonicalize encodes v with the named scheme.
+func canonicalize(v any, scheme string) ([]byte, error) {
+	switch scheme {
+	case canonLegacy:
+		return canonicalJSON(v)
+	case canonJCS:
+		return jcs.Marshal(v)
+	default:
+		return nil, fmt.Errorf("unknown canonicalization %q", scheme)
+	}
+}
+
+// hashNewEvent returns the payload hash of a new event, which is taken over
+// its JCS form, together with that form when it decodes back to e exactly,
+// so commitEvent can store the bytes that were hashed instead of encoding
+// the event a second time.
+func hashNewEvent(e *LedgerEvent, salt string) (hash string, canon []byte, err error) {
+	canon, exact, err := jcs.MarshalExact(e)
+	if err != nil {
+		return "", nil, err
+	}
+	hash = saltedHash(salt, canon)
+	if !exact {
+		canon = nil
+	}
+	return hash, canon, nil
+}
+
+// encodeStoredEvent encodes a record for the ledger. A record prepared in
+// this transaction carries its event's canonical bytes, which are spliced in
+// rather than encoded again; readers decode either form the same way.
+func encodeStoredEvent(stored *StoredEvent) ([]byte, error) {
+	if stored.canonical == nil {
+		return json.Marshal(stored)
+	}
+	type record StoredEvent
+	return json.Marshal(struct {
+		Event json.RawMessage `json:"event"`
+		*record
+	}{stored.canonical, (*record)(stored)})
+}
@@ -0,0 +1,197 @@
+package contract
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// An event may set prev_event_hash to the payload_hash_sha256 of the prior
+// event in its stream. The link is part of the canonical payload, so the
+// chain can be re-verified off-chain from the events alone. Streams are
+// linear: each event has at most one successor, recorded in chainNextIndex.
+// A stream belongs to the MSP that wrote it: an event may only follow an
+// event its own MSP wrote, so no other writer can take a stream's next link.
+const (
+	payloadHashIndex = "hash~id"
+	chainNextIndex   = "chain~next"
+)
+
+// eventByPayloadHash finds the event of creatorMSP's stream stored under a
+// payload hash, or nil. foreign reports whether only other MSPs' events
+// carry the hash.
+func eventByPayloadHash(ctx contractapi.TransactionContextInterface, hash, creatorMSP string, pending *pendingWrites) (stored *StoredEvent, foreign bool, err error) {
+	if stored, ok := pending.hashes[hash]; ok && stored.CreatorMSP == creatorMSP {
+		return stored, false, nil
+	}
+	it, err := ctx.GetStub().GetStateByPartialCompositeKey(payloadHashIndex, []string{hash})
+	if err != nil {
+		return nil, false, err
+	}
+	defer it.Close()
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return nil, false, err
+		}
+		stored, err := getStoredEvent(ctx, string(kv.Value))
+		if err != nil {
+			return nil, false, err
+		}
+		if stored.CreatorMSP == creatorMSP {
+			return stored, false, nil
+		}
+		foreign = true
+	}
+	return nil, foreign, nil
+}
+
+// chainNextKey is the successor slot of the event in creatorMSP's stream
+// whose payload hash is prev.
+func chainNextKey(ctx contractapi.TransactionContextInterface, creatorMSP, prev string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(chainNextIndex, []string{creatorMSP, prev})
+}
+
+// checkChainLink verifies e's prev_event_hash names an event creatorMSP
+// stored that has no successor yet and is not later than e.
+func checkChainLink(ctx contractapi.TransactionContextInterface, e *LedgerEvent, creatorMSP string, pending *pendingWrites) error {
+	if e.PrevEventHash == "" {
+		return nil
+	}
+	prev, foreign, err := eventByPayloadHash(ctx, e.PrevEventHash, creatorMSP, pending)
+	if err != nil {
+		return err
+	}
+	if foreign {
+		return errors.New("chain_link_foreign: prev_event_hash names another MSP's event")
+	}
+	if prev == nil {
+		return errors.New("chain_link_not_found: no event with payload hash prev_event_hash")
+	}
+	key, err := chainNextKey(ctx, creatorMSP, e.PrevEventHash)
+	if err != nil {
+		return err
+	}
+	if pending.successors[key] {
+		return errors.New("chain_fork: prev_event_hash already has a successor")
+	}
+	next, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return err
+	}
+	if next != nil {
+		return fmt.Errorf("chain_fork: prev_event_hash already followed by %s", next)
+	}
+	prevTs, _ := time.Parse(time.RFC3339, prev.Event.TimestampUTC)
+	ts, _ := time.Parse(time.RFC3339, e.TimestampUTC)
+	if ts.Before(prevTs) {
+		return errors.New("chain_timestamp_regression: event is earlier than prev_event_hash")
+	}
+	return nil
+}
+
+// claimChainLink records stored as the successor of its predecessor.
+func claimChainLink(ctx contractapi.TransactionContextInterface, stored *StoredEvent, pending *pendingWrites) error {
+	prev := stored.Event.PrevEventHash
+	if prev == "" {
+		return nil
+	}
+	key, err := chainNextKey(ctx, stored.CreatorMSP, prev)
+	if err != nil {
+		return err
+	}
+	if err := ctx.GetStub().PutState(key, []byte(stored.ref())); err != nil {
+		return err
+	}
+	pending.successors[key] = true
+	return nil
+}
+
+const maxChainWalk = 1000
+
+type ChainBreak struct {
+	EventID string `json:"event_id"`
+	Kind    string `json:"kind"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+type ChainReport struct {
+	StartEventID  string       `json:"start_event_id"`
+	EndEventID    string       `json:"end_event_id"`
+	VerifiedCount int          `json:"verified_count"`
+	ReachedStart  bool         `json:"reached_start"`
+	Intact        bool         `json:"intact"`
+	Breaks        []ChainBreak `json:"breaks"`
+	// Set when the walk stopped after maxChainWalk events; call again with
+	// this as endEventID to continue.
+	ResumeFrom string `json:"resume_from,omitempty"`
+}
+
+// VerifyChain walks prev_event_hash links back from endEventID to
+// startEventID, recomputing each event's payload hash. Payload mismatches
+// are reported and the walk continues; a missing link ends it.
+func (c *AuditLogContract) VerifyChain(ctx contractapi.TransactionContextInterface, startEventID string, endEventID string) (string, error) {
+	if !validate.UUID.MatchString(startEventID) || !validate.UUID.MatchString(endEventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	ref, err := callerRef(ctx, cfg, endEventID)
+	if err != nil {
+		return "", err
+	}
+	cur, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	report := ChainReport{StartEventID: startEventID, EndEventID: endEventID, Breaks: []ChainBreak{}}
+	none := newPendingWrites()
+	for {
+		if report.VerifiedCount == maxChainWalk {
+			report.ResumeFrom = cur.Event.EventID
+			break
+		}
+		computed, err := storedPayloadHash(ctx, cur)
+		if err != nil {
+			return "", err
+		}
+		if computed != cur.PayloadHash {
+			report.Breaks = append(report.Breaks, ChainBreak{
+				EventID: cur.Event.EventID,
+				Kind:    "payload_hash_mismatch",
+				Detail:  fmt.Sprintf("stored %s, computed %s", cur.PayloadHash, computed),
+			})
+		}
+		report.VerifiedCount++
+		if cur.Event.EventID == startEventID {
+			report.ReachedStart = true
+			break
+		}
+		if cur.Event.PrevEventHash == "" {
+			report.Breaks = append(report.Breaks, ChainBreak{EventID: cur.Event.EventID, Kind: "chain_start_not_reached"})
+			break
+		}
+		prev, _, err := eventByPayloadHash(ctx, cur.Event.PrevEventHash, cur.CreatorMSP, none)
+		if err != nil {
+			return "", err
+		}
+		if prev == nil {
+			report.Breaks = append(report.Breaks, ChainBreak{
+				EventID: cur.Event.EventID,
+				Kind:    "missing_link",
+				Detail:  cur.Event.PrevEventHash,
+			})
+			break
+		}
+		cur = prev
+	}
+	report.Intact = report.ReachedStart && len(report.Breaks) == 0
+	return marshalString(report)
+}
@@ -0,0 +1,61 @@
+package contract_test
+
+import (
+	"errors"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// A writer may only extend its own MSP's stream: linking to another MSP's
+// event must not take that event's successor slot.
+func TestChainLinkAcrossProducers(t *testing.T) {
+	for _, namespaced := range []bool{false, true} {
+		h, err := auditlogtest.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		admin := auditlogtest.Admin("Org1MSP")
+		cfg := map[string]any{"admin_msps": []string{"Org1MSP"}, "id_namespacing_by_producer": namespaced}
+		if _, err := h.Submit(admin, "Init", auditlogtest.MustJSON(cfg)); err != nil {
+			t.Fatal(err)
+		}
+		org1, org2 := auditlogtest.Client("Org1MSP"), auditlogtest.Client("Org2MSP")
+
+		head := put(t, h, org1, auditlogtest.Event("INGEST", 1))
+
+		foreign := auditlogtest.Event("INGEST", 2)
+		foreign.PrevEventHash = head
+		_, err = h.Submit(org2, "PutEvent", auditlogtest.MustJSON(foreign))
+		var txErr *auditlogtest.TxError
+		if !errors.As(err, &txErr) || txErr.Code != "ERR_FORBIDDEN" || txErr.Details["reason"] != "chain_link_foreign" {
+			t.Fatalf("namespaced=%v: foreign link err = %v, want chain_link_foreign", namespaced, err)
+		}
+
+		next := auditlogtest.Event("INGEST", 3)
+		next.PrevEventHash = head
+		put(t, h, org1, next)
+
+		fork := auditlogtest.Event("INGEST", 4)
+		fork.PrevEventHash = head
+		_, err = h.Submit(org1, "PutEvent", auditlogtest.MustJSON(fork))
+		if !errors.As(err, &txErr) || txErr.Details["reason"] != "chain_fork" {
+			t.Fatalf("namespaced=%v: fork err = %v, want chain_fork", namespaced, err)
+		}
+	}
+}
+
+// put writes e as id and returns its payload hash.
+func put(t *testing.T, h *auditlogtest.Harness, id auditlogtest.Identity, e *contract.LedgerEvent) string {
+	t.Helper()
+	out, err := h.Submit(id, "PutEvent", auditlogtest.MustJSON(e))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r contract.Receipt
+	if err := auditlogtest.Unmarshal(out, &r); err != nil {
+		t.Fatal(err)
+	}
+	return r.PayloadHash
+}
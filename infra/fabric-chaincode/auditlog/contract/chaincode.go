@@ -0,0 +1,879 @@
+package contract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// Ledger event schema:
+// {
+//   "event_id": "uuid",
+//   "event_type": "INGEST | AGENT_DECISION | FORECAST",
+//   "artifact_hash": "hex digest under hash_algorithm",
+//   "schema_version": "vX",
+//   "timestamp": "UTC",
+//   "tsa_token_hash": "sha256 (optional)",
+//   "ttl_seconds": 86400 (optional),
+//   "confidence": 0.0-1.0 (optional; required for FORECAST if configured),
+//   "forecast": {"model_id", "forecast_horizon", "input_digest"} (FORECAST, v2+),
+//   "decision": {"agent_id", "decision_code", "confidence", "policy_version"} (AGENT_DECISION, v2+),
+//   "ingest": {"source_system", "record_count", "batch_window_start", "batch_window_end"} (INGEST, v2+),
+//   "prev_event_hash": "sha256 (optional)",
+//   "hash_algorithm": "sha256 | sha3-256 | blake2b-256 (optional; default sha256)",
+//   "signature": "base64 (optional)",
+//   "signer_cert": "PEM (optional)",
+//   "supersedes": "uuid (AmendEvent only)"
+// }
+
+type AuditLogContract struct {
+	contractapi.Contract
+}
+
+type LedgerEvent struct {
+	EventID      string `json:"event_id"`
+	EventType    string `json:"event_type"`
+	ArtifactHash string `json:"artifact_hash"`
+	SchemaVer    string `json:"schema_version"`
+	TimestampUTC string `json:"timestamp"`
+	// Optional reference to an off-chain RFC 3161 token; omitted from the
+	// canonical form when empty so existing payload hashes are unchanged.
+	TSATokenHash string `json:"tsa_token_hash,omitempty"`
+	// Optional inline RFC 3161 token, verified on write; see tsa.go.
+	TSAToken string `json:"tsa_token,omitempty"`
+	// Optional validity window after timestamp; see GetEvent's "expired".
+	TTLSeconds *int64 `json:"ttl_seconds,omitempty"`
+	// Forecast confidence in [0,1]; see forecast.go.
+	Confidence *float64 `json:"confidence,omitempty"`
+	// payload_hash_sha256 of the prior event in the stream; see chain.go.
+	PrevEventHash string `json:"prev_event_hash,omitempty"`
+	// Digest algorithm of artifact_hash; empty means sha256. See hashalg.go.
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	// Optional client signature and PEM certificate; see signature.go.
+	Signature  string `json:"signature,omitempty"`
+	SignerCert string `json:"signer_cert,omitempty"`
+	// Registered key the signature is by, instead of the submitter's
+	// certificate; see signerkey.go.
+	SignerKeyID string `json:"signer_key_id,omitempty"`
+	// event_id this event corrects; set only through AmendEvent.
+	Supersedes string `json:"supersedes,omitempty"`
+	// Id of the business record this event governs; see reference.go.
+	ReferenceTx string `json:"reference_tx,omitempty"`
+	// Pipeline run the event belongs to; see correlation.go.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Events this one was derived from; see lineage.go.
+	ParentEventIDs []string `json:"parent_event_ids,omitempty"`
+	// Free-form labels, schema_version v2 and later; see metadata.go.
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Every artifact involved, with its role; see eventartifacts.go.
+	Artifacts []EventArtifact `json:"artifacts,omitempty"`
+	// Model, horizon and input digest of v2 FORECAST events; see forecast.go.
+	Forecast *ForecastDetails `json:"forecast,omitempty"`
+	// Agent, outcome and policy of v2 AGENT_DECISION events; see decision.go.
+	Decision *AgentDecision `json:"decision,omitempty"`
+	// Source, size and window of v2 INGEST batches; see ingest.go.
+	Ingest *IngestDetails `json:"ingest,omitempty"`
+}
+
+type StoredEvent struct {
+	Event       LedgerEvent `json:"event"`
+	PayloadHash string      `json:"payload_hash_sha256"`
+	// Id of the hashing salt applied to PayloadHash; empty when unsalted.
+	SaltID string `json:"salt_id,omitempty"`
+	// Submitter MSP; set only when the event is stored namespaced by producer.
+	Producer string `json:"producer,omitempty"`
+	// MSP of the identity that first wrote the event.
+	CreatorMSP string `json:"creator_msp,omitempty"`
+	// Orgs whose peers must endorse later writes; see endorsement.go.
+	RequiredOrgs []string `json:"required_orgs,omitempty"`
+	// event_type as submitted, kept only when normalization changed it.
+	OriginalEventType string `json:"original_event_type,omitempty"`
+	// Artifact hashes as submitted, by field, for those
+	// normalize_artifact_hashes rewrote.
+	OriginalArtifactHashes map[string]string `json:"original_artifact_hashes,omitempty"`
+	// Written under time_ordered_keys; decides the key of a UUIDv7 event.
+	TimeOrderedKeys bool `json:"time_ordered_keys,omitempty"`
+	// Collection holding the full payload, for events written with one.
+	PrivateCollection string `json:"private_collection,omitempty"`
+	// Data keys the private payload is encrypted under; see envelope.go.
+	PayloadKeyIDs []string `json:"payload_key_ids,omitempty"`
+	// Submission format when not JSON, e.g. "proto"; see proto.go.
+	SourceFormat string `json:"source_format,omitempty"`
+	// Encoding PayloadHash was taken over; empty for legacy records.
+	Canonicalization string `json:"canonicalization,omitempty"`
+	// JCS form of Event as hashed when the record was prepared, stored in
+	// its place; see encodeStoredEvent.
+	canonical []byte
+	// Transaction timestamp of the write, next to the declared timestamp.
+	LedgerTimestamp string `json:"ledger_timestamp,omitempty"`
+	// Set when the declared timestamp exceeded the configured drift.
+	TimestampDriftFlagged bool `json:"timestamp_drift_flagged,omitempty"`
+	// Content hash under payload_dedup, and the event_id of the first
+	// event with the same content when this one repeats it; see dedup.go.
+	ContentHash string `json:"content_hash,omitempty"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	// What a verified tsa_token attests; see tsa.go.
+	TSAStamp *TSAStamp `json:"tsa_stamp,omitempty"`
+	// Set on CONFIG_CHANGE records; see configaudit.go.
+	ConfigChange *ConfigChange `json:"config_change,omitempty"`
+	// Set once the event was tombstoned; see deletion.go.
+	Deletion *DeletionRecord `json:"deletion,omitempty"`
+	// Transaction that wrote the record; empty for records written before
+	// receipts.
+	TxID string `json:"tx_id,omitempty"`
+	// Shape of this record; 0 for records older than the field. See
+	// migrate.go.
+	StorageVersion int `json:"storage_version,omitempty"`
+}
+
+// TSARecord attaches a trusted timestamp token to an already written event.
+type TSARecord struct {
+	EventID      string `json:"event_id"`
+	TokenHash    string `json:"tsa_token_hash"`
+	TSAName      string `json:"tsa_name"`
+	RegisteredAt string `json:"registered_at"`
+	TxID         string `json:"tx_id"`
+}
+
+type VerifyResult struct {
+	EventID      string `json:"event_id"`
+	Found        bool   `json:"found"`
+	Consistent   bool   `json:"consistent"`
+	StoredHash   string `json:"stored_hash,omitempty"`
+	ComputedHash string `json:"computed_hash,omitempty"`
+	// Tombstoned by a governed deletion, so there is nothing left to
+	// hash; not counted as a failure. See deletion.go.
+	Deleted bool `json:"deleted,omitempty"`
+}
+
+type VerifySummary struct {
+	Total    int `json:"total"`
+	Failures int `json:"failures"`
+}
+
+type VerifyBatchReport struct {
+	Results []VerifyResult `json:"results"`
+	Summary VerifySummary  `json:"summary"`
+}
+
+type BatchGetResult struct {
+	EventID string `json:"event_id"`
+	Found   bool   `json:"found"`
+	// *EventView, or *EventSummary under the summary projection.
+	Event any `json:"event,omitempty"`
+}
+
+type BatchGetReport struct {
+	Results  []BatchGetResult `json:"results"`
+	Found    int              `json:"found"`
+	NotFound int              `json:"not_found"`
+}
+
+// EventSummary is the list-view projection of a StoredEvent.
+type EventSummary struct {
+	EventID     string `json:"event_id"`
+	EventType   string `json:"event_type"`
+	Timestamp   string `json:"timestamp"`
+	PayloadHash string `json:"payload_hash_sha256"`
+	Revoked     bool   `json:"revoked,omitempty"`
+	Archived    bool   `json:"archived,omitempty"`
+	Deleted     bool   `json:"deleted,omitempty"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+}
+
+type EventSummaryPage struct {
+	Records      []EventSummary `json:"records"`
+	FetchedCount int32          `json:"fetched_count"`
+	Bookmark     string         `json:"bookmark"`
+}
+
+type EventPage struct {
+	Records      []EventView `json:"records"`
+	FetchedCount int32       `json:"fetched_count"`
+	Bookmark     string      `json:"bookmark"`
+}
+
+var (
+	typeSet = map[string]bool{"INGEST": true, "AGENT_DECISION": true, "FORECAST": true, configChangeType: true, purgedType: true, deletionProposedType: true, deletionApprovedType: true, deletedType: true}
+)
+
+const (
+	eventKeyPrefix = "event:"
+	tsaKeyPrefix   = "tsa:"
+	maxPageSize    = 1000
+	maxVerifyBatch = 500
+	maxGetBatch    = 500
+)
+
+// canonicalJSON is the legacy pre-JCS encoding; see canonical.go.
+func canonicalJSON(v any) ([]byte, error) {
+	// Go's json.Marshal is stable for struct field order; for this schema, that's sufficient.
+	// No additional whitespace.
+	return json.Marshal(v)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func payloadHash(e LedgerEvent, salt, scheme string) (string, error) {
+	canon, err := canonicalize(e, scheme)
+	if err != nil {
+		return "", err
+	}
+	return saltedHash(salt, canon), nil
+}
+
+func saltedHash(salt string, canon []byte) string {
+	h := sha256.New()
+	h.Write([]byte(salt))
+	h.Write(canon)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// storedPayloadHash recomputes a record's hash with the salt it was written with.
+func storedPayloadHash(ctx contractapi.TransactionContextInterface, stored *StoredEvent) (string, error) {
+	salt, err := saltByID(ctx, stored.SaltID)
+	if err != nil {
+		return "", err
+	}
+	return payloadHash(stored.Event, salt, stored.Canonicalization)
+}
+
+func validateEvent(e *LedgerEvent, cfg *ContractConfig) error {
+	if !validate.UUID.MatchString(e.EventID) {
+		return fmt.Errorf("invalid event_id")
+	}
+	if cfg.normalizesEventType() {
+		e.EventType = strings.ToUpper(strings.TrimSpace(e.EventType))
+	}
+	// The registry check in checkEventType needs ledger access.
+	if e.EventType == "" {
+		return fmt.Errorf("invalid event_type")
+	}
+	if err := validateArtifactHash(e); err != nil {
+		return err
+	}
+	if e.SchemaVer == "" {
+		return fmt.Errorf("schema_version required")
+	}
+	if !cfg.schemaVersionAllowed(e.SchemaVer) {
+		return fmt.Errorf("schema_version_not_in_enum")
+	}
+	// Require RFC3339 timestamp; treat as UTC by normalization.
+	ts, err := time.Parse(time.RFC3339, e.TimestampUTC)
+	if err != nil {
+		return fmt.Errorf("timestamp must be RFC3339")
+	}
+	if !strings.HasSuffix(e.TimestampUTC, "Z") {
+		switch cfg.TimestampOffsets {
+		case "reject":
+			return fmt.Errorf("timestamp_offset_not_allowed: timestamp must be UTC with a Z suffix, got %s", e.TimestampUTC)
+		case "normalize":
+			e.TimestampUTC = utcTimestamp(e.TimestampUTC, ts)
+		}
+	}
+	if norm, ok := cfg.normalizeTimestamp(ts); ok {
+		e.TimestampUTC = norm
+		ts, _ = time.Parse(time.RFC3339, norm)
+	}
+	if cfg.MinTimestamp != "" {
+		// Validated at Init, so the parse cannot fail here.
+		min, _ := time.Parse(time.RFC3339, cfg.MinTimestamp)
+		if ts.Before(min) {
+			return fmt.Errorf("timestamp_before_genesis")
+		}
+	}
+	if e.TSATokenHash != "" && !validate.SHA256.MatchString(e.TSATokenHash) {
+		return fmt.Errorf("tsa_token_hash must be lowercase sha256 hex")
+	}
+	if e.Supersedes != "" && !validate.UUID.MatchString(e.Supersedes) {
+		return fmt.Errorf("supersedes must be an event_id")
+	}
+	if e.PrevEventHash != "" && !validate.SHA256.MatchString(e.PrevEventHash) {
+		return fmt.Errorf("prev_event_hash must be lowercase sha256 hex")
+	}
+	if e.TTLSeconds != nil && *e.TTLSeconds <= 0 {
+		return fmt.Errorf("ttl_seconds must be positive")
+	}
+	if err := validateConfidence(e, cfg); err != nil {
+		return err
+	}
+	if err := validateForecast(e); err != nil {
+		return err
+	}
+	if err := validateDecision(e); err != nil {
+		return err
+	}
+	if err := validateIngest(e); err != nil {
+		return err
+	}
+	if err := validateReference(e); err != nil {
+		return err
+	}
+	if err := validateCorrelationID(e); err != nil {
+		return err
+	}
+	if err := validateParents(e); err != nil {
+		return err
+	}
+	if err := validateMetadata(e, cfg); err != nil {
+		return err
+	}
+	if err := validateEventArtifacts(e); err != nil {
+		return err
+	}
+	return checkEventSize(e, cfg)
+}
+
+func txTimeUTC(ctx contractapi.TransactionContextInterface) (time.Time, error) {
+	ts, err := ctx.GetStub().GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ts.AsTime().UTC(), nil
+}
+
+func getStoredEvent(ctx contractapi.TransactionContextInterface, ref string) (*StoredEvent, error) {
+	b, err := ctx.GetStub().GetState(eventKey(ref))
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("not_found")
+	}
+	var stored StoredEvent
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return nil, fmt.Errorf("corrupt stored event")
+	}
+	return &stored, nil
+}
+
+func (c *AuditLogContract) GetEvent(ctx contractapi.TransactionContextInterface, eventID string, projection string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	ref, err := callerRef(ctx, cfg, eventID)
+	if err != nil {
+		return "", err
+	}
+	return getEventJSON(ctx, cfg, ref, projection)
+}
+
+// GetEventForProducer is the admin cross-producer lookup for channels with
+// id_namespacing_by_producer enabled.
+func (c *AuditLogContract) GetEventForProducer(ctx contractapi.TransactionContextInterface, producerMSP string, eventID string, projection string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	if !cfg.IDNamespacingByProducer {
+		return "", errors.New("id_namespacing_by_producer is not enabled")
+	}
+	if producerMSP == "" {
+		return "", fmt.Errorf("producer_msp required")
+	}
+	return getEventJSON(ctx, cfg, eventRef(producerMSP, eventID, cfg.TimeOrderedKeys), projection)
+}
+
+// getEventJSON returns the read view of the stored record under projection
+// and records the read.
+func getEventJSON(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, ref string, projection string) (string, error) {
+	b, err := ctx.GetStub().GetState(eventKey(ref))
+	if err != nil {
+		return "", err
+	}
+	if b == nil {
+		return "", fmt.Errorf("not_found")
+	}
+	var stored StoredEvent
+	if err := json.Unmarshal(b, &stored); err != nil {
+		return "", fmt.Errorf("corrupt stored event")
+	}
+	view, err := viewOf(ctx, &stored)
+	if err != nil {
+		return "", err
+	}
+	if err := recordAccess(ctx, cfg, &stored); err != nil {
+		return "", err
+	}
+	return marshalString(projectView(view, projection))
+}
+
+// GetEventsByType pages through events of one type in timestamp order.
+// mode is the projection, "summary" or "full"; see projection.go.
+func (c *AuditLogContract) GetEventsByType(ctx contractapi.TransactionContextInterface, eventType string, mode string, bookmark string, pageSize int32) (string, error) {
+	if err := requireEventType(ctx, eventType); err != nil {
+		return "", err
+	}
+	if mode != "summary" && mode != "full" {
+		return "", fmt.Errorf("invalid mode: expected summary or full")
+	}
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	stub := ctx.GetStub()
+	it, meta, err := pageIndexPrefix(stub, typeTsIndex, []string{eventType}, pageSize, bookmark)
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	records := []EventView{}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		stored, err := getStoredEvent(ctx, string(kv.Value))
+		if err != nil {
+			return "", err
+		}
+		if records, err = appendView(ctx, records, stored); err != nil {
+			return "", err
+		}
+	}
+
+	return marshalPage(&EventPage{Records: records, FetchedCount: meta.FetchedRecordsCount, Bookmark: meta.Bookmark}, mode)
+}
+
+// GetDistinctArtifactsByType returns one event per distinct artifact_hash of
+// eventType, the earliest by timestamp. Pages walk the type~artifact~ts~id
+// index, so a page may hold fewer than pageSize records.
+func (c *AuditLogContract) GetDistinctArtifactsByType(ctx contractapi.TransactionContextInterface, eventType string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := requireEventType(ctx, eventType); err != nil {
+		return "", err
+	}
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	stub := ctx.GetStub()
+	it, meta, err := stub.GetStateByPartialCompositeKeyWithPagination(typeArtifactTsIndex, []string{eventType}, pageSize, bookmark)
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	page := EventPage{Records: []EventView{}}
+	prevArtifact := ""
+	first := true
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		_, attrs, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return "", err
+		}
+		artifact, ref := attrs[1], string(kv.Value)
+		if artifact == prevArtifact {
+			continue
+		}
+		prevArtifact = artifact
+		// A resumed page may start inside an artifact's group; only emit
+		// it if this entry really is the group's earliest.
+		if first && bookmark != "" {
+			earliest, err := earliestRefForTypeArtifact(ctx, eventType, artifact)
+			if err != nil {
+				return "", err
+			}
+			if earliest != ref {
+				first = false
+				continue
+			}
+		}
+		first = false
+		stored, err := getStoredEvent(ctx, ref)
+		if err != nil {
+			return "", err
+		}
+		if page.Records, err = appendView(ctx, page.Records, stored); err != nil {
+			return "", err
+		}
+	}
+	page.FetchedCount = meta.FetchedRecordsCount
+	page.Bookmark = meta.Bookmark
+	return marshalPage(&page, projection)
+}
+
+// GetDistinctValues returns the sorted distinct values of an indexed field
+// (event_type, schema_version or producer MSP) with their event counts.
+// It walks the whole index, so it is meant for evaluate-only use.
+func (c *AuditLogContract) GetDistinctValues(ctx contractapi.TransactionContextInterface, field string) (string, error) {
+	index, ok := distinctFieldIndexes[field]
+	if !ok {
+		return "", fmt.Errorf("invalid field: expected event_type, schema_version or producer")
+	}
+	values, err := distinctLeadingValues(ctx, index)
+	if err != nil {
+		return "", err
+	}
+	return marshalString(values)
+}
+
+// VerifyEventsBatch recomputes the payload hash of each listed event and
+// compares it with the stored one. Missing events are reported, not fatal.
+func (c *AuditLogContract) VerifyEventsBatch(ctx contractapi.TransactionContextInterface, idsJSON string) (string, error) {
+	var ids []string
+	if err := json.Unmarshal([]byte(idsJSON), &ids); err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+	if len(ids) == 0 || len(ids) > maxVerifyBatch {
+		return "", fmt.Errorf("batch must contain between 1 and %d event_ids", maxVerifyBatch)
+	}
+	for i, id := range ids {
+		if !validate.UUID.MatchString(id) {
+			return "", fmt.Errorf("invalid event_id at index %d", i)
+		}
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	report := VerifyBatchReport{Results: make([]VerifyResult, 0, len(ids))}
+	for _, id := range ids {
+		res := VerifyResult{EventID: id}
+		ref, err := callerRef(ctx, cfg, id)
+		if err != nil {
+			return "", err
+		}
+		b, err := ctx.GetStub().GetState(eventKey(ref))
+		if err != nil {
+			return "", err
+		}
+		if b != nil {
+			var stored StoredEvent
+			if err := json.Unmarshal(b, &stored); err != nil {
+				return "", fmt.Errorf("corrupt stored event: %s", id)
+			}
+			res.Found = true
+			res.StoredHash = stored.PayloadHash
+			res.Deleted = stored.Deletion != nil
+			if !res.Deleted {
+				computed, err := storedPayloadHash(ctx, &stored)
+				if err != nil {
+					return "", err
+				}
+				res.ComputedHash = computed
+				res.Consistent = computed == stored.PayloadHash
+			}
+		}
+		if !res.Consistent && !res.Deleted {
+			report.Summary.Failures++
+		}
+		report.Results = append(report.Results, res)
+	}
+	report.Summary.Total = len(report.Results)
+
+	out, err := json.Marshal(report)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// GetEventsBatch returns each listed event, in request order, or reports it
+// not found. Ids resolve as in GetEvent.
+func (c *AuditLogContract) GetEventsBatch(ctx contractapi.TransactionContextInterface, eventIDsJSON string, projection string) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	var ids []string
+	if err := json.Unmarshal([]byte(eventIDsJSON), &ids); err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+	if len(ids) == 0 || len(ids) > maxGetBatch {
+		return "", fmt.Errorf("batch must contain between 1 and %d event_ids", maxGetBatch)
+	}
+	for i, id := range ids {
+		if !validate.UUID.MatchString(id) {
+			return "", fmt.Errorf("invalid event_id at index %d", i)
+		}
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	report := BatchGetReport{Results: make([]BatchGetResult, 0, len(ids))}
+	var read []*StoredEvent
+	for _, id := range ids {
+		res := BatchGetResult{EventID: id}
+		ref, err := callerRef(ctx, cfg, id)
+		if err != nil {
+			return "", err
+		}
+		b, err := ctx.GetStub().GetState(eventKey(ref))
+		if err != nil {
+			return "", err
+		}
+		if b == nil {
+			report.NotFound++
+			report.Results = append(report.Results, res)
+			continue
+		}
+		var stored StoredEvent
+		if err := json.Unmarshal(b, &stored); err != nil {
+			return "", fmt.Errorf("corrupt stored event: %s", id)
+		}
+		view, err := viewOf(ctx, &stored)
+		if err != nil {
+			return "", err
+		}
+		res.Event = projectView(view, projection)
+		res.Found = true
+		report.Found++
+		report.Results = append(report.Results, res)
+		read = append(read, &stored)
+	}
+	if err := recordAccess(ctx, cfg, read...); err != nil {
+		return "", err
+	}
+	return marshalString(report)
+}
+
+// RegisterTSAToken attaches an RFC 3161 token reference to an existing event.
+// The record is write-once; events that already carry a token are rejected.
+func (c *AuditLogContract) RegisterTSAToken(ctx contractapi.TransactionContextInterface, eventID string, tokenHash string, tsaName string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	if !validate.SHA256.MatchString(tokenHash) {
+		return "", fmt.Errorf("tsa_token_hash must be lowercase sha256 hex")
+	}
+	if tsaName == "" {
+		return "", fmt.Errorf("tsa_name required")
+	}
+	ref, err := resolveRef(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	key := tsaKeyPrefix + ref
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil || stored.Event.TSATokenHash != "" || stored.Event.TSAToken != "" {
+		return "", errors.New("tsa_already_registered")
+	}
+
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	rec := TSARecord{
+		EventID:      eventID,
+		TokenHash:    tokenHash,
+		TSAName:      tsaName,
+		RegisteredAt: now.Format(time.RFC3339Nano),
+		TxID:         ctx.GetStub().GetTxID(),
+	}
+	out, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(key, out); err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
+
+// scanEvents reads one page of the primary event keyspace and keeps the
+// records accepted by keep. Filtering happens after pagination, so a page may
+// hold fewer than pageSize records; callers continue until the bookmark comes
+// back empty.
+func scanEvents(ctx contractapi.TransactionContextInterface, bookmark string, pageSize int32, keep func(*StoredEvent) (bool, error)) (*EventPage, error) {
+	return scanEventRange(ctx, eventKeyPrefix, eventKeyPrefix+"\uffff", bookmark, pageSize, keep)
+}
+
+// scanEventRange is scanEvents over the event keys in [startKey, endKey).
+func scanEventRange(ctx contractapi.TransactionContextInterface, startKey, endKey string, bookmark string, pageSize int32, keep func(*StoredEvent) (bool, error)) (*EventPage, error) {
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return nil, err
+	}
+	it, meta, err := ctx.GetStub().GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	page := &EventPage{Records: []EventView{}}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		var stored StoredEvent
+		if err := json.Unmarshal(kv.Value, &stored); err != nil {
+			return nil, fmt.Errorf("corrupt stored event")
+		}
+		ok, err := keep(&stored)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if page.Records, err = appendView(ctx, page.Records, &stored); err != nil {
+				return nil, err
+			}
+		}
+	}
+	page.FetchedCount = meta.FetchedRecordsCount
+	page.Bookmark = meta.Bookmark
+	return page, nil
+}
+
+func keepAll(*StoredEvent) (bool, error) { return true, nil }
+
+func marshalString(v any) (string, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// ListEvents pages through every stored event in key order so auditors can
+// walk the full log without knowing event ids up front.
+func (c *AuditLogContract) ListEvents(ctx contractapi.TransactionContextInterface, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	page, err := scanEvents(ctx, bookmark, pageSize, keepAll)
+	if err != nil {
+		return "", err
+	}
+	return marshalPage(page, projection)
+}
+
+// QueryEventsByTimeRange pages through events of every type with timestamps
+// in [start, end), in timestamp order, using the ts~id index.
+func (c *AuditLogContract) QueryEventsByTimeRange(ctx contractapi.TransactionContextInterface, startRFC3339 string, endRFC3339 string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	start, end, err := parseTimeRange(startRFC3339, endRFC3339)
+	if err != nil {
+		return "", err
+	}
+	startKey, endKey, err := timeRangeKeys(ctx, tsIndex, nil, start, end)
+	if err != nil {
+		return "", err
+	}
+	page, err := pageIndexRange(ctx, startKey, endKey, bookmark, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return marshalPage(page, projection)
+}
+
+// GetEventsByArtifactHash returns the events referencing an artifact, up to
+// max_query_results of them.
+func (c *AuditLogContract) GetEventsByArtifactHash(ctx contractapi.TransactionContextInterface, artifactHash string, projection string) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	artifactHash, err := artifactHashArg(ctx, artifactHash)
+	if err != nil {
+		return "", err
+	}
+	if !validate.SHA256.MatchString(artifactHash) {
+		return "", fmt.Errorf("artifact_hash must be lowercase sha256 hex")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	it, err := ctx.GetStub().GetStateByPartialCompositeKey(artifactIndex, []string{artifactHash})
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	list := EventList{Records: []EventView{}}
+	for it.HasNext() {
+		if len(list.Records) == cfg.maxQueryResults() {
+			list.Truncated = true
+			break
+		}
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		stored, err := getStoredEvent(ctx, string(kv.Value))
+		if err != nil {
+			return "", err
+		}
+		if list.Records, err = appendView(ctx, list.Records, stored); err != nil {
+			return "", err
+		}
+	}
+	return marshalList(&list, projection)
+}
+
+// GetEventsWithoutTSA returns the events that have no inline tsa_token or
+// tsa_token_hash and no registered TSA record.
+func (c *AuditLogContract) GetEventsWithoutTSA(ctx contractapi.TransactionContextInterface, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	page, err := scanEvents(ctx, bookmark, pageSize, func(stored *StoredEvent) (bool, error) {
+		if stored.Event.TSATokenHash != "" || stored.Event.TSAToken != "" {
+			return false, nil
+		}
+		tsa, err := ctx.GetStub().GetState(tsaKeyPrefix + stored.ref())
+		if err != nil {
+			return false, err
+		}
+		return tsa == nil, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return marshalPage(page, projection)
+}
+
+// NewChaincode assembles the chaincode's contracts, see contracts.go,
+// behind the error envelope, ready for Start or for driving in-process
+// through a mock stub.
+func NewChaincode() (shim.Chaincode, error) {
+	cc, err := contractapi.NewChaincode(contracts()...)
+	if err != nil {
+		return nil, err
+	}
+	return &envelopeChaincode{cc}, nil
+}
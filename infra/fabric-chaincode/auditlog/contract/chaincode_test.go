@@ -0,0 +1,154 @@
+package contract_test
+
+import (
+	"errors"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// min_timestamp is inclusive: an event stamped exactly at it is accepted,
+// one a nanosecond earlier is not.
+func TestMinTimestampBoundary(t *testing.T) {
+	const min = "2024-01-01T00:00:10Z"
+	for _, tc := range []struct {
+		timestamp, precision string
+		ok                   bool
+	}{
+		{"2024-01-01T00:00:10Z", "", true},
+		{"2024-01-01T00:00:10.000000001Z", "", true},
+		{"2024-01-01T01:00:10+01:00", "", true},
+		{"2024-01-01T00:00:09.999999999Z", "", false},
+		{"2024-01-01T00:00:09Z", "", false},
+		{"1970-01-01T00:00:00Z", "", false},
+		// Checked after truncation to the configured precision.
+		{"2024-01-01T00:00:10.9Z", "second", true},
+		{"2024-01-01T00:00:09.999Z", "millis", false},
+	} {
+		h := initHarness(t, map[string]any{"min_timestamp": min, "timestamp_precision": tc.precision})
+		e := auditlogtest.Event("INGEST", 1)
+		e.TimestampUTC = tc.timestamp
+		_, err := h.Submit(auditlogtest.Client("Org1MSP"), "PutEvent", auditlogtest.MustJSON(e))
+		if tc.ok {
+			if err != nil {
+				t.Errorf("%s: %v, want accepted", tc.timestamp, err)
+			}
+			continue
+		}
+		var txErr *auditlogtest.TxError
+		if !errors.As(err, &txErr) || txErr.Code != "ERR_VALIDATION_TIMESTAMP" || txErr.Details["reason"] != "timestamp_before_genesis" {
+			t.Errorf("%s: err = %v, want timestamp_before_genesis", tc.timestamp, err)
+		}
+	}
+}
+
+// Every precision truncates before the event is stored; anything else is
+// refused at Init.
+func TestTimestampPrecision(t *testing.T) {
+	const submitted = "2024-01-01T01:00:01.123456789+01:00"
+	for _, tc := range []struct {
+		precision, want string
+	}{
+		{"", submitted},
+		{"second", "2024-01-01T00:00:01Z"},
+		{"millis", "2024-01-01T00:00:01.123Z"},
+		{"nanos", "2024-01-01T00:00:01.123456789Z"},
+	} {
+		h := initHarness(t, map[string]any{"timestamp_precision": tc.precision})
+		e := auditlogtest.Event("INGEST", 1)
+		e.TimestampUTC = submitted
+		if _, err := h.Submit(auditlogtest.Client("Org1MSP"), "PutEvent", auditlogtest.MustJSON(e)); err != nil {
+			t.Fatal(err)
+		}
+		if got := getEvent(t, h, e.EventID).Event.TimestampUTC; got != tc.want {
+			t.Errorf("precision %q: stored %s, want %s", tc.precision, got, tc.want)
+		}
+	}
+
+	for _, precision := range []string{"minute", "micros", "Second"} {
+		h, err := auditlogtest.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg := map[string]any{"admin_msps": []string{"Org1MSP"}, "timestamp_precision": precision}
+		_, err = h.Submit(auditlogtest.Admin("Org1MSP"), "Init", auditlogtest.MustJSON(cfg))
+		var txErr *auditlogtest.TxError
+		if !errors.As(err, &txErr) {
+			t.Errorf("precision %q: Init err = %v, want a rejection", precision, err)
+		}
+	}
+}
+
+// With normalize_event_type on, the default, each spelling is stored under
+// its canonical type and keeps the submitted one; with it off only the
+// canonical spelling is accepted.
+func TestNormalizeEventType(t *testing.T) {
+	for _, tc := range []struct {
+		submitted, want string
+	}{
+		{"ingest", "INGEST"},
+		{" INGEST ", "INGEST"},
+		{"Forecast", "FORECAST"},
+		{"INGEST", "INGEST"},
+	} {
+		for _, normalize := range []any{"", true, false} {
+			h := initHarness(t, map[string]any{"normalize_event_type": normalize})
+			e := auditlogtest.Event(tc.want, 1)
+			e.EventType = tc.submitted
+			_, err := h.Submit(auditlogtest.Client("Org1MSP"), "PutEvent", auditlogtest.MustJSON(e))
+			if normalize == false && tc.submitted != tc.want {
+				var txErr *auditlogtest.TxError
+				if !errors.As(err, &txErr) {
+					t.Errorf("%q, normalize off: err = %v, want a rejection", tc.submitted, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%q, normalize %v: %v", tc.submitted, normalize, err)
+			}
+			original := ""
+			if tc.submitted != tc.want {
+				original = tc.submitted
+			}
+			v := getEvent(t, h, e.EventID)
+			if v.Event.EventType != tc.want || v.OriginalEventType != original {
+				t.Errorf("%q, normalize %v: stored %q with original %q, want %q with %q", tc.submitted, normalize, v.Event.EventType, v.OriginalEventType, tc.want, original)
+			}
+		}
+	}
+}
+
+// initHarness returns a harness initialized with admin_msps Org1MSP and the
+// non-empty entries of cfg.
+func initHarness(t *testing.T, cfg map[string]any) *auditlogtest.Harness {
+	t.Helper()
+	h, err := auditlogtest.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := map[string]any{"admin_msps": []string{"Org1MSP"}}
+	for k, v := range cfg {
+		if v != "" {
+			full[k] = v
+		}
+	}
+	if _, err := h.Submit(auditlogtest.Admin("Org1MSP"), "Init", auditlogtest.MustJSON(full)); err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// getEvent reads eventID back as Org1MSP's client.
+func getEvent(t *testing.T, h *auditlogtest.Harness, eventID string) contract.EventView {
+	t.Helper()
+	out, err := h.Evaluate(auditlogtest.Client("Org1MSP"), "GetEvent", eventID, "full")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v contract.EventView
+	if err := auditlogtest.Unmarshal(out, &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
@@ -0,0 +1,368 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const configKey = "config"
+
+// ContractConfig is seeded once through Init or InitLedger, changed through
+// UpdateConfig and SetAccessControl, and read on every write.
+// The zero value is the default policy.
+type ContractConfig struct {
+	// MSP IDs allowed to call admin transactions.
+	AdminMSPs []string `json:"admin_msps,omitempty"`
+	// Events stamped before this RFC3339 instant are rejected.
+	MinTimestamp string `json:"min_timestamp,omitempty"`
+	// Every event referencing an artifact_hash must share one schema_version.
+	ArtifactSchemaConsistency bool `json:"artifact_schema_consistency,omitempty"`
+	// When set, schema_version must be one of these exact values.
+	SchemaVersionEnum []string `json:"schema_version_enum,omitempty"`
+	// Key events by submitter MSP as well as event_id; see namespace.go.
+	IDNamespacingByProducer bool `json:"id_namespacing_by_producer,omitempty"`
+	// Key UUIDv7 events in id (creation time) order; see uuidv7.go. Like
+	// id_namespacing_by_producer it is fixed at Init.
+	TimeOrderedKeys bool `json:"time_ordered_keys,omitempty"`
+	// "second", "millis" or "nanos". When set, timestamps are rewritten in
+	// UTC at that precision before hashing and indexing; unset keeps the
+	// submitted string (nanos, lossless). Changing it once events exist
+	// requires re-indexing, since earlier keys keep the old precision.
+	TimestampPrecision string `json:"timestamp_precision,omitempty"`
+	// "normalize" rewrites timestamps with an offset other than Z into UTC,
+	// keeping their fractional digits, before hashing; "reject" refuses
+	// them. Unset keeps the submitted string. Switching changes the hash a
+	// retried event is compared with, so events already written with an
+	// offset fail idempotency if resubmitted.
+	TimestampOffsets string `json:"timestamp_offsets,omitempty"`
+	// Field tuples that must be unique across events, e.g.
+	// [["event_type","artifact_hash"]]; see unique.go.
+	UniqueConstraints [][]string `json:"unique_constraints,omitempty"`
+	// "reject" or "annotate" events repeating an earlier event's content
+	// under another event_id; see dedup.go.
+	PayloadDedup string `json:"payload_dedup,omitempty"`
+	// FORECAST events must carry a confidence in [0,1].
+	RequireForecastConfidence bool `json:"require_forecast_confidence,omitempty"`
+	// Trim and upper-case event_type before checking it. Unset means on;
+	// set false for strict matching.
+	NormalizeEventType *bool `json:"normalize_event_type,omitempty"`
+	// Accept artifact hashes in upper case or with a 0x prefix and store
+	// them as lowercase hex; see hashalg.go.
+	NormalizeArtifactHashes bool `json:"normalize_artifact_hashes,omitempty"`
+	// Reader/writer gating; updatable by admins through SetAccessControl.
+	AccessControl AccessControl `json:"access_control"`
+	// Private data collection for INGEST payloads (collections_config.json)
+	// and the MSPs allowed to read them back.
+	PrivateCollection     string   `json:"private_collection,omitempty"`
+	PrivatePayloadReaders []string `json:"private_payload_readers,omitempty"`
+	// Current data key id per writer MSP, which its encrypted payloads
+	// must be wrapped under; require_encrypted_payloads rejects plaintext
+	// ones. See envelope.go.
+	PayloadKeys              map[string]string `json:"payload_keys,omitempty"`
+	RequireEncryptedPayloads bool              `json:"require_encrypted_payloads,omitempty"`
+	// Put a key-level endorsement policy on each new event requiring the
+	// writing org, so other orgs cannot later overwrite or delete it.
+	LockEventsToWriterOrg bool `json:"lock_events_to_writer_org,omitempty"`
+	// Maximum allowed distance in seconds between an event's timestamp and
+	// the transaction timestamp; 0 disables the check. Past it the event is
+	// rejected, or stored with timestamp_drift_flagged when the action is
+	// "flag".
+	TimestampDriftToleranceSeconds int64  `json:"timestamp_drift_tolerance_seconds,omitempty"`
+	TimestampDriftAction           string `json:"timestamp_drift_action,omitempty"`
+	// Chaincode on the channel holding the records events name in
+	// reference_tx, and its transaction that reads one record by id; see
+	// reference.go.
+	ReferenceChaincode string `json:"reference_chaincode,omitempty"`
+	ReferenceFunction  string `json:"reference_function,omitempty"`
+	// Restrict cross-org listings to auditors: auditor_msps, admins, or
+	// identities with auditlog.auditor=true. Needs
+	// id_namespacing_by_producer; see orgscope.go.
+	OrgScopedReads bool     `json:"org_scoped_reads,omitempty"`
+	AuditorMSPs    []string `json:"auditor_msps,omitempty"`
+	// Who may commit Merkle digests besides admins: digest_msps, or
+	// identities with auditlog.digester=true; see merkle.go.
+	DigestMSPs []string `json:"digest_msps,omitempty"`
+	// Who may store anchor receipts besides admins, normally the anchoring
+	// service: anchor_msps, or identities with auditlog.anchorer=true; see
+	// anchor.go.
+	AnchorMSPs []string `json:"anchor_msps,omitempty"`
+	// The channel's state database, "leveldb" or "couchdb". Empty probes
+	// the endorsing peer on each call; see richquery.go.
+	StateDatabase string `json:"state_database,omitempty"`
+	// Every event's artifact_hash must be registered in ArtifactRegistry;
+	// see artifact.go.
+	RequireRegisteredArtifacts bool `json:"require_registered_artifacts,omitempty"`
+	// Events each MSP may write per UTC ledger day, 0 meaning unlimited.
+	// daily_write_quotas overrides daily_write_quota per MSP; see quota.go.
+	DailyWriteQuota  int            `json:"daily_write_quota,omitempty"`
+	DailyWriteQuotas map[string]int `json:"daily_write_quotas,omitempty"`
+	// Record who read which event when reads are submitted; see
+	// readaudit.go.
+	RecordReads bool `json:"record_reads,omitempty"`
+	// Size limits; 0 keeps the defaults in limits.go.
+	MaxEventBytes         int `json:"max_event_bytes,omitempty"`
+	MaxMetadataValueBytes int `json:"max_metadata_value_bytes,omitempty"`
+	MaxBatchEvents        int `json:"max_batch_events,omitempty"`
+	// Query limits; 0 keeps the defaults in limits.go.
+	MaxPageSize     int `json:"max_page_size,omitempty"`
+	MaxQueryResults int `json:"max_query_results,omitempty"`
+	// PEM certificates of the timestamp authorities, or of their CAs,
+	// whose tsa_token an event may carry; require_tsa_token makes one
+	// mandatory. See tsa.go.
+	TSACertificates []string `json:"tsa_certificates,omitempty"`
+	RequireTSAToken bool     `json:"require_tsa_token,omitempty"`
+	// Event signatures must be by a key registered for the submitter's
+	// org; see signerkey.go.
+	RequireSignerKeys bool `json:"require_signer_keys,omitempty"`
+	// Orgs that approve governed deletions, and how many of them other
+	// than the proposer must; see deletion.go.
+	DeletionApproverMSPs []string `json:"deletion_approver_msps,omitempty"`
+	DeletionApprovals    int      `json:"deletion_approvals,omitempty"`
+}
+
+type timestampPrecision struct {
+	unit   time.Duration
+	layout string
+}
+
+var timestampPrecisions = map[string]timestampPrecision{
+	"second": {time.Second, "2006-01-02T15:04:05Z"},
+	"millis": {time.Millisecond, "2006-01-02T15:04:05.000Z"},
+	"nanos":  {time.Nanosecond, time.RFC3339Nano},
+}
+
+func (cfg *ContractConfig) validate() error {
+	for _, m := range cfg.AdminMSPs {
+		if m == "" {
+			return fmt.Errorf("admin_msps entries must be non-empty")
+		}
+	}
+	if err := cfg.AccessControl.validate(); err != nil {
+		return err
+	}
+	if cfg.MinTimestamp != "" {
+		if _, err := time.Parse(time.RFC3339, cfg.MinTimestamp); err != nil {
+			return fmt.Errorf("min_timestamp must be RFC3339")
+		}
+	}
+	if cfg.TimestampPrecision != "" {
+		if _, ok := timestampPrecisions[cfg.TimestampPrecision]; !ok {
+			return fmt.Errorf("timestamp_precision must be second, millis or nanos")
+		}
+	}
+	switch cfg.TimestampOffsets {
+	case "", "normalize", "reject":
+	default:
+		return fmt.Errorf("timestamp_offsets must be normalize or reject")
+	}
+	if cfg.TimestampDriftToleranceSeconds < 0 {
+		return fmt.Errorf("timestamp_drift_tolerance_seconds must not be negative")
+	}
+	switch cfg.TimestampDriftAction {
+	case "", "reject", "flag":
+	default:
+		return fmt.Errorf("timestamp_drift_action must be reject or flag")
+	}
+	if err := validateUniqueConstraints(cfg.UniqueConstraints); err != nil {
+		return err
+	}
+	if err := cfg.validateDedup(); err != nil {
+		return err
+	}
+	if err := cfg.validateReferenceLookup(); err != nil {
+		return err
+	}
+	if err := cfg.validateQuotas(); err != nil {
+		return err
+	}
+	if err := cfg.validateLimits(); err != nil {
+		return err
+	}
+	if _, err := parseTSACertificates(cfg.TSACertificates); err != nil {
+		return err
+	}
+	if cfg.RequireTSAToken && len(cfg.TSACertificates) == 0 {
+		return fmt.Errorf("require_tsa_token requires tsa_certificates")
+	}
+	if err := cfg.validatePayloadKeys(); err != nil {
+		return err
+	}
+	if err := cfg.validateDeletion(); err != nil {
+		return err
+	}
+	for _, m := range cfg.AuditorMSPs {
+		if m == "" {
+			return fmt.Errorf("auditor_msps entries must be non-empty")
+		}
+	}
+	for _, m := range cfg.DigestMSPs {
+		if m == "" {
+			return fmt.Errorf("digest_msps entries must be non-empty")
+		}
+	}
+	for _, m := range cfg.AnchorMSPs {
+		if m == "" {
+			return fmt.Errorf("anchor_msps entries must be non-empty")
+		}
+	}
+	switch cfg.StateDatabase {
+	case "", stateDBLevelDB, stateDBCouchDB:
+	default:
+		return fmt.Errorf("state_database must be leveldb or couchdb")
+	}
+	if cfg.OrgScopedReads && !cfg.IDNamespacingByProducer {
+		return fmt.Errorf("org_scoped_reads requires id_namespacing_by_producer")
+	}
+	seen := map[string]bool{}
+	for _, v := range cfg.SchemaVersionEnum {
+		if v == "" {
+			return fmt.Errorf("schema_version_enum entries must be non-empty")
+		}
+		if seen[v] {
+			return fmt.Errorf("schema_version_enum contains duplicate %q", v)
+		}
+		seen[v] = true
+	}
+	return nil
+}
+
+func (cfg *ContractConfig) normalizesEventType() bool {
+	return cfg.NormalizeEventType == nil || *cfg.NormalizeEventType
+}
+
+// normalizeTimestamp applies the configured precision to ts.
+func (cfg *ContractConfig) normalizeTimestamp(ts time.Time) (string, bool) {
+	p, ok := timestampPrecisions[cfg.TimestampPrecision]
+	if !ok {
+		return "", false
+	}
+	return ts.UTC().Truncate(p.unit).Format(p.layout), true
+}
+
+// utcTimestamp rewrites s, which parsed as ts, in UTC with the same number
+// of fractional digits, so the rewrite loses nothing and adds nothing.
+func utcTimestamp(s string, ts time.Time) string {
+	digits := 0
+	if len(s) > 19 && s[19] == '.' {
+		for _, r := range s[20:] {
+			if r < '0' || r > '9' {
+				break
+			}
+			digits++
+		}
+	}
+	layout := "2006-01-02T15:04:05"
+	if digits > 0 {
+		layout += "." + strings.Repeat("0", min(digits, 9))
+	}
+	return ts.UTC().Format(layout + "Z")
+}
+
+func (cfg *ContractConfig) schemaVersionAllowed(v string) bool {
+	if len(cfg.SchemaVersionEnum) == 0 {
+		return true
+	}
+	for _, allowed := range cfg.SchemaVersionEnum {
+		if v == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+func loadConfig(ctx contractapi.TransactionContextInterface) (*ContractConfig, error) {
+	b, err := ctx.GetStub().GetState(configKey)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &ContractConfig{}
+	if b == nil {
+		return cfg, nil
+	}
+	if err := json.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("corrupt config")
+	}
+	return cfg, nil
+}
+
+// Init stores the contract configuration. It may only run once per channel,
+// by an org admin whose MSP is in the stored admin_msps, as InitLedger.
+func (c *AuditLogContract) Init(ctx contractapi.TransactionContextInterface, configJSON string) error {
+	if err := requireOrgAdmin(ctx); err != nil {
+		return err
+	}
+	existing, err := ctx.GetStub().GetState(configKey)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.New("config_already_initialized")
+	}
+
+	var cfg ContractConfig
+	dec := json.NewDecoder(bytes.NewReader([]byte(configJSON)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return fmt.Errorf("invalid config json: %w", err)
+	}
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	if err := requireSeededAdmin(ctx, &cfg); err != nil {
+		return err
+	}
+	return saveConfig(ctx, &cfg)
+}
+
+func saveConfig(ctx contractapi.TransactionContextInterface, cfg *ContractConfig) error {
+	_, err := writeConfig(ctx, cfg)
+	return err
+}
+
+// writeConfig stores cfg and its CONFIG_CHANGE record; see configaudit.go.
+func writeConfig(ctx contractapi.TransactionContextInterface, cfg *ContractConfig) (*StoredEvent, error) {
+	before, err := ctx.GetStub().GetState(configKey)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.GetStub().PutState(configKey, out); err != nil {
+		return nil, err
+	}
+	return recordConfigChange(ctx, cfg, before, out)
+}
+
+// checkTimestampDrift records the ledger time on stored and applies the
+// configured drift tolerance to its declared timestamp.
+func checkTimestampDrift(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, stored *StoredEvent) error {
+	txTime, err := txTimeUTC(ctx)
+	if err != nil {
+		return err
+	}
+	stored.LedgerTimestamp = txTime.Format(time.RFC3339Nano)
+	if cfg.TimestampDriftToleranceSeconds == 0 {
+		return nil
+	}
+	declared, _ := time.Parse(time.RFC3339, stored.Event.TimestampUTC)
+	drift := declared.Sub(txTime)
+	if drift < 0 {
+		drift = -drift
+	}
+	if drift <= time.Duration(cfg.TimestampDriftToleranceSeconds)*time.Second {
+		return nil
+	}
+	if cfg.TimestampDriftAction == "flag" {
+		stored.TimestampDriftFlagged = true
+		return nil
+	}
+	return fmt.Errorf("timestamp_drift_exceeded: declared timestamp is %s from ledger time", drift.Round(time.Second))
+}
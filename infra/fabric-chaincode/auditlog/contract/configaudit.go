@@ -0,0 +1,154 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/identity"
+)
+
+// Every write of the contract config is itself stored as an event of the
+// reserved CONFIG_CHANGE type (see contractevent.go), so config history is
+// read, verified and exported like any other audit entry.
+const (
+	configChangeType   = "CONFIG_CHANGE"
+	configChangeSchema = "config"
+	// Identity attribute an admin also needs to call UpdateConfig.
+	configAdminAttr = "auditlog.config_admin"
+)
+
+// ConfigChange is stored on CONFIG_CHANGE records. The event's
+// artifact_hash is AfterHash, so the payload hash commits to the new config.
+type ConfigChange struct {
+	// sha256 of the config JSON before the change; empty at bootstrap.
+	BeforeHash    string   `json:"before_hash,omitempty"`
+	AfterHash     string   `json:"after_hash"`
+	ChangedFields []string `json:"changed_fields"`
+	// Transaction that made the change, e.g. UpdateConfig.
+	Transaction string `json:"transaction"`
+	ChangedBy   string `json:"changed_by_msp"`
+}
+
+func configHash(b []byte) string {
+	if b == nil {
+		return ""
+	}
+	return sha256Hex(b)
+}
+
+// changedConfigFields lists the top-level config keys that differ.
+func changedConfigFields(before, after []byte) ([]string, error) {
+	var b, a map[string]json.RawMessage
+	if before != nil {
+		if err := json.Unmarshal(before, &b); err != nil {
+			return nil, fmt.Errorf("corrupt config")
+		}
+	}
+	if err := json.Unmarshal(after, &a); err != nil {
+		return nil, err
+	}
+	changed := []string{}
+	for k, v := range a {
+		if !bytes.Equal(b[k], v) {
+			changed = append(changed, k)
+		}
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			changed = append(changed, k)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// recordConfigChange writes the CONFIG_CHANGE event for a config going from
+// before (nil at bootstrap) to after, both as stored.
+func recordConfigChange(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, before, after []byte) (*StoredEvent, error) {
+	changed, err := changedConfigFields(before, after)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := writerNamespace(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := contractEvent(ctx, cfg, producer, LedgerEvent{
+		EventType:    configChangeType,
+		ArtifactHash: configHash(after),
+		SchemaVer:    configChangeSchema,
+	})
+	if err != nil {
+		return nil, err
+	}
+	stored.ConfigChange = &ConfigChange{
+		BeforeHash:    configHash(before),
+		AfterHash:     stored.Event.ArtifactHash,
+		ChangedFields: changed,
+		Transaction:   txName(ctx),
+		ChangedBy:     stored.CreatorMSP,
+	}
+	return stored, commitContractEvent(ctx, cfg, stored)
+}
+
+// UpdateConfig replaces the contract config. The caller needs an admin MSP
+// identity that also carries auditlog.config_admin=true, and must stay in
+// admin_msps. id_namespacing_by_producer is fixed after Init because event
+// keys depend on it. The change is recorded as a CONFIG_CHANGE event, which
+// is returned.
+func (c *AuditLogContract) UpdateConfig(ctx contractapi.TransactionContextInterface, configJSON string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	if err := ctx.GetClientIdentity().AssertAttributeValue(configAdminAttr, "true"); err != nil {
+		return "", fmt.Errorf("forbidden: %s=true attribute required", configAdminAttr)
+	}
+
+	var next ContractConfig
+	dec := json.NewDecoder(bytes.NewReader([]byte(configJSON)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&next); err != nil {
+		return "", fmt.Errorf("invalid config json: %w", err)
+	}
+	if err := next.validate(); err != nil {
+		return "", err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	if !identity.Contains(next.AdminMSPs, mspID) {
+		return "", fmt.Errorf("admin_msps must include the caller's MSP %s", mspID)
+	}
+	if next.IDNamespacingByProducer != cfg.IDNamespacingByProducer {
+		return "", errors.New("config_field_immutable: id_namespacing_by_producer")
+	}
+	if next.TimeOrderedKeys != cfg.TimeOrderedKeys {
+		return "", errors.New("config_field_immutable: time_ordered_keys")
+	}
+	before, err := json.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	after, err := json.Marshal(&next)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(before, after) {
+		return "", errors.New("config_unchanged")
+	}
+	stored, err := writeConfig(ctx, &next)
+	if err != nil {
+		return "", err
+	}
+	return marshalString(stored)
+}
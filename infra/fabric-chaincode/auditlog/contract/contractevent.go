@@ -0,0 +1,66 @@
+package contract
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Some events are written by the contract itself as a side effect of an
+// admin transaction. Their types are built in but reserved: producers
+// cannot submit them, so their presence on the ledger always means the
+// contract wrote them.
+var reservedTypes = map[string]bool{
+	configChangeType:     true,
+	purgedType:           true,
+	deletionProposedType: true,
+	deletionApprovedType: true,
+	deletedType:          true,
+}
+
+// txEventID derives a stable event_id for records the contract writes on
+// its own behalf from the transaction id, in UUID form (version 8).
+func txEventID(ctx contractapi.TransactionContextInterface, purpose string) string {
+	h := sha256Hex([]byte(purpose + ":" + ctx.GetStub().GetTxID()))
+	return h[0:8] + "-" + h[8:12] + "-8" + h[13:16] + "-a" + h[17:20] + "-" + h[20:32]
+}
+
+// contractEvent completes e as a record in producer's namespace: event_id
+// and timestamp come from the transaction, and the payload is hashed as
+// for a submitted event. The caller decorates the record and passes it to
+// commitContractEvent.
+func contractEvent(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, producer string, e LedgerEvent) (*StoredEvent, error) {
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return nil, err
+	}
+	creator, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, err
+	}
+	e.EventID = txEventID(ctx, e.EventType)
+	e.TimestampUTC = now.Format(time.RFC3339Nano)
+	saltID, salt, err := currentSalt(ctx)
+	if err != nil {
+		return nil, err
+	}
+	hash, canon, err := hashNewEvent(&e, salt)
+	if err != nil {
+		return nil, err
+	}
+	stored := &StoredEvent{
+		Event: e, PayloadHash: hash, SaltID: saltID, Producer: producer, CreatorMSP: creator, Canonicalization: canonJCS,
+		LedgerTimestamp: e.TimestampUTC, canonical: canon,
+	}
+	if cfg.LockEventsToWriterOrg {
+		stored.RequiredOrgs = []string{creator}
+	}
+	return stored, nil
+}
+
+func commitContractEvent(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, stored *StoredEvent) error {
+	if err := commitEvent(ctx, cfg, stored, newPendingWrites()); err != nil {
+		return err
+	}
+	return emitWritten(ctx, stored)
+}
@@ -0,0 +1,104 @@
+package contract
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// The chaincode serves several contracts under one definition. The audit
+// log is the default and answers unqualified names; the others are called
+// as "<Name>:<tx>", e.g. "Digest:CommitDigest". ConfigContract and
+// DigestContract group transactions the default contract has always
+// served, which it keeps serving for existing clients. Every contract runs
+// authorize first, and txRoles is keyed by the unqualified name, so a
+// transaction has the same role under each of its names.
+
+// ConfigContract serves channel configuration and capability checks.
+type ConfigContract struct {
+	contractapi.Contract
+	audit AuditLogContract
+}
+
+func (c *ConfigContract) GetContractInfo(ctx contractapi.TransactionContextInterface) (string, error) {
+	return c.audit.GetContractInfo(ctx)
+}
+
+func (c *ConfigContract) UpdateConfig(ctx contractapi.TransactionContextInterface, configJSON string) (string, error) {
+	return c.audit.UpdateConfig(ctx, configJSON)
+}
+
+func (c *ConfigContract) SetAccessControl(ctx contractapi.TransactionContextInterface, aclJSON string) error {
+	return c.audit.SetAccessControl(ctx, aclJSON)
+}
+
+func (c *ConfigContract) SetHashingSalt(ctx contractapi.TransactionContextInterface, salt string) (string, error) {
+	return c.audit.SetHashingSalt(ctx, salt)
+}
+
+// DigestContract serves Merkle digests, inclusion proofs and their
+// external anchors.
+type DigestContract struct {
+	contractapi.Contract
+	audit AuditLogContract
+}
+
+func (c *DigestContract) CommitDigest(ctx contractapi.TransactionContextInterface, startRFC3339 string, endRFC3339 string) (string, error) {
+	return c.audit.CommitDigest(ctx, startRFC3339, endRFC3339)
+}
+
+func (c *DigestContract) GetDigest(ctx contractapi.TransactionContextInterface, digestID string) (string, error) {
+	return c.audit.GetDigest(ctx, digestID)
+}
+
+func (c *DigestContract) GetInclusionProof(ctx contractapi.TransactionContextInterface, eventID string, digestID string) (string, error) {
+	return c.audit.GetInclusionProof(ctx, eventID, digestID)
+}
+
+func (c *DigestContract) AnchorDigest(ctx contractapi.TransactionContextInterface, digestID string, anchorJSON string) (string, error) {
+	return c.audit.AnchorDigest(ctx, digestID, anchorJSON)
+}
+
+func (c *DigestContract) GetDigestAnchors(ctx contractapi.TransactionContextInterface, digestID string) (string, error) {
+	return c.audit.GetDigestAnchors(ctx, digestID)
+}
+
+func (c *DigestContract) VerifyEventAgainstAnchor(ctx contractapi.TransactionContextInterface, eventID string, target string) (string, error) {
+	return c.audit.VerifyEventAgainstAnchor(ctx, eventID, target)
+}
+
+// contracts returns the chaincode's contracts, the default first.
+func contracts() []contractapi.ContractInterface {
+	audit := &AuditLogContract{}
+	audit.BeforeTransaction = authorize
+	registry := &ArtifactRegistry{}
+	registry.Name = "ArtifactRegistry"
+	registry.BeforeTransaction = authorize
+	config := &ConfigContract{}
+	config.Name = "Config"
+	config.BeforeTransaction = authorize
+	digest := &DigestContract{}
+	digest.Name = "Digest"
+	digest.BeforeTransaction = authorize
+	return []contractapi.ContractInterface{audit, registry, config, digest}
+}
+
+// namespacedTransactions lists the transactions of each named contract,
+// as served under "<Name>:".
+func namespacedTransactions() map[string][]string {
+	base := reflect.TypeOf(&contractapi.Contract{})
+	out := map[string][]string{}
+	for _, c := range contracts()[1:] {
+		t := reflect.TypeOf(c)
+		txs := []string{}
+		for i := 0; i < t.NumMethod(); i++ {
+			if _, inherited := base.MethodByName(t.Method(i).Name); !inherited {
+				txs = append(txs, t.Method(i).Name)
+			}
+		}
+		sort.Strings(txs)
+		out[c.GetName()] = txs
+	}
+	return out
+}
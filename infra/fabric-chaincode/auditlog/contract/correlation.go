@@ -0,0 +1,34 @@
+package contract
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// correlation_id groups the events of one pipeline run, e.g. the INGEST,
+// AGENT_DECISION and FORECAST events of a payroll forecast. It is indexed
+// with the timestamp so a run reads back in order.
+const correlationTsIndex = "correlation~ts~id"
+
+var correlationIDRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,127}$`)
+
+func validateCorrelationID(e *LedgerEvent) error {
+	if e.CorrelationID != "" && !correlationIDRe.MatchString(e.CorrelationID) {
+		return fmt.Errorf("correlation_id must be 1-128 characters of A-Z, a-z, 0-9, '.', '_', ':' or '-'")
+	}
+	return nil
+}
+
+// GetEventsByCorrelation pages through the events sharing correlationID in
+// timestamp order.
+func (c *AuditLogContract) GetEventsByCorrelation(ctx contractapi.TransactionContextInterface, correlationID string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if !correlationIDRe.MatchString(correlationID) {
+		return "", fmt.Errorf("invalid correlation_id")
+	}
+	return pageByIndex(ctx, correlationTsIndex, []string{correlationID}, projection, bookmark, pageSize)
+}
@@ -0,0 +1,316 @@
+package contract
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Event counters are kept as per-transaction deltas under
+// statCounterIndex [dimension, value..., txID] rather than one key per
+// bucket, so concurrent writers never conflict on a shared counter.
+// CompactEventStats folds the deltas into one total per bucket under
+// statTotalIndex [dimension, value...], and a read sums a bucket's total
+// with the deltas written since. GetEventStats and GetUsage read this way.
+// Counting starts with the first write after the deltas were introduced.
+const (
+	statCounterIndex = "stat~dim~tx"
+	statTotalIndex   = "stat~dim"
+
+	// Most counter keys, totals and deltas together, one stats read or
+	// compaction visits.
+	maxCounterScan = 10000
+
+	statByType    = "type"
+	statBySchema  = "schema"
+	statByDay     = "day"
+	statByTypeDay = "type_day"
+	statByOrgDay  = "org_day"
+	// Events written under payload_dedup "annotate" that repeat earlier
+	// content, by type; they are in the other counts too.
+	statDuplicate = "duplicate"
+
+	statDayLayout = "2006-01-02"
+)
+
+type EventStats struct {
+	Total           int                       `json:"total"`
+	ByEventType     map[string]int            `json:"by_event_type"`
+	BySchemaVersion map[string]int            `json:"by_schema_version"`
+	ByDay           map[string]int            `json:"by_day"`
+	ByDayAndType    map[string]map[string]int `json:"by_day_and_type"`
+	// Annotated duplicates by event_type; see dedup.go.
+	DuplicatesByEventType map[string]int `json:"duplicates_by_event_type"`
+	// More than maxCounterScan counter keys were due; the counts are
+	// partial until CompactEventStats catches up.
+	Truncated bool `json:"truncated"`
+}
+
+// DayStats are the counts of one UTC day of event timestamps.
+type DayStats struct {
+	Day         string         `json:"day"`
+	Total       int            `json:"total"`
+	ByEventType map[string]int `json:"by_event_type"`
+	Truncated   bool           `json:"truncated"`
+}
+
+type StatsCompaction struct {
+	// Deltas folded into their bucket totals.
+	Compacted int `json:"compacted"`
+	// Bucket totals written.
+	Buckets int `json:"buckets"`
+	// More deltas remain; call again.
+	More bool `json:"more"`
+}
+
+// countEvent adds stored to this transaction's counter deltas. Fabric does
+// not let a transaction read its own writes, so running totals live in
+// pending and each put overwrites the previous one for the same key.
+func countEvent(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, stored *StoredEvent, pending *pendingWrites) error {
+	ts, err := time.Parse(time.RFC3339, stored.Event.TimestampUTC)
+	if err != nil {
+		return err
+	}
+	day := ts.UTC().Format(statDayLayout)
+	e := &stored.Event
+	for _, attrs := range [][]string{
+		{statByType, e.EventType},
+		{statBySchema, e.SchemaVer},
+		{statByDay, day},
+		{statByTypeDay, e.EventType, day},
+	} {
+		if err := putCounter(ctx, attrs, pending); err != nil {
+			return err
+		}
+	}
+	if stored.DuplicateOf != "" {
+		if err := putCounter(ctx, []string{statDuplicate, e.EventType}, pending); err != nil {
+			return err
+		}
+	}
+	if reservedTypes[e.EventType] {
+		return nil
+	}
+	// Quotas count writes by ledger day, not by declared timestamp.
+	ledger, err := ledgerDay(ctx)
+	if err != nil {
+		return err
+	}
+	if err := putCounter(ctx, []string{statByOrgDay, stored.CreatorMSP, ledger}, pending); err != nil {
+		return err
+	}
+	return countQuota(ctx, cfg, stored.CreatorMSP, ledger, pending)
+}
+
+// putCounter adds one to this transaction's delta for attrs.
+func putCounter(ctx contractapi.TransactionContextInterface, attrs []string, pending *pendingWrites) error {
+	stub := ctx.GetStub()
+	key, err := stub.CreateCompositeKey(statCounterIndex, append(attrs, stub.GetTxID()))
+	if err != nil {
+		return err
+	}
+	pending.counts[key]++
+	return stub.PutState(key, []byte(strconv.Itoa(pending.counts[key])))
+}
+
+// counterScan reads counters until it has visited a budget of keys, shared
+// by all of its sums.
+type counterScan struct {
+	ctx    contractapi.TransactionContextInterface
+	budget int
+}
+
+func newCounterScan(ctx contractapi.TransactionContextInterface) *counterScan {
+	return &counterScan{ctx: ctx, budget: maxCounterScan}
+}
+
+// sum passes the value attributes and count of every bucket total and
+// delta under the counter attribute prefix attrs to add. It reports
+// whether the budget ran out first.
+func (s *counterScan) sum(attrs []string, add func(values []string, n int)) (bool, error) {
+	for _, index := range []string{statTotalIndex, statCounterIndex} {
+		truncated, err := s.scan(index, attrs, add)
+		if err != nil || truncated {
+			return truncated, err
+		}
+	}
+	return false, nil
+}
+
+func (s *counterScan) scan(index string, attrs []string, add func(values []string, n int)) (bool, error) {
+	stub := s.ctx.GetStub()
+	it, err := stub.GetStateByPartialCompositeKey(index, attrs)
+	if err != nil {
+		return false, err
+	}
+	defer it.Close()
+	for it.HasNext() {
+		if s.budget == 0 {
+			return true, nil
+		}
+		s.budget--
+		kv, err := it.Next()
+		if err != nil {
+			return false, err
+		}
+		_, keyAttrs, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return false, err
+		}
+		n, err := strconv.Atoi(string(kv.Value))
+		if err != nil || len(keyAttrs) < 2 {
+			return false, fmt.Errorf("corrupt stat counter %q", kv.Key)
+		}
+		values := keyAttrs[1:]
+		if index == statCounterIndex {
+			values = values[:len(values)-1]
+		}
+		add(values, n)
+	}
+	return false, nil
+}
+
+// GetEventStats returns event counts by event_type, by schema_version, by
+// UTC day of the event timestamp, by day and type, and the annotated
+// duplicates by type. It reads at most maxCounterScan counter keys; the
+// by-day buckets alone grow by one a day, so dashboards asking about one
+// day should use GetEventStatsForDay.
+func (c *AuditLogContract) GetEventStats(ctx contractapi.TransactionContextInterface) (string, error) {
+	stats := EventStats{
+		ByEventType:           map[string]int{},
+		BySchemaVersion:       map[string]int{},
+		ByDay:                 map[string]int{},
+		ByDayAndType:          map[string]map[string]int{},
+		DuplicatesByEventType: map[string]int{},
+	}
+	scan := newCounterScan(ctx)
+	for _, d := range []struct {
+		dimension string
+		add       func(v []string, n int)
+	}{
+		{statByType, func(v []string, n int) {
+			stats.ByEventType[v[0]] += n
+			stats.Total += n
+		}},
+		{statBySchema, func(v []string, n int) { stats.BySchemaVersion[v[0]] += n }},
+		{statByDay, func(v []string, n int) { stats.ByDay[v[0]] += n }},
+		{statByTypeDay, func(v []string, n int) {
+			byType, ok := stats.ByDayAndType[v[1]]
+			if !ok {
+				byType = map[string]int{}
+				stats.ByDayAndType[v[1]] = byType
+			}
+			byType[v[0]] += n
+		}},
+		{statDuplicate, func(v []string, n int) { stats.DuplicatesByEventType[v[0]] += n }},
+	} {
+		truncated, err := scan.sum([]string{d.dimension}, d.add)
+		if err != nil {
+			return "", err
+		}
+		if truncated {
+			stats.Truncated = true
+			break
+		}
+	}
+	return marshalString(stats)
+}
+
+// GetEventStatsForDay returns the counts of events timestamped on day
+// (YYYY-MM-DD, UTC), reading only that day's buckets.
+func (c *AuditLogContract) GetEventStatsForDay(ctx contractapi.TransactionContextInterface, day string) (string, error) {
+	if _, err := time.Parse(statDayLayout, day); err != nil {
+		return "", fmt.Errorf("day must be YYYY-MM-DD")
+	}
+	types, err := knownEventTypes(ctx)
+	if err != nil {
+		return "", err
+	}
+	stats := DayStats{Day: day, ByEventType: map[string]int{}}
+	scan := newCounterScan(ctx)
+	truncated, err := scan.sum([]string{statByDay, day}, func(_ []string, n int) { stats.Total += n })
+	for _, t := range types {
+		if err != nil || truncated {
+			break
+		}
+		truncated, err = scan.sum([]string{statByTypeDay, t, day}, func(_ []string, n int) { stats.ByEventType[t] += n })
+	}
+	if err != nil {
+		return "", err
+	}
+	stats.Truncated = truncated
+	return marshalString(stats)
+}
+
+// CompactEventStats folds up to limit counter deltas, at most
+// maxCounterScan, into their bucket totals, so reads stay short however
+// many transactions have written. Run it periodically, e.g. daily, until
+// More is false. Deltas written in the same block make it fail MVCC
+// validation, never the writes.
+func (c *AuditLogContract) CompactEventStats(ctx contractapi.TransactionContextInterface, limit int) (string, error) {
+	if limit < 1 || limit > maxCounterScan {
+		return "", fmt.Errorf("limit must be between 1 and %d", maxCounterScan)
+	}
+	stub := ctx.GetStub()
+	it, err := stub.GetStateByPartialCompositeKey(statCounterIndex, []string{})
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+	var res StatsCompaction
+	totals := map[string]int{}
+	for it.HasNext() {
+		if res.Compacted == limit {
+			res.More = true
+			break
+		}
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		_, attrs, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return "", err
+		}
+		n, err := strconv.Atoi(string(kv.Value))
+		if err != nil || len(attrs) < 3 {
+			return "", fmt.Errorf("corrupt stat counter %q", kv.Key)
+		}
+		key, err := stub.CreateCompositeKey(statTotalIndex, attrs[:len(attrs)-1])
+		if err != nil {
+			return "", err
+		}
+		totals[key] += n
+		if err := stub.DelState(kv.Key); err != nil {
+			return "", err
+		}
+		res.Compacted++
+	}
+	keys := make([]string, 0, len(totals))
+	for key := range totals {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		n := totals[key]
+		b, err := stub.GetState(key)
+		if err != nil {
+			return "", err
+		}
+		if b != nil {
+			prior, err := strconv.Atoi(string(b))
+			if err != nil {
+				return "", fmt.Errorf("corrupt stat total %q", key)
+			}
+			n += prior
+		}
+		if err := stub.PutState(key, []byte(strconv.Itoa(n))); err != nil {
+			return "", err
+		}
+	}
+	res.Buckets = len(keys)
+	return marshalString(res)
+}
@@ -0,0 +1,89 @@
+package contract_test
+
+import (
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// Compaction folds the per-transaction deltas into bucket totals without
+// changing any count, and brings a read that had too many keys to visit
+// back under the cap.
+func TestCompactEventStats(t *testing.T) {
+	const day2 = 86400
+	// Enough single-event transactions that their deltas pass the cap.
+	const writes = 2600
+	h := initHarness(t, nil)
+	admin, client := auditlogtest.Admin("Org1MSP"), auditlogtest.Client("Org1MSP")
+	for n := 1; n <= writes; n++ {
+		e := auditlogtest.Event("INGEST", n)
+		if n%2 == 0 {
+			e = auditlogtest.Event("FORECAST", day2+n)
+		}
+		put(t, h, client, e)
+	}
+
+	stats := eventStats(t, h, client)
+	if !stats.Truncated {
+		t.Fatalf("stats over %d transactions not truncated", writes)
+	}
+	day := dayStats(t, h, client, "2024-01-02")
+	if day.Truncated || day.Total != writes/2 || day.ByEventType["FORECAST"] != writes/2 || day.ByEventType["INGEST"] != 0 {
+		t.Fatalf("2024-01-02: %+v, want %d FORECAST", day, writes/2)
+	}
+
+	for calls := 0; ; calls++ {
+		if calls == 10 {
+			t.Fatal("compaction did not finish in 10 calls")
+		}
+		out, err := h.Submit(admin, "CompactEventStats", "3000")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res contract.StatsCompaction
+		if err := auditlogtest.Unmarshal(out, &res); err != nil {
+			t.Fatal(err)
+		}
+		if !res.More {
+			break
+		}
+	}
+
+	// One more write lands as a delta beside the totals.
+	put(t, h, client, auditlogtest.Event("INGEST", writes+1))
+	stats = eventStats(t, h, client)
+	if stats.Truncated || stats.ByEventType["INGEST"] != writes/2+1 || stats.ByEventType["FORECAST"] != writes/2 ||
+		stats.ByDay["2024-01-01"] != writes/2+2 || stats.ByDayAndType["2024-01-02"]["FORECAST"] != writes/2 {
+		t.Errorf("after compaction: %+v", stats)
+	}
+	if got := dayStats(t, h, client, "2024-01-02"); got.Truncated || got.Total != day.Total || got.ByEventType["FORECAST"] != day.ByEventType["FORECAST"] {
+		t.Errorf("2024-01-02 after compaction: %+v, want %+v", got, day)
+	}
+}
+
+func eventStats(t *testing.T, h *auditlogtest.Harness, id auditlogtest.Identity) contract.EventStats {
+	t.Helper()
+	out, err := h.Evaluate(id, "GetEventStats")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s contract.EventStats
+	if err := auditlogtest.Unmarshal(out, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func dayStats(t *testing.T, h *auditlogtest.Harness, id auditlogtest.Identity, day string) contract.DayStats {
+	t.Helper()
+	out, err := h.Evaluate(id, "GetEventStatsForDay", day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s contract.DayStats
+	if err := auditlogtest.Unmarshal(out, &s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
@@ -0,0 +1,140 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// A closed day is frozen: its summary is stored once under
+// dayCloseKeyPrefix+date and later events timestamped on that UTC day are
+// rejected, so the summary stays true.
+const dayCloseKeyPrefix = "day_close:"
+
+// DaySummary is the frozen checkpoint of one UTC day. CombinedHash is
+// sha256 over "<event_id>:<payload_hash>\n" for each event in timestamp
+// order, as event_set_hash in AuditReport.
+type DaySummary struct {
+	Date         string `json:"date"`
+	Count        int    `json:"count"`
+	FirstEventID string `json:"first_event_id,omitempty"`
+	LastEventID  string `json:"last_event_id,omitempty"`
+	CombinedHash string `json:"combined_hash"`
+	ClosedByMSP  string `json:"closed_by_msp"`
+	TxID         string `json:"tx_id"`
+	ClosedAt     string `json:"closed_at"`
+}
+
+func getDaySummary(ctx contractapi.TransactionContextInterface, date string) (*DaySummary, error) {
+	b, err := ctx.GetStub().GetState(dayCloseKeyPrefix + date)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var s DaySummary
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, fmt.Errorf("corrupt day summary")
+	}
+	return &s, nil
+}
+
+// checkDayOpen rejects events timestamped on a closed day.
+func checkDayOpen(ctx contractapi.TransactionContextInterface, e *LedgerEvent) error {
+	ts, err := time.Parse(time.RFC3339, e.TimestampUTC)
+	if err != nil {
+		return err
+	}
+	date := ts.UTC().Format(statDayLayout)
+	closed, err := getDaySummary(ctx, date)
+	if err != nil {
+		return err
+	}
+	if closed != nil {
+		return fmt.Errorf("day_closed: %s", date)
+	}
+	return nil
+}
+
+// CloseDay freezes the UTC day date (YYYY-MM-DD), which must have ended by
+// the transaction timestamp, and stores its summary. A day closes once.
+func (c *AuditLogContract) CloseDay(ctx contractapi.TransactionContextInterface, date string) (string, error) {
+	day, err := time.Parse(statDayLayout, date)
+	if err != nil {
+		return "", fmt.Errorf("date must be YYYY-MM-DD")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	next := day.AddDate(0, 0, 1)
+	if now.Before(next) {
+		return "", errors.New("day_not_ended")
+	}
+	prior, err := getDaySummary(ctx, date)
+	if err != nil {
+		return "", err
+	}
+	if prior != nil {
+		return "", errors.New("day_already_closed")
+	}
+
+	events, err := windowEvents(ctx, day.Format(tsIndexLayout), next.Format(tsIndexLayout))
+	if err != nil {
+		return "", err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	summary := DaySummary{
+		Date:        date,
+		Count:       len(events),
+		ClosedByMSP: mspID,
+		TxID:        ctx.GetStub().GetTxID(),
+		ClosedAt:    now.Format(time.RFC3339Nano),
+	}
+	var lines strings.Builder
+	for _, e := range events {
+		fmt.Fprintf(&lines, "%s:%s\n", e.Event.EventID, e.PayloadHash)
+	}
+	summary.CombinedHash = sha256Hex([]byte(lines.String()))
+	if len(events) > 0 {
+		summary.FirstEventID = events[0].Event.EventID
+		summary.LastEventID = events[len(events)-1].Event.EventID
+	}
+	out, err := json.Marshal(summary)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(dayCloseKeyPrefix+date, out); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (c *AuditLogContract) GetDaySummary(ctx contractapi.TransactionContextInterface, date string) (string, error) {
+	if _, err := time.Parse(statDayLayout, date); err != nil {
+		return "", fmt.Errorf("date must be YYYY-MM-DD")
+	}
+	s, err := getDaySummary(ctx, date)
+	if err != nil {
+		return "", err
+	}
+	if s == nil {
+		return "", fmt.Errorf("not_found")
+	}
+	return marshalString(s)
+}
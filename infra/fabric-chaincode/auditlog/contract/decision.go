@@ -0,0 +1,116 @@
+package contract
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// From schema_version v2, AGENT_DECISION events must say which agent
+// decided what, how sure it was and under which policy. The decision
+// object is part of the payload hash, and decisions are indexed by agent_id
+// with the timestamp, so a model-governance review can pull every decision
+// one agent version made. v1 decisions are accepted as before and must
+// leave it out.
+const (
+	decisionAgentTsIndex = "decision~agent~ts~id"
+	minDecisionSchema    = 2
+)
+
+var (
+	agentIDRe       = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/@+-]{0,127}$`)
+	decisionCodeRe  = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,63}$`)
+	policyVersionRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]{0,63}$`)
+)
+
+type AgentDecision struct {
+	// Agent and version, e.g. "payroll-agent@2.3.0".
+	AgentID      string `json:"agent_id"`
+	DecisionCode string `json:"decision_code"`
+	// In [0,1]; a pointer so that 0 is told apart from missing.
+	Confidence    *float64 `json:"confidence"`
+	PolicyVersion string   `json:"policy_version"`
+}
+
+func validateDecision(e *LedgerEvent) error {
+	typed := e.EventType == "AGENT_DECISION" && schemaMajor(e.SchemaVer) >= minDecisionSchema
+	if e.Decision == nil {
+		if typed {
+			return fmt.Errorf("decision required on AGENT_DECISION events from schema_version v%d", minDecisionSchema)
+		}
+		return nil
+	}
+	if !typed {
+		return fmt.Errorf("decision may only be set on AGENT_DECISION events from schema_version v%d", minDecisionSchema)
+	}
+	d := e.Decision
+	if !agentIDRe.MatchString(d.AgentID) {
+		return fmt.Errorf("decision must have an agent_id of 1-128 characters A-Z, a-z, 0-9, '.', '_', ':', '/', '@', '+' or '-'")
+	}
+	if !decisionCodeRe.MatchString(d.DecisionCode) {
+		return fmt.Errorf("decision must have a decision_code of 1-64 characters A-Z, a-z, 0-9, '.', '_', ':' or '-'")
+	}
+	if d.Confidence == nil || math.IsNaN(*d.Confidence) || *d.Confidence < 0 || *d.Confidence > 1 {
+		return fmt.Errorf("decision must have a confidence within [0,1]")
+	}
+	if !policyVersionRe.MatchString(d.PolicyVersion) {
+		return fmt.Errorf("decision must have a policy_version of 1-64 characters A-Z, a-z, 0-9, '.', '_', '+' or '-'")
+	}
+	return nil
+}
+
+// decodeDecisionProto decodes the AgentDecision message of field 20.
+func decodeDecisionProto(b []byte) (*AgentDecision, error) {
+	d := &AgentDecision{}
+	strs := map[protowire.Number]*string{1: &d.AgentID, 2: &d.DecisionCode, 4: &d.PolicyVersion}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch {
+		case strs[num] != nil && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			if !utf8.Valid(v) {
+				return nil, fmt.Errorf("nested field %d is not valid UTF-8", num)
+			}
+			*strs[num] = string(v)
+			b = b[n:]
+		case num == 3 && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			c := math.Float64frombits(v)
+			d.Confidence = &c
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return d, nil
+}
+
+// QueryDecisionsByAgent pages through the AGENT_DECISION events agentID
+// made, in timestamp order.
+func (c *AuditLogContract) QueryDecisionsByAgent(ctx contractapi.TransactionContextInterface, agentID string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if !agentIDRe.MatchString(agentID) {
+		return "", fmt.Errorf("invalid agent_id")
+	}
+	return pageByIndex(ctx, decisionAgentTsIndex, []string{agentID}, projection, bookmark, pageSize)
+}
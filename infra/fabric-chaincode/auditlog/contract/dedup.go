@@ -0,0 +1,90 @@
+package contract
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// With payload_dedup set, each new event's content hash claims
+// dedupKeyPrefix+hash, so the same content submitted again under a fresh
+// event_id is caught: "reject" refuses it, "annotate" stores it with
+// duplicate_of naming the first event. The content hash is the salted
+// payload hash with the fields that differ per submission left out: the
+// event_id and the signature and TSA token over it. Duplicates are looked
+// for within the writer's producer namespace, and only among events
+// written while the mode was on.
+const dedupKeyPrefix = "dedup:"
+
+func (cfg *ContractConfig) validateDedup() error {
+	switch cfg.PayloadDedup {
+	case "", "reject", "annotate":
+		return nil
+	}
+	return fmt.Errorf("payload_dedup must be reject or annotate")
+}
+
+// contentHash hashes e as payloadHash does, without its per-submission
+// fields.
+func contentHash(e *LedgerEvent, salt string) (string, error) {
+	c := *e
+	c.EventID, c.Signature, c.SignerCert, c.SignerKeyID = "", "", "", ""
+	c.TSAToken, c.TSATokenHash = "", ""
+	return payloadHash(c, salt, canonJCS)
+}
+
+func dedupKey(producer, hash string) string {
+	if producer == "" {
+		return dedupKeyPrefix + hash
+	}
+	return dedupKeyPrefix + producer + "/" + hash
+}
+
+// checkDuplicatePayload sets stored's content hash and, when an earlier
+// event holds it, rejects stored or marks it duplicate_of per the mode.
+func checkDuplicatePayload(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, stored *StoredEvent, salt string, pending *pendingWrites) error {
+	if cfg.PayloadDedup == "" {
+		return nil
+	}
+	hash, err := contentHash(&stored.Event, salt)
+	if err != nil {
+		return err
+	}
+	stored.ContentHash = hash
+	key := dedupKey(stored.Producer, hash)
+	first, ok := pending.content[key]
+	if !ok {
+		b, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return nil
+		}
+		first = string(b)
+	}
+	if cfg.PayloadDedup == "annotate" {
+		stored.DuplicateOf = first
+		return nil
+	}
+	return &detailError{
+		msg: fmt.Sprintf("duplicate_payload: same content as event %s", first),
+		details: map[string]any{
+			"duplicate_of": first,
+			"content_hash": hash,
+		},
+	}
+}
+
+// claimContentHash records the first event with each content hash.
+func claimContentHash(ctx contractapi.TransactionContextInterface, stored *StoredEvent, pending *pendingWrites) error {
+	if stored.ContentHash == "" || stored.DuplicateOf != "" {
+		return nil
+	}
+	key := dedupKey(stored.Producer, stored.ContentHash)
+	if err := ctx.GetStub().PutState(key, []byte(stored.Event.EventID)); err != nil {
+		return err
+	}
+	pending.content[key] = stored.Event.EventID
+	return nil
+}
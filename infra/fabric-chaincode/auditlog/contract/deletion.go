@@ -0,0 +1,500 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/identity"
+	"payscope/auditlog/internal/validate"
+)
+
+// Governed deletion removes an event's content from world state, for the
+// rare case a court orders it. No single org can do it: an admin org
+// proposes the deletion, and only once deletion_approvals orgs from
+// deletion_approver_msps other than the proposer have approved it is the
+// event tombstoned. Each step is recorded as a contract-written event in
+// the deleted event's namespace, child of it: DELETION_PROPOSED,
+// DELETION_APPROVED per approval, and DELETED with the tombstone.
+//
+// The tombstone keeps the event_id, event_type, timestamps, schema_version,
+// prev_event_hash and payload hash, so listings, hash chains and committed
+// digests stay consistent, and drops everything else, along with the
+// indexes over the dropped fields and any private payload. Earlier values
+// stay in the blocks of the peers and orderers, which no chaincode can
+// rewrite; GetEventHistory stops serving them.
+const (
+	deletionProposedType = "DELETION_PROPOSED"
+	deletionApprovedType = "DELETION_APPROVED"
+	deletedType          = "DELETED"
+	deletionSchema       = "deletion"
+
+	deletionKeyPrefix       = "deletion:"
+	deletionTargetKeyPrefix = "deletion_target:"
+
+	// Identity attribute proposers and approvers also need.
+	deletionAttr = "auditlog.deletion_approver"
+
+	deletionStatusProposed = "proposed"
+	deletionStatusExecuted = "executed"
+)
+
+// deletionMarkerTypes are the types of the events recording a deletion.
+var deletionMarkerTypes = map[string]bool{
+	deletionProposedType: true,
+	deletionApprovedType: true,
+	deletedType:          true,
+}
+
+// tombstoneIndexes are the indexes a tombstone stays in; entries of every
+// other index are removed with the content they were keyed on.
+var tombstoneIndexes = map[string]bool{
+	tsIndex:          true,
+	typeTsIndex:      true,
+	schemaTsIndex:    true,
+	producerTsIndex:  true,
+	payloadHashIndex: true,
+}
+
+type DeletionProposal struct {
+	// event_id of the DELETION_PROPOSED event.
+	ProposalID string `json:"proposal_id"`
+	EventID    string `json:"event_id"`
+	Producer   string `json:"producer,omitempty"`
+	Reason     string `json:"reason"`
+	// Court order or case number the deletion answers.
+	LegalReference string `json:"legal_reference"`
+	ProposedByMSP  string `json:"proposed_by_msp"`
+	// Approval rule as configured when the deletion was proposed.
+	ApproverMSPs      []string           `json:"approver_msps"`
+	ApprovalsRequired int                `json:"approvals_required"`
+	Approvals         []DeletionApproval `json:"approvals"`
+	Status            string             `json:"status"`
+	TxID              string             `json:"tx_id"`
+	ProposedAt        string             `json:"proposed_at"`
+	// event_id of the DELETED event, once executed.
+	TombstoneEventID string `json:"tombstone_event_id,omitempty"`
+	ExecutedAt       string `json:"executed_at,omitempty"`
+}
+
+type DeletionApproval struct {
+	MSPID string `json:"msp_id"`
+	// event_id of the DELETION_APPROVED event.
+	MarkerEventID string `json:"marker_event_id"`
+	TxID          string `json:"tx_id"`
+	ApprovedAt    string `json:"approved_at"`
+}
+
+// DeletionRecord is set on a tombstoned StoredEvent.
+type DeletionRecord struct {
+	ProposalID     string   `json:"proposal_id"`
+	Reason         string   `json:"reason"`
+	LegalReference string   `json:"legal_reference"`
+	ProposedByMSP  string   `json:"proposed_by_msp"`
+	ApprovedByMSPs []string `json:"approved_by_msps"`
+	// event_id of the DELETED event.
+	MarkerEventID string `json:"marker_event_id"`
+	TxID          string `json:"tx_id"`
+	DeletedAt     string `json:"deleted_at"`
+}
+
+func (cfg *ContractConfig) validateDeletion() error {
+	if len(cfg.DeletionApproverMSPs) == 0 && cfg.DeletionApprovals == 0 {
+		return nil
+	}
+	seen := map[string]bool{}
+	for _, m := range cfg.DeletionApproverMSPs {
+		if m == "" || seen[m] {
+			return fmt.Errorf("deletion_approver_msps entries must be unique and non-empty")
+		}
+		seen[m] = true
+	}
+	if cfg.DeletionApprovals < 1 || cfg.DeletionApprovals > len(cfg.DeletionApproverMSPs) {
+		return fmt.Errorf("deletion_approvals must be between 1 and the number of deletion_approver_msps")
+	}
+	return nil
+}
+
+func getDeletionProposal(ctx contractapi.TransactionContextInterface, proposalID string) (*DeletionProposal, error) {
+	b, err := ctx.GetStub().GetState(deletionKeyPrefix + proposalID)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("not_found: no deletion proposal %s", proposalID)
+	}
+	var p DeletionProposal
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("corrupt deletion proposal")
+	}
+	return &p, nil
+}
+
+func putDeletionProposal(ctx contractapi.TransactionContextInterface, p *DeletionProposal) (string, error) {
+	out, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(deletionKeyPrefix+p.ProposalID, out); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// requireDeletionAttr refuses identities without auditlog.deletion_approver.
+func requireDeletionAttr(ctx contractapi.TransactionContextInterface) error {
+	if err := ctx.GetClientIdentity().AssertAttributeValue(deletionAttr, "true"); err != nil {
+		return fmt.Errorf("forbidden: %s=true attribute required", deletionAttr)
+	}
+	return nil
+}
+
+// deletionMarker writes one step's contract event as a child of stored.
+// Its artifact_hash commits to the proposal as of the step.
+func deletionMarker(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, stored *StoredEvent, eventType string, p *DeletionProposal) (*StoredEvent, error) {
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	marker, err := contractEvent(ctx, cfg, stored.Producer, LedgerEvent{
+		EventType:      eventType,
+		ArtifactHash:   sha256Hex(b),
+		SchemaVer:      deletionSchema,
+		ParentEventIDs: []string{stored.Event.EventID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marker, commitContractEvent(ctx, cfg, marker)
+}
+
+// ProposeDeletion opens the deletion of an event in the caller's namespace.
+// The caller needs an admin MSP identity carrying
+// auditlog.deletion_approver=true. Its own org cannot approve, so enough
+// other approver orgs must be configured. Events under a legal hold, and
+// contract-written ones, cannot be deleted.
+func (c *AuditLogContract) ProposeDeletion(ctx contractapi.TransactionContextInterface, eventID string, reason string, legalReference string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	if reason == "" || len(reason) > maxJustificationLen {
+		return "", fmt.Errorf("reason must be 1 to %d bytes", maxJustificationLen)
+	}
+	if legalReference == "" || len(legalReference) > maxJustificationLen {
+		return "", fmt.Errorf("legal_reference must be 1 to %d bytes", maxJustificationLen)
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	if err := requireDeletionAttr(ctx); err != nil {
+		return "", err
+	}
+	if cfg.DeletionApprovals == 0 {
+		return "", errors.New("deletion_not_configured: set deletion_approver_msps and deletion_approvals")
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	others := 0
+	for _, m := range cfg.DeletionApproverMSPs {
+		if m != mspID {
+			others++
+		}
+	}
+	if others < cfg.DeletionApprovals {
+		return "", fmt.Errorf("deletion_quorum_unreachable: %d approvals needed from orgs other than %s, %d configured", cfg.DeletionApprovals, mspID, others)
+	}
+	ref, err := callerRef(ctx, cfg, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if stored.Deletion != nil {
+		return "", fmt.Errorf("already_deleted: by proposal %s", stored.Deletion.ProposalID)
+	}
+	if reservedTypes[stored.Event.EventType] {
+		return "", fmt.Errorf("event_type_reserved: %s events cannot be deleted", stored.Event.EventType)
+	}
+	if err := requireNoHold(ctx, stored); err != nil {
+		return "", err
+	}
+	stub := ctx.GetStub()
+	open, err := stub.GetState(deletionTargetKeyPrefix + ref)
+	if err != nil {
+		return "", err
+	}
+	if open != nil {
+		return "", fmt.Errorf("deletion_pending: proposal %s is open for this event", open)
+	}
+
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	p := &DeletionProposal{
+		ProposalID:        txEventID(ctx, deletionProposedType),
+		EventID:           eventID,
+		Producer:          stored.Producer,
+		Reason:            reason,
+		LegalReference:    legalReference,
+		ProposedByMSP:     mspID,
+		ApproverMSPs:      append([]string{}, cfg.DeletionApproverMSPs...),
+		ApprovalsRequired: cfg.DeletionApprovals,
+		Approvals:         []DeletionApproval{},
+		Status:            deletionStatusProposed,
+		TxID:              stub.GetTxID(),
+		ProposedAt:        now.Format(time.RFC3339Nano),
+	}
+	if _, err := deletionMarker(ctx, cfg, stored, deletionProposedType, p); err != nil {
+		return "", err
+	}
+	if err := stub.PutState(deletionTargetKeyPrefix+ref, []byte(p.ProposalID)); err != nil {
+		return "", err
+	}
+	return putDeletionProposal(ctx, p)
+}
+
+// ApproveDeletion records the caller's org's approval of a proposal. The
+// caller's MSP must be one of the proposal's approver_msps, other than the
+// proposer's, and carry auditlog.deletion_approver=true; each org approves
+// once. The approval that completes the quorum also tombstones the event,
+// in the same transaction, and returns the executed proposal.
+func (c *AuditLogContract) ApproveDeletion(ctx contractapi.TransactionContextInterface, proposalID string) (string, error) {
+	if !validate.UUID.MatchString(proposalID) {
+		return "", fmt.Errorf("invalid proposal_id")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireDeletionAttr(ctx); err != nil {
+		return "", err
+	}
+	p, err := getDeletionProposal(ctx, proposalID)
+	if err != nil {
+		return "", err
+	}
+	if p.Status != deletionStatusProposed {
+		return "", fmt.Errorf("already_deleted: proposal %s was executed", proposalID)
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	if !identity.Contains(p.ApproverMSPs, mspID) {
+		return "", fmt.Errorf("forbidden: %s is not a deletion approver of this proposal", mspID)
+	}
+	if mspID == p.ProposedByMSP {
+		return "", errors.New("forbidden: the proposing org cannot approve its own deletion")
+	}
+	for _, a := range p.Approvals {
+		if a.MSPID == mspID {
+			return "", fmt.Errorf("deletion_already_approved: by %s", mspID)
+		}
+	}
+	ref := eventRef(p.Producer, p.EventID, cfg.TimeOrderedKeys)
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if err := requireNoHold(ctx, stored); err != nil {
+		return "", err
+	}
+
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	stub := ctx.GetStub()
+	approval := DeletionApproval{
+		MSPID:         mspID,
+		MarkerEventID: txEventID(ctx, deletionApprovedType),
+		TxID:          stub.GetTxID(),
+		ApprovedAt:    now.Format(time.RFC3339Nano),
+	}
+	p.Approvals = append(p.Approvals, approval)
+	if _, err := deletionMarker(ctx, cfg, stored, deletionApprovedType, p); err != nil {
+		return "", err
+	}
+	if len(p.Approvals) >= p.ApprovalsRequired {
+		if err := executeDeletion(ctx, cfg, stored, p); err != nil {
+			return "", err
+		}
+	}
+	return putDeletionProposal(ctx, p)
+}
+
+// executeDeletion tombstones stored under p and writes the DELETED event.
+func executeDeletion(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, stored *StoredEvent, p *DeletionProposal) error {
+	stub := ctx.GetStub()
+	ref := stored.ref()
+	entries, err := indexEntries(stored)
+	if err != nil {
+		return err
+	}
+	legacy, err := legacyIndexEntries(stored)
+	if err != nil {
+		return err
+	}
+	bucket, err := bucketAttrs(stored)
+	if err != nil {
+		return err
+	}
+	entries = append(entries, indexEntry{bucketIndex, bucket})
+	legacy = append(legacy, indexEntry{bucketIndex, bucket})
+	// Drop every entry, in the layouts of all storage versions, and
+	// rewrite the kept ones in the current layout below. Keys ending in
+	// the bare event_id may belong to another producer's record sharing
+	// it, so those go only if they point here.
+	for i, ie := range entries {
+		key, err := indexKey(stub, ie.objectType, ie.attrs)
+		if err != nil {
+			return err
+		}
+		keys := []string{key}
+		if rangeIndexes[ie.objectType] {
+			old, err := stub.CreateCompositeKey(ie.objectType, ie.attrs)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, old)
+		}
+		for _, k := range keys {
+			if err := stub.DelState(k); err != nil {
+				return err
+			}
+		}
+		if err := dropLegacyIndexEntry(stub, legacy[i], key, ref); err != nil {
+			return err
+		}
+		if tombstoneIndexes[ie.objectType] {
+			if err := stub.PutState(key, []byte(ref)); err != nil {
+				return err
+			}
+		}
+	}
+	if stored.PrivateCollection != "" {
+		purged, err := getPurge(ctx, ref)
+		if err != nil {
+			return err
+		}
+		if purged == nil {
+			if err := stub.PurgePrivateData(stored.PrivateCollection, ref); err != nil {
+				return err
+			}
+		}
+	}
+
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return err
+	}
+	p.Status = deletionStatusExecuted
+	p.TombstoneEventID = txEventID(ctx, deletedType)
+	p.ExecutedAt = now.Format(time.RFC3339Nano)
+	approvers := make([]string, len(p.Approvals))
+	for i, a := range p.Approvals {
+		approvers[i] = a.MSPID
+	}
+	if _, err := deletionMarker(ctx, cfg, stored, deletedType, p); err != nil {
+		return err
+	}
+
+	e := &stored.Event
+	tomb := &StoredEvent{
+		Event: LedgerEvent{
+			EventID:       e.EventID,
+			EventType:     e.EventType,
+			TimestampUTC:  e.TimestampUTC,
+			SchemaVer:     e.SchemaVer,
+			HashAlgorithm: e.HashAlgorithm,
+			PrevEventHash: e.PrevEventHash,
+		},
+		PayloadHash:       stored.PayloadHash,
+		SaltID:            stored.SaltID,
+		Producer:          stored.Producer,
+		CreatorMSP:        stored.CreatorMSP,
+		RequiredOrgs:      stored.RequiredOrgs,
+		TimeOrderedKeys:   stored.TimeOrderedKeys,
+		PrivateCollection: stored.PrivateCollection,
+		Canonicalization:  stored.Canonicalization,
+		LedgerTimestamp:   stored.LedgerTimestamp,
+		TxID:              stored.TxID,
+		StorageVersion:    storageVersion,
+		Deletion: &DeletionRecord{
+			ProposalID:     p.ProposalID,
+			Reason:         p.Reason,
+			LegalReference: p.LegalReference,
+			ProposedByMSP:  p.ProposedByMSP,
+			ApprovedByMSPs: approvers,
+			MarkerEventID:  p.TombstoneEventID,
+			TxID:           stub.GetTxID(),
+			DeletedAt:      p.ExecutedAt,
+		},
+	}
+	out, err := json.Marshal(tomb)
+	if err != nil {
+		return err
+	}
+	// The key keeps its endorsement policy, so a locked event needs its
+	// writer org's peers to endorse the deletion too.
+	if err := putStoredEvent(ctx, tomb, out, nil); err != nil {
+		return err
+	}
+	return stub.DelState(deletionTargetKeyPrefix + ref)
+}
+
+// GetDeletionProposal reads a deletion proposal and its approvals.
+func (c *AuditLogContract) GetDeletionProposal(ctx contractapi.TransactionContextInterface, proposalID string) (string, error) {
+	p, err := getDeletionProposal(ctx, proposalID)
+	if err != nil {
+		return "", err
+	}
+	return marshalString(p)
+}
+
+// dropLegacyIndexEntry deletes the pre-storage_version 4 keys of ie, in
+// both layouts, that differ from key and still point at ref.
+func dropLegacyIndexEntry(stub shim.ChaincodeStubInterface, ie indexEntry, key, ref string) error {
+	keys := []string{}
+	moved, err := indexKey(stub, ie.objectType, ie.attrs)
+	if err != nil {
+		return err
+	}
+	keys = append(keys, moved)
+	if rangeIndexes[ie.objectType] {
+		old, err := stub.CreateCompositeKey(ie.objectType, ie.attrs)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, old)
+	}
+	for _, k := range keys {
+		if k == key {
+			continue
+		}
+		v, err := stub.GetState(k)
+		if err != nil {
+			return err
+		}
+		if string(v) != ref {
+			continue
+		}
+		if err := stub.DelState(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+const deliveryKeyPrefix = "delivery:"
+
+var consumerRe = regexp.MustCompile("^[A-Za-z0-9._-]{1,64}$")
+
+// DeliveryReceipt records that a consumer processed the chaincode event
+// emitted for an audit event.
+type DeliveryReceipt struct {
+	EventID     string `json:"event_id"`
+	Consumer    string `json:"consumer"`
+	DeliveredAt string `json:"delivered_at"`
+}
+
+func deliveryKey(consumerID, ref string) string {
+	return deliveryKeyPrefix + consumerID + ":" + ref
+}
+
+// AckEventDelivery marks an event as delivered to consumerID. Repeated acks
+// are a no-op so consumers with at-least-once delivery can ack freely.
+func (c *AuditLogContract) AckEventDelivery(ctx contractapi.TransactionContextInterface, eventID string, consumerID string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	if !consumerRe.MatchString(consumerID) {
+		return "", fmt.Errorf("invalid consumer_id")
+	}
+	ref, err := resolveRef(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := getStoredEvent(ctx, ref); err != nil {
+		return "", err
+	}
+	key := deliveryKey(consumerID, ref)
+	existing, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return ctx.GetStub().GetTxID(), nil
+	}
+
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(DeliveryReceipt{
+		EventID:     eventID,
+		Consumer:    consumerID,
+		DeliveredAt: now.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(key, out); err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
+
+// GetUndeliveredEvents returns the events consumerID has not acked yet.
+func (c *AuditLogContract) GetUndeliveredEvents(ctx contractapi.TransactionContextInterface, consumerID string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if !consumerRe.MatchString(consumerID) {
+		return "", fmt.Errorf("invalid consumer_id")
+	}
+	page, err := scanEvents(ctx, bookmark, pageSize, func(stored *StoredEvent) (bool, error) {
+		ack, err := ctx.GetStub().GetState(deliveryKey(consumerID, stored.ref()))
+		if err != nil {
+			return false, err
+		}
+		return ack == nil, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return marshalPage(page, projection)
+}
@@ -0,0 +1,129 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/fabric-chaincode-go/pkg/statebased"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// Fabric constraints on per-event endorsement:
+//
+//   - Chaincode cannot see which peers endorsed its own proposal, so the
+//     contract records the intended policy (the required orgs), not proof
+//     that those orgs signed. Proof lives in the block's endorsements.
+//   - A key-level policy only governs later writes to that key. The
+//     transaction that sets it is validated against the key's current
+//     policy (or the chaincode policy if none is set yet).
+//   - Orgs are MSP IDs and the policy requires a peer of every listed org.
+//     Updating the policy again needs endorsement from the orgs already on it.
+
+const maxEndorsingOrgs = 16
+
+type EndorsementRequirements struct {
+	EventID       string   `json:"event_id"`
+	CreatorMSP    string   `json:"creator_msp"`
+	RequiredOrgs  []string `json:"required_orgs"`
+	PolicyOnState bool     `json:"policy_on_state"`
+}
+
+// endorsementPolicy builds a key-level policy requiring a peer of every org.
+func endorsementPolicy(orgs []string) ([]byte, error) {
+	ep, err := statebased.NewStateEP(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := ep.AddOrgs(statebased.RoleTypePeer, orgs...); err != nil {
+		return nil, err
+	}
+	return ep.Policy()
+}
+
+// SetStateBasedEndorsement requires peers of every org in orgsJSON to
+// endorse future writes to the event's key, and records the list on the
+// StoredEvent. Only the event's creator MSP or an admin may call it.
+func (c *AuditLogContract) SetStateBasedEndorsement(ctx contractapi.TransactionContextInterface, eventID string, orgsJSON string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	var orgs []string
+	if err := json.Unmarshal([]byte(orgsJSON), &orgs); err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+	if len(orgs) == 0 || len(orgs) > maxEndorsingOrgs {
+		return "", fmt.Errorf("orgs must list between 1 and %d MSP IDs", maxEndorsingOrgs)
+	}
+	seen := map[string]bool{}
+	for _, o := range orgs {
+		if o == "" || seen[o] {
+			return "", fmt.Errorf("orgs must be distinct non-empty MSP IDs")
+		}
+		seen[o] = true
+	}
+	sort.Strings(orgs)
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	ref, err := callerRef(ctx, cfg, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if err := requireCreatorOrAdmin(ctx, cfg, stored, "set endorsement"); err != nil {
+		return "", err
+	}
+
+	policy, err := endorsementPolicy(orgs)
+	if err != nil {
+		return "", err
+	}
+
+	stored.RequiredOrgs = orgs
+	out, err := json.Marshal(stored)
+	if err != nil {
+		return "", err
+	}
+	if err := putStoredEvent(ctx, stored, out, policy); err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
+
+// GetEventEndorsementRequirements returns the creator MSP and required orgs
+// recorded for an event, and whether a key-level policy is set on state.
+func (c *AuditLogContract) GetEventEndorsementRequirements(ctx contractapi.TransactionContextInterface, eventID string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	ref, err := resolveRef(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	policy, err := ctx.GetStub().GetStateValidationParameter(eventKey(ref))
+	if err != nil {
+		return "", err
+	}
+	req := EndorsementRequirements{
+		EventID:       eventID,
+		CreatorMSP:    stored.CreatorMSP,
+		RequiredOrgs:  stored.RequiredOrgs,
+		PolicyOnState: len(policy) > 0,
+	}
+	if req.RequiredOrgs == nil {
+		req.RequiredOrgs = []string{}
+	}
+	return marshalString(req)
+}
@@ -0,0 +1,110 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// A private payload may arrive encrypted by the writer, under the transient
+// key "payload_envelope" instead of "payload", so even the peers of
+// collection members hold only ciphertext. The payload is sealed with a
+// fresh data encryption key, which is wrapped under one or more per-org data
+// keys held off-chain; the public record lists their key ids. The chaincode
+// cannot check the plaintext against artifact_hash, which readers do after
+// decrypting (DecryptPayload in the Go client).
+const (
+	transientEnvelopeKey = "payload_envelope"
+
+	envelopeVersion = 1
+	envelopeAlg     = "AES-256-GCM"
+)
+
+var payloadKeyIDRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,127}$`)
+
+// payloadEnvelope is the encrypted payload as the client seals it. Every
+// binary field is base64. The payload is sealed with the event_id as
+// additional data, each wrapped key with its key_id.
+type payloadEnvelope struct {
+	Version    int           `json:"version"`
+	Alg        string        `json:"alg"`
+	Keys       []envelopeKey `json:"keys"`
+	Nonce      string        `json:"nonce"`
+	Ciphertext string        `json:"ciphertext"`
+}
+
+type envelopeKey struct {
+	KeyID      string `json:"key_id"`
+	Nonce      string `json:"nonce"`
+	WrappedKey string `json:"wrapped_key"`
+}
+
+// parseEnvelope checks that b is a well-formed envelope and returns the ids
+// of the keys it is wrapped under.
+func parseEnvelope(b []byte) ([]string, error) {
+	var env payloadEnvelope
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&env); err != nil {
+		return nil, fmt.Errorf("invalid_payload_envelope: %v", err)
+	}
+	if env.Version != envelopeVersion || env.Alg != envelopeAlg {
+		return nil, fmt.Errorf("invalid_payload_envelope: expected version %d with %s", envelopeVersion, envelopeAlg)
+	}
+	if len(env.Keys) == 0 {
+		return nil, errors.New("invalid_payload_envelope: no wrapped keys")
+	}
+	if !base64Len(env.Nonce, 12) || !base64Len(env.Ciphertext, -1) {
+		return nil, errors.New("invalid_payload_envelope: nonce and ciphertext must be base64")
+	}
+	ids := make([]string, len(env.Keys))
+	seen := map[string]bool{}
+	for i, k := range env.Keys {
+		if !payloadKeyIDRe.MatchString(k.KeyID) || seen[k.KeyID] {
+			return nil, fmt.Errorf("invalid_payload_envelope: keys[%d]: key_id must be unique, 1-128 letters, digits or ._:-", i)
+		}
+		if !base64Len(k.Nonce, 12) || !base64Len(k.WrappedKey, -1) {
+			return nil, fmt.Errorf("invalid_payload_envelope: keys[%d]: nonce and wrapped_key must be base64", i)
+		}
+		seen[k.KeyID] = true
+		ids[i] = k.KeyID
+	}
+	return ids, nil
+}
+
+// base64Len reports whether s is non-empty standard base64, of n bytes
+// unless n is negative.
+func base64Len(s string, n int) bool {
+	b, err := base64.StdEncoding.DecodeString(s)
+	return err == nil && len(b) > 0 && (n < 0 || len(b) == n)
+}
+
+// checkPayloadKeys requires ids to include the writer's current data key
+// when payload_keys names one for its MSP.
+func checkPayloadKeys(cfg *ContractConfig, mspID string, ids []string) error {
+	want, ok := cfg.PayloadKeys[mspID]
+	if !ok {
+		return nil
+	}
+	for _, id := range ids {
+		if id == want {
+			return nil
+		}
+	}
+	return fmt.Errorf("payload_key_missing: payloads from %s must be wrapped under key %s", mspID, want)
+}
+
+func (cfg *ContractConfig) validatePayloadKeys() error {
+	for msp, id := range cfg.PayloadKeys {
+		if msp == "" || !payloadKeyIDRe.MatchString(id) {
+			return fmt.Errorf("payload_keys must map MSP ids to key ids of 1-128 letters, digits or ._:-")
+		}
+	}
+	if cfg.RequireEncryptedPayloads && cfg.PrivateCollection == "" {
+		return fmt.Errorf("require_encrypted_payloads requires private_collection")
+	}
+	return nil
+}
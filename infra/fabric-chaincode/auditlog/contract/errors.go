@@ -0,0 +1,284 @@
+package contract
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Stable error codes returned in ErrorEnvelope. Transactions keep failing
+// with "<reason>: detail" or plain validation text; envelopeChaincode maps
+// those messages to a code once, on the way out, so clients can switch on
+// the code instead of matching strings.
+const (
+	errCodeNotFound            = "ERR_NOT_FOUND"
+	errCodeIdempotency         = "ERR_IDEMPOTENCY"
+	errCodeForbidden           = "ERR_FORBIDDEN"
+	errCodeConflict            = "ERR_CONFLICT"
+	errCodePrecondition        = "ERR_PRECONDITION"
+	errCodeQuotaExceeded       = "ERR_QUOTA_EXCEEDED"
+	errCodeTooLarge            = "ERR_TOO_LARGE"
+	errCodeUnsupported         = "ERR_UNSUPPORTED"
+	errCodeBadRequest          = "ERR_BAD_REQUEST"
+	errCodeValidation          = "ERR_VALIDATION"
+	errCodeValidationJSON      = "ERR_VALIDATION_JSON"
+	errCodeValidationField     = "ERR_VALIDATION_FIELD"
+	errCodeValidationSchema    = "ERR_VALIDATION_SCHEMA"
+	errCodeValidationSignature = "ERR_VALIDATION_SIGNATURE"
+	errCodeValidationTimestamp = "ERR_VALIDATION_TIMESTAMP"
+	errCodeValidationHash      = "ERR_VALIDATION_HASH"
+	errCodeValidationChain     = "ERR_VALIDATION_CHAIN"
+	errCodeInternal            = "ERR_INTERNAL"
+)
+
+// ErrorEnvelope is the JSON error message of every failed transaction.
+// Message is the original error text; Details carries the snake_case
+// reason, when there is one, the batch index of index-specific errors, and
+// any details the error itself carries.
+type ErrorEnvelope struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Field   string         `json:"field,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+type reasonCode struct {
+	code  string
+	field string
+}
+
+// reasonCodes maps the snake_case reasons transactions fail with to their
+// stable code, and the event field at fault where there is one.
+var reasonCodes = map[string]reasonCode{
+	"not_found":             {errCodeNotFound, ""},
+	"anchor_not_found":      {errCodeNotFound, "target"},
+	"idempotency_violation": {errCodeIdempotency, "event_id"},
+	"forbidden":             {errCodeForbidden, ""},
+	"chain_link_foreign":    {errCodeForbidden, "prev_event_hash"},
+
+	"unique_constraint_violation":     {errCodeConflict, ""},
+	"duplicate_payload":               {errCodeConflict, "event_id"},
+	"artifact_schema_conflict":        {errCodeConflict, "schema_version"},
+	"chain_fork":                      {errCodeConflict, "prev_event_hash"},
+	"tsa_already_registered":          {errCodeConflict, ""},
+	"schema_already_registered":       {errCodeConflict, ""},
+	"event_schema_already_registered": {errCodeConflict, ""},
+	"event_type_already_registered":   {errCodeConflict, ""},
+	"event_type_builtin":              {errCodeConflict, ""},
+	"legal_hold_exists":               {errCodeConflict, ""},
+	"legal_hold_released":             {errCodeConflict, ""},
+	"already_superseded":              {errCodeConflict, ""},
+	"already_revoked":                 {errCodeConflict, ""},
+	"day_already_closed":              {errCodeConflict, ""},
+	"config_already_initialized":      {errCodeConflict, ""},
+	"config_unchanged":                {errCodeConflict, ""},
+	"reference_lookup_not_configured": {errCodePrecondition, ""},
+	"reference_tx_missing":            {errCodePrecondition, "reference_tx"},
+	"artifact_already_registered":     {errCodeConflict, "artifact_hash"},
+	"artifact_not_registered":         {errCodePrecondition, "artifact_hash"},
+	"already_purged":                  {errCodeConflict, ""},
+	"archive_location_recorded":       {errCodeConflict, ""},
+	"already_deleted":                 {errCodeConflict, ""},
+	"deletion_pending":                {errCodeConflict, ""},
+	"deletion_already_approved":       {errCodeConflict, ""},
+	"anchor_already_recorded":         {errCodeConflict, "target"},
+	"signer_key_already_registered":   {errCodeConflict, ""},
+	"config_field_immutable":          {errCodePrecondition, ""},
+
+	"legal_hold_active":           {errCodePrecondition, ""},
+	"day_closed":                  {errCodePrecondition, "timestamp"},
+	"day_not_ended":               {errCodePrecondition, ""},
+	"no_retention_policy":         {errCodePrecondition, ""},
+	"not_archived":                {errCodePrecondition, ""},
+	"digest_window_empty":         {errCodePrecondition, ""},
+	"digest_window_too_large":     {errCodePrecondition, ""},
+	"not_in_digest":               {errCodePrecondition, ""},
+	"payload_encryption_required": {errCodePrecondition, "payload"},
+	"deletion_not_configured":     {errCodePrecondition, ""},
+	"deletion_quorum_unreachable": {errCodePrecondition, ""},
+
+	"quota_exceeded": {errCodeQuotaExceeded, ""},
+
+	"event_too_large":          {errCodeTooLarge, ""},
+	"batch_too_large":          {errCodeTooLarge, ""},
+	"metadata_value_too_large": {errCodeTooLarge, "metadata"},
+
+	"rich_query_unsupported_on_leveldb": {errCodeUnsupported, ""},
+	"private_payload_unsupported":       {errCodeUnsupported, ""},
+
+	"invalid_payload_envelope": {errCodeValidationField, "payload"},
+	"payload_key_missing":      {errCodeValidationField, "payload"},
+
+	"schema_version_not_registered": {errCodeValidationSchema, "schema_version"},
+	"schema_version_not_in_enum":    {errCodeValidationSchema, "schema_version"},
+	"schema_version_deprecated":     {errCodeValidationSchema, "schema_version"},
+	"event_type_retired":            {errCodeValidationSchema, "event_type"},
+	"event_type_reserved":           {errCodeValidationSchema, "event_type"},
+	"invalid_confidence":            {errCodeValidationSchema, "confidence"},
+	"event_schema_violation":        {errCodeValidationSchema, ""},
+
+	"invalid_signature":         {errCodeValidationSignature, "signature"},
+	"signer_cert_mismatch":      {errCodeValidationSignature, "signer_cert"},
+	"signer_key_unknown":        {errCodeValidationSignature, "signer_key_id"},
+	"signer_key_not_authorized": {errCodeValidationSignature, "signer_key_id"},
+	"signer_key_inactive":       {errCodeValidationSignature, "signer_key_id"},
+	"signer_key_required":       {errCodeValidationSignature, "signer_key_id"},
+
+	"invalid_tsa_token":  {errCodeValidationTimestamp, "tsa_token"},
+	"tsa_token_required": {errCodeValidationTimestamp, "tsa_token"},
+	"tsa_not_configured": {errCodePrecondition, "tsa_token"},
+
+	"timestamp_drift_exceeded":     {errCodeValidationTimestamp, "timestamp"},
+	"timestamp_before_genesis":     {errCodeValidationTimestamp, "timestamp"},
+	"timestamp_offset_not_allowed": {errCodeValidationTimestamp, "timestamp"},
+	"chain_timestamp_regression":   {errCodeValidationTimestamp, "timestamp"},
+
+	"private_payload_hash_mismatch": {errCodeValidationHash, "artifact_hash"},
+	"anchor_root_mismatch":          {errCodeValidationHash, "merkle_root"},
+	"chain_link_not_found":          {errCodeValidationChain, "prev_event_hash"},
+}
+
+// Error texts raised by contractapi itself before a transaction runs.
+var badRequestPrefixes = []string{
+	"Contract not found with name",
+	"Blank function name passed",
+	"Function ",
+	"Incorrect number of params",
+	"Error managing parameter",
+}
+
+var (
+	reasonRe    = regexp.MustCompile(`^([a-z][a-z0-9]*(?:_[a-z0-9]+)+|not_found|forbidden)(?::|$)`)
+	invalidJSON = regexp.MustCompile(`^invalid (?:[a-z_]+ )?(?:json|protobuf)`)
+	fieldRe     = regexp.MustCompile(`^([a-z][a-z0-9_]*)(?:\[\d+\])?:? (?:must|required|given|may|takes|contains|entries|bounds|is|duplicate)\b`)
+	invalidRe   = regexp.MustCompile(`^(?:invalid|unknown|unsupported) ([a-z][a-z0-9_]*)`)
+	indexRe     = regexp.MustCompile(`at index (\d+)|\[(\d+)\]`)
+)
+
+// detailsMarker separates an error's text from the JSON details a
+// detailError appends to it. contractapi passes only the error text on,
+// so classifyError splits them apart again.
+const detailsMarker = " details="
+
+// detailError is an error whose envelope carries structured details beyond
+// the reason, such as the field diff of an idempotency_violation.
+type detailError struct {
+	msg     string
+	details map[string]any
+}
+
+func (e *detailError) Error() string {
+	b, err := json.Marshal(e.details)
+	if err != nil {
+		return e.msg
+	}
+	return e.msg + detailsMarker + string(b)
+}
+
+// splitDetails separates the details a detailError appended to msg.
+func splitDetails(msg string) (string, map[string]any) {
+	i := strings.LastIndex(msg, detailsMarker)
+	if i < 0 {
+		return msg, nil
+	}
+	var details map[string]any
+	if err := json.Unmarshal([]byte(msg[i+len(detailsMarker):]), &details); err != nil {
+		return msg, nil
+	}
+	return msg[:i], details
+}
+
+// classifyError builds the envelope for an error message.
+func classifyError(msg string) ErrorEnvelope {
+	msg, details := splitDetails(msg)
+	env := ErrorEnvelope{Message: msg, Details: details}
+	addDetail := func(k string, v any) {
+		if env.Details == nil {
+			env.Details = map[string]any{}
+		}
+		env.Details[k] = v
+	}
+	if m := indexRe.FindStringSubmatch(msg); m != nil {
+		if i, err := strconv.Atoi(m[1] + m[2]); err == nil {
+			addDetail("index", i)
+		}
+	}
+
+	for _, p := range badRequestPrefixes {
+		if strings.HasPrefix(msg, p) {
+			env.Code = errCodeBadRequest
+			return env
+		}
+	}
+	if m := reasonRe.FindStringSubmatch(msg); m != nil {
+		addDetail("reason", m[1])
+		if rc, ok := reasonCodes[m[1]]; ok {
+			env.Code, env.Field = rc.code, rc.field
+		} else {
+			env.Code = errCodeValidation
+		}
+		return env
+	}
+	switch {
+	case strings.HasPrefix(msg, "corrupt "):
+		env.Code = errCodeInternal
+	case invalidJSON.MatchString(msg):
+		env.Code = errCodeValidationJSON
+	case fieldRe.MatchString(msg):
+		env.Code, env.Field = errCodeValidationField, fieldRe.FindStringSubmatch(msg)[1]
+	case invalidRe.MatchString(msg):
+		env.Code, env.Field = errCodeValidationField, invalidRe.FindStringSubmatch(msg)[1]
+	case msg != "" && msg[0] >= 'a' && msg[0] <= 'z':
+		// The contract's own messages are lower case; peer and shim
+		// failures are not.
+		env.Code = errCodeValidation
+	default:
+		env.Code = errCodeInternal
+	}
+	return env
+}
+
+func errorCode(err error) string {
+	return classifyError(err.Error()).Code
+}
+
+// envelopeChaincode rewrites the message of every failed Init or Invoke into
+// a JSON ErrorEnvelope.
+type envelopeChaincode struct {
+	*contractapi.ContractChaincode
+}
+
+func (cc *envelopeChaincode) Init(stub shim.ChaincodeStubInterface) peer.Response {
+	resp, _ := envelope(cc.ContractChaincode.Init(stub))
+	return resp
+}
+
+// Invoke also records the call for metrics.go and health.go.
+func (cc *envelopeChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Response {
+	start := time.Now()
+	health.invoked()
+	fn, _ := stub.GetFunctionAndParameters()
+	resp, code := envelope(cc.ContractChaincode.Invoke(stub))
+	observeInvoke(fn, start, resp, code)
+	return resp
+}
+
+// envelope returns resp with its error message enveloped, and the error
+// code, or "" for a successful response.
+func envelope(resp peer.Response) (peer.Response, string) {
+	if resp.Status < shim.ERRORTHRESHOLD {
+		return resp, ""
+	}
+	env := classifyError(resp.Message)
+	out, err := json.Marshal(env)
+	if err != nil {
+		return resp, env.Code
+	}
+	return shim.Error(string(out)), env.Code
+}
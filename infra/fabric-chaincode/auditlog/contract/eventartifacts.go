@@ -0,0 +1,72 @@
+package contract
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// An event names its primary artifact in artifact_hash and may list every
+// artifact it involves in artifacts, each with a role such as "input",
+// "model" or "output". Listed artifacts are indexed by hash and role;
+// artifact_hash keeps its own index and the checks that use it.
+const (
+	artifactRoleIndex = "artifact~role~id"
+	maxEventArtifacts = 32
+)
+
+var artifactRoleRe = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+type EventArtifact struct {
+	ArtifactHash string `json:"artifact_hash"`
+	Role         string `json:"role"`
+	// Empty means sha256, as for the event's own hash_algorithm.
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+}
+
+func validateEventArtifacts(e *LedgerEvent) error {
+	if len(e.Artifacts) > maxEventArtifacts {
+		return fmt.Errorf("artifacts must have at most %d entries", maxEventArtifacts)
+	}
+	seen := map[[2]string]bool{}
+	for i, a := range e.Artifacts {
+		if err := validateArtifactHash(&LedgerEvent{ArtifactHash: a.ArtifactHash, HashAlgorithm: a.HashAlgorithm}); err != nil {
+			return fmt.Errorf("artifacts[%d] must have a valid artifact_hash: %v", i, err)
+		}
+		if !artifactRoleRe.MatchString(a.Role) {
+			return fmt.Errorf("artifacts[%d] must have a role of 1-32 characters a-z, 0-9, '_' or '-' starting with a letter", i)
+		}
+		k := [2]string{a.ArtifactHash, a.Role}
+		if seen[k] {
+			return fmt.Errorf("artifacts[%d] duplicate artifact_hash and role", i)
+		}
+		seen[k] = true
+	}
+	return nil
+}
+
+// GetEventsByArtifactRole pages through the events listing artifactHash in
+// artifacts, restricted to role when it is not empty.
+func (c *AuditLogContract) GetEventsByArtifactRole(ctx contractapi.TransactionContextInterface, artifactHash string, role string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	artifactHash, err := artifactHashArg(ctx, artifactHash)
+	if err != nil {
+		return "", err
+	}
+	if !validate.SHA256.MatchString(artifactHash) {
+		return "", fmt.Errorf("artifact_hash must be lowercase hex")
+	}
+	attrs := []string{artifactHash}
+	if role != "" {
+		if !artifactRoleRe.MatchString(role) {
+			return "", fmt.Errorf("invalid role")
+		}
+		attrs = append(attrs, role)
+	}
+	return pageByIndex(ctx, artifactRoleIndex, attrs, projection, bookmark, pageSize)
+}
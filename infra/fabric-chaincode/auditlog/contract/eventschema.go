@@ -0,0 +1,200 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// Each (event_type, schema_version) pair may have a JSON Schema stored under
+// eventSchemaIndex. Writes validate the full event payload against the
+// schema of their pair, on top of the built-in checks in validateEvent,
+// which stay because ids, hashes and timestamps key the indexes. Pairs
+// without a schema are not checked further. Schemas are write-once; a new
+// shape gets a new schema_version.
+const (
+	eventSchemaIndex    = "event_schema~type~version"
+	maxSchemaViolations = 3
+)
+
+type EventSchemaRecord struct {
+	EventType    string          `json:"event_type"`
+	SchemaVer    string          `json:"schema_version"`
+	JSONSchema   json.RawMessage `json:"json_schema"`
+	RegisteredBy string          `json:"registered_by_msp"`
+	RegisteredAt string          `json:"registered_at"`
+}
+
+func eventSchemaKey(ctx contractapi.TransactionContextInterface, eventType, version string) (string, error) {
+	return ctx.GetStub().CreateCompositeKey(eventSchemaIndex, []string{eventType, version})
+}
+
+func getEventSchema(ctx contractapi.TransactionContextInterface, eventType, version string) (*EventSchemaRecord, error) {
+	key, err := eventSchemaKey(ctx, eventType, version)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var rec EventSchemaRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("corrupt event schema record")
+	}
+	return &rec, nil
+}
+
+// localRefsOnly rejects $ref values outside the schema itself. Endorsing
+// peers must never fetch documents, or their results could differ.
+func localRefsOnly(v any) error {
+	switch n := v.(type) {
+	case map[string]any:
+		for k, child := range n {
+			if ref, ok := child.(string); ok && k == "$ref" && !strings.HasPrefix(ref, "#") {
+				return fmt.Errorf("json_schema may only use local $ref, got %q", ref)
+			}
+			if err := localRefsOnly(child); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, child := range n {
+			if err := localRefsOnly(child); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func compileEventSchema(raw []byte) (*gojsonschema.Schema, error) {
+	var doc any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("invalid json_schema: %v", err)
+	}
+	if err := localRefsOnly(doc); err != nil {
+		return nil, err
+	}
+	schema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid json_schema: %v", err)
+	}
+	return schema, nil
+}
+
+// checkEventSchema validates e against its pair's schema, if one is
+// registered. Compiled schemas are reused across a batch through pending.
+func checkEventSchema(ctx contractapi.TransactionContextInterface, e *LedgerEvent, pending *pendingWrites) error {
+	pair := e.EventType + "\x00" + e.SchemaVer
+	schema, ok := pending.schemas[pair]
+	if !ok {
+		rec, err := getEventSchema(ctx, e.EventType, e.SchemaVer)
+		if err != nil {
+			return err
+		}
+		if rec != nil {
+			if schema, err = compileEventSchema(rec.JSONSchema); err != nil {
+				return err
+			}
+		}
+		pending.schemas[pair] = schema
+	}
+	if schema == nil {
+		return nil
+	}
+	doc, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	res, err := schema.Validate(gojsonschema.NewBytesLoader(doc))
+	if err != nil {
+		return err
+	}
+	if res.Valid() {
+		return nil
+	}
+	msgs := []string{}
+	for i, re := range res.Errors() {
+		if i == maxSchemaViolations {
+			msgs = append(msgs, fmt.Sprintf("and %d more", len(res.Errors())-i))
+			break
+		}
+		msgs = append(msgs, re.Field()+": "+re.Description())
+	}
+	return fmt.Errorf("event_schema_violation: %s", strings.Join(msgs, "; "))
+}
+
+// RegisterEventSchema stores the JSON Schema events of (eventType,
+// schemaVersion) must satisfy from now on. Only local $ref are allowed.
+func (c *AuditLogContract) RegisterEventSchema(ctx contractapi.TransactionContextInterface, eventType string, schemaVersion string, jsonSchema string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	if err := requireEventType(ctx, eventType); err != nil {
+		return "", err
+	}
+	if !schemaVersionRe.MatchString(schemaVersion) {
+		return "", fmt.Errorf("invalid schema_version")
+	}
+	if _, err := compileEventSchema([]byte(jsonSchema)); err != nil {
+		return "", err
+	}
+	existing, err := getEventSchema(ctx, eventType, schemaVersion)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return "", errors.New("event_schema_already_registered")
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(EventSchemaRecord{
+		EventType:    eventType,
+		SchemaVer:    schemaVersion,
+		JSONSchema:   json.RawMessage(jsonSchema),
+		RegisteredBy: mspID,
+		RegisteredAt: now.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+	key, err := eventSchemaKey(ctx, eventType, schemaVersion)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(key, out); err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
+
+func (c *AuditLogContract) GetEventSchema(ctx contractapi.TransactionContextInterface, eventType string, schemaVersion string) (string, error) {
+	rec, err := getEventSchema(ctx, eventType, schemaVersion)
+	if err != nil {
+		return "", err
+	}
+	if rec == nil {
+		return "", fmt.Errorf("not_found")
+	}
+	return marshalString(rec)
+}
@@ -0,0 +1,244 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Event types beyond the built-in typeSet are registered on-ledger. A
+// retired type accepts no new events but stays queryable.
+const eventTypeKeyPrefix = "event_type:"
+
+var eventTypeNameRe = regexp.MustCompile("^[A-Z][A-Z0-9_]{0,63}$")
+
+// requiredEventFields lists the optional LedgerEvent fields a registered
+// type may make mandatory.
+var requiredEventFields = map[string]func(e *LedgerEvent) bool{
+	"tsa_token_hash":   func(e *LedgerEvent) bool { return e.TSATokenHash != "" },
+	"ttl_seconds":      func(e *LedgerEvent) bool { return e.TTLSeconds != nil },
+	"confidence":       func(e *LedgerEvent) bool { return e.Confidence != nil },
+	"reference_tx":     func(e *LedgerEvent) bool { return e.ReferenceTx != "" },
+	"correlation_id":   func(e *LedgerEvent) bool { return e.CorrelationID != "" },
+	"parent_event_ids": func(e *LedgerEvent) bool { return len(e.ParentEventIDs) > 0 },
+}
+
+type EventTypeRecord struct {
+	Name           string   `json:"name"`
+	RequiredFields []string `json:"required_fields"`
+	Status         string   `json:"status"`
+	RegisteredAt   string   `json:"registered_at"`
+	RetiredAt      string   `json:"retired_at,omitempty"`
+}
+
+func getEventType(ctx contractapi.TransactionContextInterface, name string) (*EventTypeRecord, error) {
+	b, err := ctx.GetStub().GetState(eventTypeKeyPrefix + name)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var rec EventTypeRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("corrupt event type record")
+	}
+	return &rec, nil
+}
+
+func putEventType(ctx contractapi.TransactionContextInterface, rec *EventTypeRecord) error {
+	out, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(eventTypeKeyPrefix+rec.Name, out)
+}
+
+// requireEventType rejects names that are neither built in nor registered.
+func requireEventType(ctx contractapi.TransactionContextInterface, name string) error {
+	if typeSet[name] {
+		return nil
+	}
+	rec, err := getEventType(ctx, name)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return fmt.Errorf("invalid event_type")
+	}
+	return nil
+}
+
+// checkEventType applies the registry to a write: the type must be built in
+// or registered and active, and carry the type's required fields.
+func checkEventType(ctx contractapi.TransactionContextInterface, e *LedgerEvent) error {
+	if reservedTypes[e.EventType] {
+		return fmt.Errorf("event_type_reserved: %s is written by the contract", e.EventType)
+	}
+	if typeSet[e.EventType] {
+		return nil
+	}
+	rec, err := getEventType(ctx, e.EventType)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return fmt.Errorf("invalid event_type")
+	}
+	if rec.Status == "retired" {
+		return fmt.Errorf("event_type_retired: %s", e.EventType)
+	}
+	for _, f := range rec.RequiredFields {
+		if !requiredEventFields[f](e) {
+			return fmt.Errorf("%s required for event_type %s", f, e.EventType)
+		}
+	}
+	return nil
+}
+
+// knownEventTypes lists built-in and registered types, retired included, in
+// a stable order.
+func knownEventTypes(ctx contractapi.TransactionContextInterface) ([]string, error) {
+	types := sortedTypes()
+	it, err := ctx.GetStub().GetStateByRange(eventTypeKeyPrefix, eventTypeKeyPrefix+string(rune(0x10FFFF)))
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, kv.Key[len(eventTypeKeyPrefix):])
+	}
+	sort.Strings(types)
+	return types, nil
+}
+
+// RegisterEventType adds a new event type. requiredFieldsJSON is a JSON array
+// drawn from requiredEventFields, or empty for none.
+func (c *AuditLogContract) RegisterEventType(ctx contractapi.TransactionContextInterface, name string, requiredFieldsJSON string) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return err
+	}
+	required := []string{}
+	if requiredFieldsJSON != "" {
+		if err := json.Unmarshal([]byte(requiredFieldsJSON), &required); err != nil {
+			return fmt.Errorf("invalid required fields: %v", err)
+		}
+	}
+	return registerEventType(ctx, name, required)
+}
+
+func registerEventType(ctx contractapi.TransactionContextInterface, name string, required []string) error {
+	if !eventTypeNameRe.MatchString(name) {
+		return fmt.Errorf("event type name must match %s", eventTypeNameRe)
+	}
+	if typeSet[name] {
+		return errors.New("event_type_builtin")
+	}
+	existing, err := getEventType(ctx, name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.New("event_type_already_registered")
+	}
+
+	seen := map[string]bool{}
+	for _, f := range required {
+		if requiredEventFields[f] == nil {
+			return fmt.Errorf("unsupported required field %q", f)
+		}
+		if seen[f] {
+			return fmt.Errorf("duplicate required field %q", f)
+		}
+		seen[f] = true
+	}
+
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return err
+	}
+	return putEventType(ctx, &EventTypeRecord{
+		Name:           name,
+		RequiredFields: required,
+		Status:         "active",
+		RegisteredAt:   now.Format(time.RFC3339Nano),
+	})
+}
+
+// RetireEventType stops new events of a registered type. Built-in types
+// cannot be retired.
+func (c *AuditLogContract) RetireEventType(ctx contractapi.TransactionContextInterface, name string) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return err
+	}
+	if typeSet[name] {
+		return errors.New("event_type_builtin")
+	}
+	rec, err := getEventType(ctx, name)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return fmt.Errorf("not_found")
+	}
+	if rec.Status == "retired" {
+		return nil
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return err
+	}
+	rec.Status = "retired"
+	rec.RetiredAt = now.Format(time.RFC3339Nano)
+	return putEventType(ctx, rec)
+}
+
+// ListEventTypes returns the built-in types followed by registered ones.
+func (c *AuditLogContract) ListEventTypes(ctx contractapi.TransactionContextInterface) (string, error) {
+	types, err := listEventTypes(ctx)
+	if err != nil {
+		return "", err
+	}
+	return marshalString(types)
+}
+
+func listEventTypes(ctx contractapi.TransactionContextInterface) ([]EventTypeRecord, error) {
+	types := []EventTypeRecord{}
+	for _, t := range sortedTypes() {
+		types = append(types, EventTypeRecord{Name: t, RequiredFields: []string{}, Status: "builtin"})
+	}
+	it, err := ctx.GetStub().GetStateByRange(eventTypeKeyPrefix, eventTypeKeyPrefix+string(rune(0x10FFFF)))
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		var rec EventTypeRecord
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			return nil, fmt.Errorf("corrupt event type record")
+		}
+		types = append(types, rec)
+	}
+	return types, nil
+}
@@ -0,0 +1,130 @@
+package contract
+
+import (
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// EventView is a StoredEvent as returned by reads, with fields derived at
+// read time. Nothing here is written to state.
+type EventView struct {
+	StoredEvent
+	Expired *bool `json:"expired,omitempty"`
+	// event_id of the amendment replacing this event; see supersede.go.
+	SupersededBy string `json:"superseded_by,omitempty"`
+	// Set once an admin revoked the event; see revoke.go.
+	Revocation *RevocationRecord `json:"revocation,omitempty"`
+	// Set once the event passed retention; see retention.go.
+	Archival *ArchiveRecord `json:"archival,omitempty"`
+	// Where to fetch the archived original; see RecordArchiveLocation.
+	Resolution *ResolutionHints `json:"resolution,omitempty"`
+	// Set once the private payload was purged; see purge.go.
+	Purge *PurgeRecord `json:"purge,omitempty"`
+	// Registered metadata of artifact_hash; see artifact.go.
+	Artifact *ArtifactRecord `json:"artifact,omitempty"`
+	// Where the record was read from; see receipt.go.
+	ChannelID string `json:"channel_id,omitempty"`
+	Chaincode string `json:"chaincode,omitempty"`
+}
+
+// expiredAt reports whether an event with a TTL has expired at now. Events
+// without a TTL never expire.
+func expiredAt(e *LedgerEvent, now time.Time) bool {
+	if e.TTLSeconds == nil {
+		return false
+	}
+	ts, err := time.Parse(time.RFC3339, e.TimestampUTC)
+	if err != nil {
+		return false
+	}
+	return !now.Before(ts.Add(time.Duration(*e.TTLSeconds) * time.Second))
+}
+
+func viewOf(ctx contractapi.TransactionContextInterface, stored *StoredEvent) (*EventView, error) {
+	stub := ctx.GetStub()
+	view := &EventView{StoredEvent: *stored, ChannelID: stub.GetChannelID(), Chaincode: chaincodeName(ctx)}
+	if stored.Event.TTLSeconds != nil {
+		now, err := txTimeUTC(ctx)
+		if err != nil {
+			return nil, err
+		}
+		expired := expiredAt(&stored.Event, now)
+		view.Expired = &expired
+	}
+	sup, err := getSupersession(ctx, stored.ref())
+	if err != nil {
+		return nil, err
+	}
+	if sup != nil {
+		view.SupersededBy = sup.SupersededBy
+	}
+	if view.Revocation, err = getRevocation(ctx, stored.ref()); err != nil {
+		return nil, err
+	}
+	if view.Archival, err = getArchival(ctx, stored.ref()); err != nil {
+		return nil, err
+	}
+	view.Resolution = resolutionHints(stored, view.Archival)
+	if view.Artifact, err = getArtifactRecord(ctx, stored.Event.ArtifactHash); err != nil {
+		return nil, err
+	}
+	if stored.PrivateCollection != "" {
+		if view.Purge, err = getPurge(ctx, stored.ref()); err != nil {
+			return nil, err
+		}
+	}
+	return view, nil
+}
+
+// appendView appends stored's read view to records.
+func appendView(ctx contractapi.TransactionContextInterface, records []EventView, stored *StoredEvent) ([]EventView, error) {
+	view, err := viewOf(ctx, stored)
+	if err != nil {
+		return nil, err
+	}
+	return append(records, *view), nil
+}
+
+// GetActiveEvents pages through events of one type in timestamp order and
+// drops those whose TTL has elapsed at the transaction timestamp.
+func (c *AuditLogContract) GetActiveEvents(ctx contractapi.TransactionContextInterface, eventType string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if err := requireEventType(ctx, eventType); err != nil {
+		return "", err
+	}
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	it, meta, err := pageIndexPrefix(ctx.GetStub(), typeTsIndex, []string{eventType}, pageSize, bookmark)
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	page := EventPage{Records: []EventView{}}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		stored, err := getStoredEvent(ctx, string(kv.Value))
+		if err != nil {
+			return "", err
+		}
+		if !expiredAt(&stored.Event, now) {
+			if page.Records, err = appendView(ctx, page.Records, stored); err != nil {
+				return "", err
+			}
+		}
+	}
+	page.FetchedCount = meta.FetchedRecordsCount
+	page.Bookmark = meta.Bookmark
+	return marshalPage(&page, projection)
+}
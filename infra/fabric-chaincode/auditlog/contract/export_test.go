@@ -0,0 +1,75 @@
+package contract_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/vmihailenco/msgpack/v5"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// Decoding an ExportEventsMsgpack page gives back the records ListEvents
+// returns as JSON.
+func TestExportEventsMsgpackRoundTrip(t *testing.T) {
+	h := initHarness(t, nil)
+	client := auditlogtest.Client("Org1MSP")
+	ingest := auditlogtest.IngestEvent(1)
+	decision := auditlogtest.DecisionEvent(2)
+	decision.ParentEventIDs = []string{ingest.EventID}
+	ttl := int64(3600)
+	forecast := auditlogtest.ForecastEvent(3)
+	forecast.TTLSeconds = &ttl
+	for _, e := range []*contract.LedgerEvent{ingest, decision, forecast} {
+		put(t, h, client, e)
+	}
+	admin := auditlogtest.Admin("Org1MSP")
+
+	out, err := h.Evaluate(admin, "ExportEventsMsgpack", "", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page contract.MsgpackPage
+	if err := auditlogtest.Unmarshal(out, &page); err != nil {
+		t.Fatal(err)
+	}
+	packed, err := base64.StdEncoding.DecodeString(page.Records)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := msgpack.NewDecoder(bytes.NewReader(packed))
+	dec.SetCustomStructTag("json")
+	var got []contract.EventView
+	if err := dec.Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err = h.Evaluate(admin, "ListEvents", "full", "", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want contract.EventPage
+	if err := auditlogtest.Unmarshal(out, &want); err != nil {
+		t.Fatal(err)
+	}
+	// Init's CONFIG_CHANGE event comes with the three written here.
+	if page.Count != 4 || len(got) != page.Count || len(want.Records) != page.Count {
+		t.Fatalf("count %d, decoded %d records, ListEvents %d; want 4", page.Count, len(got), len(want.Records))
+	}
+	for i := range got {
+		g, err := json.Marshal(got[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, err := json.Marshal(want.Records[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(g, w) {
+			t.Errorf("record %d decodes to\n%s\nwant\n%s", i, g, w)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package contract
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// FORECAST events carrying a confidence are indexed by it; the value is
+// formatted fixed-width so that key order matches numeric order.
+const forecastConfidenceIndex = "forecast~confidence~id"
+
+// From schema_version v2, FORECAST events must also say what produced them:
+// the model, how far ahead it forecasts and a digest of its inputs. The
+// forecast object is part of the payload hash. v1 forecasts are accepted as
+// before and must leave it out.
+const minForecastSchema = 2
+
+var (
+	modelIDRe         = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/@+-]{0,127}$`)
+	forecastHorizonRe = regexp.MustCompile(`^P(?:\d+Y)?(?:\d+M)?(?:\d+W)?(?:\d+D)?(?:T(?:\d+H)?(?:\d+M)?(?:\d+S)?)?$`)
+)
+
+type ForecastDetails struct {
+	ModelID string `json:"model_id"`
+	// ISO 8601 duration, e.g. "P30D" or "P3M".
+	Horizon string `json:"forecast_horizon"`
+	// Lowercase sha256 hex of the forecast's inputs.
+	InputDigest string `json:"input_digest"`
+}
+
+func validateForecast(e *LedgerEvent) error {
+	typed := e.EventType == "FORECAST" && schemaMajor(e.SchemaVer) >= minForecastSchema
+	if e.Forecast == nil {
+		if typed {
+			return fmt.Errorf("forecast required on FORECAST events from schema_version v%d", minForecastSchema)
+		}
+		return nil
+	}
+	if !typed {
+		return fmt.Errorf("forecast may only be set on FORECAST events from schema_version v%d", minForecastSchema)
+	}
+	f := e.Forecast
+	if !modelIDRe.MatchString(f.ModelID) {
+		return fmt.Errorf("forecast must have a model_id of 1-128 characters A-Z, a-z, 0-9, '.', '_', ':', '/', '@', '+' or '-'")
+	}
+	if len(f.Horizon) > 32 || !forecastHorizonRe.MatchString(f.Horizon) || f.Horizon == "P" || strings.HasSuffix(f.Horizon, "T") {
+		return fmt.Errorf("forecast must have a forecast_horizon that is an ISO 8601 duration such as P30D")
+	}
+	if !validate.SHA256.MatchString(f.InputDigest) {
+		return fmt.Errorf("forecast must have an input_digest of lowercase sha256 hex")
+	}
+	return nil
+}
+
+type EventList struct {
+	Records   []EventView `json:"records"`
+	Truncated bool        `json:"truncated"`
+}
+
+func confidenceKey(c float64) string {
+	return fmt.Sprintf("%.6f", c)
+}
+
+func validateConfidence(e *LedgerEvent, cfg *ContractConfig) error {
+	if e.Confidence == nil {
+		if cfg.RequireForecastConfidence && e.EventType == "FORECAST" {
+			return fmt.Errorf("invalid_confidence: FORECAST events require confidence")
+		}
+		return nil
+	}
+	if *e.Confidence < 0 || *e.Confidence > 1 {
+		return fmt.Errorf("invalid_confidence: must be within [0,1]")
+	}
+	return nil
+}
+
+// GetForecastsByConfidenceRange returns FORECAST events whose confidence lies
+// in [min, max], ordered by confidence. Matching is done at the index's six
+// decimal places; at most max_query_results records are returned.
+func (c *AuditLogContract) GetForecastsByConfidenceRange(ctx contractapi.TransactionContextInterface, min float64, max float64, projection string) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if min < 0 || max > 1 {
+		return "", fmt.Errorf("confidence bounds must be within [0,1]")
+	}
+	if min > max {
+		return "", fmt.Errorf("min must be <= max")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	stub := ctx.GetStub()
+	startKey, err := indexKey(stub, forecastConfidenceIndex, []string{confidenceKey(min)})
+	if err != nil {
+		return "", err
+	}
+	endKey, err := indexKey(stub, forecastConfidenceIndex, []string{confidenceKey(max)})
+	if err != nil {
+		return "", err
+	}
+	// Extend the end bound past every id under max so the range is inclusive.
+	it, err := stub.GetStateByRange(startKey, endKey+string(rune(0x10FFFF)))
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	list := EventList{Records: []EventView{}}
+	for it.HasNext() {
+		if len(list.Records) == cfg.maxQueryResults() {
+			list.Truncated = true
+			break
+		}
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		stored, err := getStoredEvent(ctx, string(kv.Value))
+		if err != nil {
+			return "", err
+		}
+		if list.Records, err = appendView(ctx, list.Records, stored); err != nil {
+			return "", err
+		}
+	}
+	return marshalList(&list, projection)
+}
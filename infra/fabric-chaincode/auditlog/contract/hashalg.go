@@ -0,0 +1,101 @@
+package contract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// artifactHashers maps each accepted hash_algorithm to its digest function.
+// An empty hash_algorithm means sha256.
+var artifactHashers = map[string]func([]byte) []byte{
+	"sha256":      func(b []byte) []byte { sum := sha256.Sum256(b); return sum[:] },
+	"sha3-256":    func(b []byte) []byte { sum := sha3.Sum256(b); return sum[:] },
+	"blake2b-256": func(b []byte) []byte { sum := blake2b.Sum256(b); return sum[:] },
+}
+
+// artifactAlgorithm returns e's hash_algorithm with the default applied.
+func artifactAlgorithm(e *LedgerEvent) string {
+	if e.HashAlgorithm == "" {
+		return "sha256"
+	}
+	return e.HashAlgorithm
+}
+
+// validateArtifactHash checks artifact_hash is a lowercase hex digest of the
+// length its algorithm produces.
+func validateArtifactHash(e *LedgerEvent) error {
+	alg := artifactAlgorithm(e)
+	hasher, ok := artifactHashers[alg]
+	if !ok {
+		return fmt.Errorf("unsupported hash_algorithm; expected sha256, sha3-256 or blake2b-256")
+	}
+	want := 2 * len(hasher(nil))
+	if len(e.ArtifactHash) != want {
+		return fmt.Errorf("artifact_hash must be %d hex chars for %s", want, alg)
+	}
+	if !validate.SHA256.MatchString(e.ArtifactHash) {
+		return fmt.Errorf("artifact_hash must be lowercase %s hex", alg)
+	}
+	return nil
+}
+
+// normalizeHex lower-cases h and strips a 0x prefix.
+func normalizeHex(h string) string {
+	if len(h) > 2 && (h[:2] == "0x" || h[:2] == "0X") {
+		h = h[2:]
+	}
+	return strings.ToLower(h)
+}
+
+// normalizeArtifactHashes rewrites e's artifact hashes to lowercase hex
+// and returns the submitted form of those it changed, keyed by field.
+func normalizeArtifactHashes(e *LedgerEvent) map[string]string {
+	var orig map[string]string
+	note := func(field, from string) {
+		if orig == nil {
+			orig = map[string]string{}
+		}
+		orig[field] = from
+	}
+	if n := normalizeHex(e.ArtifactHash); n != e.ArtifactHash {
+		note("artifact_hash", e.ArtifactHash)
+		e.ArtifactHash = n
+	}
+	if len(e.Artifacts) > 0 {
+		e.Artifacts = append([]EventArtifact(nil), e.Artifacts...)
+	}
+	for i, a := range e.Artifacts {
+		if n := normalizeHex(a.ArtifactHash); n != a.ArtifactHash {
+			note(fmt.Sprintf("artifacts[%d]", i), a.ArtifactHash)
+			e.Artifacts[i].ArtifactHash = n
+		}
+	}
+	return orig
+}
+
+// artifactHashArg applies normalize_artifact_hashes to a hash passed to a
+// query or registry transaction, so lookups find what writes stored.
+func artifactHashArg(ctx contractapi.TransactionContextInterface, h string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cfg.NormalizeArtifactHashes {
+		h = normalizeHex(h)
+	}
+	return h, nil
+}
+
+// artifactDigest hashes b with e's artifact algorithm.
+func artifactDigest(e *LedgerEvent, b []byte) string {
+	return hex.EncodeToString(artifactHashers[artifactAlgorithm(e)](b))
+}
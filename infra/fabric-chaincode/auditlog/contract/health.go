@@ -0,0 +1,140 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+type HealthReport struct {
+	Status    string `json:"status"`
+	ChannelID string `json:"channel_id"`
+	TxID      string `json:"tx_id"`
+	TxTime    string `json:"tx_time"`
+	// ConfigInitialized is false until Init has run on the channel.
+	ConfigInitialized bool `json:"config_initialized"`
+}
+
+// HealthCheck is an open, read-only round trip through the chaincode: it
+// proves the peer can reach the container and that the channel config
+// loads. Smoke tests evaluate it before their first PutEvent.
+func (c *AuditLogContract) HealthCheck(ctx contractapi.TransactionContextInterface) (string, error) {
+	stub := ctx.GetStub()
+	b, err := stub.GetState(configKey)
+	if err == nil && b != nil {
+		err = json.Unmarshal(b, &ContractConfig{})
+		if err != nil {
+			err = fmt.Errorf("corrupt config")
+		}
+	}
+	health.observe(err)
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	return marshalString(HealthReport{
+		Status:            "ok",
+		ChannelID:         stub.GetChannelID(),
+		TxID:              stub.GetTxID(),
+		TxTime:            now.Format(time.RFC3339Nano),
+		ConfigInitialized: b != nil,
+	})
+}
+
+// healthState backs the service-mode probes. The chaincode cannot call the
+// peer on its own, so readiness rests on the listener accepting connections
+// and on the outcome of the most recent HealthCheck, if any ran.
+type healthState struct {
+	mu         sync.Mutex
+	address    string
+	stopped    error
+	lastInvoke time.Time
+	lastCheck  time.Time
+	checkErr   error
+}
+
+var health = &healthState{}
+
+func (h *healthState) invoked() {
+	h.mu.Lock()
+	h.lastInvoke = time.Now()
+	h.mu.Unlock()
+}
+
+func (h *healthState) observe(err error) {
+	h.mu.Lock()
+	h.lastCheck, h.checkErr = time.Now(), err
+	h.mu.Unlock()
+}
+
+func (h *healthState) serving(address string) {
+	h.mu.Lock()
+	h.address = address
+	h.mu.Unlock()
+}
+
+func (h *healthState) stop(err error) {
+	h.mu.Lock()
+	h.stopped = err
+	h.mu.Unlock()
+}
+
+type probeReport struct {
+	Status     string `json:"status"`
+	Reason     string `json:"reason,omitempty"`
+	LastInvoke string `json:"last_invoke,omitempty"`
+	LastCheck  string `json:"last_health_check,omitempty"`
+}
+
+func (h *healthState) report(ready bool) (int, probeReport) {
+	h.mu.Lock()
+	address, stopped := h.address, h.stopped
+	lastInvoke, lastCheck, checkErr := h.lastInvoke, h.lastCheck, h.checkErr
+	h.mu.Unlock()
+
+	r := probeReport{Status: "ok"}
+	if !lastInvoke.IsZero() {
+		r.LastInvoke = lastInvoke.UTC().Format(time.RFC3339)
+	}
+	if !lastCheck.IsZero() {
+		r.LastCheck = lastCheck.UTC().Format(time.RFC3339)
+	}
+	fail := func(reason string) (int, probeReport) {
+		r.Status, r.Reason = "unavailable", reason
+		return http.StatusServiceUnavailable, r
+	}
+	switch {
+	case stopped != nil:
+		return fail(fmt.Sprintf("chaincode server stopped: %v", stopped))
+	case !ready:
+		return http.StatusOK, r
+	case address == "":
+		return fail("chaincode server not started")
+	case checkErr != nil:
+		return fail(fmt.Sprintf("health check failed: %v", checkErr))
+	}
+	conn, err := net.DialTimeout("tcp", address, time.Second)
+	if err != nil {
+		return fail(fmt.Sprintf("chaincode listener unreachable: %v", err))
+	}
+	conn.Close()
+	return http.StatusOK, r
+}
+
+// probeHandler serves /healthz (ready false) and /readyz (ready true).
+func probeHandler(ready bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		status, r := health.report(ready)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(r)
+	}
+}
@@ -0,0 +1,84 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+type HistoryEntry struct {
+	TxID      string          `json:"tx_id"`
+	Timestamp string          `json:"timestamp"`
+	IsDelete  bool            `json:"is_delete"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	// The value predates a governed deletion and is withheld; see
+	// deletion.go.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// redactDeleted withholds every value but the tombstones once an event was
+// deleted.
+func redactDeleted(entries []HistoryEntry) {
+	deleted := false
+	tombstone := make([]bool, len(entries))
+	for i, entry := range entries {
+		var rec struct {
+			Deletion *DeletionRecord `json:"deletion"`
+		}
+		if entry.Value != nil && json.Unmarshal(entry.Value, &rec) == nil && rec.Deletion != nil {
+			deleted, tombstone[i] = true, true
+		}
+	}
+	if !deleted {
+		return
+	}
+	for i := range entries {
+		if !tombstone[i] && entries[i].Value != nil {
+			entries[i].Value, entries[i].Redacted = nil, true
+		}
+	}
+}
+
+// GetEventHistory returns every committed value of an event's key, newest
+// first as Fabric 2.x reports them, so auditors can show it was never
+// modified after the initial write, other than by MigrateState. Requires
+// the peer's history database.
+func (c *AuditLogContract) GetEventHistory(ctx contractapi.TransactionContextInterface, eventID string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	ref, err := resolveRef(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	it, err := ctx.GetStub().GetHistoryForKey(eventKey(ref))
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	entries := []HistoryEntry{}
+	for it.HasNext() {
+		m, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		entry := HistoryEntry{TxID: m.TxId, IsDelete: m.IsDelete}
+		if m.Timestamp != nil {
+			entry.Timestamp = m.Timestamp.AsTime().UTC().Format(time.RFC3339Nano)
+		}
+		if !m.IsDelete && len(m.Value) > 0 {
+			entry.Value = json.RawMessage(m.Value)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("not_found")
+	}
+	redactDeleted(entries)
+	return marshalString(entries)
+}
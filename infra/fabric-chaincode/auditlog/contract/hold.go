@@ -0,0 +1,292 @@
+package contract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// A legal hold covers listed events or a timestamp range [start, end).
+// While active it blocks revocation and archival of the events it covers. Released holds keep their record; only the lookup indexes go.
+const (
+	holdKeyPrefix  = "legal_hold:"
+	holdEventIndex = "hold~ref~id"
+	holdRangeIndex = "hold_range~id"
+	maxHoldEvents  = 500
+)
+
+var holdIDRe = regexp.MustCompile("^[A-Za-z0-9._-]{1,64}$")
+
+type HoldScope struct {
+	EventIDs []string `json:"event_ids,omitempty"`
+	Start    string   `json:"start,omitempty"`
+	End      string   `json:"end,omitempty"`
+}
+
+type LegalHold struct {
+	HoldID      string    `json:"hold_id"`
+	Scope       HoldScope `json:"scope"`
+	Reason      string    `json:"reason"`
+	PlacedByMSP string    `json:"placed_by_msp"`
+	PlacedAt    string    `json:"placed_at"`
+	// Refs are the resolved keys of Scope.EventIDs; see namespace.go.
+	Refs       []string `json:"refs,omitempty"`
+	Active     bool     `json:"active"`
+	ReleasedAt string   `json:"released_at,omitempty"`
+	ReleasedBy string   `json:"released_by_msp,omitempty"`
+}
+
+func getLegalHold(ctx contractapi.TransactionContextInterface, holdID string) (*LegalHold, error) {
+	b, err := ctx.GetStub().GetState(holdKeyPrefix + holdID)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var h LegalHold
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, fmt.Errorf("corrupt legal hold")
+	}
+	return &h, nil
+}
+
+func putLegalHold(ctx contractapi.TransactionContextInterface, h *LegalHold) error {
+	out, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(holdKeyPrefix+h.HoldID, out)
+}
+
+// holdIndexKeys lists the lookup index keys of h.
+func holdIndexKeys(ctx contractapi.TransactionContextInterface, h *LegalHold) ([]string, error) {
+	stub := ctx.GetStub()
+	if len(h.Refs) == 0 {
+		key, err := stub.CreateCompositeKey(holdRangeIndex, []string{h.HoldID})
+		if err != nil {
+			return nil, err
+		}
+		return []string{key}, nil
+	}
+	keys := make([]string, 0, len(h.Refs))
+	for _, ref := range h.Refs {
+		key, err := stub.CreateCompositeKey(holdEventIndex, []string{ref, h.HoldID})
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// activeHoldFor returns the id of an active hold covering stored, or "".
+func activeHoldFor(ctx contractapi.TransactionContextInterface, stored *StoredEvent) (string, error) {
+	stub := ctx.GetStub()
+	it, err := stub.GetStateByPartialCompositeKey(holdEventIndex, []string{stored.ref()})
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+	if it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		return string(kv.Value), nil
+	}
+
+	ranges, err := stub.GetStateByPartialCompositeKey(holdRangeIndex, []string{})
+	if err != nil {
+		return "", err
+	}
+	defer ranges.Close()
+	ts, err := indexTimestamp(stored.Event.TimestampUTC)
+	if err != nil {
+		return "", err
+	}
+	for ranges.HasNext() {
+		kv, err := ranges.Next()
+		if err != nil {
+			return "", err
+		}
+		h, err := getLegalHold(ctx, string(kv.Value))
+		if err != nil {
+			return "", err
+		}
+		if h == nil {
+			continue
+		}
+		start, end, err := parseTimeRange(h.Scope.Start, h.Scope.End)
+		if err != nil {
+			return "", err
+		}
+		if ts >= start && ts < end {
+			return h.HoldID, nil
+		}
+	}
+	return "", nil
+}
+
+// requireNoHold fails when an active legal hold covers stored.
+func requireNoHold(ctx contractapi.TransactionContextInterface, stored *StoredEvent) error {
+	holdID, err := activeHoldFor(ctx, stored)
+	if err != nil {
+		return err
+	}
+	if holdID != "" {
+		return fmt.Errorf("legal_hold_active: %s", holdID)
+	}
+	return nil
+}
+
+// PlaceLegalHold places hold holdID over the events or range in scopeJSON,
+// either {"event_ids": [...]} or {"start": RFC3339, "end": RFC3339}.
+func (c *AuditLogContract) PlaceLegalHold(ctx contractapi.TransactionContextInterface, holdID string, scopeJSON string, reason string) (string, error) {
+	if !holdIDRe.MatchString(holdID) {
+		return "", fmt.Errorf("hold_id must match %s", holdIDRe)
+	}
+	if reason == "" || len(reason) > maxJustificationLen {
+		return "", fmt.Errorf("reason must be 1 to %d bytes", maxJustificationLen)
+	}
+	var scope HoldScope
+	dec := json.NewDecoder(bytes.NewReader([]byte(scopeJSON)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&scope); err != nil {
+		return "", fmt.Errorf("invalid scope: %v", err)
+	}
+	switch {
+	case len(scope.EventIDs) > 0 && (scope.Start != "" || scope.End != ""):
+		return "", fmt.Errorf("scope takes event_ids or start/end, not both")
+	case len(scope.EventIDs) > maxHoldEvents:
+		return "", fmt.Errorf("scope may list at most %d event_ids", maxHoldEvents)
+	case len(scope.EventIDs) > 0:
+		for i, id := range scope.EventIDs {
+			if !validate.UUID.MatchString(id) {
+				return "", fmt.Errorf("invalid event_id at index %d", i)
+			}
+		}
+	default:
+		if _, _, err := parseTimeRange(scope.Start, scope.End); err != nil {
+			return "", err
+		}
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	existing, err := getLegalHold(ctx, holdID)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return "", errors.New("legal_hold_exists")
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	h := &LegalHold{
+		HoldID:      holdID,
+		Scope:       scope,
+		Reason:      reason,
+		PlacedByMSP: mspID,
+		PlacedAt:    now.Format(time.RFC3339Nano),
+		Active:      true,
+	}
+	for _, id := range scope.EventIDs {
+		ref, err := callerRef(ctx, cfg, id)
+		if err != nil {
+			return "", err
+		}
+		if _, err := getStoredEvent(ctx, ref); err != nil {
+			return "", err
+		}
+		h.Refs = append(h.Refs, ref)
+	}
+	keys, err := holdIndexKeys(ctx, h)
+	if err != nil {
+		return "", err
+	}
+	for _, key := range keys {
+		if err := ctx.GetStub().PutState(key, []byte(holdID)); err != nil {
+			return "", err
+		}
+	}
+	if err := putLegalHold(ctx, h); err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
+
+// ReleaseLegalHold deactivates a hold. The record stays for audit.
+func (c *AuditLogContract) ReleaseLegalHold(ctx contractapi.TransactionContextInterface, holdID string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	h, err := getLegalHold(ctx, holdID)
+	if err != nil {
+		return "", err
+	}
+	if h == nil {
+		return "", fmt.Errorf("not_found")
+	}
+	if !h.Active {
+		return "", errors.New("legal_hold_released")
+	}
+	keys, err := holdIndexKeys(ctx, h)
+	if err != nil {
+		return "", err
+	}
+	for _, key := range keys {
+		if err := ctx.GetStub().DelState(key); err != nil {
+			return "", err
+		}
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	h.Active = false
+	h.ReleasedAt = now.Format(time.RFC3339Nano)
+	h.ReleasedBy = mspID
+	if err := putLegalHold(ctx, h); err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
+
+func (c *AuditLogContract) GetLegalHold(ctx contractapi.TransactionContextInterface, holdID string) (string, error) {
+	h, err := getLegalHold(ctx, holdID)
+	if err != nil {
+		return "", err
+	}
+	if h == nil {
+		return "", fmt.Errorf("not_found")
+	}
+	return marshalString(h)
+}
@@ -0,0 +1,84 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldDiff is one event field whose value differs between the stored event
+// and a resubmission under the same event_id. A nil side means the field is
+// absent there.
+type FieldDiff struct {
+	Field     string `json:"field"`
+	Stored    any    `json:"stored"`
+	Submitted any    `json:"submitted"`
+}
+
+// idempotencyViolation reports a resubmitted event_id whose payload differs
+// from the stored one, with the differing fields and both payload hashes in
+// the error's details.
+func idempotencyViolation(prior *StoredEvent, e *LedgerEvent, hash string) error {
+	diff, err := diffEvents(&prior.Event, e)
+	if err != nil {
+		return err
+	}
+	fields := make([]string, len(diff))
+	for i, d := range diff {
+		fields[i] = d.Field
+	}
+	msg := "idempotency_violation: event_id exists with different payload"
+	if len(fields) > 0 {
+		msg += fmt.Sprintf(" (%s differ)", strings.Join(fields, ", "))
+	}
+	return &detailError{msg: msg, details: map[string]any{
+		"stored_payload_hash":    prior.PayloadHash,
+		"submitted_payload_hash": hash,
+		"differing_fields":       diff,
+	}}
+}
+
+// diffEvents compares the JSON fields of two events, sorted by name. Both
+// have been through the write path's normalization, so only fields that
+// change the payload hash are listed.
+func diffEvents(stored, submitted *LedgerEvent) ([]FieldDiff, error) {
+	a, err := eventFields(stored)
+	if err != nil {
+		return nil, err
+	}
+	b, err := eventFields(submitted)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		names = append(names, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+	diff := []FieldDiff{}
+	for _, k := range names {
+		if !reflect.DeepEqual(a[k], b[k]) {
+			diff = append(diff, FieldDiff{Field: k, Stored: a[k], Submitted: b[k]})
+		}
+	}
+	return diff, nil
+}
+
+func eventFields(e *LedgerEvent) (map[string]any, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,412 @@
+package contract
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+// Secondary indexes are composite keys whose value is the event ref (see
+// namespace.go); the event itself is read back from its primary key.
+//
+// The shim refuses composite keys, which start with 0x00, as
+// GetStateByRange bounds. Indexes read by time or value range therefore
+// keep the composite layout behind rangeKeyNamespace instead, a first byte
+// the shim accepts; SplitCompositeKey parses those keys unchanged. Use
+// indexKey and scanIndexPrefix rather than the shim's composite key calls
+// for any index.
+const (
+	tsIndex             = "ts~id"
+	typeTsIndex         = "type~ts~id"
+	artifactIndex       = "artifact~id"
+	typeArtifactTsIndex = "type~artifact~ts~id"
+	schemaTsIndex       = "schema~ts~id"
+	producerTsIndex     = "producer~ts~id"
+
+	// Fixed-width UTC layout so index keys sort chronologically.
+	tsIndexLayout = "2006-01-02T15:04:05.000000000Z"
+
+	rangeKeyNamespace = "\x01"
+)
+
+// rangeIndexes are the indexes kept under rangeKeyNamespace, from
+// storage_version 3; see migrate.go.
+var rangeIndexes = map[string]bool{
+	tsIndex:                 true,
+	typeTsIndex:             true,
+	bucketIndex:             true,
+	ingestSourceWindowIndex: true,
+	forecastConfidenceIndex: true,
+}
+
+// indexKey is the state key of the entry attrs of index.
+func indexKey(stub shim.ChaincodeStubInterface, index string, attrs []string) (string, error) {
+	key, err := stub.CreateCompositeKey(index, attrs)
+	if err != nil || !rangeIndexes[index] {
+		return key, err
+	}
+	return rangeKeyNamespace + key[1:], nil
+}
+
+// indexPrefixRange returns the key bounds of the entries of index whose
+// attributes start with attrs.
+func indexPrefixRange(stub shim.ChaincodeStubInterface, index string, attrs []string) (string, string, error) {
+	start, err := indexKey(stub, index, attrs)
+	if err != nil {
+		return "", "", err
+	}
+	return start, start + string(utf8.MaxRune), nil
+}
+
+// scanIndexPrefix iterates the entries of index whose attributes start
+// with attrs, in key order.
+func scanIndexPrefix(stub shim.ChaincodeStubInterface, index string, attrs []string) (shim.StateQueryIteratorInterface, error) {
+	if !rangeIndexes[index] {
+		return stub.GetStateByPartialCompositeKey(index, attrs)
+	}
+	start, end, err := indexPrefixRange(stub, index, attrs)
+	if err != nil {
+		return nil, err
+	}
+	return stub.GetStateByRange(start, end)
+}
+
+// pageIndexPrefix is scanIndexPrefix one page at a time.
+func pageIndexPrefix(stub shim.ChaincodeStubInterface, index string, attrs []string, pageSize int32, bookmark string) (shim.StateQueryIteratorInterface, *peer.QueryResponseMetadata, error) {
+	if !rangeIndexes[index] {
+		return stub.GetStateByPartialCompositeKeyWithPagination(index, attrs, pageSize, bookmark)
+	}
+	start, end, err := indexPrefixRange(stub, index, attrs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stub.GetStateByRangeWithPagination(start, end, pageSize, bookmark)
+}
+
+// distinctFieldIndexes maps the fields GetDistinctValues accepts to the index
+// whose leading attribute holds that field's value.
+var distinctFieldIndexes = map[string]string{
+	"event_type":     typeTsIndex,
+	"schema_version": schemaTsIndex,
+	"producer":       producerTsIndex,
+}
+
+func indexTimestamp(ts string) (string, error) {
+	t, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return "", err
+	}
+	return t.UTC().Format(tsIndexLayout), nil
+}
+
+type indexEntry struct {
+	objectType string
+	attrs      []string
+}
+
+// indexEntries lists every secondary index key for a stored event. Each
+// key ends in the event's ref, so events sharing an event_id under
+// id_namespacing_by_producer keep separate entries.
+func indexEntries(stored *StoredEvent) ([]indexEntry, error) {
+	return indexEntriesFor(stored, stored.ref())
+}
+
+// legacyIndexEntries lists the keys indexEntries returned before
+// storage_version 4, which ended in the bare event_id; entries line up
+// with indexEntries one for one.
+func legacyIndexEntries(stored *StoredEvent) ([]indexEntry, error) {
+	return indexEntriesFor(stored, stored.Event.EventID)
+}
+
+func indexEntriesFor(stored *StoredEvent, id string) ([]indexEntry, error) {
+	e, producer := &stored.Event, stored.CreatorMSP
+	ts, err := indexTimestamp(e.TimestampUTC)
+	if err != nil {
+		return nil, err
+	}
+	entries := []indexEntry{
+		{tsIndex, []string{ts, id}},
+		{typeTsIndex, []string{e.EventType, ts, id}},
+		{artifactIndex, []string{e.ArtifactHash, id}},
+		{typeArtifactTsIndex, []string{e.EventType, e.ArtifactHash, ts, id}},
+		{schemaTsIndex, []string{e.SchemaVer, ts, id}},
+		{producerTsIndex, []string{producer, ts, id}},
+		{payloadHashIndex, []string{stored.PayloadHash, id}},
+	}
+	for _, p := range e.ParentEventIDs {
+		entries = append(entries, indexEntry{lineageIndex, []string{eventRef(stored.Producer, p, stored.TimeOrderedKeys), stored.ref()}})
+	}
+	for _, a := range e.Artifacts {
+		entries = append(entries, indexEntry{artifactRoleIndex, []string{a.ArtifactHash, a.Role, id}})
+	}
+	if e.CorrelationID != "" {
+		entries = append(entries, indexEntry{correlationTsIndex, []string{e.CorrelationID, ts, id}})
+	}
+	if e.Ingest != nil {
+		window, err := indexTimestamp(e.Ingest.BatchWindowStart)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, indexEntry{ingestSourceWindowIndex, []string{e.Ingest.SourceSystem, window, id}})
+	}
+	if e.Decision != nil {
+		entries = append(entries, indexEntry{decisionAgentTsIndex, []string{e.Decision.AgentID, ts, id}})
+	}
+	if e.EventType == "FORECAST" && e.Confidence != nil {
+		entries = append(entries, indexEntry{forecastConfidenceIndex, []string{confidenceKey(*e.Confidence), id}})
+	}
+	return entries, nil
+}
+
+func writeIndexes(ctx contractapi.TransactionContextInterface, stored *StoredEvent) error {
+	stub := ctx.GetStub()
+	entries, err := indexEntries(stored)
+	if err != nil {
+		return err
+	}
+	ref := []byte(stored.ref())
+	for _, ie := range entries {
+		key, err := indexKey(stub, ie.objectType, ie.attrs)
+		if err != nil {
+			return err
+		}
+		if err := stub.PutState(key, ref); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firstEventForArtifact returns any one event already indexed under
+// artifactHash, or nil when the artifact has not been seen.
+func firstEventForArtifact(ctx contractapi.TransactionContextInterface, artifactHash string) (*StoredEvent, error) {
+	stub := ctx.GetStub()
+	it, err := stub.GetStateByPartialCompositeKey(artifactIndex, []string{artifactHash})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	if !it.HasNext() {
+		return nil, nil
+	}
+	kv, err := it.Next()
+	if err != nil {
+		return nil, err
+	}
+	return getStoredEvent(ctx, string(kv.Value))
+}
+
+// earliestRefForTypeArtifact returns the ref of the earliest event of
+// eventType referencing artifactHash.
+func earliestRefForTypeArtifact(ctx contractapi.TransactionContextInterface, eventType, artifactHash string) (string, error) {
+	stub := ctx.GetStub()
+	it, err := stub.GetStateByPartialCompositeKey(typeArtifactTsIndex, []string{eventType, artifactHash})
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+	if !it.HasNext() {
+		return "", nil
+	}
+	kv, err := it.Next()
+	if err != nil {
+		return "", err
+	}
+	return string(kv.Value), nil
+}
+
+type DistinctValue struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+// distinctLeadingValues counts index entries by their first attribute.
+func distinctLeadingValues(ctx contractapi.TransactionContextInterface, index string) ([]DistinctValue, error) {
+	stub := ctx.GetStub()
+	it, err := scanIndexPrefix(stub, index, []string{})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	counts := map[string]int{}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		_, attrs, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		if len(attrs) == 0 {
+			return nil, fmt.Errorf("corrupt index key in %s", index)
+		}
+		counts[attrs[0]]++
+	}
+
+	values := make([]DistinctValue, 0, len(counts))
+	for v, n := range counts {
+		values = append(values, DistinctValue{Value: v, Count: n})
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i].Value < values[j].Value })
+	return values, nil
+}
+
+// sortedTypes lists the accepted event types in a stable order.
+func sortedTypes() []string {
+	types := make([]string, 0, len(typeSet))
+	for t := range typeSet {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// parseTimeRange validates a half-open [start, end) RFC3339 range and returns
+// both bounds in index layout.
+func parseTimeRange(startRFC3339, endRFC3339 string) (string, string, error) {
+	start, err := time.Parse(time.RFC3339, startRFC3339)
+	if err != nil {
+		return "", "", fmt.Errorf("start must be RFC3339")
+	}
+	end, err := time.Parse(time.RFC3339, endRFC3339)
+	if err != nil {
+		return "", "", fmt.Errorf("end must be RFC3339")
+	}
+	if !start.Before(end) {
+		return "", "", fmt.Errorf("start must be before end")
+	}
+	return start.UTC().Format(tsIndexLayout), end.UTC().Format(tsIndexLayout), nil
+}
+
+// scanTimeRange visits index entries whose attributes are prefix followed by
+// a timestamp in [start, end), in timestamp order. It stops after limit
+// entries and reports whether more remained.
+func scanTimeRange(ctx contractapi.TransactionContextInterface, index string, prefix []string, start, end string, limit int, visit func(ts, ref string) error) (bool, error) {
+	startKey, endKey, err := timeRangeKeys(ctx, index, prefix, start, end)
+	if err != nil {
+		return false, err
+	}
+	return scanKeyRange(ctx, startKey, endKey, len(prefix), limit, func(_, ts, ref string) error {
+		return visit(ts, ref)
+	})
+}
+
+// scanKeyRange is scanTimeRange over the keys in [startKey, endKey), with
+// the timestamp the attribute after the first prefixLen; visit also gets
+// each entry's key, for resuming after it.
+func scanKeyRange(ctx contractapi.TransactionContextInterface, startKey, endKey string, prefixLen, limit int, visit func(key, ts, ref string) error) (bool, error) {
+	stub := ctx.GetStub()
+	it, err := stub.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return false, err
+	}
+	defer it.Close()
+
+	n := 0
+	for it.HasNext() {
+		if n == limit {
+			return true, nil
+		}
+		kv, err := it.Next()
+		if err != nil {
+			return false, err
+		}
+		_, attrs, err := stub.SplitCompositeKey(kv.Key)
+		if err != nil {
+			return false, err
+		}
+		if err := visit(kv.Key, attrs[prefixLen], string(kv.Value)); err != nil {
+			return false, err
+		}
+		n++
+	}
+	return false, nil
+}
+
+// pageIndexRange reads one page of index entries in [startKey, endKey) and
+// resolves each to its StoredEvent.
+func pageIndexRange(ctx contractapi.TransactionContextInterface, startKey, endKey, bookmark string, pageSize int32) (*EventPage, error) {
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return nil, err
+	}
+	it, meta, err := ctx.GetStub().GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	page := &EventPage{Records: []EventView{}}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		ref, err := entryRef(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		stored, err := getStoredEvent(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		if page.Records, err = appendView(ctx, page.Records, stored); err != nil {
+			return nil, err
+		}
+	}
+	page.FetchedCount = meta.FetchedRecordsCount
+	page.Bookmark = meta.Bookmark
+	return page, nil
+}
+
+// timeRangeKeys returns the [start, end) key bounds of a time range within
+// an index whose attributes are prefix followed by a timestamp.
+func timeRangeKeys(ctx contractapi.TransactionContextInterface, index string, prefix []string, start, end string) (string, string, error) {
+	stub := ctx.GetStub()
+	startKey, err := indexKey(stub, index, append(append([]string{}, prefix...), start))
+	if err != nil {
+		return "", "", err
+	}
+	endKey, err := indexKey(stub, index, append(append([]string{}, prefix...), end))
+	if err != nil {
+		return "", "", err
+	}
+	return startKey, endKey, nil
+}
+
+// pageByIndex returns one page of the events under the partial key attrs of
+// index, in key order.
+func pageByIndex(ctx contractapi.TransactionContextInterface, index string, attrs []string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	it, meta, err := pageIndexPrefix(ctx.GetStub(), index, attrs, pageSize, bookmark)
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	page := EventPage{Records: []EventView{}}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		stored, err := getStoredEvent(ctx, string(kv.Value))
+		if err != nil {
+			return "", err
+		}
+		if page.Records, err = appendView(ctx, page.Records, stored); err != nil {
+			return "", err
+		}
+	}
+	page.FetchedCount = meta.FetchedRecordsCount
+	page.Bookmark = meta.Bookmark
+	return marshalPage(&page, projection)
+}
@@ -0,0 +1,174 @@
+package contract
+
+import (
+	"sort"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// contractVersion names the build; release images set it with
+// -ldflags "-X payscope/auditlog/contract.contractVersion=<version>".
+var contractVersion = "dev"
+
+// ContractInfo is what clients check at startup instead of assuming a
+// contract build and channel config.
+type ContractInfo struct {
+	ContractVersion string `json:"contract_version"`
+	StorageVersion  int    `json:"storage_version"`
+	ChannelID       string `json:"channel_id"`
+	// ConfigInitialized is false until Init has run on the channel.
+	ConfigInitialized bool `json:"config_initialized"`
+	// StateDatabase is the peer's state database, leveldb or couchdb;
+	// Features["rich_queries"] is set on couchdb.
+	StateDatabase string `json:"state_database"`
+	// SchemaVersions lists the schema_version values a write may use,
+	// unless AnySchemaVersion is set, when any non-empty one is accepted.
+	SchemaVersions           []string `json:"schema_versions"`
+	AnySchemaVersion         bool     `json:"any_schema_version"`
+	DeprecatedSchemaVersions []string `json:"deprecated_schema_versions"`
+	// EventTypes is ListEventTypes' answer; ReservedEventTypes are the ones
+	// only the contract writes.
+	EventTypes         []EventTypeRecord `json:"event_types"`
+	ReservedEventTypes []string          `json:"reserved_event_types"`
+	Collections        []string          `json:"collections"`
+	Features           map[string]bool   `json:"features"`
+	Limits             ContractLimits    `json:"limits"`
+	// Transactions the chaincode serves, by unqualified name.
+	Transactions []string `json:"transactions"`
+	// Namespaces lists the transactions of each named contract, called as
+	// "<namespace>:<tx>".
+	Namespaces map[string][]string `json:"namespaces"`
+}
+
+// ContractLimits are the effective size and query limits, defaults
+// applied.
+type ContractLimits struct {
+	MaxEventBytes   int   `json:"max_event_bytes"`
+	MaxBatchEvents  int   `json:"max_batch_events"`
+	MaxPageSize     int32 `json:"max_page_size"`
+	MaxQueryResults int   `json:"max_query_results"`
+	MaxDigestLeaves int   `json:"max_digest_leaves"`
+}
+
+// GetContractInfo reports the contract build and the capabilities the
+// channel config turns on.
+func (c *AuditLogContract) GetContractInfo(ctx contractapi.TransactionContextInterface) (string, error) {
+	stub := ctx.GetStub()
+	b, err := stub.GetState(configKey)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	db, err := stateDatabase(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	info := ContractInfo{
+		ContractVersion:          contractVersion,
+		StorageVersion:           storageVersion,
+		ChannelID:                stub.GetChannelID(),
+		ConfigInitialized:        b != nil,
+		StateDatabase:            db,
+		SchemaVersions:           []string{},
+		DeprecatedSchemaVersions: []string{},
+		ReservedEventTypes:       []string{},
+		Collections:              []string{},
+		Features:                 cfg.features(),
+		Limits: ContractLimits{
+			MaxEventBytes:   cfg.maxEventBytes(),
+			MaxBatchEvents:  cfg.maxBatchEvents(),
+			MaxPageSize:     cfg.maxPageSize(),
+			MaxQueryResults: cfg.maxQueryResults(),
+			MaxDigestLeaves: maxDigestLeaves,
+		},
+		Transactions: make([]string, 0, len(txRoles)),
+		Namespaces:   namespacedTransactions(),
+	}
+	info.Features["rich_queries"] = db == stateDBCouchDB
+	if err := schemaVersionInfo(ctx, cfg, &info); err != nil {
+		return "", err
+	}
+	if info.EventTypes, err = listEventTypes(ctx); err != nil {
+		return "", err
+	}
+	for t := range reservedTypes {
+		info.ReservedEventTypes = append(info.ReservedEventTypes, t)
+	}
+	sort.Strings(info.ReservedEventTypes)
+	if cfg.PrivateCollection != "" {
+		info.Collections = append(info.Collections, cfg.PrivateCollection)
+	}
+	for tx := range txRoles {
+		info.Transactions = append(info.Transactions, tx)
+	}
+	sort.Strings(info.Transactions)
+	return marshalString(info)
+}
+
+// schemaVersionInfo combines the two limits on schema_version: the
+// config's schema_version_enum and, once active, the schema registry.
+func schemaVersionInfo(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, info *ContractInfo) error {
+	active, err := ctx.GetStub().GetState(schemaRegistryActive)
+	if err != nil {
+		return err
+	}
+	if active == nil {
+		if len(cfg.SchemaVersionEnum) == 0 {
+			info.AnySchemaVersion = true
+		}
+		info.SchemaVersions = append(info.SchemaVersions, cfg.SchemaVersionEnum...)
+		return nil
+	}
+	schemas, err := listSchemas(ctx)
+	if err != nil {
+		return err
+	}
+	for _, s := range schemas {
+		switch {
+		case !cfg.schemaVersionAllowed(s.Version):
+		case s.Status == "deprecated":
+			info.DeprecatedSchemaVersions = append(info.DeprecatedSchemaVersions, s.Version)
+		default:
+			info.SchemaVersions = append(info.SchemaVersions, s.Version)
+		}
+	}
+	return nil
+}
+
+// features names the optional behaviours the config switches on, by
+// their config keys.
+func (cfg *ContractConfig) features() map[string]bool {
+	ac := &cfg.AccessControl
+	return map[string]bool{
+		"id_namespacing_by_producer":      cfg.IDNamespacingByProducer,
+		"time_ordered_keys":               cfg.TimeOrderedKeys,
+		"timestamp_precision":             cfg.TimestampPrecision != "",
+		"timestamp_offsets":               cfg.TimestampOffsets != "",
+		"unique_constraints":              len(cfg.UniqueConstraints) > 0,
+		"payload_dedup":                   cfg.PayloadDedup != "",
+		"artifact_schema_consistency":     cfg.ArtifactSchemaConsistency,
+		"require_forecast_confidence":     cfg.RequireForecastConfidence,
+		"normalize_event_type":            cfg.normalizesEventType(),
+		"normalize_artifact_hashes":       cfg.NormalizeArtifactHashes,
+		"access_control.enforce":          ac.Enforce,
+		"access_control.enforce_reads":    ac.EnforceReads,
+		"access_control.auditor_listings": ac.AuditorListings,
+		"type_writer_attrs":               len(ac.TypeWriterAttrs) > 0,
+		"private_payloads":                cfg.PrivateCollection != "",
+		"require_encrypted_payloads":      cfg.RequireEncryptedPayloads,
+		"lock_events_to_writer_org":       cfg.LockEventsToWriterOrg,
+		"timestamp_drift_check":           cfg.TimestampDriftToleranceSeconds > 0,
+		"reference_lookup":                cfg.ReferenceChaincode != "",
+		"org_scoped_reads":                cfg.OrgScopedReads,
+		"require_registered_artifacts":    cfg.RequireRegisteredArtifacts,
+		"write_quotas":                    cfg.DailyWriteQuota > 0 || len(cfg.DailyWriteQuotas) > 0,
+		"record_reads":                    cfg.RecordReads,
+		"tsa_tokens":                      len(cfg.TSACertificates) > 0,
+		"require_tsa_token":               cfg.RequireTSAToken,
+		"require_signer_keys":             cfg.RequireSignerKeys,
+		"governed_deletion":               cfg.DeletionApprovals > 0,
+	}
+}
@@ -0,0 +1,63 @@
+package contract_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// The harness models LevelDB state. Unconfigured, GetContractInfo probes
+// it without recording anything; configured, rich queries fail before
+// reaching the peer.
+func TestStateDatabase(t *testing.T) {
+	for _, tc := range []struct {
+		fn, arg string
+	}{
+		{"Init", `{"admin_msps":["Org1MSP"]}`},
+		{"InitLedger", `{"config":{"admin_msps":["Org1MSP"]}}`},
+		{"Init", `{"admin_msps":["Org1MSP"],"state_database":"leveldb"}`},
+		{"InitLedger", `{"config":{"admin_msps":["Org1MSP"],"state_database":"leveldb"}}`},
+	} {
+		h, err := auditlogtest.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		admin := auditlogtest.Admin("Org1MSP")
+		if _, err := h.Submit(admin, tc.fn, tc.arg); err != nil {
+			t.Fatal(err)
+		}
+		if got := h.State("state_database"); got != nil {
+			t.Errorf("%s %s: state_database recorded as %q", tc.fn, tc.arg, got)
+		}
+
+		out, err := h.Evaluate(admin, "GetContractInfo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var info contract.ContractInfo
+		if err := auditlogtest.Unmarshal(out, &info); err != nil {
+			t.Fatal(err)
+		}
+		if info.StateDatabase != "leveldb" || info.Features["rich_queries"] {
+			t.Errorf("%s %s: state_database %q, rich_queries %v; want leveldb without rich queries", tc.fn, tc.arg, info.StateDatabase, info.Features["rich_queries"])
+		}
+
+		_, err = h.Evaluate(admin, "QueryEventsBySelector", `{"event.event_type":"INGEST"}`, "full", "", "10")
+		var txErr *auditlogtest.TxError
+		if !errors.As(err, &txErr) || txErr.Code != "ERR_UNSUPPORTED" || txErr.Details["reason"] != "rich_query_unsupported_on_leveldb" {
+			t.Errorf("%s %s: QueryEventsBySelector err = %v, want rich_query_unsupported_on_leveldb", tc.fn, tc.arg, err)
+		}
+	}
+
+	h, err := auditlogtest.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = h.Submit(auditlogtest.Admin("Org1MSP"), "Init", `{"admin_msps":["Org1MSP"],"state_database":"mongodb"}`)
+	if err == nil || !strings.Contains(err.Error(), "state_database must be leveldb or couchdb") {
+		t.Errorf("Init with state_database mongodb: err = %v, want rejection", err)
+	}
+}
@@ -0,0 +1,134 @@
+package contract
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// From schema_version v2, INGEST events must say where their batch came
+// from: the source system, how many records it held and the window it
+// covers. The ingest object is part of the payload hash, and ingests are
+// indexed by source_system and batch_window_start, so reconciliation can
+// check each source's arrivals against the log window by window. v1 ingests
+// are accepted as before and must leave it out.
+const (
+	ingestSourceWindowIndex = "ingest~source~window~id"
+	minIngestSchema         = 2
+)
+
+var sourceSystemRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,63}$`)
+
+type IngestDetails struct {
+	SourceSystem string `json:"source_system"`
+	// A pointer so that 0 is told apart from missing.
+	RecordCount *int64 `json:"record_count"`
+	// RFC3339; start must not be after end.
+	BatchWindowStart string `json:"batch_window_start"`
+	BatchWindowEnd   string `json:"batch_window_end"`
+}
+
+func validateIngest(e *LedgerEvent) error {
+	typed := e.EventType == "INGEST" && schemaMajor(e.SchemaVer) >= minIngestSchema
+	if e.Ingest == nil {
+		if typed {
+			return fmt.Errorf("ingest required on INGEST events from schema_version v%d", minIngestSchema)
+		}
+		return nil
+	}
+	if !typed {
+		return fmt.Errorf("ingest may only be set on INGEST events from schema_version v%d", minIngestSchema)
+	}
+	in := e.Ingest
+	if !sourceSystemRe.MatchString(in.SourceSystem) {
+		return fmt.Errorf("ingest must have a source_system of 1-64 characters A-Z, a-z, 0-9, '.', '_', ':' or '-'")
+	}
+	if in.RecordCount == nil || *in.RecordCount < 0 {
+		return fmt.Errorf("ingest must have a non-negative record_count")
+	}
+	start, err := time.Parse(time.RFC3339, in.BatchWindowStart)
+	if err != nil {
+		return fmt.Errorf("ingest must have an RFC3339 batch_window_start")
+	}
+	end, err := time.Parse(time.RFC3339, in.BatchWindowEnd)
+	if err != nil {
+		return fmt.Errorf("ingest must have an RFC3339 batch_window_end")
+	}
+	if end.Before(start) {
+		return fmt.Errorf("ingest must have batch_window_start at or before batch_window_end")
+	}
+	return nil
+}
+
+// decodeIngestProto decodes the IngestDetails message of field 21.
+func decodeIngestProto(b []byte) (*IngestDetails, error) {
+	in := &IngestDetails{}
+	strs := map[protowire.Number]*string{1: &in.SourceSystem, 3: &in.BatchWindowStart, 4: &in.BatchWindowEnd}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch {
+		case strs[num] != nil && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			if !utf8.Valid(v) {
+				return nil, fmt.Errorf("nested field %d is not valid UTF-8", num)
+			}
+			*strs[num] = string(v)
+			b = b[n:]
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			count := int64(v)
+			in.RecordCount = &count
+			b = b[n:]
+		default:
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return in, nil
+}
+
+// QueryIngestsBySource pages through the INGEST events of sourceSystem in
+// batch_window_start order. With startRFC3339 and endRFC3339 set, only
+// batches whose window starts in [start, end) are returned; leave both
+// empty for every batch of the source.
+func (c *AuditLogContract) QueryIngestsBySource(ctx contractapi.TransactionContextInterface, sourceSystem string, startRFC3339 string, endRFC3339 string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if !sourceSystemRe.MatchString(sourceSystem) {
+		return "", fmt.Errorf("invalid source_system")
+	}
+	if startRFC3339 == "" && endRFC3339 == "" {
+		return pageByIndex(ctx, ingestSourceWindowIndex, []string{sourceSystem}, projection, bookmark, pageSize)
+	}
+	start, end, err := parseTimeRange(startRFC3339, endRFC3339)
+	if err != nil {
+		return "", err
+	}
+	startKey, endKey, err := timeRangeKeys(ctx, ingestSourceWindowIndex, []string{sourceSystem}, start, end)
+	if err != nil {
+		return "", err
+	}
+	page, err := pageIndexRange(ctx, startKey, endKey, bookmark, pageSize)
+	if err != nil {
+		return "", err
+	}
+	return marshalPage(page, projection)
+}
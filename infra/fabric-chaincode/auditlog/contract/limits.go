@@ -0,0 +1,104 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Size limits, each overridable in the config. defaultMaxEventBytes bounds
+// an event's JSON encoding, whatever format it was submitted in, so a
+// protobuf submission cannot get around it.
+const (
+	defaultMaxEventBytes  = 64 << 10
+	maxMaxEventBytes      = 1 << 20
+	defaultMaxBatchEvents = maxPutBatch
+)
+
+// Query limits. Every paged query checks its page_size against
+// max_page_size, which can only lower maxPageSize; each page is its own
+// transaction, so that bounds the work of one. Queries that answer in a
+// single list instead stop at max_query_results and set truncated.
+const (
+	defaultMaxQueryResults = 1000
+	maxMaxQueryResults     = 10000
+)
+
+func (cfg *ContractConfig) validateLimits() error {
+	if cfg.MaxEventBytes < 0 || cfg.MaxEventBytes > maxMaxEventBytes {
+		return fmt.Errorf("max_event_bytes must be between 0 and %d", maxMaxEventBytes)
+	}
+	if cfg.MaxMetadataValueBytes < 0 || cfg.MaxMetadataValueBytes > cfg.maxEventBytes() {
+		return fmt.Errorf("max_metadata_value_bytes must be between 0 and max_event_bytes")
+	}
+	if cfg.MaxBatchEvents < 0 || cfg.MaxBatchEvents > maxPutBatch {
+		return fmt.Errorf("max_batch_events must be between 0 and %d", maxPutBatch)
+	}
+	if cfg.MaxPageSize < 0 || cfg.MaxPageSize > maxPageSize {
+		return fmt.Errorf("max_page_size must be between 0 and %d", maxPageSize)
+	}
+	if cfg.MaxQueryResults < 0 || cfg.MaxQueryResults > maxMaxQueryResults {
+		return fmt.Errorf("max_query_results must be between 0 and %d", maxMaxQueryResults)
+	}
+	return nil
+}
+
+func (cfg *ContractConfig) maxEventBytes() int {
+	if cfg.MaxEventBytes == 0 {
+		return defaultMaxEventBytes
+	}
+	return cfg.MaxEventBytes
+}
+
+func (cfg *ContractConfig) maxMetadataValueBytes() int {
+	if cfg.MaxMetadataValueBytes == 0 {
+		return defaultMaxMetadataValueBytes
+	}
+	return cfg.MaxMetadataValueBytes
+}
+
+func (cfg *ContractConfig) maxBatchEvents() int {
+	if cfg.MaxBatchEvents == 0 {
+		return defaultMaxBatchEvents
+	}
+	return cfg.MaxBatchEvents
+}
+
+func (cfg *ContractConfig) maxPageSize() int32 {
+	if cfg.MaxPageSize == 0 {
+		return maxPageSize
+	}
+	return int32(cfg.MaxPageSize)
+}
+
+func (cfg *ContractConfig) maxQueryResults() int {
+	if cfg.MaxQueryResults == 0 {
+		return defaultMaxQueryResults
+	}
+	return cfg.MaxQueryResults
+}
+
+// validatePageSize checks a paged query's page_size against the config.
+func validatePageSize(ctx contractapi.TransactionContextInterface, pageSize int32) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if limit := cfg.maxPageSize(); pageSize <= 0 || pageSize > limit {
+		return fmt.Errorf("page_size must be between 1 and %d", limit)
+	}
+	return nil
+}
+
+// checkEventSize rejects events whose JSON encoding exceeds the limit.
+func checkEventSize(e *LedgerEvent, cfg *ContractConfig) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if limit := cfg.maxEventBytes(); len(b) > limit {
+		return fmt.Errorf("event_too_large: event is %d bytes, limit %d", len(b), limit)
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package contract
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// parent_event_ids links an event to the events it was derived from, e.g. a
+// FORECAST to the AGENT_DECISION that produced it. Parents must already be
+// stored, in the writer's namespace, so the links always form a DAG. Each
+// link is indexed parent-first to find descendants; ancestors are read from
+// the events themselves.
+const (
+	lineageIndex = "parent~child"
+
+	maxParents      = 16
+	maxLineageDepth = 10
+	maxLineageNodes = 500
+
+	// GetEventsByTypeWithLineage embeds parents inline, so its depth is
+	// held lower.
+	maxEmbeddedLineageDepth = 5
+)
+
+func validateParents(e *LedgerEvent) error {
+	if len(e.ParentEventIDs) > maxParents {
+		return fmt.Errorf("parent_event_ids may list at most %d events", maxParents)
+	}
+	seen := map[string]bool{}
+	for _, p := range e.ParentEventIDs {
+		switch {
+		case !validate.UUID.MatchString(p):
+			return fmt.Errorf("parent_event_ids entries must be event_ids")
+		case p == e.EventID:
+			return fmt.Errorf("parent_event_ids must not include the event itself")
+		case seen[p]:
+			return fmt.Errorf("parent_event_ids contains duplicate %s", p)
+		}
+		seen[p] = true
+	}
+	return nil
+}
+
+// checkParents verifies every parent of e exists in producer's namespace.
+func checkParents(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, e *LedgerEvent, producer string, pending *pendingWrites) error {
+	for _, p := range e.ParentEventIDs {
+		ref := eventRef(producer, p, cfg.TimeOrderedKeys)
+		if pending.events[ref] != nil {
+			continue
+		}
+		b, err := ctx.GetStub().GetState(eventKey(ref))
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("parent_not_found: %s", p)
+		}
+	}
+	return nil
+}
+
+type LineageEdge struct {
+	Parent string `json:"parent"`
+	Child  string `json:"child"`
+}
+
+type LineageNode struct {
+	EventSummary
+	// Negative for ancestors, positive for descendants, 0 for the root.
+	Depth int `json:"depth"`
+}
+
+type LineageGraph struct {
+	Root  string        `json:"root"`
+	Nodes []LineageNode `json:"nodes"`
+	Edges []LineageEdge `json:"edges"`
+	// Set when maxLineageNodes was reached before depth.
+	Truncated bool `json:"truncated"`
+}
+
+type lineageWalk struct {
+	ctx   contractapi.TransactionContextInterface
+	graph *LineageGraph
+	seen  map[string]bool
+	edges map[LineageEdge]bool
+}
+
+func (w *lineageWalk) addNode(stored *StoredEvent, depth int) (bool, error) {
+	ref := stored.ref()
+	if w.seen[ref] {
+		return false, nil
+	}
+	if len(w.graph.Nodes) == maxLineageNodes {
+		w.graph.Truncated = true
+		return false, nil
+	}
+	view, err := viewOf(w.ctx, stored)
+	if err != nil {
+		return false, err
+	}
+	w.seen[ref] = true
+	w.graph.Nodes = append(w.graph.Nodes, LineageNode{EventSummary: summaryOf(view), Depth: depth})
+	return true, nil
+}
+
+func (w *lineageWalk) addEdge(parent, child string) {
+	e := LineageEdge{Parent: parent, Child: child}
+	if !w.edges[e] {
+		w.edges[e] = true
+		w.graph.Edges = append(w.graph.Edges, e)
+	}
+}
+
+// children returns the events listing stored as a parent.
+func children(ctx contractapi.TransactionContextInterface, stored *StoredEvent) ([]*StoredEvent, error) {
+	it, err := ctx.GetStub().GetStateByPartialCompositeKey(lineageIndex, []string{stored.ref()})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	var out []*StoredEvent
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		child, err := getStoredEvent(ctx, string(kv.Value))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, child)
+	}
+	return out, nil
+}
+
+// GetEventLineage returns the DAG around eventID: its ancestors and
+// descendants up to depth links away, as event summaries and parent→child
+// edges keyed by event_id. Nodes are listed breadth first.
+func (c *AuditLogContract) GetEventLineage(ctx contractapi.TransactionContextInterface, eventID string, depth int) (string, error) {
+	if depth < 1 || depth > maxLineageDepth {
+		return "", fmt.Errorf("depth must be between 1 and %d", maxLineageDepth)
+	}
+	ref, err := resolveRef(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	root, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+
+	graph := &LineageGraph{Root: root.Event.EventID, Nodes: []LineageNode{}, Edges: []LineageEdge{}}
+	w := &lineageWalk{ctx: ctx, graph: graph, seen: map[string]bool{}, edges: map[LineageEdge]bool{}}
+	if _, err := w.addNode(root, 0); err != nil {
+		return "", err
+	}
+
+	// Ancestors never turn up as descendants, as links only point back in
+	// time, so the two walks share the seen set safely.
+	frontier := []*StoredEvent{root}
+	for d := 1; d <= depth && len(frontier) > 0; d++ {
+		var next []*StoredEvent
+		for _, child := range frontier {
+			for _, p := range child.Event.ParentEventIDs {
+				parent, err := getStoredEvent(ctx, eventRef(child.Producer, p, child.TimeOrderedKeys))
+				if err != nil {
+					return "", err
+				}
+				w.addEdge(p, child.Event.EventID)
+				added, err := w.addNode(parent, -d)
+				if err != nil {
+					return "", err
+				}
+				if added {
+					next = append(next, parent)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	frontier = []*StoredEvent{root}
+	for d := 1; d <= depth && len(frontier) > 0; d++ {
+		var next []*StoredEvent
+		for _, parent := range frontier {
+			kids, err := children(ctx, parent)
+			if err != nil {
+				return "", err
+			}
+			for _, child := range kids {
+				w.addEdge(parent.Event.EventID, child.Event.EventID)
+				added, err := w.addNode(child, d)
+				if err != nil {
+					return "", err
+				}
+				if added {
+					next = append(next, child)
+				}
+			}
+		}
+		frontier = next
+	}
+	return marshalString(graph)
+}
+
+// LineageEvent is an event with its parents embedded, each with its own,
+// down to the requested depth.
+type LineageEvent struct {
+	EventView
+	Parents []LineageEvent `json:"parents"`
+}
+
+type LineagePage struct {
+	Records      []LineageEvent `json:"records"`
+	FetchedCount int32          `json:"fetched_count"`
+	Bookmark     string         `json:"bookmark"`
+	// Set when maxLineageNodes parents were embedded on the page before
+	// depth was reached; events past the cap list no parents.
+	Truncated bool `json:"truncated"`
+}
+
+// lineageEmbed resolves parents for one page, reading each event once.
+type lineageEmbed struct {
+	ctx       contractapi.TransactionContextInterface
+	views     map[string]*EventView
+	embedded  int
+	truncated bool
+}
+
+func (l *lineageEmbed) view(ref string) (*EventView, error) {
+	if v, ok := l.views[ref]; ok {
+		return v, nil
+	}
+	stored, err := getStoredEvent(l.ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	v, err := viewOf(l.ctx, stored)
+	if err != nil {
+		return nil, err
+	}
+	l.views[ref] = v
+	return v, nil
+}
+
+// event returns v with its parents embedded to depth levels.
+func (l *lineageEmbed) event(v *EventView, depth int) (LineageEvent, error) {
+	out := LineageEvent{EventView: *v, Parents: []LineageEvent{}}
+	if depth == 0 {
+		return out, nil
+	}
+	for _, p := range v.Event.ParentEventIDs {
+		if l.embedded == maxLineageNodes {
+			l.truncated = true
+			break
+		}
+		l.embedded++
+		pv, err := l.view(eventRef(v.Producer, p, v.TimeOrderedKeys))
+		if err != nil {
+			return LineageEvent{}, err
+		}
+		parent, err := l.event(pv, depth-1)
+		if err != nil {
+			return LineageEvent{}, err
+		}
+		out.Parents = append(out.Parents, parent)
+	}
+	return out, nil
+}
+
+// GetEventsByTypeWithLineage pages through events of one type in
+// timestamp order, as GetEventsByType does, with each event's parent chain
+// embedded inline up to depth levels. At most maxLineageNodes parents are
+// embedded per page.
+func (c *AuditLogContract) GetEventsByTypeWithLineage(ctx contractapi.TransactionContextInterface, eventType string, depth int, bookmark string, pageSize int32) (string, error) {
+	if err := requireEventType(ctx, eventType); err != nil {
+		return "", err
+	}
+	if depth < 1 || depth > maxEmbeddedLineageDepth {
+		return "", fmt.Errorf("depth must be between 1 and %d", maxEmbeddedLineageDepth)
+	}
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	it, meta, err := pageIndexPrefix(ctx.GetStub(), typeTsIndex, []string{eventType}, pageSize, bookmark)
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	l := &lineageEmbed{ctx: ctx, views: map[string]*EventView{}}
+	page := LineagePage{Records: []LineageEvent{}, FetchedCount: meta.FetchedRecordsCount, Bookmark: meta.Bookmark}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		v, err := l.view(string(kv.Value))
+		if err != nil {
+			return "", err
+		}
+		rec, err := l.event(v, depth)
+		if err != nil {
+			return "", err
+		}
+		page.Records = append(page.Records, rec)
+	}
+	page.Truncated = l.truncated
+	return marshalString(page)
+}
@@ -0,0 +1,77 @@
+package contract_test
+
+import (
+	"errors"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+func TestGetEventsByTypeWithLineage(t *testing.T) {
+	h, err := auditlogtest.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := auditlogtest.Admin("Org1MSP")
+	if _, err := h.Submit(admin, "Init", `{"admin_msps":["Org1MSP"]}`); err != nil {
+		t.Fatal(err)
+	}
+	ingest := auditlogtest.IngestEvent(1)
+	decision := auditlogtest.DecisionEvent(2)
+	decision.ParentEventIDs = []string{ingest.EventID}
+	forecast := auditlogtest.ForecastEvent(3)
+	forecast.ParentEventIDs = []string{decision.EventID}
+	client := auditlogtest.Client("Org1MSP")
+	for _, e := range []*contract.LedgerEvent{ingest, decision, forecast} {
+		if _, err := h.Submit(client, "PutEvent", auditlogtest.MustJSON(e)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		depth string
+		// event_ids from the forecast up its first-parent chain.
+		want []string
+	}{
+		{"1", []string{forecast.EventID, decision.EventID}},
+		{"2", []string{forecast.EventID, decision.EventID, ingest.EventID}},
+		{"5", []string{forecast.EventID, decision.EventID, ingest.EventID}},
+	} {
+		out, err := h.Evaluate(client, "GetEventsByTypeWithLineage", "FORECAST", tc.depth, "", "10")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page contract.LineagePage
+		if err := auditlogtest.Unmarshal(out, &page); err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Records) != 1 || page.Truncated {
+			t.Fatalf("depth %s: %d records, truncated %v; want 1 record", tc.depth, len(page.Records), page.Truncated)
+		}
+		got := []string{}
+		for e := &page.Records[0]; ; e = &e.Parents[0] {
+			got = append(got, e.Event.EventID)
+			if len(e.Parents) == 0 {
+				break
+			}
+		}
+		if !equal(got, tc.want) {
+			t.Errorf("depth %s: chain %v, want %v", tc.depth, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		eventType, depth string
+	}{
+		{"FORECAST", "0"},
+		{"FORECAST", "6"},
+		{"NOT_A_TYPE", "1"},
+	} {
+		_, err := h.Evaluate(client, "GetEventsByTypeWithLineage", tc.eventType, tc.depth, "", "10")
+		var txErr *auditlogtest.TxError
+		if !errors.As(err, &txErr) {
+			t.Errorf("type %s, depth %s: err = %v, want a rejection", tc.eventType, tc.depth, err)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+package contract
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Digests commit to the events with timestamps in a window as a Merkle tree
+// over their payload hashes, in ts~id order:
+//
+//	leaf = sha256(0x00 || payload_hash)
+//	node = sha256(0x01 || left || right)
+//
+// with hashes taken as raw bytes. An unpaired node moves up a level
+// unchanged. The ordered leaves are kept next to the digest so proofs stay
+// valid when backdated events later land in the window.
+const (
+	digestKeyPrefix       = "digest:"
+	digestLeavesKeyPrefix = "digest_leaves:"
+	// Composite index start~end~digest_id, with bounds in index layout.
+	digestWindowIndex = "digest~window"
+	maxDigestLeaves   = 10000
+)
+
+type MerkleDigest struct {
+	DigestID    string `json:"digest_id"`
+	Start       string `json:"start"`
+	End         string `json:"end"`
+	Root        string `json:"merkle_root"`
+	LeafCount   int    `json:"leaf_count"`
+	CommittedAt string `json:"committed_at"`
+}
+
+type ProofStep struct {
+	Hash string `json:"hash"`
+	// Side of the sibling: "left" or "right".
+	Side string `json:"side"`
+}
+
+type InclusionProof struct {
+	DigestID    string      `json:"digest_id"`
+	EventID     string      `json:"event_id"`
+	PayloadHash string      `json:"payload_hash_sha256"`
+	LeafIndex   int         `json:"leaf_index"`
+	LeafCount   int         `json:"leaf_count"`
+	Root        string      `json:"merkle_root"`
+	Proof       []ProofStep `json:"proof"`
+}
+
+func merkleLeaf(payloadHash string) ([]byte, error) {
+	b, err := hex.DecodeString(payloadHash)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(append([]byte{0x00}, b...))
+	return sum[:], nil
+}
+
+func merkleNode(left, right []byte) []byte {
+	buf := make([]byte, 0, 1+len(left)+len(right))
+	buf = append(append(append(buf, 0x01), left...), right...)
+	sum := sha256.Sum256(buf)
+	return sum[:]
+}
+
+// merkleTree returns every level of the tree, leaves first, root last.
+func merkleTree(payloadHashes []string) ([][][]byte, error) {
+	level := make([][]byte, len(payloadHashes))
+	for i, h := range payloadHashes {
+		leaf, err := merkleLeaf(h)
+		if err != nil {
+			return nil, err
+		}
+		level[i] = leaf
+	}
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, merkleNode(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels, nil
+}
+
+func getDigest(ctx contractapi.TransactionContextInterface, digestID string) (*MerkleDigest, error) {
+	b, err := ctx.GetStub().GetState(digestKeyPrefix + digestID)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("not_found")
+	}
+	var d MerkleDigest
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("corrupt digest record")
+	}
+	return &d, nil
+}
+
+// windowEvents loads the events with timestamps in [start, end), given in
+// index layout, in ts~id order. Windows holding more than maxDigestLeaves
+// events are refused before any event is loaded.
+func windowEvents(ctx contractapi.TransactionContextInterface, start, end string) ([]*StoredEvent, error) {
+	refs := []string{}
+	truncated, err := scanTimeRange(ctx, tsIndex, []string{}, start, end, maxDigestLeaves, func(_, ref string) error {
+		refs = append(refs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if truncated {
+		return nil, fmt.Errorf("digest_window_too_large: more than %d events, use shorter windows", maxDigestLeaves)
+	}
+	events := make([]*StoredEvent, len(refs))
+	for i, ref := range refs {
+		if events[i], err = getStoredEvent(ctx, ref); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+func payloadHashes(events []*StoredEvent) []string {
+	hashes := make([]string, len(events))
+	for i, e := range events {
+		hashes[i] = e.PayloadHash
+	}
+	return hashes
+}
+
+// CommitDigest computes and stores the Merkle root over events with
+// timestamps in [start, end), at most maxDigestLeaves of them. Only digest
+// identities may commit, since the latest digest of a window is the one
+// reports and proofs use. The digest id is the transaction id. The
+// stored digest is also emitted as a DigestCommitted event; see anchor.go.
+func (c *AuditLogContract) CommitDigest(ctx contractapi.TransactionContextInterface, startRFC3339 string, endRFC3339 string) (string, error) {
+	start, end, err := parseTimeRange(startRFC3339, endRFC3339)
+	if err != nil {
+		return "", err
+	}
+	events, err := windowEvents(ctx, start, end)
+	if err != nil {
+		return "", err
+	}
+	hashes := payloadHashes(events)
+	if len(hashes) == 0 {
+		return "", errors.New("digest_window_empty")
+	}
+	levels, err := merkleTree(hashes)
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	d := MerkleDigest{
+		DigestID:    ctx.GetStub().GetTxID(),
+		Start:       startRFC3339,
+		End:         endRFC3339,
+		Root:        hex.EncodeToString(levels[len(levels)-1][0]),
+		LeafCount:   len(hashes),
+		CommittedAt: now.Format(time.RFC3339Nano),
+	}
+	out, err := json.Marshal(d)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(digestKeyPrefix+d.DigestID, out); err != nil {
+		return "", err
+	}
+	leaves, err := json.Marshal(hashes)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(digestLeavesKeyPrefix+d.DigestID, leaves); err != nil {
+		return "", err
+	}
+	windowKey, err := ctx.GetStub().CreateCompositeKey(digestWindowIndex, []string{start, end, d.DigestID})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(windowKey, []byte(d.DigestID)); err != nil {
+		return "", err
+	}
+	if err := emitDigestCommitted(ctx, out); err != nil {
+		return "", err
+	}
+	return marshalString(d)
+}
+
+// latestDigestForWindow returns the most recently committed digest over
+// exactly [start, end), or nil when there is none.
+func latestDigestForWindow(ctx contractapi.TransactionContextInterface, start, end string) (*MerkleDigest, error) {
+	it, err := ctx.GetStub().GetStateByPartialCompositeKey(digestWindowIndex, []string{start, end})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	var latest *MerkleDigest
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		d, err := getDigest(ctx, string(kv.Value))
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil || committedAfter(d, latest) {
+			latest = d
+		}
+	}
+	return latest, nil
+}
+
+func committedAfter(a, b *MerkleDigest) bool {
+	ta, _ := time.Parse(time.RFC3339Nano, a.CommittedAt)
+	tb, _ := time.Parse(time.RFC3339Nano, b.CommittedAt)
+	return ta.After(tb)
+}
+
+func (c *AuditLogContract) GetDigest(ctx contractapi.TransactionContextInterface, digestID string) (string, error) {
+	d, err := getDigest(ctx, digestID)
+	if err != nil {
+		return "", err
+	}
+	return marshalString(d)
+}
+
+// GetInclusionProof returns the sibling path from eventID's leaf to the root
+// of digestID. Events outside the digest yield not_in_digest.
+func (c *AuditLogContract) GetInclusionProof(ctx contractapi.TransactionContextInterface, eventID string, digestID string) (string, error) {
+	d, err := getDigest(ctx, digestID)
+	if err != nil {
+		return "", err
+	}
+	ref, err := resolveRef(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	proof, err := inclusionProof(ctx, d, stored)
+	if err != nil {
+		return "", err
+	}
+	if proof == nil {
+		return "", errors.New("not_in_digest")
+	}
+	return marshalString(proof)
+}
+
+// inclusionProof returns the path from stored's leaf to the root of d, or
+// nil when stored is not among d's leaves.
+func inclusionProof(ctx contractapi.TransactionContextInterface, d *MerkleDigest, stored *StoredEvent) (*InclusionProof, error) {
+	b, err := ctx.GetStub().GetState(digestLeavesKeyPrefix + d.DigestID)
+	if err != nil {
+		return nil, err
+	}
+	var hashes []string
+	if err := json.Unmarshal(b, &hashes); err != nil {
+		return nil, fmt.Errorf("corrupt digest leaves")
+	}
+	index := -1
+	for i, h := range hashes {
+		if h == stored.PayloadHash {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, nil
+	}
+
+	levels, err := merkleTree(hashes)
+	if err != nil {
+		return nil, err
+	}
+	proof := &InclusionProof{
+		DigestID:    d.DigestID,
+		EventID:     stored.Event.EventID,
+		PayloadHash: stored.PayloadHash,
+		LeafIndex:   index,
+		LeafCount:   d.LeafCount,
+		Root:        d.Root,
+		Proof:       []ProofStep{},
+	}
+	i := index
+	for _, level := range levels[:len(levels)-1] {
+		sibling := i ^ 1
+		if sibling < len(level) {
+			side := "right"
+			if sibling < i {
+				side = "left"
+			}
+			proof.Proof = append(proof.Proof, ProofStep{Hash: hex.EncodeToString(level[sibling]), Side: side})
+		}
+		i /= 2
+	}
+	return proof, nil
+}
+
+// proofRoot recomputes the root a proof leads to, as a verifier would.
+func proofRoot(p *InclusionProof) (string, error) {
+	node, err := merkleLeaf(p.PayloadHash)
+	if err != nil {
+		return "", err
+	}
+	for _, step := range p.Proof {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return "", err
+		}
+		if step.Side == "left" {
+			node = merkleNode(sibling, node)
+		} else {
+			node = merkleNode(node, sibling)
+		}
+	}
+	return hex.EncodeToString(node), nil
+}
@@ -0,0 +1,57 @@
+package contract
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// metadata carries free-form labels such as source_system, environment or
+// run on events of schema_version v2 and later. It is part of the payload
+// hash like every other event field; v1 events must leave it empty, so their
+// hashes are unchanged.
+const (
+	maxMetadataEntries           = 32
+	defaultMaxMetadataValueBytes = 256
+	minMetadataSchema            = 2
+)
+
+var (
+	metadataKeyRe = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,63}$`)
+	schemaMajorRe = regexp.MustCompile(`^v?(\d+)`)
+)
+
+// schemaMajor returns the leading version number of a schema_version such
+// as "v2" or "2.1", or 0 when it has none.
+func schemaMajor(v string) int {
+	m := schemaMajorRe.FindStringSubmatch(v)
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func validateMetadata(e *LedgerEvent, cfg *ContractConfig) error {
+	if len(e.Metadata) == 0 {
+		return nil
+	}
+	if schemaMajor(e.SchemaVer) < minMetadataSchema {
+		return fmt.Errorf("metadata may only be set from schema_version v%d", minMetadataSchema)
+	}
+	if len(e.Metadata) > maxMetadataEntries {
+		return fmt.Errorf("metadata must have at most %d entries", maxMetadataEntries)
+	}
+	for k, v := range e.Metadata {
+		if !metadataKeyRe.MatchString(k) {
+			return fmt.Errorf("metadata must have keys of 1-64 characters a-z, 0-9, '_', '.' or '-' starting with a letter, got %q", k)
+		}
+		if limit := cfg.maxMetadataValueBytes(); len(v) > limit {
+			return fmt.Errorf("metadata_value_too_large: %q is %d bytes, limit %d", k, len(v), limit)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package contract
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics are collected in every mode but only served over HTTP when the
+// chaincode runs as a service, on CHAINCODE_METRICS_ADDRESS (default
+// defaultMetricsAddress). They count this process's work, so each peer's
+// chaincode container reports its own endorsements.
+const (
+	envMetricsAddress     = "CHAINCODE_METRICS_ADDRESS"
+	defaultMetricsAddress = ":9443"
+)
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	txDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "auditlog_transaction_duration_seconds",
+		Help:    "Time to execute a transaction proposal, by transaction name.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+	}, []string{"function"})
+	txErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auditlog_transaction_errors_total",
+		Help: "Failed transaction proposals, by transaction name and error code.",
+	}, []string{"function", "code"})
+	rejectedEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "auditlog_batch_rejected_events_total",
+		Help: "Events rejected individually inside PutEvents, by error code.",
+	}, []string{"code"})
+	idempotentHits = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "auditlog_idempotent_hits_total",
+		Help: "Event writes that matched an existing identical event and wrote nothing.",
+	})
+	responseBytes = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "auditlog_response_bytes",
+		Help:    "Size of successful transaction responses, by transaction name.",
+		Buckets: prometheus.ExponentialBuckets(256, 4, 10),
+	}, []string{"function"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(txDuration, txErrors, rejectedEvents, idempotentHits, responseBytes,
+		prometheus.NewGoCollector(), prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+}
+
+// metricFunction bounds label cardinality to the contract's transactions.
+func metricFunction(fn string) string {
+	if i := strings.LastIndex(fn, ":"); i >= 0 {
+		fn = fn[i+1:]
+	}
+	if _, ok := txRoles[fn]; ok {
+		return fn
+	}
+	return "unknown"
+}
+
+func observeInvoke(fn string, start time.Time, resp peer.Response, code string) {
+	fn = metricFunction(fn)
+	txDuration.WithLabelValues(fn).Observe(time.Since(start).Seconds())
+	if code != "" {
+		txErrors.WithLabelValues(fn, code).Inc()
+		return
+	}
+	responseBytes.WithLabelValues(fn).Observe(float64(len(resp.Payload)))
+}
+
+// opsMux serves the operational endpoints of the chaincode service: metrics
+// and the probes of health.go.
+func opsMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+	mux.Handle("/healthz", probeHandler(false))
+	mux.Handle("/readyz", probeHandler(true))
+	return mux
+}
+
+func opsAddress() string {
+	if addr := os.Getenv(envMetricsAddress); addr != "" {
+		return addr
+	}
+	return defaultMetricsAddress
+}
@@ -0,0 +1,248 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// storageVersion is the StoredEvent shape new writes produce. Records
+// written before storage_version existed are version 0. To change the
+// shape, bump it and append the step that upgrades the previous version to
+// migrations; MigrateState applies the missing steps in order. Steps must
+// leave the event and its payload hash alone: that is what the record
+// attests to.
+const storageVersion = 6
+
+// migrations[v] upgrades a record from version v to v+1 in place, with key
+// its state key.
+var migrations = []func(ctx contractapi.TransactionContextInterface, key string, stored *StoredEvent) error{
+	// 0 -> 1: backfill the writing transaction, recorded on new writes
+	// since receipts, from the key's first history entry.
+	func(ctx contractapi.TransactionContextInterface, key string, stored *StoredEvent) error {
+		if stored.TxID != "" && stored.LedgerTimestamp != "" {
+			return nil
+		}
+		var first Receipt
+		if err := firstWrite(ctx, key, &first); err != nil {
+			return err
+		}
+		if stored.TxID == "" {
+			stored.TxID = first.TxID
+		}
+		if stored.LedgerTimestamp == "" {
+			stored.LedgerTimestamp = first.TxTimestamp
+		}
+		return nil
+	},
+	// 1 -> 2: nothing to change in the record; saving it through
+	// putStoredEvent writes its bucket entry (see bucket.go).
+	func(ctx contractapi.TransactionContextInterface, key string, stored *StoredEvent) error {
+		return nil
+	},
+	// 2 -> 3: move the record's entries in rangeIndexes out of the
+	// composite key namespace, which range scans cannot read (see
+	// index.go), and drop its old bucket entry; putStoredEvent writes the
+	// new one.
+	func(ctx contractapi.TransactionContextInterface, key string, stored *StoredEvent) error {
+		stub := ctx.GetStub()
+		entries, err := legacyIndexEntries(stored)
+		if err != nil {
+			return err
+		}
+		bucket, err := bucketAttrs(stored)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, indexEntry{bucketIndex, bucket})
+		ref := []byte(stored.ref())
+		for _, ie := range entries {
+			if !rangeIndexes[ie.objectType] {
+				continue
+			}
+			old, err := stub.CreateCompositeKey(ie.objectType, ie.attrs)
+			if err != nil {
+				return err
+			}
+			if err := stub.DelState(old); err != nil {
+				return err
+			}
+			if ie.objectType == bucketIndex {
+				continue
+			}
+			moved, err := indexKey(stub, ie.objectType, ie.attrs)
+			if err != nil {
+				return err
+			}
+			if err := stub.PutState(moved, ref); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 3 -> 4: rekey the record's index entries from its event_id to its
+	// ref, so producers sharing an event_id under
+	// id_namespacing_by_producer no longer overwrite each other's entries.
+	// An entry another producer's record overwrote is rewritten by that
+	// record's own migration. A deleted record keeps only its
+	// tombstoneIndexes entries.
+	func(ctx contractapi.TransactionContextInterface, key string, stored *StoredEvent) error {
+		stub := ctx.GetStub()
+		legacy, err := legacyIndexEntries(stored)
+		if err != nil {
+			return err
+		}
+		entries, err := indexEntries(stored)
+		if err != nil {
+			return err
+		}
+		ref := []byte(stored.ref())
+		for i, ie := range entries {
+			if stored.Deletion != nil && !tombstoneIndexes[ie.objectType] {
+				continue
+			}
+			old, err := indexKey(stub, legacy[i].objectType, legacy[i].attrs)
+			if err != nil {
+				return err
+			}
+			current, err := indexKey(stub, ie.objectType, ie.attrs)
+			if err != nil {
+				return err
+			}
+			if old == current {
+				continue
+			}
+			// Another producer's record may hold the legacy key now.
+			v, err := stub.GetState(old)
+			if err != nil {
+				return err
+			}
+			if string(v) == string(ref) {
+				if err := stub.DelState(old); err != nil {
+					return err
+				}
+			}
+			if err := stub.PutState(current, ref); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 4 -> 5: move the record's claim on its predecessor's successor
+	// slot into its MSP's stream (see chain.go).
+	func(ctx contractapi.TransactionContextInterface, key string, stored *StoredEvent) error {
+		prev := stored.Event.PrevEventHash
+		if prev == "" {
+			return nil
+		}
+		stub := ctx.GetStub()
+		old, err := stub.CreateCompositeKey(chainNextIndex, []string{prev})
+		if err != nil {
+			return err
+		}
+		ref := stored.ref()
+		v, err := stub.GetState(old)
+		if err != nil {
+			return err
+		}
+		if string(v) != ref {
+			return nil
+		}
+		if err := stub.DelState(old); err != nil {
+			return err
+		}
+		slot, err := chainNextKey(ctx, stored.CreatorMSP, prev)
+		if err != nil {
+			return err
+		}
+		return stub.PutState(slot, []byte(ref))
+	},
+	// 5 -> 6: nothing to change in the record; saving it through
+	// putStoredEvent replaces its whole bucket copy with the ref (see
+	// bucket.go).
+	func(ctx contractapi.TransactionContextInterface, key string, stored *StoredEvent) error {
+		return nil
+	},
+}
+
+type MigrationPage struct {
+	StorageVersion int    `json:"storage_version"`
+	Migrated       int    `json:"migrated"`
+	Current        int    `json:"current"`
+	FetchedCount   int32  `json:"fetched_count"`
+	Bookmark       string `json:"bookmark"`
+}
+
+// MigrateState upgrades one page of event records to storageVersion. Run
+// it from an empty bookmark until the bookmark comes back empty; records
+// already current are skipped, so reruns are harmless. Each migrated
+// record's key gains a history entry written by the migrating transaction.
+// Peers refuse paginated queries in transactions that write, so the page is
+// cut from a plain range scan and the bookmark is the next key to read.
+func (c *AuditLogContract) MigrateState(ctx contractapi.TransactionContextInterface, bookmark string, pageSize int32) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	start := eventKeyPrefix
+	if bookmark != "" {
+		if !strings.HasPrefix(bookmark, eventKeyPrefix) {
+			return "", fmt.Errorf("invalid bookmark")
+		}
+		start = bookmark
+	}
+	stub := ctx.GetStub()
+	it, err := stub.GetStateByRange(start, eventKeyPrefix+"\uffff")
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	page := MigrationPage{StorageVersion: storageVersion}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		if page.FetchedCount == pageSize {
+			page.Bookmark = kv.Key
+			break
+		}
+		page.FetchedCount++
+		var stored StoredEvent
+		if err := json.Unmarshal(kv.Value, &stored); err != nil {
+			return "", fmt.Errorf("corrupt stored event %q", kv.Key)
+		}
+		if stored.StorageVersion >= storageVersion {
+			page.Current++
+			continue
+		}
+		for v := stored.StorageVersion; v < storageVersion; v++ {
+			if err := migrations[v](ctx, kv.Key, &stored); err != nil {
+				return "", fmt.Errorf("migrate %s to storage_version %d: %w", kv.Key, v+1, err)
+			}
+		}
+		stored.StorageVersion = storageVersion
+		out, err := json.Marshal(&stored)
+		if err != nil {
+			return "", err
+		}
+		policy, err := stub.GetStateValidationParameter(kv.Key)
+		if err != nil {
+			return "", err
+		}
+		if err := putStoredEvent(ctx, &stored, out, policy); err != nil {
+			return "", err
+		}
+		page.Migrated++
+	}
+	return marshalString(page)
+}
@@ -0,0 +1,74 @@
+package contract
+
+import (
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// With id_namespacing_by_producer enabled, events are keyed by the
+// submitter's MSP as well as their event_id, so identical UUIDs from two
+// producers are stored side by side. The "ref" of an event is the part of
+// its keys after the prefix: "<event_id>" in flat mode and
+// "<msp>:<event_id>" when namespaced. Indexes store the ref as their value
+// so list queries resolve the right record in either mode.
+//
+// Read path: GetEvent and every other transaction that takes a bare
+// event_id resolve it inside the caller's own MSP namespace, so a producer
+// cannot see another producer's event by id. Admins use
+// GetEventForProducer to look across namespaces. The mode only makes sense
+// if set at Init before any event is written; flipping it later would make
+// earlier records unreachable by id. time_ordered_keys changes the ref of
+// UUIDv7 events; see uuidv7.go.
+
+// eventRef is the ref of eventID in producer's namespace. timeOrdered is
+// the time_ordered_keys setting the event is or was written under.
+func eventRef(producer, eventID string, timeOrdered bool) string {
+	if timeOrdered && isUUIDv7(eventID) {
+		ref := timeOrderedRefPrefix + strings.ToLower(eventID)
+		if producer != "" {
+			ref += "/" + producer
+		}
+		return ref
+	}
+	if producer == "" {
+		return eventID
+	}
+	return producer + ":" + eventID
+}
+
+func eventKey(ref string) string {
+	return eventKeyPrefix + ref
+}
+
+func (s *StoredEvent) ref() string {
+	return eventRef(s.Producer, s.Event.EventID, s.TimeOrderedKeys)
+}
+
+// writerNamespace is the producer recorded on new writes: the caller's MSP
+// when namespacing is on, otherwise empty.
+func writerNamespace(ctx contractapi.TransactionContextInterface, cfg *ContractConfig) (string, error) {
+	if !cfg.IDNamespacingByProducer {
+		return "", nil
+	}
+	return ctx.GetClientIdentity().GetMSPID()
+}
+
+// callerRef resolves a bare event_id within the caller's namespace.
+func callerRef(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, eventID string) (string, error) {
+	producer, err := writerNamespace(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	return eventRef(producer, eventID, cfg.TimeOrderedKeys), nil
+}
+
+// resolveRef loads the config and resolves eventID for the caller; it is the
+// entry point for transactions that take a bare event_id.
+func resolveRef(ctx contractapi.TransactionContextInterface, eventID string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	return callerRef(ctx, cfg, eventID)
+}
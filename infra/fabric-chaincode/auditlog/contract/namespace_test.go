@@ -0,0 +1,99 @@
+package contract_test
+
+import (
+	"sort"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// Two producers writing the same event must both stay listed under
+// id_namespacing_by_producer. Without it the second write is an
+// idempotent replay of the first.
+func TestSameEventIDAcrossProducers(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		namespaced bool
+		want       []string
+	}{
+		{"namespaced", true, []string{"Org1MSP", "Org2MSP"}},
+		{"flat", false, []string{"Org1MSP"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h, err := auditlogtest.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			admin := auditlogtest.Admin("Org1MSP")
+			cfg := map[string]any{"admin_msps": []string{"Org1MSP"}, "id_namespacing_by_producer": tc.namespaced}
+			if _, err := h.Submit(admin, "Init", auditlogtest.MustJSON(cfg)); err != nil {
+				t.Fatal(err)
+			}
+
+			e := auditlogtest.Event("INGEST", 1)
+			for _, msp := range []string{"Org1MSP", "Org2MSP"} {
+				if _, err := h.Submit(auditlogtest.Client(msp), "PutEvent", auditlogtest.MustJSON(e)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var byRange contract.EventPage
+			out, err := h.Evaluate(admin, "QueryEventsByTimeRange", auditlogtest.Timestamp(1), auditlogtest.Timestamp(10), "full", "", "100")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := auditlogtest.Unmarshal(out, &byRange); err != nil {
+				t.Fatal(err)
+			}
+			if got := creators(byRange.Records); !equal(got, tc.want) {
+				t.Errorf("QueryEventsByTimeRange creators = %v, want %v", got, tc.want)
+			}
+
+			var byType contract.EventPage
+			out, err = h.Evaluate(admin, "GetEventsByType", "INGEST", "full", "", "100")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := auditlogtest.Unmarshal(out, &byType); err != nil {
+				t.Fatal(err)
+			}
+			if got := creators(byType.Records); !equal(got, tc.want) {
+				t.Errorf("GetEventsByType creators = %v, want %v", got, tc.want)
+			}
+
+			var byArtifact contract.EventList
+			out, err = h.Evaluate(admin, "GetEventsByArtifactHash", e.ArtifactHash, "full")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := auditlogtest.Unmarshal(out, &byArtifact); err != nil {
+				t.Fatal(err)
+			}
+			if got := creators(byArtifact.Records); !equal(got, tc.want) {
+				t.Errorf("GetEventsByArtifactHash creators = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func creators(records []contract.EventView) []string {
+	out := []string{}
+	for _, r := range records {
+		out = append(out, r.CreatorMSP)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,77 @@
+package contract
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Chaincode event names. Fabric keeps only the last SetEvent of a
+// transaction, so a batch emits one event listing every written record.
+const (
+	eventWrittenName  = "EventWritten"
+	eventsWrittenName = "EventsWritten"
+)
+
+// WrittenNotice is the chaincode event payload for one newly written event.
+type WrittenNotice struct {
+	EventID     string `json:"event_id"`
+	EventType   string `json:"event_type"`
+	PayloadHash string `json:"payload_hash_sha256"`
+	TxID        string `json:"tx_id"`
+	// The writer's trace, when it sent one; see traceContext.
+	TraceID string `json:"trace_id,omitempty"`
+	SpanID  string `json:"span_id,omitempty"`
+}
+
+func noticeFor(ctx contractapi.TransactionContextInterface, stored *StoredEvent) WrittenNotice {
+	n := WrittenNotice{
+		EventID:     stored.Event.EventID,
+		EventType:   stored.Event.EventType,
+		PayloadHash: stored.PayloadHash,
+		TxID:        ctx.GetStub().GetTxID(),
+	}
+	n.TraceID, n.SpanID = traceContext(ctx)
+	return n
+}
+
+// Writers may pass their W3C trace context in the transient map under
+// "traceparent". Its trace and span IDs are copied into the written-event
+// notices, so services following chaincode events can continue the
+// writer's trace. The header never reaches world state, and a missing or
+// malformed one is ignored: tracing must not fail a write.
+const transientTraceKey = "traceparent"
+
+var traceparentRe = regexp.MustCompile(`^[0-9a-f]{2}-([0-9a-f]{32})-([0-9a-f]{16})-[0-9a-f]{2}$`)
+
+func traceContext(ctx contractapi.TransactionContextInterface) (traceID, spanID string) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return "", ""
+	}
+	m := traceparentRe.FindStringSubmatch(string(transient[transientTraceKey]))
+	if m == nil || m[1] == "00000000000000000000000000000000" || m[2] == "0000000000000000" {
+		return "", ""
+	}
+	return m[1], m[2]
+}
+
+func emitWritten(ctx contractapi.TransactionContextInterface, stored *StoredEvent) error {
+	payload, err := json.Marshal(noticeFor(ctx, stored))
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(eventWrittenName, payload)
+}
+
+func emitBatchWritten(ctx contractapi.TransactionContextInterface, notices []WrittenNotice) error {
+	if len(notices) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(notices)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().SetEvent(eventsWrittenName, payload)
+}
@@ -0,0 +1,51 @@
+package contract
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// With org_scoped_reads on, an org sees only its own events: event ids
+// already resolve inside the caller's namespace, and the transactions that
+// enumerate across namespaces (roleCrossOrg in txRoles) are reserved for
+// auditors. Orgs list their own events with ListOrgEvents instead.
+const auditorAttr = "auditlog.auditor"
+
+// requireAuditor allows identities from auditor_msps or admin_msps, or
+// carrying auditlog.auditor=true.
+func requireAuditor(ctx contractapi.TransactionContextInterface, cfg *ContractConfig) error {
+	ok, err := hasAccess(ctx, cfg, cfg.AuditorMSPs, auditorAttr)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("forbidden: auditor identity required")
+	}
+	return nil
+}
+
+// ListOrgEvents pages through the events written by the caller's MSP in
+// timestamp order.
+func (c *AuditLogContract) ListOrgEvents(ctx contractapi.TransactionContextInterface, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	return pageByIndex(ctx, producerTsIndex, []string{mspID}, projection, bookmark, pageSize)
+}
+
+// ListEventsForOrg is ListOrgEvents for any org, for auditors.
+func (c *AuditLogContract) ListEventsForOrg(ctx contractapi.TransactionContextInterface, mspID string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if mspID == "" {
+		return "", fmt.Errorf("msp_id required")
+	}
+	return pageByIndex(ctx, producerTsIndex, []string{mspID}, projection, bookmark, pageSize)
+}
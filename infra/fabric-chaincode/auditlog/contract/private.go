@@ -0,0 +1,120 @@
+package contract
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// Full INGEST payloads can be stored in a private data collection, passed
+// in the transient map under "payload" so they never reach the public
+// ledger. The public record keeps artifact_hash, which must be the digest
+// of the payload under the event's hash_algorithm, and names the collection
+// holding it. Encrypted payloads come under "payload_envelope"; see
+// envelope.go.
+const transientPayloadKey = "payload"
+
+// privatePayload returns the transient payload supplied with the proposal,
+// or nil when there is none, and whether it is an encrypted envelope.
+func privatePayload(ctx contractapi.TransactionContextInterface) ([]byte, bool, error) {
+	transient, err := ctx.GetStub().GetTransient()
+	if err != nil {
+		return nil, false, err
+	}
+	plain, sealed := transient[transientPayloadKey], transient[transientEnvelopeKey]
+	if plain != nil && sealed != nil {
+		return nil, false, errors.New("invalid_payload_envelope: pass either payload or payload_envelope")
+	}
+	if sealed != nil {
+		return sealed, true, nil
+	}
+	return plain, false, nil
+}
+
+// attachPrivatePayload checks a transient payload against the prepared
+// event and records which collection it will be written to. An encrypted
+// payload is checked for form only, and its key ids are recorded.
+func attachPrivatePayload(cfg *ContractConfig, stored *StoredEvent, payload []byte, encrypted bool) error {
+	if cfg.PrivateCollection == "" {
+		return errors.New("private_payload_unsupported: no private_collection configured")
+	}
+	if stored.Event.EventType != "INGEST" {
+		return errors.New("private_payload_unsupported: only INGEST events may carry a private payload")
+	}
+	if encrypted {
+		ids, err := parseEnvelope(payload)
+		if err != nil {
+			return err
+		}
+		if err := checkPayloadKeys(cfg, stored.CreatorMSP, ids); err != nil {
+			return err
+		}
+		stored.PayloadKeyIDs = ids
+	} else {
+		if cfg.RequireEncryptedPayloads {
+			return errors.New("payload_encryption_required: send the payload as payload_envelope")
+		}
+		if artifactDigest(&stored.Event, payload) != stored.Event.ArtifactHash {
+			return errors.New("private_payload_hash_mismatch: digest of payload must equal artifact_hash")
+		}
+	}
+	stored.PrivateCollection = cfg.PrivateCollection
+	return nil
+}
+
+func writePrivatePayload(ctx contractapi.TransactionContextInterface, stored *StoredEvent, payload []byte) error {
+	return ctx.GetStub().PutPrivateData(stored.PrivateCollection, stored.ref(), payload)
+}
+
+// GetPrivatePayload returns the base64 payload of an event stored in the
+// private collection, the envelope JSON when it was written encrypted. The caller's MSP must be in private_payload_readers
+// (or admin_msps) and its peer must be a member of the collection.
+func (c *AuditLogContract) GetPrivatePayload(ctx contractapi.TransactionContextInterface, eventID string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	ok, err := mspIn(ctx, append(append([]string{}, cfg.PrivatePayloadReaders...), cfg.AdminMSPs...))
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.New("forbidden: not a private payload reader")
+	}
+	ref, err := callerRef(ctx, cfg, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if stored.PrivateCollection == "" {
+		return "", fmt.Errorf("not_found: event has no private payload")
+	}
+	purge, err := getPurge(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if purge != nil {
+		return "", errors.New("not_found: private payload purged")
+	}
+	payload, err := ctx.GetStub().GetPrivateData(stored.PrivateCollection, ref)
+	if err != nil {
+		return "", err
+	}
+	if payload == nil {
+		return "", fmt.Errorf("not_found: private payload not available on this peer")
+	}
+	if err := recordAccess(ctx, cfg, stored); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(payload), nil
+}
@@ -0,0 +1,69 @@
+package contract
+
+import (
+	"fmt"
+)
+
+// Event reads take a projection: "full" (or empty) returns whole EventViews,
+// "summary" only the EventSummary metadata, which keeps large result sets
+// well under the gateway's response size limit.
+const (
+	projectionFull    = "full"
+	projectionSummary = "summary"
+)
+
+type EventSummaryList struct {
+	Records   []EventSummary `json:"records"`
+	Truncated bool           `json:"truncated"`
+}
+
+func validateProjection(projection string) error {
+	switch projection {
+	case "", projectionFull, projectionSummary:
+		return nil
+	}
+	return fmt.Errorf("invalid projection: expected summary or full")
+}
+
+func summaryOf(v *EventView) EventSummary {
+	return EventSummary{
+		EventID:     v.Event.EventID,
+		EventType:   v.Event.EventType,
+		Timestamp:   v.Event.TimestampUTC,
+		PayloadHash: v.PayloadHash,
+		Revoked:     v.Revocation != nil,
+		Archived:    v.Archival != nil,
+		Deleted:     v.Deletion != nil,
+		DuplicateOf: v.DuplicateOf,
+	}
+}
+
+func summariesOf(records []EventView) []EventSummary {
+	out := make([]EventSummary, 0, len(records))
+	for i := range records {
+		out = append(out, summaryOf(&records[i]))
+	}
+	return out
+}
+
+func projectView(v *EventView, projection string) any {
+	if projection == projectionSummary {
+		s := summaryOf(v)
+		return &s
+	}
+	return v
+}
+
+func marshalPage(page *EventPage, projection string) (string, error) {
+	if projection == projectionSummary {
+		return marshalString(EventSummaryPage{Records: summariesOf(page.Records), FetchedCount: page.FetchedCount, Bookmark: page.Bookmark})
+	}
+	return marshalString(page)
+}
+
+func marshalList(list *EventList, projection string) (string, error) {
+	if projection == projectionSummary {
+		return marshalString(EventSummaryList{Records: summariesOf(list.Records), Truncated: list.Truncated})
+	}
+	return marshalString(list)
+}
@@ -0,0 +1,204 @@
+package contract
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// PutEventProto decodes proto/ledger_event.proto by hand with protowire so
+// the chaincode needs no generated code. The decoded event is stored exactly
+// as a JSON submission would be; only StoredEvent.SourceFormat records the
+// format it arrived in.
+const formatProto = "proto"
+
+func decodeEventProto(b []byte) (LedgerEvent, error) {
+	var e LedgerEvent
+	strs := map[protowire.Number]*string{
+		1: &e.EventID, 2: &e.EventType, 3: &e.ArtifactHash, 4: &e.SchemaVer,
+		5: &e.TimestampUTC, 6: &e.TSATokenHash, 9: &e.PrevEventHash,
+		10: &e.HashAlgorithm, 12: &e.SignerCert, 13: &e.Supersedes,
+		14: &e.ReferenceTx, 15: &e.CorrelationID, 23: &e.SignerKeyID,
+	}
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return e, protowire.ParseError(n)
+		}
+		b = b[n:]
+		switch {
+		case strs[num] != nil && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			if !utf8.Valid(v) {
+				return e, fmt.Errorf("field %d is not valid UTF-8", num)
+			}
+			*strs[num] = string(v)
+			b = b[n:]
+		case num == 16 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			if !utf8.Valid(v) {
+				return e, fmt.Errorf("field %d is not valid UTF-8", num)
+			}
+			e.ParentEventIDs = append(e.ParentEventIDs, string(v))
+			b = b[n:]
+		case num == 17 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			kv, err := decodeStrings(v, 2)
+			if err != nil {
+				return e, fmt.Errorf("field 17: %v", err)
+			}
+			if e.Metadata == nil {
+				e.Metadata = map[string]string{}
+			}
+			e.Metadata[kv[1]] = kv[2]
+			b = b[n:]
+		case num == 18 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			f, err := decodeStrings(v, 3)
+			if err != nil {
+				return e, fmt.Errorf("field 18: %v", err)
+			}
+			e.Artifacts = append(e.Artifacts, EventArtifact{ArtifactHash: f[1], Role: f[2], HashAlgorithm: f[3]})
+			b = b[n:]
+		case num == 19 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			f, err := decodeStrings(v, 3)
+			if err != nil {
+				return e, fmt.Errorf("field 19: %v", err)
+			}
+			e.Forecast = &ForecastDetails{ModelID: f[1], Horizon: f[2], InputDigest: f[3]}
+			b = b[n:]
+		case num == 20 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			d, err := decodeDecisionProto(v)
+			if err != nil {
+				return e, fmt.Errorf("field 20: %v", err)
+			}
+			e.Decision = d
+			b = b[n:]
+		case num == 21 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			in, err := decodeIngestProto(v)
+			if err != nil {
+				return e, fmt.Errorf("field 21: %v", err)
+			}
+			e.Ingest = in
+			b = b[n:]
+		case num == 11 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			if len(v) > 0 {
+				e.Signature = base64.StdEncoding.EncodeToString(v)
+			}
+			b = b[n:]
+		case num == 22 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			if len(v) > 0 {
+				e.TSAToken = base64.StdEncoding.EncodeToString(v)
+			}
+			b = b[n:]
+		case num == 7 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			ttl := int64(v)
+			e.TTLSeconds = &ttl
+			b = b[n:]
+		case num == 8 && typ == protowire.Fixed64Type:
+			v, n := protowire.ConsumeFixed64(b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			c := math.Float64frombits(v)
+			e.Confidence = &c
+			b = b[n:]
+		case strs[num] != nil || (num >= 7 && num <= 11) || (num >= 16 && num <= 22):
+			return e, fmt.Errorf("field %d has wrong wire type %d", num, typ)
+		default:
+			// Unknown fields from newer producers are skipped.
+			n := protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return e, protowire.ParseError(n)
+			}
+			b = b[n:]
+		}
+	}
+	return e, nil
+}
+
+// decodeStrings decodes a nested message whose fields 1 to max are
+// strings, such as a map<string, string> entry. f[i] holds field i, empty
+// when absent; other fields are skipped.
+func decodeStrings(b []byte, max protowire.Number) ([]string, error) {
+	f := make([]string, max+1)
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+		if num >= 1 && num <= max && typ == protowire.BytesType {
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+			if !utf8.Valid(v) {
+				return nil, fmt.Errorf("nested field %d is not valid UTF-8", num)
+			}
+			f[num] = string(v)
+			b = b[n:]
+			continue
+		}
+		n = protowire.ConsumeFieldValue(num, typ, b)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+		b = b[n:]
+	}
+	return f, nil
+}
+
+// PutEventProto is PutEvent for a base64-encoded protobuf LedgerEvent.
+func (c *AuditLogContract) PutEventProto(ctx contractapi.TransactionContextInterface, eventProtoB64 string) (string, error) {
+	raw, err := base64.StdEncoding.DecodeString(eventProtoB64)
+	if err != nil {
+		return "", errors.New("invalid protobuf: event must be base64")
+	}
+	e, err := decodeEventProto(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid protobuf: %v", err)
+	}
+	return putEvent(ctx, e, formatProto)
+}
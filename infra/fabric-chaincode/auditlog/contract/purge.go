@@ -0,0 +1,120 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// PurgePrivatePayload serves data-subject deletion for payloads kept in the
+// private collection. The payload is purged from the collection, including
+// its private history, while the public event and its artifact_hash stay,
+// so the ledger still proves what was recorded without holding it. A
+// PURGED event, child of the purged one, records the deletion.
+const (
+	purgedType      = "PURGED"
+	purgedSchema    = "purge"
+	purgedKeyPrefix = "purged:"
+)
+
+type PurgeRecord struct {
+	PurgedByMSP string `json:"purged_by_msp"`
+	// event_id of the PURGED event recording the deletion.
+	MarkerEventID string `json:"marker_event_id"`
+	TxID          string `json:"tx_id"`
+	PurgedAt      string `json:"purged_at"`
+}
+
+func getPurge(ctx contractapi.TransactionContextInterface, ref string) (*PurgeRecord, error) {
+	b, err := ctx.GetStub().GetState(purgedKeyPrefix + ref)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var rec PurgeRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("corrupt purge record")
+	}
+	return &rec, nil
+}
+
+// PurgePrivatePayload deletes an event's private payload. Events under an
+// active legal hold cannot be purged. Peers need Fabric 2.5 or later.
+func (c *AuditLogContract) PurgePrivatePayload(ctx contractapi.TransactionContextInterface, eventID string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	ref, err := callerRef(ctx, cfg, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if stored.PrivateCollection == "" {
+		return "", fmt.Errorf("not_found: event has no private payload")
+	}
+	prior, err := getPurge(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if prior != nil {
+		return "", fmt.Errorf("already_purged: by %s", prior.MarkerEventID)
+	}
+	if err := requireNoHold(ctx, stored); err != nil {
+		return "", err
+	}
+
+	if err := ctx.GetStub().PurgePrivateData(stored.PrivateCollection, ref); err != nil {
+		return "", err
+	}
+	// The marker lives in the purged event's namespace so the lineage link
+	// resolves.
+	marker, err := contractEvent(ctx, cfg, stored.Producer, LedgerEvent{
+		EventType:      purgedType,
+		ArtifactHash:   stored.Event.ArtifactHash,
+		HashAlgorithm:  stored.Event.HashAlgorithm,
+		SchemaVer:      purgedSchema,
+		CorrelationID:  stored.Event.CorrelationID,
+		ParentEventIDs: []string{stored.Event.EventID},
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := commitContractEvent(ctx, cfg, marker); err != nil {
+		return "", err
+	}
+
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	rec := PurgeRecord{
+		PurgedByMSP:   marker.CreatorMSP,
+		MarkerEventID: marker.Event.EventID,
+		TxID:          ctx.GetStub().GetTxID(),
+		PurgedAt:      now.Format(time.RFC3339Nano),
+	}
+	out, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(purgedKeyPrefix+ref, out); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
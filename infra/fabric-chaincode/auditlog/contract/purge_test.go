@@ -0,0 +1,21 @@
+package contract_test
+
+import (
+	"errors"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+)
+
+// Malformed ids are refused before any lookup, as by every other
+// transaction taking an event_id.
+func TestPurgePrivatePayloadRejectsInvalidEventID(t *testing.T) {
+	h := initHarness(t, nil)
+	for _, eventID := range []string{"", "not-a-uuid", "../" + auditlogtest.ID("INGEST", 1)} {
+		_, err := h.Submit(auditlogtest.Client("Org1MSP"), "PurgePrivatePayload", eventID)
+		var txErr *auditlogtest.TxError
+		if !errors.As(err, &txErr) || txErr.Message != "invalid event_id" {
+			t.Errorf("%q: err = %v, want invalid event_id", eventID, err)
+		}
+	}
+}
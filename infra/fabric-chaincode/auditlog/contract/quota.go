@@ -0,0 +1,187 @@
+package contract
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Writes are counted per creator MSP and UTC ledger day under the
+// statByOrgDay counter dimension; see counters.go. Contract-written events
+// are not counted.
+//
+// Quotas are enforced against one usage key per org and day,
+// quotaUsageIndex [msp, day], which each counted write of an org with a
+// quota reads and increments. The check thus costs one read however many
+// writes the day has seen, but it makes the org's transactions in one
+// block conflict: all but the first fail MVCC validation. Orgs without a
+// quota never touch the key and do not conflict. The key is seeded from
+// the day's counters the first time the org's quota applies that day.
+const quotaUsageIndex = "quota~msp~day"
+
+// Usage is one org's write count for one ledger day against its quota.
+// Quota and Remaining are 0 when the org has no quota.
+type Usage struct {
+	MSPID     string `json:"msp_id"`
+	Day       string `json:"day"`
+	Written   int    `json:"written"`
+	Quota     int    `json:"quota"`
+	Remaining int    `json:"remaining"`
+}
+
+func (cfg *ContractConfig) validateQuotas() error {
+	if cfg.DailyWriteQuota < 0 {
+		return fmt.Errorf("daily_write_quota must not be negative")
+	}
+	for m, q := range cfg.DailyWriteQuotas {
+		if m == "" {
+			return fmt.Errorf("daily_write_quotas keys must be non-empty MSP IDs")
+		}
+		if q < 0 {
+			return fmt.Errorf("daily_write_quotas entries must not be negative")
+		}
+	}
+	return nil
+}
+
+// quotaFor returns the daily write quota of mspID, 0 meaning unlimited.
+func (cfg *ContractConfig) quotaFor(mspID string) int {
+	if q, ok := cfg.DailyWriteQuotas[mspID]; ok {
+		return q
+	}
+	return cfg.DailyWriteQuota
+}
+
+func ledgerDay(ctx contractapi.TransactionContextInterface) (string, error) {
+	txTime, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	return txTime.Format(statDayLayout), nil
+}
+
+// orgDayUsage sums the committed writes of mspID on day and those made
+// earlier in this transaction.
+func orgDayUsage(ctx contractapi.TransactionContextInterface, mspID, day string, pending *pendingWrites) (int, error) {
+	total := 0
+	truncated, err := newCounterScan(ctx).sum([]string{statByOrgDay, mspID, day}, func(_ []string, n int) { total += n })
+	if err != nil {
+		return 0, err
+	}
+	if truncated {
+		return 0, fmt.Errorf("usage of %s on %s spans more than %d counter keys; run CompactEventStats", mspID, day, maxCounterScan)
+	}
+	stub := ctx.GetStub()
+	key, err := stub.CreateCompositeKey(statCounterIndex, []string{statByOrgDay, mspID, day, stub.GetTxID()})
+	if err != nil {
+		return 0, err
+	}
+	return total + pending.counts[key], nil
+}
+
+// quotaUsed returns mspID's writes on day as the usage key has them,
+// including those made earlier in this transaction.
+func quotaUsed(ctx contractapi.TransactionContextInterface, mspID, day string, pending *pendingWrites) (string, int, error) {
+	key, err := ctx.GetStub().CreateCompositeKey(quotaUsageIndex, []string{mspID, day})
+	if err != nil {
+		return "", 0, err
+	}
+	if used, ok := pending.quota[key]; ok {
+		return key, used, nil
+	}
+	b, err := ctx.GetStub().GetState(key)
+	if err != nil {
+		return "", 0, err
+	}
+	var used int
+	if b == nil {
+		used, err = orgDayUsage(ctx, mspID, day, newPendingWrites())
+	} else if used, err = strconv.Atoi(string(b)); err != nil {
+		err = fmt.Errorf("corrupt quota usage %q", key)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	pending.quota[key] = used
+	return key, used, nil
+}
+
+// checkQuota refuses a new write by mspID once it has used up its quota
+// for the ledger day.
+func checkQuota(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, mspID string, pending *pendingWrites) error {
+	quota := cfg.quotaFor(mspID)
+	if quota == 0 {
+		return nil
+	}
+	day, err := ledgerDay(ctx)
+	if err != nil {
+		return err
+	}
+	_, used, err := quotaUsed(ctx, mspID, day, pending)
+	if err != nil {
+		return err
+	}
+	if used >= quota {
+		return fmt.Errorf("quota_exceeded: %s has written %d of %d events on %s", mspID, used, quota, day)
+	}
+	return nil
+}
+
+// countQuota adds a write by mspID on day to its usage key when it has a
+// quota.
+func countQuota(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, mspID, day string, pending *pendingWrites) error {
+	if cfg.quotaFor(mspID) == 0 {
+		return nil
+	}
+	key, used, err := quotaUsed(ctx, mspID, day, pending)
+	if err != nil {
+		return err
+	}
+	pending.quota[key] = used + 1
+	return ctx.GetStub().PutState(key, []byte(strconv.Itoa(used+1)))
+}
+
+// GetUsage returns the writes of mspID on day (YYYY-MM-DD, UTC ledger
+// time). Empty mspID means the caller's MSP and empty day the current day.
+// With org_scoped_reads only auditors may read another org's usage.
+func (c *AuditLogContract) GetUsage(ctx contractapi.TransactionContextInterface, mspID string, day string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	caller, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	if mspID == "" {
+		mspID = caller
+	}
+	if mspID != caller && cfg.OrgScopedReads {
+		if err := requireAuditor(ctx, cfg); err != nil {
+			return "", err
+		}
+	}
+	if day == "" {
+		if day, err = ledgerDay(ctx); err != nil {
+			return "", err
+		}
+	} else if _, err := time.Parse(statDayLayout, day); err != nil {
+		return "", fmt.Errorf("day must be YYYY-MM-DD")
+	}
+	var written int
+	if cfg.quotaFor(mspID) > 0 {
+		_, written, err = quotaUsed(ctx, mspID, day, newPendingWrites())
+	} else {
+		written, err = orgDayUsage(ctx, mspID, day, newPendingWrites())
+	}
+	if err != nil {
+		return "", err
+	}
+	u := Usage{MSPID: mspID, Day: day, Written: written, Quota: cfg.quotaFor(mspID)}
+	if u.Quota > 0 && written < u.Quota {
+		u.Remaining = u.Quota - written
+	}
+	return marshalString(u)
+}
@@ -0,0 +1,61 @@
+package contract_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// Writes of an org with a quota in one block conflict on its usage key, so
+// only the first commits; orgs without a quota are not held back.
+func TestQuotaSameBlockWrites(t *testing.T) {
+	h := initHarness(t, map[string]any{"daily_write_quotas": map[string]int{"Org1MSP": 3}})
+	org1, org2 := auditlogtest.Client("Org1MSP"), auditlogtest.Client("Org2MSP")
+	put(t, h, org1, auditlogtest.Event("INGEST", 1))
+
+	var block []*auditlogtest.Stub
+	for _, w := range []struct {
+		id auditlogtest.Identity
+		n  int
+	}{{org1, 2}, {org1, 3}, {org2, 4}, {org2, 5}} {
+		stub, err := h.Endorse(w.id, "PutEvent", auditlogtest.MustJSON(auditlogtest.Event("INGEST", w.n)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = append(block, stub)
+	}
+	errs := h.CommitBlock(block...)
+	if errs[0] != nil || errs[2] != nil || errs[3] != nil {
+		t.Fatalf("block validation %v, want only the second Org1MSP write invalid", errs)
+	}
+	if errs[1] == nil || !strings.Contains(errs[1].Error(), "MVCC_READ_CONFLICT") {
+		t.Fatalf("second Org1MSP write: %v, want an MVCC read conflict", errs[1])
+	}
+
+	// The invalidated write did not count.
+	put(t, h, org1, auditlogtest.Event("INGEST", 3))
+	_, err := h.Submit(org1, "PutEvent", auditlogtest.MustJSON(auditlogtest.Event("INGEST", 6)))
+	var txErr *auditlogtest.TxError
+	if !errors.As(err, &txErr) || txErr.Code != "ERR_QUOTA_EXCEEDED" {
+		t.Fatalf("fourth Org1MSP write: err = %v, want quota_exceeded", err)
+	}
+	for _, tc := range []struct {
+		msp           string
+		written, left int
+	}{{"Org1MSP", 3, 0}, {"Org2MSP", 2, 0}} {
+		out, err := h.Evaluate(auditlogtest.Client(tc.msp), "GetUsage", "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var u contract.Usage
+		if err := auditlogtest.Unmarshal(out, &u); err != nil {
+			t.Fatal(err)
+		}
+		if u.Written != tc.written || u.Remaining != tc.left {
+			t.Errorf("%s usage %+v, want %d written, %d remaining", tc.msp, u, tc.written, tc.left)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// With record_reads on, GetEvent, GetEventForProducer, GetEventsBatch and
+// GetPrivatePayload write an AccessRecord for every event they return. Evaluated queries never commit, so only reads
+// submitted as transactions leave a record; callers that must be on the
+// record submit them. Records live under their own composite keys, one by
+// event and one by reader MSP, and are not events.
+const (
+	accessByEventIndex  = "access~ref~ts~tx"
+	accessByReaderIndex = "access~msp~ts~tx"
+)
+
+type AccessRecord struct {
+	EventID     string `json:"event_id"`
+	Producer    string `json:"producer,omitempty"`
+	ReaderMSP   string `json:"reader_msp"`
+	ReaderID    string `json:"reader_id"`
+	Transaction string `json:"transaction"`
+	TxID        string `json:"tx_id"`
+	AccessedAt  string `json:"accessed_at"`
+}
+
+type AccessPage struct {
+	Records      []AccessRecord `json:"records"`
+	FetchedCount int32          `json:"fetched_count"`
+	Bookmark     string         `json:"bookmark"`
+}
+
+// recordAccess writes an AccessRecord for each of events when record_reads
+// is on.
+func recordAccess(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, events ...*StoredEvent) error {
+	if !cfg.RecordReads || len(events) == 0 {
+		return nil
+	}
+	stub := ctx.GetStub()
+	id := ctx.GetClientIdentity()
+	mspID, err := id.GetMSPID()
+	if err != nil {
+		return err
+	}
+	readerID, err := id.GetID()
+	if err != nil {
+		return err
+	}
+	txTime, err := txTimeUTC(ctx)
+	if err != nil {
+		return err
+	}
+	ts := txTime.Format(tsIndexLayout)
+	for _, stored := range events {
+		ref := stored.ref()
+		out, err := json.Marshal(AccessRecord{
+			EventID:     stored.Event.EventID,
+			Producer:    stored.Producer,
+			ReaderMSP:   mspID,
+			ReaderID:    readerID,
+			Transaction: txName(ctx),
+			TxID:        stub.GetTxID(),
+			AccessedAt:  txTime.Format(time.RFC3339Nano),
+		})
+		if err != nil {
+			return err
+		}
+		for _, k := range []indexEntry{
+			{accessByEventIndex, []string{ref, ts, stub.GetTxID()}},
+			{accessByReaderIndex, []string{mspID, ts, stub.GetTxID(), ref}},
+		} {
+			key, err := stub.CreateCompositeKey(k.objectType, k.attrs)
+			if err != nil {
+				return err
+			}
+			if err := stub.PutState(key, out); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetAccessLog pages through who read an event, oldest first. Empty
+// producerMSP resolves eventID in the caller's namespace.
+func (c *AuditLogContract) GetAccessLog(ctx contractapi.TransactionContextInterface, producerMSP string, eventID string, bookmark string, pageSize int32) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	ref := eventRef(producerMSP, eventID, cfg.TimeOrderedKeys)
+	if producerMSP == "" {
+		if ref, err = callerRef(ctx, cfg, eventID); err != nil {
+			return "", err
+		}
+	}
+	return pageAccess(ctx, accessByEventIndex, []string{ref}, bookmark, pageSize)
+}
+
+// GetAccessesByReader pages through the reads made by identities of
+// readerMSP, oldest first.
+func (c *AuditLogContract) GetAccessesByReader(ctx contractapi.TransactionContextInterface, readerMSP string, bookmark string, pageSize int32) (string, error) {
+	if readerMSP == "" {
+		return "", fmt.Errorf("reader_msp required")
+	}
+	return pageAccess(ctx, accessByReaderIndex, []string{readerMSP}, bookmark, pageSize)
+}
+
+func pageAccess(ctx contractapi.TransactionContextInterface, index string, attrs []string, bookmark string, pageSize int32) (string, error) {
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	it, meta, err := ctx.GetStub().GetStateByPartialCompositeKeyWithPagination(index, attrs, pageSize, bookmark)
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	page := AccessPage{Records: []AccessRecord{}}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		var rec AccessRecord
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			return "", fmt.Errorf("corrupt access record %q", kv.Key)
+		}
+		page.Records = append(page.Records, rec)
+	}
+	page.FetchedCount = meta.FetchedRecordsCount
+	page.Bookmark = meta.Bookmark
+	return marshalString(page)
+}
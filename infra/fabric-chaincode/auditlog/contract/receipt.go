@@ -0,0 +1,142 @@
+package contract
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/hyperledger/fabric-protos-go/peer"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// Receipt is what write transactions return. The same contract runs on
+// several channels, so it names the channel and chaincode as well as the
+// transaction. The block number is not known at endorsement; clients read
+// it from the commit status.
+type Receipt struct {
+	ChannelID   string `json:"channel_id"`
+	Chaincode   string `json:"chaincode,omitempty"`
+	TxID        string `json:"tx_id"`
+	TxTimestamp string `json:"tx_timestamp"`
+	EventID     string `json:"event_id,omitempty"`
+	PayloadHash string `json:"payload_hash_sha256,omitempty"`
+	// Deduped is set when the event already existed and nothing was
+	// written; TxID is then the current transaction, except for AmendEvent,
+	// which reports the transaction that recorded the amendment.
+	Deduped bool `json:"deduped,omitempty"`
+}
+
+// chaincodeName reads the invoked chaincode's name from the signed
+// proposal, or returns "" when it cannot be decoded.
+func chaincodeName(ctx contractapi.TransactionContextInterface) string {
+	sp, err := ctx.GetStub().GetSignedProposal()
+	if err != nil || sp == nil {
+		return ""
+	}
+	var prop peer.Proposal
+	if err := proto.Unmarshal(sp.ProposalBytes, &prop); err != nil {
+		return ""
+	}
+	var payload peer.ChaincodeProposalPayload
+	if err := proto.Unmarshal(prop.Payload, &payload); err != nil {
+		return ""
+	}
+	var spec peer.ChaincodeInvocationSpec
+	if err := proto.Unmarshal(payload.Input, &spec); err != nil {
+		return ""
+	}
+	return spec.GetChaincodeSpec().GetChaincodeId().GetName()
+}
+
+// newReceipt describes the current transaction.
+func newReceipt(ctx contractapi.TransactionContextInterface) (Receipt, error) {
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return Receipt{}, err
+	}
+	return Receipt{
+		ChannelID:   ctx.GetStub().GetChannelID(),
+		Chaincode:   chaincodeName(ctx),
+		TxID:        ctx.GetStub().GetTxID(),
+		TxTimestamp: now.Format(time.RFC3339Nano),
+	}, nil
+}
+
+// eventReceipt is newReceipt for a write of stored.
+func eventReceipt(ctx contractapi.TransactionContextInterface, stored *StoredEvent, dup bool) (string, error) {
+	r, err := newReceipt(ctx)
+	if err != nil {
+		return "", err
+	}
+	r.EventID, r.PayloadHash, r.Deduped = stored.Event.EventID, stored.PayloadHash, dup
+	return marshalString(r)
+}
+
+// EventProof is the receipt of the transaction that first wrote an event,
+// read back from state. LedgerKey is the state key the event lives under,
+// whose history and block the client can check independently.
+type EventProof struct {
+	Receipt
+	LedgerKey string `json:"ledger_key"`
+}
+
+// GetEventProof returns the writing transaction and stored payload hash of
+// eventID. Events written before receipts were added have no tx_id on
+// record; their first history entry is used instead, which needs the
+// peer's history database.
+func (c *AuditLogContract) GetEventProof(ctx contractapi.TransactionContextInterface, eventID string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	ref, err := resolveRef(ctx, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	p := EventProof{
+		Receipt: Receipt{
+			ChannelID:   ctx.GetStub().GetChannelID(),
+			Chaincode:   chaincodeName(ctx),
+			TxID:        stored.TxID,
+			TxTimestamp: stored.LedgerTimestamp,
+			EventID:     stored.Event.EventID,
+			PayloadHash: stored.PayloadHash,
+		},
+		LedgerKey: eventKey(ref),
+	}
+	if p.TxID == "" {
+		if err := firstWrite(ctx, p.LedgerKey, &p.Receipt); err != nil {
+			return "", err
+		}
+	}
+	return marshalString(p)
+}
+
+// firstWrite fills r from the oldest history entry of key. Fabric returns
+// history newest first, so that is the last one.
+func firstWrite(ctx contractapi.TransactionContextInterface, key string, r *Receipt) error {
+	it, err := ctx.GetStub().GetHistoryForKey(key)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+	for it.HasNext() {
+		m, err := it.Next()
+		if err != nil {
+			return err
+		}
+		r.TxID, r.TxTimestamp = m.TxId, ""
+		if m.Timestamp != nil {
+			r.TxTimestamp = m.Timestamp.AsTime().UTC().Format(time.RFC3339Nano)
+		}
+	}
+	if r.TxID == "" {
+		return fmt.Errorf("not_found: no history for %s", key)
+	}
+	return nil
+}
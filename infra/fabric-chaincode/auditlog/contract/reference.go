@@ -0,0 +1,94 @@
+package contract
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Events may name, in reference_tx, the business record they govern in the
+// payments/forecast chaincode on the same channel. The contract config says
+// which chaincode holds those records and which of its transactions reads
+// one by id; VerifyReference calls it through InvokeChaincode.
+var (
+	referenceTxRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:-]{0,127}$`)
+	chaincodeRe   = regexp.MustCompile(`^[a-zA-Z0-9]+(?:[-_][a-zA-Z0-9]+)*$`)
+)
+
+func validateReference(e *LedgerEvent) error {
+	if e.ReferenceTx != "" && !referenceTxRe.MatchString(e.ReferenceTx) {
+		return fmt.Errorf("reference_tx must be 1-128 characters of A-Z, a-z, 0-9, '.', '_', ':' or '-'")
+	}
+	return nil
+}
+
+func (cfg *ContractConfig) validateReferenceLookup() error {
+	switch {
+	case cfg.ReferenceChaincode == "" && cfg.ReferenceFunction == "":
+		return nil
+	case !chaincodeRe.MatchString(cfg.ReferenceChaincode):
+		return fmt.Errorf("reference_chaincode must be a chaincode name")
+	case cfg.ReferenceFunction == "":
+		return fmt.Errorf("reference_function required with reference_chaincode")
+	}
+	return nil
+}
+
+type ReferenceCheck struct {
+	EventID     string `json:"event_id"`
+	ReferenceTx string `json:"reference_tx"`
+	Chaincode   string `json:"chaincode"`
+	Function    string `json:"function"`
+	// Exists is true when the lookup succeeded. Any failure counts as
+	// missing; Status and Message carry the called chaincode's answer.
+	Exists    bool   `json:"exists"`
+	Status    int32  `json:"status"`
+	Message   string `json:"message,omitempty"`
+	CheckedAt string `json:"checked_at"`
+}
+
+// VerifyReference confirms that an event's reference_tx exists in the
+// configured reference chaincode. It is read-only: the payments record is
+// looked up in this transaction's simulation and nothing is written, so
+// evaluate it rather than submitting.
+func (c *AuditLogContract) VerifyReference(ctx contractapi.TransactionContextInterface, eventID string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cfg.ReferenceChaincode == "" {
+		return "", errors.New("reference_lookup_not_configured")
+	}
+	ref, err := callerRef(ctx, cfg, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if stored.Event.ReferenceTx == "" {
+		return "", errors.New("reference_tx_missing: event has no reference_tx")
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	args := [][]byte{[]byte(cfg.ReferenceFunction), []byte(stored.Event.ReferenceTx)}
+	resp := ctx.GetStub().InvokeChaincode(cfg.ReferenceChaincode, args, "")
+	return marshalString(ReferenceCheck{
+		EventID:     stored.Event.EventID,
+		ReferenceTx: stored.Event.ReferenceTx,
+		Chaincode:   cfg.ReferenceChaincode,
+		Function:    cfg.ReferenceFunction,
+		Exists:      resp.Status < shim.ERRORTHRESHOLD,
+		Status:      resp.Status,
+		Message:     resp.Message,
+		CheckedAt:   now.Format(time.RFC3339Nano),
+	})
+}
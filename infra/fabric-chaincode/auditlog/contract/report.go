@@ -0,0 +1,192 @@
+package contract
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+const maxReportSamples = 5
+
+// ComplianceReportPage is one link of a compliance report. Digest chains
+// every page: the first page starts from sha256("<start>|<end>") and each
+// page folds in "<event_id>:<payload_hash>\n" for its records in timestamp
+// order. The Digest of the final page (Complete) is the content digest of
+// the whole range, which anyone holding the records can recompute.
+type ComplianceReportPage struct {
+	Start        string         `json:"start"`
+	End          string         `json:"end"`
+	PageNumber   int            `json:"page_number"`
+	TypeCounts   map[string]int `json:"type_counts"`
+	Entries      []EventSummary `json:"entries"`
+	Samples      []EventView    `json:"samples"`
+	PrevDigest   string         `json:"prev_digest"`
+	Digest       string         `json:"digest"`
+	Complete     bool           `json:"complete"`
+	NextBookmark string         `json:"next_bookmark"`
+}
+
+// reportCursor is carried inside the opaque report bookmark so the chain
+// continues across pages without client-side state.
+type reportCursor struct {
+	Bookmark   string `json:"b"`
+	Digest     string `json:"d"`
+	PageNumber int    `json:"n"`
+}
+
+func decodeReportCursor(bookmark, start, end string) (*reportCursor, error) {
+	if bookmark == "" {
+		return &reportCursor{Digest: sha256Hex([]byte(start + "|" + end))}, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(bookmark)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bookmark")
+	}
+	var cur reportCursor
+	if err := json.Unmarshal(raw, &cur); err != nil || !validate.SHA256.MatchString(cur.Digest) {
+		return nil, fmt.Errorf("invalid bookmark")
+	}
+	return &cur, nil
+}
+
+// GenerateComplianceReport returns one page of the compliance report for
+// events with timestamps in [start, end). Pass each page's next_bookmark to
+// get the next; the last page has complete=true and an empty bookmark.
+func (c *AuditLogContract) GenerateComplianceReport(ctx contractapi.TransactionContextInterface, startRFC3339 string, endRFC3339 string, bookmark string, pageSize int32) (string, error) {
+	start, end, err := parseTimeRange(startRFC3339, endRFC3339)
+	if err != nil {
+		return "", err
+	}
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	cur, err := decodeReportCursor(bookmark, start, end)
+	if err != nil {
+		return "", err
+	}
+
+	startKey, endKey, err := timeRangeKeys(ctx, tsIndex, nil, start, end)
+	if err != nil {
+		return "", err
+	}
+	it, meta, err := ctx.GetStub().GetStateByRangeWithPagination(startKey, endKey, pageSize, cur.Bookmark)
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	page := ComplianceReportPage{
+		Start:      startRFC3339,
+		End:        endRFC3339,
+		PageNumber: cur.PageNumber + 1,
+		TypeCounts: map[string]int{},
+		Entries:    []EventSummary{},
+		Samples:    []EventView{},
+		PrevDigest: cur.Digest,
+	}
+	leaves := []byte(cur.Digest)
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		stored, err := getStoredEvent(ctx, string(kv.Value))
+		if err != nil {
+			return "", err
+		}
+		page.TypeCounts[stored.Event.EventType]++
+		page.Entries = append(page.Entries, EventSummary{
+			EventID:     stored.Event.EventID,
+			EventType:   stored.Event.EventType,
+			Timestamp:   stored.Event.TimestampUTC,
+			PayloadHash: stored.PayloadHash,
+		})
+		if len(page.Samples) < maxReportSamples {
+			if page.Samples, err = appendView(ctx, page.Samples, stored); err != nil {
+				return "", err
+			}
+		}
+		leaves = append(leaves, stored.Event.EventID+":"+stored.PayloadHash+"\n"...)
+	}
+	page.Digest = sha256Hex(leaves)
+
+	if meta.Bookmark == "" || meta.FetchedRecordsCount < pageSize {
+		page.Complete = true
+	} else {
+		next, err := json.Marshal(reportCursor{Bookmark: meta.Bookmark, Digest: page.Digest, PageNumber: page.PageNumber})
+		if err != nil {
+			return "", err
+		}
+		page.NextBookmark = base64.RawURLEncoding.EncodeToString(next)
+	}
+	return marshalString(page)
+}
+
+// AuditReport summarises every event with a timestamp in [start, end) for
+// handing to external auditors. EventSetHash is sha256 over
+// "<event_id>:<payload_hash>\n" for each event in timestamp order;
+// MerkleRoot is the root CommitDigest would compute for the window, and
+// Digest the latest digest committed for exactly this window, if any.
+// Signing happens client side; see client/auditlog.
+type AuditReport struct {
+	Start         string         `json:"start"`
+	End           string         `json:"end"`
+	AsOf          string         `json:"as_of"`
+	Total         int            `json:"total"`
+	TypeCounts    map[string]int `json:"type_counts"`
+	EventSetHash  string         `json:"event_set_hash"`
+	MerkleRoot    string         `json:"merkle_root,omitempty"`
+	Digest        *MerkleDigest  `json:"digest,omitempty"`
+	DigestMatches bool           `json:"digest_matches"`
+}
+
+// ExportAuditReport builds the AuditReport for [start, end). Windows holding
+// more than maxDigestLeaves events are rejected.
+func (c *AuditLogContract) ExportAuditReport(ctx contractapi.TransactionContextInterface, startRFC3339 string, endRFC3339 string) (string, error) {
+	start, end, err := parseTimeRange(startRFC3339, endRFC3339)
+	if err != nil {
+		return "", err
+	}
+	events, err := windowEvents(ctx, start, end)
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	report := AuditReport{
+		Start:      startRFC3339,
+		End:        endRFC3339,
+		AsOf:       now.Format(time.RFC3339Nano),
+		Total:      len(events),
+		TypeCounts: map[string]int{},
+	}
+	var lines strings.Builder
+	for _, e := range events {
+		report.TypeCounts[e.Event.EventType]++
+		fmt.Fprintf(&lines, "%s:%s\n", e.Event.EventID, e.PayloadHash)
+	}
+	report.EventSetHash = sha256Hex([]byte(lines.String()))
+	if len(events) > 0 {
+		levels, err := merkleTree(payloadHashes(events))
+		if err != nil {
+			return "", err
+		}
+		report.MerkleRoot = hex.EncodeToString(levels[len(levels)-1][0])
+	}
+	if report.Digest, err = latestDigestForWindow(ctx, start, end); err != nil {
+		return "", err
+	}
+	report.DigestMatches = report.Digest != nil && report.Digest.Root == report.MerkleRoot &&
+		report.Digest.LeafCount == report.Total
+	return marshalString(report)
+}
@@ -0,0 +1,545 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+	"payscope/auditlog/jcs"
+)
+
+// Retention is configured per event_type under retentionKeyPrefix+type.
+// ArchiveExpiredEvents stores an archival marker under archivedKeyPrefix+ref
+// for events past retention; the event record and its hashes stay readable.
+// Its pass over a type resumes from the index key under
+// archiveCursorKeyPrefix+type, so entries already archived or held are not
+// read again until the pass reaches the cutoff and starts over.
+const (
+	retentionKeyPrefix     = "retention:"
+	archivedKeyPrefix      = "archived:"
+	archiveCursorKeyPrefix = "archive_cursor:"
+	eventsArchivedName     = "EventsArchived"
+	maxArchiveBatch        = 500
+)
+
+var errArchiveBatchFull = errors.New("archive batch full")
+
+type RetentionPolicy struct {
+	EventType        string `json:"event_type"`
+	RetentionSeconds int64  `json:"retention_seconds"`
+	SetByMSP         string `json:"set_by_msp"`
+	UpdatedAt        string `json:"updated_at"`
+}
+
+type ArchiveRecord struct {
+	RetentionSeconds int64  `json:"retention_seconds"`
+	TxID             string `json:"tx_id"`
+	ArchivedAt       string `json:"archived_at"`
+	// Set by RecordArchiveLocation once the payload is in cold storage.
+	// LocationHash is the receipt over where it went; see locationHash.
+	StorageURI   string `json:"storage_uri,omitempty"`
+	StorageProof string `json:"storage_proof,omitempty"`
+	LocationHash string `json:"location_hash,omitempty"`
+	LocatedByMSP string `json:"located_by_msp,omitempty"`
+	LocationTxID string `json:"location_tx_id,omitempty"`
+	LocatedAt    string `json:"located_at,omitempty"`
+}
+
+// ResolutionHints tell an auditor where an archived event's original lives
+// and how to check what comes back: a plain payload must digest to
+// artifact_hash under hash_algorithm, an encrypted one names its data keys.
+type ResolutionHints struct {
+	StorageURI string `json:"storage_uri"`
+	// Where the payload was kept before archival.
+	PrivateCollection string   `json:"private_collection,omitempty"`
+	ArtifactHash      string   `json:"artifact_hash"`
+	HashAlgorithm     string   `json:"hash_algorithm,omitempty"`
+	PayloadKeyIDs     []string `json:"payload_key_ids,omitempty"`
+	LocationHash      string   `json:"location_hash"`
+}
+
+// archiveLocation is what LocationHash is taken over, in JCS.
+type archiveLocation struct {
+	EventID      string `json:"event_id"`
+	PayloadHash  string `json:"payload_hash_sha256"`
+	StorageURI   string `json:"storage_uri"`
+	StorageProof string `json:"storage_proof"`
+}
+
+const maxStorageProofLength = 1024
+
+// ArchivedNotice is the chaincode event payload for one archived event. An
+// off-chain mover uses PrivateCollection to find payloads to move to cold
+// storage.
+type ArchivedNotice struct {
+	EventID           string `json:"event_id"`
+	EventType         string `json:"event_type"`
+	PayloadHash       string `json:"payload_hash_sha256"`
+	PrivateCollection string `json:"private_collection,omitempty"`
+	TxID              string `json:"tx_id"`
+}
+
+type ArchiveResult struct {
+	ArchivedIDs []string `json:"archived_ids"`
+	// Past retention but skipped because a legal hold covers them.
+	HeldIDs []string `json:"held_ids"`
+	// More events past retention remain; call again.
+	More bool `json:"more"`
+}
+
+func getRetentionPolicy(ctx contractapi.TransactionContextInterface, eventType string) (*RetentionPolicy, error) {
+	b, err := ctx.GetStub().GetState(retentionKeyPrefix + eventType)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var p RetentionPolicy
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("corrupt retention policy")
+	}
+	return &p, nil
+}
+
+func getArchival(ctx contractapi.TransactionContextInterface, ref string) (*ArchiveRecord, error) {
+	b, err := ctx.GetStub().GetState(archivedKeyPrefix + ref)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var rec ArchiveRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("corrupt archive record")
+	}
+	return &rec, nil
+}
+
+// locationHash is the hex SHA-256 of the JCS encoding of archiveLocation,
+// binding the storage location to the event it holds.
+func locationHash(stored *StoredEvent, storageURI, storageProof string) (string, error) {
+	b, err := jcs.Marshal(archiveLocation{
+		EventID:      stored.Event.EventID,
+		PayloadHash:  stored.PayloadHash,
+		StorageURI:   storageURI,
+		StorageProof: storageProof,
+	})
+	if err != nil {
+		return "", err
+	}
+	return sha256Hex(b), nil
+}
+
+func resolutionHints(stored *StoredEvent, rec *ArchiveRecord) *ResolutionHints {
+	if rec == nil || rec.StorageURI == "" {
+		return nil
+	}
+	return &ResolutionHints{
+		StorageURI:        rec.StorageURI,
+		PrivateCollection: stored.PrivateCollection,
+		ArtifactHash:      stored.Event.ArtifactHash,
+		HashAlgorithm:     stored.Event.HashAlgorithm,
+		PayloadKeyIDs:     stored.PayloadKeyIDs,
+		LocationHash:      rec.LocationHash,
+	}
+}
+
+// SetRetentionPolicy sets how long events of eventType are kept before they
+// may be archived. retentionSeconds 0 removes the policy. Changes apply to
+// the next ArchiveExpiredEvents run; archived events stay archived.
+func (c *AuditLogContract) SetRetentionPolicy(ctx contractapi.TransactionContextInterface, eventType string, retentionSeconds int64) (string, error) {
+	if retentionSeconds < 0 {
+		return "", fmt.Errorf("retention_seconds must be non-negative")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	if err := requireEventType(ctx, eventType); err != nil {
+		return "", err
+	}
+	stub := ctx.GetStub()
+	if retentionSeconds == 0 {
+		if err := stub.DelState(retentionKeyPrefix + eventType); err != nil {
+			return "", err
+		}
+		return stub.GetTxID(), nil
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(RetentionPolicy{
+		EventType:        eventType,
+		RetentionSeconds: retentionSeconds,
+		SetByMSP:         mspID,
+		UpdatedAt:        now.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := stub.PutState(retentionKeyPrefix+eventType, out); err != nil {
+		return "", err
+	}
+	return stub.GetTxID(), nil
+}
+
+func (c *AuditLogContract) ListRetentionPolicies(ctx contractapi.TransactionContextInterface) (string, error) {
+	it, err := ctx.GetStub().GetStateByRange(retentionKeyPrefix, retentionKeyPrefix+string(rune(0x10FFFF)))
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	policies := []RetentionPolicy{}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		var p RetentionPolicy
+		if err := json.Unmarshal(kv.Value, &p); err != nil {
+			return "", fmt.Errorf("corrupt retention policy")
+		}
+		policies = append(policies, p)
+	}
+	sort.Slice(policies, func(i, j int) bool { return policies[i].EventType < policies[j].EventType })
+	return marshalString(policies)
+}
+
+// ArchiveExpiredEvents marks up to limit events of eventType whose
+// timestamp is older than the type's retention as archived, oldest first,
+// and emits one EventsArchived chaincode event listing them. Events under a
+// legal hold are skipped. At most maxStatsScan index entries are read per
+// call, continuing where the previous call stopped; More reports that
+// another call is needed. Once a pass reaches the cutoff, the next call
+// starts over and retries the events it held.
+func (c *AuditLogContract) ArchiveExpiredEvents(ctx contractapi.TransactionContextInterface, eventType string, limit int) (string, error) {
+	if limit < 1 || limit > maxArchiveBatch {
+		return "", fmt.Errorf("limit must be between 1 and %d", maxArchiveBatch)
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	if err := requireEventType(ctx, eventType); err != nil {
+		return "", err
+	}
+	policy, err := getRetentionPolicy(ctx, eventType)
+	if err != nil {
+		return "", err
+	}
+	if policy == nil {
+		return "", errors.New("no_retention_policy")
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	cutoff := now.Add(-time.Duration(policy.RetentionSeconds) * time.Second).UTC().Format(tsIndexLayout)
+
+	stub := ctx.GetStub()
+	txID := stub.GetTxID()
+	rec, err := json.Marshal(ArchiveRecord{
+		RetentionSeconds: policy.RetentionSeconds,
+		TxID:             txID,
+		ArchivedAt:       now.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	result := ArchiveResult{ArchivedIDs: []string{}, HeldIDs: []string{}}
+	var notices []ArchivedNotice
+	startKey, endKey, err := timeRangeKeys(ctx, typeTsIndex, []string{eventType}, "", cutoff)
+	if err != nil {
+		return "", err
+	}
+	cursorKey := archiveCursorKeyPrefix + eventType
+	cursor, err := stub.GetState(cursorKey)
+	if err != nil {
+		return "", err
+	}
+	if c := string(cursor); c >= startKey && c < endKey {
+		// The key right after the cursor's.
+		startKey = c + "\x00"
+	}
+	last := ""
+	truncated, err := scanKeyRange(ctx, startKey, endKey, 1, maxStatsScan, func(key, _, ref string) error {
+		if len(result.ArchivedIDs) == limit {
+			return errArchiveBatchFull
+		}
+		last = key
+		prior, err := getArchival(ctx, ref)
+		if err != nil {
+			return err
+		}
+		if prior != nil {
+			return nil
+		}
+		stored, err := getStoredEvent(ctx, ref)
+		if err != nil {
+			return err
+		}
+		holdID, err := activeHoldFor(ctx, stored)
+		if err != nil {
+			return err
+		}
+		if holdID != "" {
+			result.HeldIDs = append(result.HeldIDs, stored.Event.EventID)
+			return nil
+		}
+		if err := stub.PutState(archivedKeyPrefix+ref, rec); err != nil {
+			return err
+		}
+		result.ArchivedIDs = append(result.ArchivedIDs, stored.Event.EventID)
+		notices = append(notices, ArchivedNotice{
+			EventID:           stored.Event.EventID,
+			EventType:         stored.Event.EventType,
+			PayloadHash:       stored.PayloadHash,
+			PrivateCollection: stored.PrivateCollection,
+			TxID:              txID,
+		})
+		return nil
+	})
+	switch {
+	case errors.Is(err, errArchiveBatchFull):
+		result.More = true
+	case err != nil:
+		return "", err
+	default:
+		result.More = truncated
+	}
+	if result.More {
+		if last != "" {
+			err = stub.PutState(cursorKey, []byte(last))
+		}
+	} else if cursor != nil {
+		err = stub.DelState(cursorKey)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if len(notices) > 0 {
+		payload, err := json.Marshal(notices)
+		if err != nil {
+			return "", err
+		}
+		if err := stub.SetEvent(eventsArchivedName, payload); err != nil {
+			return "", err
+		}
+	}
+	return marshalString(result)
+}
+
+// RecordArchiveLocation records where an archived event's payload was moved
+// to in cold storage. storageProof is the mover's evidence of the stored
+// object, such as its version id or digest. The location is recorded once;
+// reads of the event then carry resolution hints pointing at it.
+func (c *AuditLogContract) RecordArchiveLocation(ctx contractapi.TransactionContextInterface, eventID string, storageURI string, storageProof string) (string, error) {
+	if err := validate.StorageURI(storageURI); err != nil {
+		return "", err
+	}
+	if storageProof == "" || len(storageProof) > maxStorageProofLength {
+		return "", fmt.Errorf("storage_proof must be 1 to %d characters", maxStorageProofLength)
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	ref, err := callerRef(ctx, cfg, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	rec, err := getArchival(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if rec == nil {
+		return "", errors.New("not_archived: archive the event before recording its location")
+	}
+	if rec.StorageURI != "" {
+		return "", fmt.Errorf("archive_location_recorded: at %s", rec.StorageURI)
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	if rec.LocationHash, err = locationHash(stored, storageURI, storageProof); err != nil {
+		return "", err
+	}
+	rec.StorageURI, rec.StorageProof = storageURI, storageProof
+	rec.LocatedByMSP = mspID
+	rec.LocationTxID = ctx.GetStub().GetTxID()
+	rec.LocatedAt = now.Format(time.RFC3339Nano)
+	out, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(archivedKeyPrefix+ref, out); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// ExpiredEventPage is one page of QueryExpiredEvents. Records holds the
+// projected views of events past retention that ArchiveExpiredEvents would
+// archive; Backlog counts all events past retention, up to maxStatsScan
+// index entries.
+type ExpiredEventPage struct {
+	EventType    string         `json:"event_type"`
+	AsOf         string         `json:"as_of"`
+	Cutoff       string         `json:"cutoff"`
+	Records      []any          `json:"records"`
+	FetchedCount int32          `json:"fetched_count"`
+	Bookmark     string         `json:"bookmark"`
+	Backlog      ExpiredBacklog `json:"backlog"`
+}
+
+type ExpiredBacklog struct {
+	// Past retention and neither archived nor held.
+	Pending  int `json:"pending"`
+	Archived int `json:"archived"`
+	Held     int `json:"held"`
+	// The scan stopped at maxStatsScan entries; the counts are lower bounds.
+	Truncated bool `json:"truncated"`
+}
+
+// QueryExpiredEvents pages through the events of eventType whose timestamp
+// is older than the type's retention at asOf (the transaction time when
+// empty), oldest first. Archived events and events under a legal hold are
+// left out of the records but counted in the backlog. Pages may hold fewer
+// than fetched_count records; keep paging until the bookmark is empty.
+func (c *AuditLogContract) QueryExpiredEvents(ctx contractapi.TransactionContextInterface, eventType string, asOfRFC3339 string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if err := requireEventType(ctx, eventType); err != nil {
+		return "", err
+	}
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	asOf, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	if asOfRFC3339 != "" {
+		if asOf, err = time.Parse(time.RFC3339, asOfRFC3339); err != nil {
+			return "", fmt.Errorf("as_of must be RFC3339")
+		}
+	}
+	policy, err := getRetentionPolicy(ctx, eventType)
+	if err != nil {
+		return "", err
+	}
+	if policy == nil {
+		return "", errors.New("no_retention_policy")
+	}
+	cutoff := asOf.Add(-time.Duration(policy.RetentionSeconds) * time.Second).UTC()
+	end := cutoff.Format(tsIndexLayout)
+
+	page := ExpiredEventPage{
+		EventType: eventType,
+		AsOf:      asOf.UTC().Format(time.RFC3339Nano),
+		Cutoff:    cutoff.Format(time.RFC3339Nano),
+		Records:   []any{},
+	}
+	page.Backlog.Truncated, err = scanTimeRange(ctx, typeTsIndex, []string{eventType}, "", end, maxStatsScan, func(_, ref string) error {
+		archivable, archived, err := expiredState(ctx, ref)
+		switch {
+		case err != nil:
+			return err
+		case archived:
+			page.Backlog.Archived++
+		case archivable == nil:
+			page.Backlog.Held++
+		default:
+			page.Backlog.Pending++
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	startKey, endKey, err := timeRangeKeys(ctx, typeTsIndex, []string{eventType}, "", end)
+	if err != nil {
+		return "", err
+	}
+	it, meta, err := ctx.GetStub().GetStateByRangeWithPagination(startKey, endKey, pageSize, bookmark)
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		stored, _, err := expiredState(ctx, string(kv.Value))
+		if err != nil {
+			return "", err
+		}
+		if stored == nil {
+			continue
+		}
+		view, err := viewOf(ctx, stored)
+		if err != nil {
+			return "", err
+		}
+		page.Records = append(page.Records, projectView(view, projection))
+	}
+	page.FetchedCount = meta.FetchedRecordsCount
+	page.Bookmark = meta.Bookmark
+	return marshalString(page)
+}
+
+// expiredState returns the event at ref if ArchiveExpiredEvents would
+// archive it, or nil with archived reporting why not.
+func expiredState(ctx contractapi.TransactionContextInterface, ref string) (*StoredEvent, bool, error) {
+	prior, err := getArchival(ctx, ref)
+	if err != nil || prior != nil {
+		return nil, prior != nil, err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return nil, false, err
+	}
+	holdID, err := activeHoldFor(ctx, stored)
+	if err != nil || holdID != "" {
+		return nil, false, err
+	}
+	return stored, false, nil
+}
@@ -0,0 +1,76 @@
+package contract_test
+
+import (
+	"testing"
+	"time"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// More events past retention than one call scans must all get archived:
+// entries already archived do not count against later calls' scan cap.
+func TestArchiveExpiredEventsPastScanCap(t *testing.T) {
+	const total = 10500
+	h, err := auditlogtest.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := auditlogtest.Admin("Org1MSP")
+	if _, err := h.Submit(admin, "Init", `{"admin_msps":["Org1MSP"]}`); err != nil {
+		t.Fatal(err)
+	}
+	client := auditlogtest.Client("Org1MSP")
+	for start := 1; start <= total; start += 500 {
+		batch := make([]*contract.LedgerEvent, 0, 500)
+		for n := start; n < start+500; n++ {
+			batch = append(batch, auditlogtest.Event("INGEST", n))
+		}
+		if _, err := h.Submit(client, "PutEvents", auditlogtest.MustJSON(batch)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := h.Submit(admin, "SetRetentionPolicy", "INGEST", "60"); err != nil {
+		t.Fatal(err)
+	}
+	h.Advance(24 * time.Hour)
+
+	archived := map[string]bool{}
+	archive(t, h, admin, archived)
+	if len(archived) != total {
+		t.Fatalf("archived %d events, want %d", len(archived), total)
+	}
+
+	// The finished pass starts over and finds nothing left.
+	again := map[string]bool{}
+	archive(t, h, admin, again)
+	if len(again) != 0 {
+		t.Fatalf("second pass archived %d events", len(again))
+	}
+}
+
+// archive calls ArchiveExpiredEvents for INGEST until it reports no more,
+// recording the archived ids in archived.
+func archive(t *testing.T, h *auditlogtest.Harness, admin auditlogtest.Identity, archived map[string]bool) {
+	t.Helper()
+	for call := 0; call < 100; call++ {
+		out, err := h.Submit(admin, "ArchiveExpiredEvents", "INGEST", "500")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res contract.ArchiveResult
+		if err := auditlogtest.Unmarshal(out, &res); err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range res.ArchivedIDs {
+			if archived[id] {
+				t.Fatalf("%s archived twice", id)
+			}
+			archived[id] = true
+		}
+		if !res.More {
+			return
+		}
+	}
+	t.Fatalf("no end after 100 calls: %d archived", len(archived))
+}
@@ -0,0 +1,114 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// Revocation appends a marker under revokedKeyPrefix+ref; the event record
+// itself is never changed. Reads attach the marker as "revocation".
+const (
+	revokedKeyPrefix    = "revoked:"
+	maxJustificationLen = 1024
+)
+
+var revocationReasons = map[string]bool{
+	"COMPROMISED_CREDENTIAL": true,
+	"ERRONEOUS_DATA":         true,
+	"DUPLICATE":              true,
+	"POLICY_VIOLATION":       true,
+	"OTHER":                  true,
+}
+
+type RevocationRecord struct {
+	ReasonCode    string `json:"reason_code"`
+	Justification string `json:"justification"`
+	RevokedByMSP  string `json:"revoked_by_msp"`
+	TxID          string `json:"tx_id"`
+	RevokedAt     string `json:"revoked_at"`
+}
+
+func getRevocation(ctx contractapi.TransactionContextInterface, ref string) (*RevocationRecord, error) {
+	b, err := ctx.GetStub().GetState(revokedKeyPrefix + ref)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var rec RevocationRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("corrupt revocation record")
+	}
+	return &rec, nil
+}
+
+// RevokeEvent marks an event as revoked, for example because it was written
+// with a compromised pipeline credential. reasonCode is one of
+// revocationReasons. Revocation is permanent, happens once, and is refused
+// while a legal hold covers the event.
+func (c *AuditLogContract) RevokeEvent(ctx contractapi.TransactionContextInterface, eventID string, reasonCode string, justification string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	if !revocationReasons[reasonCode] {
+		return "", fmt.Errorf("invalid reason_code")
+	}
+	if justification == "" || len(justification) > maxJustificationLen {
+		return "", fmt.Errorf("justification must be 1 to %d bytes", maxJustificationLen)
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	ref, err := callerRef(ctx, cfg, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if err := requireNoHold(ctx, stored); err != nil {
+		return "", err
+	}
+	prior, err := getRevocation(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	if prior != nil {
+		return "", errors.New("already_revoked")
+	}
+
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(RevocationRecord{
+		ReasonCode:    reasonCode,
+		Justification: justification,
+		RevokedByMSP:  mspID,
+		TxID:          ctx.GetStub().GetTxID(),
+		RevokedAt:     now.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(revokedKeyPrefix+ref, out); err != nil {
+		return "", err
+	}
+	return ctx.GetStub().GetTxID(), nil
+}
@@ -0,0 +1,135 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Rich queries need a CouchDB state database. Indexes for the common
+// multi-field filters ship in META-INF/statedb/couchdb/indexes and are
+// installed with the chaincode. Timestamps compare as strings in CouchDB,
+// so range filters on event.timestamp are only reliable when producers
+// send one format (see timestamp_precision).
+//
+// The state database is the state_database config option, so rich queries
+// on LevelDB fail before reaching the peer. Without it, each call probes
+// the endorsing peer; the probe is never written to state, since peers of
+// one channel may answer differently and their writes would not match.
+const (
+	stateDBLevelDB = "leveldb"
+	stateDBCouchDB = "couchdb"
+)
+
+var errRichQueryLevelDB = errors.New("rich_query_unsupported_on_leveldb: use GetEventsByType, QueryEventsByTimeRange or GetEventsByArtifactHash instead")
+
+// richQueryErr turns the peer's LevelDB error into an actionable one.
+func richQueryErr(err error) error {
+	if isLevelDBErr(err) {
+		return errRichQueryLevelDB
+	}
+	return err
+}
+
+func isLevelDBErr(err error) bool {
+	return strings.Contains(strings.ToLower(err.Error()), "leveldb")
+}
+
+// detectStateDatabase runs a trivial rich query, which LevelDB refuses.
+func detectStateDatabase(ctx contractapi.TransactionContextInterface) (string, error) {
+	it, err := ctx.GetStub().GetQueryResult(`{"selector":{"_id":"` + configKey + `"}}`)
+	if err != nil {
+		if isLevelDBErr(err) {
+			return stateDBLevelDB, nil
+		}
+		return "", err
+	}
+	it.Close()
+	return stateDBCouchDB, nil
+}
+
+// stateDatabase is the configured state database, or the endorsing peer's
+// when none is configured.
+func stateDatabase(ctx contractapi.TransactionContextInterface, cfg *ContractConfig) (string, error) {
+	if cfg.StateDatabase != "" {
+		return cfg.StateDatabase, nil
+	}
+	return detectStateDatabase(ctx)
+}
+
+// eventSelectorQuery restricts a client selector to stored event documents;
+// config, receipts and other JSON records share the namespace.
+func eventSelectorQuery(selectorJSON string) (string, error) {
+	var selector map[string]any
+	if err := json.Unmarshal([]byte(selectorJSON), &selector); err != nil {
+		return "", fmt.Errorf("invalid selector json: %w", err)
+	}
+	if len(selector) == 0 {
+		return "", fmt.Errorf("selector must not be empty")
+	}
+	query := map[string]any{
+		"selector": map[string]any{
+			"$and": []any{
+				selector,
+				map[string]any{
+					"event.event_id":      map[string]any{"$exists": true},
+					"payload_hash_sha256": map[string]any{"$exists": true},
+				},
+			},
+		},
+	}
+	out, err := json.Marshal(query)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// QueryEventsBySelector runs a CouchDB Mango selector over stored events,
+// e.g. {"event.event_type":"FORECAST","event.schema_version":"v2"}, and
+// returns one page of matches.
+func (c *AuditLogContract) QueryEventsBySelector(ctx contractapi.TransactionContextInterface, selectorJSON string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	query, err := eventSelectorQuery(selectorJSON)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cfg.StateDatabase == stateDBLevelDB {
+		return "", errRichQueryLevelDB
+	}
+	it, meta, err := ctx.GetStub().GetQueryResultWithPagination(query, pageSize, bookmark)
+	if err != nil {
+		return "", richQueryErr(err)
+	}
+	defer it.Close()
+
+	page := EventPage{Records: []EventView{}}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		var stored StoredEvent
+		if err := json.Unmarshal(kv.Value, &stored); err != nil {
+			return "", fmt.Errorf("corrupt stored event")
+		}
+		if page.Records, err = appendView(ctx, page.Records, &stored); err != nil {
+			return "", err
+		}
+	}
+	page.FetchedCount = meta.FetchedRecordsCount
+	page.Bookmark = meta.Bookmark
+	return marshalPage(&page, projection)
+}
@@ -0,0 +1,77 @@
+package contract
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// Salts are kept under their own keys so that rotating one never changes how
+// earlier records verify. StoredEvent only records the salt id.
+const (
+	saltKeyPrefix  = "salt:"
+	currentSaltKey = "salt_current"
+	maxSaltLen     = 256
+)
+
+func saltID(salt string) string {
+	return sha256Hex([]byte(salt))[:16]
+}
+
+// saltByID returns the salt registered under id; the empty id means unsalted.
+func saltByID(ctx contractapi.TransactionContextInterface, id string) (string, error) {
+	if id == "" {
+		return "", nil
+	}
+	b, err := ctx.GetStub().GetState(saltKeyPrefix + id)
+	if err != nil {
+		return "", err
+	}
+	if b == nil {
+		return "", fmt.Errorf("unknown salt_id %s", id)
+	}
+	return string(b), nil
+}
+
+// currentSalt returns the salt id and value applied to new writes.
+func currentSalt(ctx contractapi.TransactionContextInterface) (string, string, error) {
+	b, err := ctx.GetStub().GetState(currentSaltKey)
+	if err != nil {
+		return "", "", err
+	}
+	id := string(b)
+	salt, err := saltByID(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	return id, salt, nil
+}
+
+// SetHashingSalt sets the salt prepended to the canonical payload before
+// hashing new events. An empty salt switches new writes back to unsalted.
+// Returns the salt id recorded on events written with it.
+func (c *AuditLogContract) SetHashingSalt(ctx contractapi.TransactionContextInterface, salt string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	if len(salt) > maxSaltLen {
+		return "", fmt.Errorf("salt must be at most %d bytes", maxSaltLen)
+	}
+
+	stub := ctx.GetStub()
+	if salt == "" {
+		return "", stub.DelState(currentSaltKey)
+	}
+	id := saltID(salt)
+	if err := stub.PutState(saltKeyPrefix+id, []byte(salt)); err != nil {
+		return "", err
+	}
+	if err := stub.PutState(currentSaltKey, []byte(id)); err != nil {
+		return "", err
+	}
+	return id, nil
+}
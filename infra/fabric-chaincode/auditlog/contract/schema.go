@@ -0,0 +1,189 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// The schema registry is opt-in: until the first RegisterSchema call any
+// non-empty schema_version is accepted. Afterwards an event's version must
+// be registered and not deprecated.
+const (
+	schemaKeyPrefix      = "schema:"
+	schemaRegistryActive = "schema_registry_active"
+)
+
+var schemaVersionRe = regexp.MustCompile("^[A-Za-z0-9._-]{1,64}$")
+
+type SchemaRecord struct {
+	Version      string          `json:"version"`
+	JSONSchema   json.RawMessage `json:"json_schema,omitempty"`
+	Status       string          `json:"status"`
+	RegisteredAt string          `json:"registered_at"`
+	DeprecatedAt string          `json:"deprecated_at,omitempty"`
+}
+
+func getSchema(ctx contractapi.TransactionContextInterface, version string) (*SchemaRecord, error) {
+	b, err := ctx.GetStub().GetState(schemaKeyPrefix + version)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var rec SchemaRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("corrupt schema record")
+	}
+	return &rec, nil
+}
+
+func putSchema(ctx contractapi.TransactionContextInterface, rec *SchemaRecord) error {
+	out, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(schemaKeyPrefix+rec.Version, out)
+}
+
+// checkSchemaRegistered enforces the registry once it is active.
+func checkSchemaRegistered(ctx contractapi.TransactionContextInterface, version string) error {
+	active, err := ctx.GetStub().GetState(schemaRegistryActive)
+	if err != nil {
+		return err
+	}
+	if active == nil {
+		return nil
+	}
+	rec, err := getSchema(ctx, version)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return fmt.Errorf("schema_version_not_registered: %s", version)
+	}
+	if rec.Status == "deprecated" {
+		return fmt.Errorf("schema_version_deprecated: %s", version)
+	}
+	return nil
+}
+
+// RegisterSchema adds an allowed schema_version, optionally with the JSON
+// Schema describing it. Re-registering an existing version is rejected.
+func (c *AuditLogContract) RegisterSchema(ctx contractapi.TransactionContextInterface, version string, jsonSchema string) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return err
+	}
+	return registerSchema(ctx, version, jsonSchema)
+}
+
+func registerSchema(ctx contractapi.TransactionContextInterface, version string, jsonSchema string) error {
+	if !schemaVersionRe.MatchString(version) {
+		return fmt.Errorf("invalid schema_version")
+	}
+	existing, err := getSchema(ctx, version)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return errors.New("schema_already_registered")
+	}
+	rec := &SchemaRecord{Version: version, Status: "active"}
+	if jsonSchema != "" {
+		if !json.Valid([]byte(jsonSchema)) {
+			return fmt.Errorf("json_schema must be valid JSON")
+		}
+		rec.JSONSchema = json.RawMessage(jsonSchema)
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return err
+	}
+	rec.RegisteredAt = now.Format(time.RFC3339Nano)
+	if err := putSchema(ctx, rec); err != nil {
+		return err
+	}
+	return ctx.GetStub().PutState(schemaRegistryActive, []byte("1"))
+}
+
+// DeprecateSchema stops new events from using a version. Existing events
+// keep it and stay readable.
+func (c *AuditLogContract) DeprecateSchema(ctx contractapi.TransactionContextInterface, version string) error {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return err
+	}
+	rec, err := getSchema(ctx, version)
+	if err != nil {
+		return err
+	}
+	if rec == nil {
+		return fmt.Errorf("not_found")
+	}
+	if rec.Status == "deprecated" {
+		return nil
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return err
+	}
+	rec.Status = "deprecated"
+	rec.DeprecatedAt = now.Format(time.RFC3339Nano)
+	return putSchema(ctx, rec)
+}
+
+// ListSchemas returns every registered schema version in key order.
+func (c *AuditLogContract) ListSchemas(ctx contractapi.TransactionContextInterface) (string, error) {
+	schemas, err := listSchemas(ctx)
+	if err != nil {
+		return "", err
+	}
+	return marshalString(schemas)
+}
+
+func listSchemas(ctx contractapi.TransactionContextInterface) ([]SchemaRecord, error) {
+	it, err := ctx.GetStub().GetStateByRange(schemaKeyPrefix, schemaKeyPrefix+string(rune(0x10FFFF)))
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	schemas := []SchemaRecord{}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return nil, err
+		}
+		var rec SchemaRecord
+		if err := json.Unmarshal(kv.Value, &rec); err != nil {
+			return nil, fmt.Errorf("corrupt schema record")
+		}
+		schemas = append(schemas, rec)
+	}
+	return schemas, nil
+}
+
+// QueryEventsBySchemaVersion pages through the events written under
+// schemaVersion in timestamp order, e.g. to re-validate everything still on
+// a deprecated version.
+func (c *AuditLogContract) QueryEventsBySchemaVersion(ctx contractapi.TransactionContextInterface, schemaVersion string, projection string, bookmark string, pageSize int32) (string, error) {
+	if err := validateProjection(projection); err != nil {
+		return "", err
+	}
+	if schemaVersion == "" {
+		return "", fmt.Errorf("schema_version required")
+	}
+	return pageByIndex(ctx, schemaTsIndex, []string{schemaVersion}, projection, bookmark, pageSize)
+}
@@ -0,0 +1,102 @@
+package contract
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+)
+
+// Chaincode-as-a-service settings. With CHAINCODE_SERVER_ADDRESS set the
+// chaincode listens for the peer itself, as the ccaas builder expects;
+// otherwise it dials the peer through the legacy launcher. The variable
+// names follow fabric-samples' external chaincode.
+const (
+	envServerAddress = "CHAINCODE_SERVER_ADDRESS"
+	envChaincodeID   = "CHAINCODE_ID"
+	envTLSDisabled   = "CHAINCODE_TLS_DISABLED"
+	envTLSKey        = "CHAINCODE_TLS_KEY"
+	envTLSCert       = "CHAINCODE_TLS_CERT"
+	envClientCACert  = "CHAINCODE_CLIENT_CA_CERT"
+)
+
+// serverConfig returns the CCAAS server for cc, or nil when
+// CHAINCODE_SERVER_ADDRESS is unset. TLS is off unless
+// CHAINCODE_TLS_DISABLED=false; it then needs the key and certificate file,
+// and verifies peers against the client CA file when one is given.
+func serverConfig(cc shim.Chaincode) (*shim.ChaincodeServer, error) {
+	address := os.Getenv(envServerAddress)
+	if address == "" {
+		return nil, nil
+	}
+	ccid := os.Getenv(envChaincodeID)
+	if ccid == "" {
+		return nil, fmt.Errorf("%s required with %s", envChaincodeID, envServerAddress)
+	}
+	server := &shim.ChaincodeServer{CCID: ccid, Address: address, CC: cc}
+
+	disabled := true
+	if v := os.Getenv(envTLSDisabled); v != "" {
+		var err error
+		if disabled, err = strconv.ParseBool(v); err != nil {
+			return nil, fmt.Errorf("%s must be true or false", envTLSDisabled)
+		}
+	}
+	if disabled {
+		server.TLSProps.Disabled = true
+		return server, nil
+	}
+	var err error
+	if server.TLSProps.Key, err = readEnvFile(envTLSKey, true); err != nil {
+		return nil, err
+	}
+	if server.TLSProps.Cert, err = readEnvFile(envTLSCert, true); err != nil {
+		return nil, err
+	}
+	if server.TLSProps.ClientCACerts, err = readEnvFile(envClientCACert, false); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+func readEnvFile(name string, required bool) ([]byte, error) {
+	path := os.Getenv(name)
+	if path == "" {
+		if required {
+			return nil, fmt.Errorf("%s required when TLS is enabled", name)
+		}
+		return nil, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return b, nil
+}
+
+// Start runs cc as a CCAAS server, with the operations endpoint of
+// metrics.go beside it, when configured; otherwise under the peer's
+// launcher.
+func Start(cc shim.Chaincode) error {
+	server, err := serverConfig(cc)
+	if err != nil {
+		return err
+	}
+	if server != nil {
+		ops := &http.Server{Addr: opsAddress(), Handler: opsMux(), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := ops.ListenAndServe(); err != nil {
+				log.Printf("operations endpoint stopped: %v", err)
+			}
+		}()
+		health.serving(server.Address)
+		err := server.Start()
+		health.stop(err)
+		return err
+	}
+	return shim.Start(cc)
+}
@@ -0,0 +1,106 @@
+package contract
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/jcs"
+)
+
+// An event may carry a client signature for application-level
+// non-repudiation. The signed message is the RFC 8785 form of the event with
+// signature and signer_cert left out; signature is its base64 signature
+// (ECDSA ASN.1 or RSA PKCS#1 v1.5 over SHA-256, or Ed25519 over the message)
+// by the key of the submitting identity's certificate, or by the registered
+// key signer_key_id names. signer_cert, when given, is the submitter's
+// certificate in PEM.
+func signedMessage(e *LedgerEvent) ([]byte, error) {
+	unsigned := *e
+	unsigned.Signature, unsigned.SignerCert = "", ""
+	return jcs.Marshal(unsigned)
+}
+
+// verifyEventSignature checks e's signature against its registered
+// signer_key_id (see signerkey.go) or else the submitter's certificate.
+// Events without a signature pass.
+func verifyEventSignature(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, e *LedgerEvent) error {
+	if e.Signature == "" {
+		if e.SignerCert != "" || e.SignerKeyID != "" {
+			return errors.New("signer_cert or signer_key_id given without signature")
+		}
+		return nil
+	}
+	sig, err := base64.StdEncoding.DecodeString(e.Signature)
+	if err != nil {
+		return fmt.Errorf("signature must be base64")
+	}
+	var pub any
+	if e.SignerKeyID != "" {
+		if pub, err = registeredSignerKey(ctx, e); err != nil {
+			return err
+		}
+	} else {
+		if cfg.RequireSignerKeys {
+			return errors.New("signer_key_required: signatures must name a registered signer_key_id")
+		}
+		cert, err := ctx.GetClientIdentity().GetX509Certificate()
+		if err != nil {
+			return err
+		}
+		if e.SignerCert != "" {
+			block, _ := pem.Decode([]byte(e.SignerCert))
+			if block == nil || block.Type != "CERTIFICATE" {
+				return fmt.Errorf("signer_cert must be a PEM certificate")
+			}
+			if !bytes.Equal(block.Bytes, cert.Raw) {
+				return errors.New("signer_cert_mismatch: signer_cert is not the submitting identity's certificate")
+			}
+		}
+		pub = cert.PublicKey
+	}
+	msg, err := signedMessage(e)
+	if err != nil {
+		return err
+	}
+	return verifySignature(pub, msg, sig)
+}
+
+// verifySignature checks sig over msg by pub.
+func verifySignature(pub any, msg, sig []byte) error {
+	if err := checkSignerKeyType(pub); err != nil {
+		return err
+	}
+	digest := sha256.Sum256(msg)
+	ok := false
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		ok = ecdsa.VerifyASN1(pub, digest[:], sig)
+	case *rsa.PublicKey:
+		ok = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case ed25519.PublicKey:
+		ok = ed25519.Verify(pub, msg, sig)
+	}
+	if !ok {
+		return errors.New("invalid_signature")
+	}
+	return nil
+}
+
+func checkSignerKeyType(pub any) error {
+	switch pub.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey, ed25519.PublicKey:
+		return nil
+	default:
+		return fmt.Errorf("unsupported signer key type %T", pub)
+	}
+}
@@ -0,0 +1,426 @@
+package contract
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// Orgs register the keys their pipelines sign events with, so a service
+// identity can be rotated without losing the ability to verify what it
+// signed. An event naming signer_key_id is verified against that key, which
+// must belong to the submitter's MSP and be active. Revoked keys stay in
+// the registry with the time they stopped being valid, so events signed
+// before then still verify.
+const signerKeyPrefix = "signer_key:"
+
+const (
+	signerKeyActive  = "active"
+	signerKeyRevoked = "revoked"
+)
+
+// SignerKey is a registered signing key. KeyID is the lowercase hex SHA-256
+// of the key's DER SubjectPublicKeyInfo.
+type SignerKey struct {
+	KeyID string `json:"key_id"`
+	MSPID string `json:"msp_id"`
+	// PEM PUBLIC KEY.
+	PublicKey string `json:"public_key"`
+	// PEM certificate, when the key was registered from one.
+	Certificate string `json:"certificate,omitempty"`
+	Label       string `json:"label,omitempty"`
+	// RFC3339 window in which the key may sign new events.
+	ValidFrom  string `json:"valid_from"`
+	ValidUntil string `json:"valid_until,omitempty"`
+	Status     string `json:"status"`
+	// Events written at or after RevokedAt no longer verify; RevokedAt may
+	// predate the revocation transaction, e.g. when a key leaked.
+	RevokedAt       string `json:"revoked_at,omitempty"`
+	RevokedReason   string `json:"revoked_reason,omitempty"`
+	RevokedByMSP    string `json:"revoked_by_msp,omitempty"`
+	RegisteredByMSP string `json:"registered_by_msp"`
+	TxID            string `json:"tx_id"`
+	RegisteredAt    string `json:"registered_at"`
+}
+
+// activeAt reports whether k could sign an event written at t.
+func (k *SignerKey) activeAt(t time.Time) error {
+	from, _ := time.Parse(time.RFC3339, k.ValidFrom)
+	if t.Before(from) {
+		return fmt.Errorf("signer_key_inactive: key %s is valid from %s", k.KeyID, k.ValidFrom)
+	}
+	if k.ValidUntil != "" {
+		until, _ := time.Parse(time.RFC3339, k.ValidUntil)
+		if !t.Before(until) {
+			return fmt.Errorf("signer_key_inactive: key %s expired at %s", k.KeyID, k.ValidUntil)
+		}
+	}
+	if k.RevokedAt != "" {
+		revoked, _ := time.Parse(time.RFC3339Nano, k.RevokedAt)
+		if !t.Before(revoked) {
+			return fmt.Errorf("signer_key_inactive: key %s revoked at %s", k.KeyID, k.RevokedAt)
+		}
+	}
+	return nil
+}
+
+// publicKey parses k's registered key.
+func (k *SignerKey) publicKey() (any, error) {
+	block, _ := pem.Decode([]byte(k.PublicKey))
+	if block == nil {
+		return nil, fmt.Errorf("corrupt signer key record")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+func getSignerKey(ctx contractapi.TransactionContextInterface, keyID string) (*SignerKey, error) {
+	b, err := ctx.GetStub().GetState(signerKeyPrefix + keyID)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var k SignerKey
+	if err := json.Unmarshal(b, &k); err != nil {
+		return nil, fmt.Errorf("corrupt signer key record")
+	}
+	return &k, nil
+}
+
+func putSignerKey(ctx contractapi.TransactionContextInterface, k *SignerKey) (string, error) {
+	out, err := json.Marshal(k)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(signerKeyPrefix+k.KeyID, out); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// registeredSignerKey returns the key e names, checked as usable by the
+// submitter at the transaction time.
+func registeredSignerKey(ctx contractapi.TransactionContextInterface, e *LedgerEvent) (any, error) {
+	if e.SignerCert != "" {
+		return nil, errors.New("use either signer_key_id or signer_cert")
+	}
+	k, err := getSignerKey(ctx, e.SignerKeyID)
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, fmt.Errorf("signer_key_unknown: no registered key %s", e.SignerKeyID)
+	}
+	mspID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, err
+	}
+	if k.MSPID != mspID {
+		return nil, fmt.Errorf("signer_key_not_authorized: key %s belongs to %s", k.KeyID, k.MSPID)
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.activeAt(now); err != nil {
+		return nil, err
+	}
+	return k.publicKey()
+}
+
+type signerKeyInput struct {
+	MSPID       string `json:"msp_id"`
+	PublicKey   string `json:"public_key"`
+	Certificate string `json:"certificate"`
+	Label       string `json:"label"`
+	ValidFrom   string `json:"valid_from"`
+	ValidUntil  string `json:"valid_until"`
+}
+
+// RegisterKey adds a signing key for an org. keyJSON names msp_id and either
+// public_key (PEM PUBLIC KEY) or certificate (PEM), with an optional label
+// and RFC3339 valid_from and valid_until. valid_from defaults to now, or
+// for a certificate to its validity period. Returns the stored SignerKey.
+func (c *AuditLogContract) RegisterKey(ctx contractapi.TransactionContextInterface, keyJSON string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	var in signerKeyInput
+	dec := json.NewDecoder(bytes.NewReader([]byte(keyJSON)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&in); err != nil {
+		return "", fmt.Errorf("invalid key json: %w", err)
+	}
+	if in.MSPID == "" {
+		return "", fmt.Errorf("msp_id required")
+	}
+	if len(in.Label) > 128 {
+		return "", fmt.Errorf("label must be at most 128 bytes")
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var spki []byte
+	var from, until time.Time
+	switch {
+	case (in.PublicKey == "") == (in.Certificate == ""):
+		return "", fmt.Errorf("public_key or certificate required, not both")
+	case in.Certificate != "":
+		block, _ := pem.Decode([]byte(in.Certificate))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return "", fmt.Errorf("certificate must be a PEM certificate")
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return "", fmt.Errorf("certificate must be a PEM certificate")
+		}
+		spki, from, until = cert.RawSubjectPublicKeyInfo, cert.NotBefore, cert.NotAfter
+	default:
+		block, _ := pem.Decode([]byte(in.PublicKey))
+		if block == nil || block.Type != "PUBLIC KEY" {
+			return "", fmt.Errorf("public_key must be a PEM PUBLIC KEY")
+		}
+		spki, from = block.Bytes, now
+	}
+	pub, err := x509.ParsePKIXPublicKey(spki)
+	if err != nil {
+		return "", fmt.Errorf("public_key must be a PEM PUBLIC KEY")
+	}
+	if err := checkSignerKeyType(pub); err != nil {
+		return "", err
+	}
+	if in.ValidFrom != "" {
+		if from, err = time.Parse(time.RFC3339, in.ValidFrom); err != nil {
+			return "", fmt.Errorf("valid_from must be RFC3339")
+		}
+	}
+	if in.ValidUntil != "" {
+		if until, err = time.Parse(time.RFC3339, in.ValidUntil); err != nil {
+			return "", fmt.Errorf("valid_until must be RFC3339")
+		}
+	}
+	if !until.IsZero() && !until.After(from) {
+		return "", fmt.Errorf("valid_until must be after valid_from")
+	}
+
+	sum := sha256.Sum256(spki)
+	k := &SignerKey{
+		KeyID:        hex.EncodeToString(sum[:]),
+		MSPID:        in.MSPID,
+		PublicKey:    string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: spki})),
+		Certificate:  in.Certificate,
+		Label:        in.Label,
+		ValidFrom:    from.UTC().Format(time.RFC3339),
+		Status:       signerKeyActive,
+		TxID:         ctx.GetStub().GetTxID(),
+		RegisteredAt: now.Format(time.RFC3339Nano),
+	}
+	if !until.IsZero() {
+		k.ValidUntil = until.UTC().Format(time.RFC3339)
+	}
+	existing, err := getSignerKey(ctx, k.KeyID)
+	if err != nil {
+		return "", err
+	}
+	if existing != nil {
+		return "", fmt.Errorf("signer_key_already_registered: %s for %s", k.KeyID, existing.MSPID)
+	}
+	if k.RegisteredByMSP, err = ctx.GetClientIdentity().GetMSPID(); err != nil {
+		return "", err
+	}
+	return putSignerKey(ctx, k)
+}
+
+// RevokeKey stops a signing key. Events written from effectiveRFC3339 on no
+// longer verify; empty means now, and an earlier time covers a key that
+// leaked before it was revoked. The key stays registered.
+func (c *AuditLogContract) RevokeKey(ctx contractapi.TransactionContextInterface, keyID string, reason string, effectiveRFC3339 string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := requireAdmin(ctx, cfg); err != nil {
+		return "", err
+	}
+	if reason == "" || len(reason) > maxJustificationLen {
+		return "", fmt.Errorf("reason must be 1-%d bytes", maxJustificationLen)
+	}
+	k, err := getSignerKey(ctx, keyID)
+	if err != nil {
+		return "", err
+	}
+	if k == nil {
+		return "", errors.New("not_found")
+	}
+	if k.Status == signerKeyRevoked {
+		return "", fmt.Errorf("already_revoked: key %s revoked at %s", keyID, k.RevokedAt)
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	effective := now
+	if effectiveRFC3339 != "" {
+		if effective, err = time.Parse(time.RFC3339Nano, effectiveRFC3339); err != nil {
+			return "", fmt.Errorf("effective time must be RFC3339")
+		}
+		if effective.After(now) {
+			return "", fmt.Errorf("effective time must not be in the future")
+		}
+	}
+	if k.RevokedByMSP, err = ctx.GetClientIdentity().GetMSPID(); err != nil {
+		return "", err
+	}
+	k.Status = signerKeyRevoked
+	k.RevokedAt = effective.UTC().Format(time.RFC3339Nano)
+	k.RevokedReason = reason
+	return putSignerKey(ctx, k)
+}
+
+// GetSignerKey reads a registered signing key.
+func (c *AuditLogContract) GetSignerKey(ctx contractapi.TransactionContextInterface, keyID string) (string, error) {
+	k, err := getSignerKey(ctx, keyID)
+	if err != nil {
+		return "", err
+	}
+	if k == nil {
+		return "", errors.New("not_found")
+	}
+	return marshalString(k)
+}
+
+// ListSignerKeys lists the signing keys registered for mspID, or for every
+// org when it is empty, revoked ones included, by registration time.
+func (c *AuditLogContract) ListSignerKeys(ctx contractapi.TransactionContextInterface, mspID string) (string, error) {
+	it, err := ctx.GetStub().GetStateByRange(signerKeyPrefix, signerKeyPrefix+string(rune(0x10FFFF)))
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+	keys := []SignerKey{}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		var k SignerKey
+		if err := json.Unmarshal(kv.Value, &k); err != nil {
+			return "", fmt.Errorf("corrupt signer key record")
+		}
+		if mspID == "" || k.MSPID == mspID {
+			keys = append(keys, k)
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].RegisteredAt < keys[j].RegisteredAt })
+	return marshalString(keys)
+}
+
+// SignatureCheck is VerifyEventSignature's result.
+type SignatureCheck struct {
+	EventID     string `json:"event_id"`
+	Signed      bool   `json:"signed"`
+	SignerKeyID string `json:"signer_key_id,omitempty"`
+	// Status of the key now; it may have been revoked after the write.
+	KeyStatus string `json:"key_status,omitempty"`
+	Valid     bool   `json:"valid"`
+	Detail    string `json:"detail,omitempty"`
+}
+
+// VerifyEventSignature re-checks a stored event's signature: against its
+// registered key, which must have been active when the event was written,
+// or against its signer_cert. Events signed by the submitting identity
+// without signer_cert cannot be re-checked and report invalid with a reason.
+func (c *AuditLogContract) VerifyEventSignature(ctx contractapi.TransactionContextInterface, eventID string) (string, error) {
+	if !validate.UUID.MatchString(eventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	ref, err := callerRef(ctx, cfg, eventID)
+	if err != nil {
+		return "", err
+	}
+	stored, err := getStoredEvent(ctx, ref)
+	if err != nil {
+		return "", err
+	}
+	e := &stored.Event
+	res := SignatureCheck{EventID: eventID, Signed: e.Signature != "", SignerKeyID: e.SignerKeyID}
+	if !res.Signed {
+		return marshalString(res)
+	}
+
+	var pub any
+	switch {
+	case e.SignerKeyID != "":
+		k, err := getSignerKey(ctx, e.SignerKeyID)
+		if err != nil {
+			return "", err
+		}
+		if k == nil {
+			res.Detail = "signer key is not registered"
+			return marshalString(res)
+		}
+		res.KeyStatus = k.Status
+		written, err := time.Parse(time.RFC3339Nano, stored.LedgerTimestamp)
+		if err != nil {
+			res.Detail = "write time not recorded"
+			return marshalString(res)
+		}
+		if err := k.activeAt(written); err != nil {
+			res.Detail = err.Error()
+			return marshalString(res)
+		}
+		if pub, err = k.publicKey(); err != nil {
+			return "", err
+		}
+	case e.SignerCert != "":
+		var cert *x509.Certificate
+		if block, _ := pem.Decode([]byte(e.SignerCert)); block != nil {
+			cert, err = x509.ParseCertificate(block.Bytes)
+		}
+		if cert == nil {
+			res.Detail = "signer_cert unreadable"
+			return marshalString(res)
+		}
+		pub = cert.PublicKey
+	default:
+		res.Detail = "signed by the submitting identity without signer_cert"
+		return marshalString(res)
+	}
+	sig, err := base64.StdEncoding.DecodeString(e.Signature)
+	if err != nil {
+		return "", fmt.Errorf("corrupt event record")
+	}
+	msg, err := signedMessage(e)
+	if err != nil {
+		return "", err
+	}
+	if err := verifySignature(pub, msg, sig); err != nil {
+		res.Detail = err.Error()
+		return marshalString(res)
+	}
+	res.Valid = true
+	return marshalString(res)
+}
@@ -0,0 +1,125 @@
+package contract
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// ExportSnapshot pages through the raw event records in key order and
+// chains them into a running digest, so a backup can be checked against
+// the ledger without a peer snapshot:
+//
+//	d_0 = 32 zero bytes
+//	d_n = SHA-256(d_{n-1} || SHA-256(key_n) || SHA-256(value_n))
+//
+// The bookmark carries the digest from page to page, so the last page's
+// running_digest covers every record exported. Pages are read at different
+// times; a record changed behind the bookmark after it was read, e.g. by
+// MigrateState, shows its new value only in the next snapshot.
+
+// SnapshotRecord is one state entry as stored, with the hashes that feed
+// the running digest.
+type SnapshotRecord struct {
+	Key         string          `json:"key"`
+	Value       json.RawMessage `json:"value"`
+	PayloadHash string          `json:"payload_hash_sha256"`
+	ValueHash   string          `json:"value_sha256"`
+}
+
+type SnapshotPage struct {
+	Records       []SnapshotRecord `json:"records"`
+	FetchedCount  int32            `json:"fetched_count"`
+	RunningDigest string           `json:"running_digest"`
+	Bookmark      string           `json:"bookmark"`
+	// Complete is set on the last page.
+	Complete bool `json:"complete"`
+}
+
+// snapshotCursor is the decoded bookmark: the digest so far and the range
+// query's own bookmark.
+type snapshotCursor struct {
+	Digest string `json:"digest"`
+	Next   string `json:"next"`
+}
+
+func decodeSnapshotBookmark(bookmark string) (snapshotCursor, error) {
+	cur := snapshotCursor{Digest: strings.Repeat("0", 64)}
+	if bookmark == "" {
+		return cur, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(bookmark)
+	if err != nil {
+		return cur, fmt.Errorf("invalid bookmark")
+	}
+	if err := json.Unmarshal(b, &cur); err != nil || !validate.SHA256.MatchString(cur.Digest) {
+		return cur, fmt.Errorf("invalid bookmark")
+	}
+	return cur, nil
+}
+
+func snapshotLink(prev []byte, key string, value []byte) []byte {
+	k, v := sha256.Sum256([]byte(key)), sha256.Sum256(value)
+	h := sha256.New()
+	h.Write(prev)
+	h.Write(k[:])
+	h.Write(v[:])
+	return h.Sum(nil)
+}
+
+// ExportSnapshot returns one page of the event keyspace for backups. Start
+// with an empty bookmark and pass each page's bookmark to the next call
+// until complete is set.
+func (c *AuditLogContract) ExportSnapshot(ctx contractapi.TransactionContextInterface, bookmark string, pageSize int32) (string, error) {
+	if err := validatePageSize(ctx, pageSize); err != nil {
+		return "", err
+	}
+	cur, err := decodeSnapshotBookmark(bookmark)
+	if err != nil {
+		return "", err
+	}
+	digest, _ := hex.DecodeString(cur.Digest)
+	it, meta, err := ctx.GetStub().GetStateByRangeWithPagination(eventKeyPrefix, eventKeyPrefix+"\uffff", pageSize, cur.Next)
+	if err != nil {
+		return "", err
+	}
+	defer it.Close()
+
+	page := SnapshotPage{Records: []SnapshotRecord{}}
+	for it.HasNext() {
+		kv, err := it.Next()
+		if err != nil {
+			return "", err
+		}
+		var stored StoredEvent
+		if err := json.Unmarshal(kv.Value, &stored); err != nil {
+			return "", fmt.Errorf("corrupt stored event")
+		}
+		valueHash := sha256.Sum256(kv.Value)
+		page.Records = append(page.Records, SnapshotRecord{
+			Key:         kv.Key,
+			Value:       json.RawMessage(kv.Value),
+			PayloadHash: stored.PayloadHash,
+			ValueHash:   hex.EncodeToString(valueHash[:]),
+		})
+		digest = snapshotLink(digest, kv.Key, kv.Value)
+	}
+	page.FetchedCount = meta.FetchedRecordsCount
+	page.RunningDigest = hex.EncodeToString(digest)
+	page.Complete = meta.Bookmark == "" || meta.FetchedRecordsCount < pageSize
+	if !page.Complete {
+		b, err := json.Marshal(snapshotCursor{Digest: page.RunningDigest, Next: meta.Bookmark})
+		if err != nil {
+			return "", err
+		}
+		page.Bookmark = base64.RawURLEncoding.EncodeToString(b)
+	}
+	return marshalString(page)
+}
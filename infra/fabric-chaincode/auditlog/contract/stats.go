@@ -0,0 +1,216 @@
+package contract
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const maxStatsScan = 10000
+
+type ArrivalStats struct {
+	Count          int     `json:"count"`
+	MeanGapSeconds float64 `json:"mean_gap_seconds"`
+	P50GapSeconds  float64 `json:"p50_gap_seconds"`
+	P95GapSeconds  float64 `json:"p95_gap_seconds"`
+	MaxGapSeconds  float64 `json:"max_gap_seconds"`
+	Truncated      bool    `json:"truncated"`
+}
+
+// percentile uses the nearest-rank method on sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// GetArrivalStats summarises the gaps between consecutive events of one type
+// with timestamps in [start, end). At most maxStatsScan events are read;
+// Truncated reports whether the range held more.
+func (c *AuditLogContract) GetArrivalStats(ctx contractapi.TransactionContextInterface, eventType string, startRFC3339 string, endRFC3339 string) (string, error) {
+	if err := requireEventType(ctx, eventType); err != nil {
+		return "", err
+	}
+	start, end, err := parseTimeRange(startRFC3339, endRFC3339)
+	if err != nil {
+		return "", err
+	}
+
+	var stamps []time.Time
+	truncated, err := scanTimeRange(ctx, typeTsIndex, []string{eventType}, start, end, maxStatsScan, func(ts, _ string) error {
+		t, err := time.Parse(tsIndexLayout, ts)
+		if err != nil {
+			return fmt.Errorf("corrupt index timestamp %s", ts)
+		}
+		stamps = append(stamps, t)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	stats := ArrivalStats{Count: len(stamps), Truncated: truncated}
+	if len(stamps) > 1 {
+		gaps := make([]float64, 0, len(stamps)-1)
+		sum := 0.0
+		for i := 1; i < len(stamps); i++ {
+			g := stamps[i].Sub(stamps[i-1]).Seconds()
+			gaps = append(gaps, g)
+			sum += g
+		}
+		sort.Float64s(gaps)
+		stats.MeanGapSeconds = sum / float64(len(gaps))
+		stats.P50GapSeconds = percentile(gaps, 50)
+		stats.P95GapSeconds = percentile(gaps, 95)
+		stats.MaxGapSeconds = gaps[len(gaps)-1]
+	}
+	return marshalString(stats)
+}
+
+const maxRangeSamples = 10
+
+type RangeEmptyReport struct {
+	Empty bool `json:"empty"`
+	// Live events counted in the range, up to maxStatsScan per event type.
+	LiveCount int `json:"live_count"`
+	// Revoked and deleted events in the range, with the marker events of
+	// governed deletions; they do not make it non-empty.
+	TombstoneCount int `json:"tombstone_count"`
+	// event_ids of live events, as "<msp>:<event_id>" when
+	// id_namespacing_by_producer is on.
+	SampleIDs []string `json:"sample_ids"`
+	Truncated bool     `json:"truncated"`
+}
+
+// AssertRangeEmpty reports whether any events have timestamps in [start, end).
+// It is read-only and walks each type's type~ts~id index over the range.
+func (c *AuditLogContract) AssertRangeEmpty(ctx contractapi.TransactionContextInterface, startRFC3339 string, endRFC3339 string) (string, error) {
+	start, end, err := parseTimeRange(startRFC3339, endRFC3339)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	report := RangeEmptyReport{SampleIDs: []string{}}
+	types, err := knownEventTypes(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, eventType := range types {
+		truncated, err := scanTimeRange(ctx, typeTsIndex, []string{eventType}, start, end, maxStatsScan, func(_, ref string) error {
+			if deletionMarkerTypes[eventType] {
+				report.TombstoneCount++
+				return nil
+			}
+			stored, err := getStoredEvent(ctx, ref)
+			if err != nil {
+				return err
+			}
+			if stored.Deletion != nil {
+				report.TombstoneCount++
+				return nil
+			}
+			revoked, err := getRevocation(ctx, ref)
+			if err != nil {
+				return err
+			}
+			if revoked != nil {
+				report.TombstoneCount++
+				return nil
+			}
+			report.LiveCount++
+			if len(report.SampleIDs) < maxRangeSamples {
+				id := stored.Event.EventID
+				if cfg.IDNamespacingByProducer {
+					id = stored.Producer + ":" + id
+				}
+				report.SampleIDs = append(report.SampleIDs, id)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		report.Truncated = report.Truncated || truncated
+	}
+	report.Empty = report.LiveCount == 0
+	return marshalString(report)
+}
+
+type TimestampCluster struct {
+	Timestamp string   `json:"timestamp"`
+	Count     int      `json:"count"`
+	SampleIDs []string `json:"sample_ids"`
+}
+
+type ClusterReport struct {
+	Clusters  []TimestampCluster `json:"clusters"`
+	Truncated bool               `json:"truncated"`
+}
+
+// FindTimestampClusters reports instants in [start, end) shared by more than
+// threshold events across all types. Timestamps are grouped at the channel's
+// timestamp_precision, or at full index precision when that is unset.
+func (c *AuditLogContract) FindTimestampClusters(ctx contractapi.TransactionContextInterface, startRFC3339 string, endRFC3339 string, threshold int) (string, error) {
+	if threshold < 1 {
+		return "", fmt.Errorf("threshold must be at least 1")
+	}
+	start, end, err := parseTimeRange(startRFC3339, endRFC3339)
+	if err != nil {
+		return "", err
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	groups := map[string]*TimestampCluster{}
+	report := ClusterReport{Clusters: []TimestampCluster{}}
+	types, err := knownEventTypes(ctx)
+	if err != nil {
+		return "", err
+	}
+	for _, eventType := range types {
+		truncated, err := scanTimeRange(ctx, typeTsIndex, []string{eventType}, start, end, maxStatsScan, func(ts, ref string) error {
+			bucket := ts
+			if t, err := time.Parse(tsIndexLayout, ts); err == nil {
+				if norm, ok := cfg.normalizeTimestamp(t); ok {
+					bucket = norm
+				}
+			}
+			g, ok := groups[bucket]
+			if !ok {
+				g = &TimestampCluster{Timestamp: bucket, SampleIDs: []string{}}
+				groups[bucket] = g
+			}
+			g.Count++
+			if len(g.SampleIDs) < maxRangeSamples {
+				g.SampleIDs = append(g.SampleIDs, ref)
+			}
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+		report.Truncated = report.Truncated || truncated
+	}
+
+	for _, g := range groups {
+		if g.Count > threshold {
+			report.Clusters = append(report.Clusters, *g)
+		}
+	}
+	sort.Slice(report.Clusters, func(i, j int) bool { return report.Clusters[i].Timestamp < report.Clusters[j].Timestamp })
+	return marshalString(report)
+}
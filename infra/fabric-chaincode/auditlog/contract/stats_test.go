@@ -0,0 +1,78 @@
+package contract_test
+
+import (
+	"testing"
+	"time"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// A governed deletion leaves a tombstone and its markers in the range;
+// neither makes it non-empty.
+func TestAssertRangeEmptyAfterDeletion(t *testing.T) {
+	for _, namespaced := range []bool{false, true} {
+		h, err := auditlogtest.New()
+		if err != nil {
+			t.Fatal(err)
+		}
+		admin := auditlogtest.Admin("Org1MSP")
+		cfg := map[string]any{
+			"admin_msps":                 []string{"Org1MSP"},
+			"deletion_approver_msps":     []string{"Org1MSP", "Org2MSP"},
+			"deletion_approvals":         1,
+			"id_namespacing_by_producer": namespaced,
+		}
+		if _, err := h.Submit(admin, "Init", auditlogtest.MustJSON(cfg)); err != nil {
+			t.Fatal(err)
+		}
+		client := auditlogtest.Client("Org1MSP")
+		deleted, kept := auditlogtest.Event("INGEST", 1), auditlogtest.Event("INGEST", 2)
+		put(t, h, client, deleted)
+		put(t, h, client, kept)
+
+		// The markers land at transaction time, inside the range.
+		h.Advance(5 * time.Second)
+		approver := map[string]string{"auditlog.deletion_approver": "true"}
+		out, err := h.Submit(admin.WithAttrs(approver), "ProposeDeletion", deleted.EventID, "court order", "case 1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var p contract.DeletionProposal
+		if err := auditlogtest.Unmarshal(out, &p); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := h.Submit(auditlogtest.Client("Org2MSP").WithAttrs(approver), "ApproveDeletion", p.ProposalID); err != nil {
+			t.Fatal(err)
+		}
+
+		want := kept.EventID
+		if namespaced {
+			want = "Org1MSP:" + want
+		}
+		r := rangeReport(t, h, admin)
+		if r.Empty || r.LiveCount != 1 || r.TombstoneCount != 4 || len(r.SampleIDs) != 1 || r.SampleIDs[0] != want {
+			t.Errorf("namespaced=%v: %+v, want %s live beside 4 tombstones", namespaced, r, want)
+		}
+
+		if _, err := h.Submit(admin, "RevokeEvent", kept.EventID, "OTHER", "test"); err != nil {
+			t.Fatal(err)
+		}
+		if r = rangeReport(t, h, admin); !r.Empty || r.TombstoneCount != 5 || len(r.SampleIDs) != 0 {
+			t.Errorf("namespaced=%v: after revocation %+v, want empty", namespaced, r)
+		}
+	}
+}
+
+func rangeReport(t *testing.T, h *auditlogtest.Harness, id auditlogtest.Identity) contract.RangeEmptyReport {
+	t.Helper()
+	out, err := h.Evaluate(id, "AssertRangeEmpty", auditlogtest.Timestamp(1), auditlogtest.Timestamp(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r contract.RangeEmptyReport
+	if err := auditlogtest.Unmarshal(out, &r); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
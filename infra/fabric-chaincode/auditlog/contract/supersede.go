@@ -0,0 +1,131 @@
+package contract
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+
+	"payscope/auditlog/internal/validate"
+)
+
+// Corrections are new events carrying supersedes: <old event_id>, written
+// through AmendEvent. The old record is left untouched; a separate marker
+// under supersededKeyPrefix+ref points at its replacement and reads surface
+// it as superseded_by.
+const supersededKeyPrefix = "superseded:"
+
+type SupersessionRecord struct {
+	SupersededBy string `json:"superseded_by"`
+	TxID         string `json:"tx_id"`
+	SupersededAt string `json:"superseded_at"`
+}
+
+func getSupersession(ctx contractapi.TransactionContextInterface, ref string) (*SupersessionRecord, error) {
+	b, err := ctx.GetStub().GetState(supersededKeyPrefix + ref)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, nil
+	}
+	var rec SupersessionRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, fmt.Errorf("corrupt supersession record")
+	}
+	return &rec, nil
+}
+
+var errSupersedesViaAmend = errors.New("supersedes may only be set through AmendEvent")
+
+// AmendEvent writes newEventJSON as the replacement of oldEventID. The new
+// event must have the same event_type; supersedes is filled in when empty.
+// Only the old event's creator MSP or an admin may amend it, and an event
+// can be superseded once.
+func (c *AuditLogContract) AmendEvent(ctx contractapi.TransactionContextInterface, oldEventID string, newEventJSON string) (string, error) {
+	if !validate.UUID.MatchString(oldEventID) {
+		return "", fmt.Errorf("invalid event_id")
+	}
+	var e LedgerEvent
+	if err := json.Unmarshal([]byte(newEventJSON), &e); err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+	if e.Supersedes == "" {
+		e.Supersedes = oldEventID
+	}
+	if e.Supersedes != oldEventID {
+		return "", fmt.Errorf("supersedes must equal the amended event_id")
+	}
+	if e.EventID == oldEventID {
+		return "", fmt.Errorf("amendment needs a new event_id")
+	}
+
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	oldRef, err := callerRef(ctx, cfg, oldEventID)
+	if err != nil {
+		return "", err
+	}
+	old, err := getStoredEvent(ctx, oldRef)
+	if err != nil {
+		return "", err
+	}
+	if err := requireCreatorOrAdmin(ctx, cfg, old, "amend an event"); err != nil {
+		return "", err
+	}
+
+	pending := newPendingWrites()
+	stored, dup, err := prepareEvent(ctx, cfg, e, pending)
+	if err != nil {
+		return "", err
+	}
+	if stored.Event.EventType != old.Event.EventType {
+		return "", fmt.Errorf("amendment must keep event_type %s", old.Event.EventType)
+	}
+	prior, err := getSupersession(ctx, oldRef)
+	if err != nil {
+		return "", err
+	}
+	if prior != nil {
+		if dup && prior.SupersededBy == stored.Event.EventID {
+			r, err := newReceipt(ctx)
+			if err != nil {
+				return "", err
+			}
+			r.TxID, r.TxTimestamp = prior.TxID, prior.SupersededAt
+			r.EventID, r.PayloadHash, r.Deduped = stored.Event.EventID, stored.PayloadHash, true
+			return marshalString(r)
+		}
+		return "", fmt.Errorf("already_superseded: by %s", prior.SupersededBy)
+	}
+	if dup {
+		return "", errors.New("idempotency_violation: amendment event_id already written")
+	}
+
+	if err := commitEvent(ctx, cfg, stored, pending); err != nil {
+		return "", err
+	}
+	now, err := txTimeUTC(ctx)
+	if err != nil {
+		return "", err
+	}
+	marker, err := json.Marshal(SupersessionRecord{
+		SupersededBy: stored.Event.EventID,
+		TxID:         ctx.GetStub().GetTxID(),
+		SupersededAt: now.Format(time.RFC3339Nano),
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.GetStub().PutState(supersededKeyPrefix+oldRef, marker); err != nil {
+		return "", err
+	}
+	if err := emitWritten(ctx, stored); err != nil {
+		return "", err
+	}
+	return eventReceipt(ctx, stored, false)
+}
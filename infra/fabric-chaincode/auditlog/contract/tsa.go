@@ -0,0 +1,403 @@
+package contract
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"payscope/auditlog/jcs"
+)
+
+// An event may carry tsa_token, the base64 DER RFC 3161 TimeStampToken (or
+// the whole TimeStampResp) of an accredited timestamp authority. Its message
+// imprint must be the SHA-256, SHA-384 or SHA-512 of the RFC 8785 form of
+// the event with tsa_token, tsa_token_hash, signature and signer_cert left
+// out, so a client timestamps the event first and signs it, token included,
+// afterwards. The
+// token must be signed by a certificate for timestamping that chains to one
+// of tsa_certificates at the token's genTime. tsa_token_hash, when also
+// given, must be the SHA-256 of the token.
+
+var (
+	oidSignedData      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTSTInfo         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+	oidAttrContentType = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttrDigest      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA384 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 2}
+	oidSHA512 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 3}
+
+	oidRSA         = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidRSASHA256   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 11}
+	oidRSASHA384   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 12}
+	oidRSASHA512   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 13}
+	oidECDSA       = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidECDSASHA256 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidECDSASHA384 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 3}
+	oidECDSASHA512 = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 4}
+	oidEd25519     = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+var tsaHashes = []struct {
+	oid  asn1.ObjectIdentifier
+	hash crypto.Hash
+}{
+	{oidSHA256, crypto.SHA256},
+	{oidSHA384, crypto.SHA384},
+	{oidSHA512, crypto.SHA512},
+}
+
+// TSAStamp is what the contract read from a verified tsa_token.
+type TSAStamp struct {
+	GenTime string `json:"gen_time"`
+	// Subject of the certificate that signed the token.
+	Authority string `json:"authority"`
+	Serial    string `json:"serial"`
+	Policy    string `json:"policy"`
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type timeStampResp struct {
+	Status struct {
+		Status int
+	}
+	Token asn1.RawValue `asn1:"optional"`
+}
+
+// rawSet keeps an implicitly tagged SET as it is; unlike asn1.RawValue it
+// only matches its own tag, so it can be optional.
+type rawSet struct {
+	Raw asn1.RawContent
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo struct {
+		EContentType asn1.ObjectIdentifier
+		EContent     []byte `asn1:"explicit,tag:0"`
+	}
+	Certificates rawSet       `asn1:"optional,tag:0"`
+	CRLs         rawSet       `asn1:"optional,tag:1"`
+	SignerInfos  []signerInfo `asn1:"set"`
+}
+
+// signerInfo requires signed attributes, which RFC 3161 tokens always have.
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+type issuerAndSerial struct {
+	Issuer asn1.RawValue
+	Serial *big.Int
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint struct {
+		HashAlgorithm pkix.AlgorithmIdentifier
+		HashedMessage []byte
+	}
+	SerialNumber *big.Int
+	GenTime      time.Time `asn1:"generalized"`
+}
+
+// timestampedMessage is the message a tsa_token's imprint covers.
+func timestampedMessage(e *LedgerEvent) ([]byte, error) {
+	unstamped := *e
+	unstamped.TSAToken, unstamped.TSATokenHash = "", ""
+	unstamped.Signature, unstamped.SignerCert = "", ""
+	return jcs.Marshal(unstamped)
+}
+
+// parseTSACertificates decodes the PEM certificates of tsa_certificates.
+func parseTSACertificates(pems []string) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for i, p := range pems {
+		block, _ := pem.Decode([]byte(p))
+		if block == nil || block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("tsa_certificates[%d] must be a PEM certificate", i)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("tsa_certificates[%d] must be a PEM certificate", i)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// verifyTSAToken checks e's tsa_token and returns what it attests, or nil
+// for an event without one.
+func verifyTSAToken(cfg *ContractConfig, e *LedgerEvent) (*TSAStamp, error) {
+	if e.TSAToken == "" {
+		if cfg.RequireTSAToken {
+			return nil, errors.New("tsa_token_required: events must carry a tsa_token")
+		}
+		return nil, nil
+	}
+	if len(cfg.TSACertificates) == 0 {
+		return nil, errors.New("tsa_not_configured: no tsa_certificates to verify tsa_token against")
+	}
+	der, err := base64.StdEncoding.DecodeString(e.TSAToken)
+	if err != nil {
+		return nil, fmt.Errorf("tsa_token must be base64")
+	}
+	if e.TSATokenHash != "" {
+		sum := sha256.Sum256(der)
+		if hex.EncodeToString(sum[:]) != e.TSATokenHash {
+			return nil, errors.New("invalid_tsa_token: tsa_token_hash is not the SHA-256 of tsa_token")
+		}
+	}
+	roots, err := parseTSACertificates(cfg.TSACertificates)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := timestampedMessage(e)
+	if err != nil {
+		return nil, err
+	}
+	stamp, err := verifyTimeStampToken(der, msg, roots)
+	if err != nil {
+		return nil, fmt.Errorf("invalid_tsa_token: %v", err)
+	}
+	return stamp, nil
+}
+
+// verifyTimeStampToken checks that token is a TimeStampToken over msg
+// signed by a timestamping certificate that chains to roots.
+func verifyTimeStampToken(token, msg []byte, roots []*x509.Certificate) (*TSAStamp, error) {
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(token, &ci); err != nil || len(rest) > 0 {
+		// Not a token; it may be the TimeStampResp carrying one.
+		var resp timeStampResp
+		if rest, err := asn1.Unmarshal(token, &resp); err != nil || len(rest) > 0 {
+			return nil, errors.New("not a DER TimeStampToken or TimeStampResp")
+		}
+		if resp.Status.Status > 1 || len(resp.Token.FullBytes) == 0 {
+			return nil, fmt.Errorf("timestamp response was not granted (status %d)", resp.Status.Status)
+		}
+		if _, err := asn1.Unmarshal(resp.Token.FullBytes, &ci); err != nil {
+			return nil, errors.New("malformed TimeStampToken")
+		}
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("not CMS SignedData")
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("malformed SignedData: %v", err)
+	}
+	if !sd.EncapContentInfo.EContentType.Equal(oidTSTInfo) {
+		return nil, errors.New("content is not a TSTInfo")
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("token has %d signers, want 1", len(sd.SignerInfos))
+	}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(sd.EncapContentInfo.EContent, &info); err != nil {
+		return nil, fmt.Errorf("malformed TSTInfo: %v", err)
+	}
+
+	imprintHash, ok := tsaHash(info.MessageImprint.HashAlgorithm.Algorithm)
+	if !ok {
+		return nil, fmt.Errorf("unsupported message imprint algorithm %v", info.MessageImprint.HashAlgorithm.Algorithm)
+	}
+	h := imprintHash.New()
+	h.Write(msg)
+	if !bytes.Equal(h.Sum(nil), info.MessageImprint.HashedMessage) {
+		return nil, errors.New("message imprint does not match the event")
+	}
+
+	embedded, err := tokenCertificates(sd.Certificates)
+	if err != nil {
+		return nil, err
+	}
+	si := sd.SignerInfos[0]
+	signer := findSigner(si.SID, append(embedded, roots...))
+	if signer == nil {
+		return nil, errors.New("signer certificate not found in the token or tsa_certificates")
+	}
+	if err := checkSignerInfo(&si, sd.EncapContentInfo.EContent, signer); err != nil {
+		return nil, err
+	}
+
+	pool, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	for _, c := range roots {
+		pool.AddCert(c)
+	}
+	for _, c := range embedded {
+		intermediates.AddCert(c)
+	}
+	if _, err := signer.Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		CurrentTime:   info.GenTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+	}); err != nil {
+		return nil, fmt.Errorf("signer certificate: %v", err)
+	}
+	return &TSAStamp{
+		GenTime:   info.GenTime.UTC().Format(time.RFC3339Nano),
+		Authority: signer.Subject.String(),
+		Serial:    info.SerialNumber.Text(16),
+		Policy:    info.Policy.String(),
+	}, nil
+}
+
+func tsaHash(oid asn1.ObjectIdentifier) (crypto.Hash, bool) {
+	for _, h := range tsaHashes {
+		if h.oid.Equal(oid) {
+			return h.hash, true
+		}
+	}
+	return 0, false
+}
+
+func tokenCertificates(set rawSet) ([]*x509.Certificate, error) {
+	if len(set.Raw) == 0 {
+		return nil, nil
+	}
+	var raw asn1.RawValue
+	if _, err := asn1.Unmarshal(set.Raw, &raw); err != nil {
+		return nil, errors.New("malformed certificates")
+	}
+	certs, err := x509.ParseCertificates(raw.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("certificates: %v", err)
+	}
+	return certs, nil
+}
+
+// findSigner returns the certificate sid names, by issuer and serial number
+// or by subject key identifier.
+func findSigner(sid asn1.RawValue, certs []*x509.Certificate) *x509.Certificate {
+	for _, c := range certs {
+		switch {
+		case sid.Class == asn1.ClassUniversal && sid.Tag == asn1.TagSequence:
+			var ias issuerAndSerial
+			if _, err := asn1.Unmarshal(sid.FullBytes, &ias); err != nil {
+				return nil
+			}
+			if bytes.Equal(ias.Issuer.FullBytes, c.RawIssuer) && ias.Serial.Cmp(c.SerialNumber) == 0 {
+				return c
+			}
+		case sid.Class == asn1.ClassContextSpecific && sid.Tag == 0:
+			if len(c.SubjectKeyId) > 0 && bytes.Equal(sid.Bytes, c.SubjectKeyId) {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// checkSignerInfo checks the signed attributes against content and their
+// signature against signer.
+func checkSignerInfo(si *signerInfo, content []byte, signer *x509.Certificate) error {
+	if si.SignedAttrs.Class != asn1.ClassContextSpecific || si.SignedAttrs.Tag != 0 {
+		return errors.New("signer has no signed attributes")
+	}
+	digestHash, ok := tsaHash(si.DigestAlgorithm.Algorithm)
+	if !ok {
+		return fmt.Errorf("unsupported digest algorithm %v", si.DigestAlgorithm.Algorithm)
+	}
+	// The signature covers the attributes with their universal SET tag.
+	signed := append([]byte{0x31}, si.SignedAttrs.FullBytes[1:]...)
+	var attrs []attribute
+	if _, err := asn1.UnmarshalWithParams(signed, &attrs, "set"); err != nil {
+		return errors.New("malformed signed attributes")
+	}
+	var contentType asn1.ObjectIdentifier
+	var digest []byte
+	for _, a := range attrs {
+		switch {
+		case a.Type.Equal(oidAttrContentType):
+			if _, err := asn1.Unmarshal(a.Values.Bytes, &contentType); err != nil {
+				return errors.New("malformed content-type attribute")
+			}
+		case a.Type.Equal(oidAttrDigest):
+			if _, err := asn1.Unmarshal(a.Values.Bytes, &digest); err != nil {
+				return errors.New("malformed message-digest attribute")
+			}
+		}
+	}
+	if !contentType.Equal(oidTSTInfo) {
+		return errors.New("content-type attribute is not TSTInfo")
+	}
+	h := digestHash.New()
+	h.Write(content)
+	if !bytes.Equal(h.Sum(nil), digest) {
+		return errors.New("message-digest attribute does not match the TSTInfo")
+	}
+
+	alg, err := signatureAlgorithm(si.SignatureAlgorithm.Algorithm, digestHash)
+	if err != nil {
+		return err
+	}
+	if err := signer.CheckSignature(alg, signed, si.Signature); err != nil {
+		return fmt.Errorf("signature: %v", err)
+	}
+	return nil
+}
+
+// signatureAlgorithm maps a CMS signature algorithm, which may name only the
+// key type, and the signer's digest algorithm to an x509 algorithm.
+func signatureAlgorithm(oid asn1.ObjectIdentifier, digest crypto.Hash) (x509.SignatureAlgorithm, error) {
+	byDigest := func(sha256, sha384, sha512 x509.SignatureAlgorithm) x509.SignatureAlgorithm {
+		switch digest {
+		case crypto.SHA384:
+			return sha384
+		case crypto.SHA512:
+			return sha512
+		}
+		return sha256
+	}
+	switch {
+	case oid.Equal(oidRSA):
+		return byDigest(x509.SHA256WithRSA, x509.SHA384WithRSA, x509.SHA512WithRSA), nil
+	case oid.Equal(oidRSASHA256):
+		return x509.SHA256WithRSA, nil
+	case oid.Equal(oidRSASHA384):
+		return x509.SHA384WithRSA, nil
+	case oid.Equal(oidRSASHA512):
+		return x509.SHA512WithRSA, nil
+	case oid.Equal(oidECDSA):
+		return byDigest(x509.ECDSAWithSHA256, x509.ECDSAWithSHA384, x509.ECDSAWithSHA512), nil
+	case oid.Equal(oidECDSASHA256):
+		return x509.ECDSAWithSHA256, nil
+	case oid.Equal(oidECDSASHA384):
+		return x509.ECDSAWithSHA384, nil
+	case oid.Equal(oidECDSASHA512):
+		return x509.ECDSAWithSHA512, nil
+	case oid.Equal(oidEd25519):
+		return x509.PureEd25519, nil
+	}
+	return 0, fmt.Errorf("unsupported signature algorithm %v", oid)
+}
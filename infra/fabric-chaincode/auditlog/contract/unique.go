@@ -0,0 +1,80 @@
+package contract
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+const uniqKeyPrefix = "uniq:"
+
+// uniqueFields are the LedgerEvent fields a unique constraint may name.
+var uniqueFields = map[string]func(*LedgerEvent) string{
+	"event_type":     func(e *LedgerEvent) string { return e.EventType },
+	"artifact_hash":  func(e *LedgerEvent) string { return e.ArtifactHash },
+	"schema_version": func(e *LedgerEvent) string { return e.SchemaVer },
+	"timestamp":      func(e *LedgerEvent) string { return e.TimestampUTC },
+	"tsa_token_hash": func(e *LedgerEvent) string { return e.TSATokenHash },
+}
+
+func validateUniqueConstraints(constraints [][]string) error {
+	for i, fields := range constraints {
+		if len(fields) == 0 {
+			return fmt.Errorf("unique_constraints[%d] must name at least one field", i)
+		}
+		seen := map[string]bool{}
+		for _, f := range fields {
+			if _, ok := uniqueFields[f]; !ok {
+				return fmt.Errorf("unique_constraints[%d]: unsupported field %q", i, f)
+			}
+			if seen[f] {
+				return fmt.Errorf("unique_constraints[%d]: duplicate field %q", i, f)
+			}
+			seen[f] = true
+		}
+	}
+	return nil
+}
+
+// uniqKey derives the key claimed by an event for one constraint. Values are
+// NUL-joined before hashing so ("ab","c") and ("a","bc") cannot collide.
+func uniqKey(fields []string, e *LedgerEvent) string {
+	parts := make([]string, 0, len(fields)+1)
+	parts = append(parts, strings.Join(fields, ","))
+	for _, f := range fields {
+		parts = append(parts, uniqueFields[f](e))
+	}
+	return uniqKeyPrefix + sha256Hex([]byte(strings.Join(parts, "\x00")))
+}
+
+// checkUniqueConstraints rejects e if another event already holds any of the
+// configured constraint keys, in state or earlier in the transaction.
+func checkUniqueConstraints(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, e *LedgerEvent, pending *pendingWrites) error {
+	for _, fields := range cfg.UniqueConstraints {
+		key := uniqKey(fields, e)
+		if pending.uniq[key] {
+			return fmt.Errorf("unique_constraint_violation: %s", strings.Join(fields, ","))
+		}
+		b, err := ctx.GetStub().GetState(key)
+		if err != nil {
+			return err
+		}
+		if b != nil {
+			return fmt.Errorf("unique_constraint_violation: %s", strings.Join(fields, ","))
+		}
+	}
+	return nil
+}
+
+// claimUniqueConstraints records the constraint keys for a newly written event.
+func claimUniqueConstraints(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, stored *StoredEvent, pending *pendingWrites) error {
+	for _, fields := range cfg.UniqueConstraints {
+		key := uniqKey(fields, &stored.Event)
+		if err := ctx.GetStub().PutState(key, []byte(stored.ref())); err != nil {
+			return err
+		}
+		pending.uniq[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package contract_test
+
+import (
+	"errors"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+)
+
+// An event violates a constraint only when it matches an earlier event on
+// every field the constraint names; a match on some of them is fine.
+func TestUniqueConstraints(t *testing.T) {
+	pair := []string{"event_type", "artifact_hash"}
+	triple := []string{"event_type", "artifact_hash", "schema_version"}
+	first := auditlogtest.Event("INGEST", 1)
+
+	for _, tc := range []struct {
+		name        string
+		constraints [][]string
+		// Changes the second event makes to a copy of the first.
+		edit func(e *contract.LedgerEvent)
+		// Violated constraint, "" when the second write is accepted.
+		violated string
+	}{
+		{"pair, same tuple", [][]string{pair}, func(e *contract.LedgerEvent) {}, "event_type,artifact_hash"},
+		{"pair, schema differs", [][]string{pair}, func(e *contract.LedgerEvent) { e.SchemaVer = "v1.1" }, "event_type,artifact_hash"},
+		{"pair, type differs", [][]string{pair}, func(e *contract.LedgerEvent) { e.EventType = "FORECAST" }, ""},
+		{"pair, hash differs", [][]string{pair}, func(e *contract.LedgerEvent) { e.ArtifactHash = auditlogtest.Event("INGEST", 2).ArtifactHash }, ""},
+		{"triple, same tuple", [][]string{triple}, func(e *contract.LedgerEvent) {}, "event_type,artifact_hash,schema_version"},
+		{"triple, schema differs", [][]string{triple}, func(e *contract.LedgerEvent) { e.SchemaVer = "v1.1" }, ""},
+		{"triple, type and hash differ", [][]string{triple}, func(e *contract.LedgerEvent) {
+			e.EventType = "FORECAST"
+			e.ArtifactHash = auditlogtest.Event("INGEST", 2).ArtifactHash
+		}, ""},
+		// The first constraint listed that the event violates is reported.
+		{"both, schema differs", [][]string{triple, pair}, func(e *contract.LedgerEvent) { e.SchemaVer = "v1.1" }, "event_type,artifact_hash"},
+	} {
+		h := initHarness(t, map[string]any{"unique_constraints": tc.constraints})
+		client := auditlogtest.Client("Org1MSP")
+		put(t, h, client, first)
+
+		second := *first
+		second.EventID = auditlogtest.ID("INGEST", 2)
+		second.TimestampUTC = auditlogtest.Timestamp(2)
+		tc.edit(&second)
+		_, err := h.Submit(client, "PutEvent", auditlogtest.MustJSON(&second))
+		if tc.violated == "" {
+			if err != nil {
+				t.Errorf("%s: %v, want accepted", tc.name, err)
+			}
+			continue
+		}
+		var txErr *auditlogtest.TxError
+		if !errors.As(err, &txErr) || txErr.Code != "ERR_CONFLICT" || txErr.Message != "unique_constraint_violation: "+tc.violated {
+			t.Errorf("%s: err = %v, want unique_constraint_violation: %s", tc.name, err, tc.violated)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+)
+
+// ValidationResult is the outcome of a dry-run write. Status is "valid",
+// "deduped" when PutEvent would return the stored event unchanged, or
+// "rejected" with the ErrorEnvelope code and reason PutEvent would fail with.
+type ValidationResult struct {
+	EventID               string         `json:"event_id,omitempty"`
+	Status                string         `json:"status"`
+	PayloadHash           string         `json:"payload_hash_sha256,omitempty"`
+	TimestampDriftFlagged bool           `json:"timestamp_drift_flagged,omitempty"`
+	Code                  string         `json:"code,omitempty"`
+	Reason                string         `json:"reason,omitempty"`
+	Details               map[string]any `json:"details,omitempty"`
+}
+
+// ValidateEvent runs eventJSON through the same checks as PutEvent, as the
+// caller, and reports the payload hash it would be stored with. Nothing is
+// written, so evaluate it rather than submitting it. Peer and state
+// failures still fail the transaction. The result holds for the state read;
+// a later write may still be refused, e.g. by a chain link claimed since.
+func (c *AuditLogContract) ValidateEvent(ctx contractapi.TransactionContextInterface, eventJSON string) (string, error) {
+	var e LedgerEvent
+	if err := json.Unmarshal([]byte(eventJSON), &e); err != nil {
+		return marshalString(rejected(ValidationResult{}, fmt.Errorf("invalid json: %w", err)))
+	}
+	res := ValidationResult{EventID: e.EventID}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if e.Supersedes != "" {
+		return marshalString(rejected(res, errSupersedesViaAmend))
+	}
+	stored, dup, err := prepareEvent(ctx, cfg, e, newPendingWrites())
+	switch {
+	case err != nil && errorCode(err) == errCodeInternal:
+		return "", err
+	case err != nil:
+		res = rejected(res, err)
+	case dup:
+		res.Status, res.PayloadHash = "deduped", stored.PayloadHash
+	default:
+		res.Status, res.PayloadHash = "valid", stored.PayloadHash
+		res.TimestampDriftFlagged = stored.TimestampDriftFlagged
+	}
+	return marshalString(res)
+}
+
+func rejected(res ValidationResult, err error) ValidationResult {
+	res.Status, res.Code = "rejected", errorCode(err)
+	res.Reason, res.Details = splitDetails(err.Error())
+	return res
+}
@@ -0,0 +1,43 @@
+package contract
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// validateSink keeps the compiler from dropping the benchmarked call.
+var validateSink error
+
+// BenchmarkValidate runs the ledger-free event checks, validateEvent, on one
+// schema v2 INGEST event per operation under the default config.
+func BenchmarkValidate(b *testing.B) {
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := make([]LedgerEvent, 1000)
+	for i := range events {
+		at := epoch.Add(time.Duration(i) * time.Second)
+		count := int64(i * 100)
+		events[i] = LedgerEvent{
+			EventID:      fmt.Sprintf("00000000-0000-4000-8000-%012d", i),
+			EventType:    "INGEST",
+			ArtifactHash: fmt.Sprintf("%064x", i),
+			SchemaVer:    "v2",
+			TimestampUTC: at.Format(time.RFC3339),
+			Ingest: &IngestDetails{
+				SourceSystem:     "core-banking",
+				RecordCount:      &count,
+				BatchWindowStart: at.Add(-time.Hour).Format(time.RFC3339),
+				BatchWindowEnd:   at.Format(time.RFC3339),
+			},
+		}
+	}
+	cfg := &ContractConfig{}
+	if err := validateEvent(&events[0], cfg); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		validateSink = validateEvent(&events[i%len(events)], cfg)
+	}
+}
@@ -0,0 +1,367 @@
+package contract
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const maxPutBatch = 500
+
+// pendingWrites tracks what earlier events of the same transaction wrote.
+// Fabric reads never observe the transaction's own writes, so batch checks
+// for duplicates and constraints must consult this as well as state.
+type pendingWrites struct {
+	events     map[string]*StoredEvent
+	uniq       map[string]bool
+	content    map[string]string
+	artifacts  map[string]string
+	hashes     map[string]*StoredEvent
+	successors map[string]bool
+	counts     map[string]int
+	quota      map[string]int
+	schemas    map[string]*gojsonschema.Schema
+}
+
+func newPendingWrites() *pendingWrites {
+	return &pendingWrites{
+		events:     map[string]*StoredEvent{},
+		uniq:       map[string]bool{},
+		content:    map[string]string{},
+		artifacts:  map[string]string{},
+		hashes:     map[string]*StoredEvent{},
+		successors: map[string]bool{},
+		counts:     map[string]int{},
+		quota:      map[string]int{},
+		schemas:    map[string]*gojsonschema.Schema{},
+	}
+}
+
+// prepareEvent validates e and builds the record to store without writing
+// anything. It reports dup when the event is an idempotent resubmission.
+func prepareEvent(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, e LedgerEvent, pending *pendingWrites) (*StoredEvent, bool, error) {
+	submittedType := e.EventType
+	var submittedHashes map[string]string
+	if cfg.NormalizeArtifactHashes {
+		submittedHashes = normalizeArtifactHashes(&e)
+	}
+	if err := validateEvent(&e, cfg); err != nil {
+		return nil, false, err
+	}
+	if err := checkEventType(ctx, &e); err != nil {
+		return nil, false, err
+	}
+	if err := checkTypeWriter(ctx, cfg, &e); err != nil {
+		return nil, false, err
+	}
+	if err := checkSchemaRegistered(ctx, e.SchemaVer); err != nil {
+		return nil, false, err
+	}
+	if err := checkEventSchema(ctx, &e, pending); err != nil {
+		return nil, false, err
+	}
+
+	producer, err := writerNamespace(ctx, cfg)
+	if err != nil {
+		return nil, false, err
+	}
+	ref := eventRef(producer, e.EventID, cfg.TimeOrderedKeys)
+
+	// Idempotency: same event_id must be identical payload.
+	prior := pending.events[ref]
+	if prior == nil {
+		existing, err := ctx.GetStub().GetState(eventKey(ref))
+		if err != nil {
+			return nil, false, err
+		}
+		if existing != nil {
+			prior = &StoredEvent{}
+			if err := json.Unmarshal(existing, prior); err != nil {
+				return nil, false, fmt.Errorf("corrupt stored event")
+			}
+		}
+	}
+	if prior != nil {
+		salt, err := saltByID(ctx, prior.SaltID)
+		if err != nil {
+			return nil, false, err
+		}
+		hash, err := payloadHash(e, salt, prior.Canonicalization)
+		if err != nil {
+			return nil, false, err
+		}
+		if prior.PayloadHash != hash {
+			return nil, false, idempotencyViolation(prior, &e, hash)
+		}
+		return prior, true, nil
+	}
+
+	if cfg.ArtifactSchemaConsistency {
+		// Events are checked on every write, so any indexed event is
+		// representative of the artifact's schema_version.
+		schema, ok := pending.artifacts[e.ArtifactHash]
+		if !ok {
+			first, err := firstEventForArtifact(ctx, e.ArtifactHash)
+			if err != nil {
+				return nil, false, err
+			}
+			if first != nil {
+				schema, ok = first.Event.SchemaVer, true
+			}
+		}
+		if ok && schema != e.SchemaVer {
+			return nil, false, fmt.Errorf("artifact_schema_conflict: artifact_hash already recorded with schema_version %s", schema)
+		}
+	}
+
+	if err := checkUniqueConstraints(ctx, cfg, &e, pending); err != nil {
+		return nil, false, err
+	}
+	creator, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, false, err
+	}
+	if err := checkChainLink(ctx, &e, creator, pending); err != nil {
+		return nil, false, err
+	}
+	if err := checkParents(ctx, cfg, &e, producer, pending); err != nil {
+		return nil, false, err
+	}
+	if err := checkArtifactRegistered(ctx, cfg, &e); err != nil {
+		return nil, false, err
+	}
+	if err := checkDayOpen(ctx, &e); err != nil {
+		return nil, false, err
+	}
+	if err := verifyEventSignature(ctx, cfg, &e); err != nil {
+		return nil, false, err
+	}
+	stamp, err := verifyTSAToken(cfg, &e)
+	if err != nil {
+		return nil, false, err
+	}
+
+	saltID, salt, err := currentSalt(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	hash, canon, err := hashNewEvent(&e, salt)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := checkQuota(ctx, cfg, creator, pending); err != nil {
+		return nil, false, err
+	}
+	stored := &StoredEvent{Event: e, PayloadHash: hash, SaltID: saltID, Producer: producer, CreatorMSP: creator, Canonicalization: canonJCS, canonical: canon}
+	if err := checkTimestampDrift(ctx, cfg, stored); err != nil {
+		return nil, false, err
+	}
+	if err := checkDuplicatePayload(ctx, cfg, stored, salt, pending); err != nil {
+		return nil, false, err
+	}
+	if submittedType != e.EventType {
+		stored.OriginalEventType = submittedType
+	}
+	stored.OriginalArtifactHashes = submittedHashes
+	stored.TimeOrderedKeys = cfg.TimeOrderedKeys
+	stored.TSAStamp = stamp
+	if cfg.LockEventsToWriterOrg {
+		stored.RequiredOrgs = []string{creator}
+	}
+	return stored, false, nil
+}
+
+// commitEvent writes a prepared record with its indexes and constraint keys.
+func commitEvent(ctx contractapi.TransactionContextInterface, cfg *ContractConfig, stored *StoredEvent, pending *pendingWrites) error {
+	stored.TxID = ctx.GetStub().GetTxID()
+	stored.StorageVersion = storageVersion
+	out, err := encodeStoredEvent(stored)
+	if err != nil {
+		return err
+	}
+	var policy []byte
+	if cfg.LockEventsToWriterOrg {
+		if policy, err = endorsementPolicy(stored.RequiredOrgs); err != nil {
+			return err
+		}
+	}
+	if err := putStoredEvent(ctx, stored, out, policy); err != nil {
+		return err
+	}
+	if err := writeIndexes(ctx, stored); err != nil {
+		return err
+	}
+	if err := claimUniqueConstraints(ctx, cfg, stored, pending); err != nil {
+		return err
+	}
+	if err := claimContentHash(ctx, stored, pending); err != nil {
+		return err
+	}
+	if err := claimChainLink(ctx, stored, pending); err != nil {
+		return err
+	}
+	if err := countEvent(ctx, cfg, stored, pending); err != nil {
+		return err
+	}
+	pending.events[stored.ref()] = stored
+	pending.hashes[stored.PayloadHash] = stored
+	pending.artifacts[stored.Event.ArtifactHash] = stored.Event.SchemaVer
+	return nil
+}
+
+func (c *AuditLogContract) PutEvent(ctx contractapi.TransactionContextInterface, eventJSON string) (string, error) {
+	var e LedgerEvent
+	if err := json.Unmarshal([]byte(eventJSON), &e); err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+	return putEvent(ctx, e, "")
+}
+
+// putEvent writes one decoded event; format names the submission format
+// when it is not JSON.
+func putEvent(ctx contractapi.TransactionContextInterface, e LedgerEvent, format string) (string, error) {
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	payload, encrypted, err := privatePayload(ctx)
+	if err != nil {
+		return "", err
+	}
+	if e.Supersedes != "" {
+		return "", errSupersedesViaAmend
+	}
+	pending := newPendingWrites()
+	stored, dup, err := prepareEvent(ctx, cfg, e, pending)
+	if err != nil {
+		return "", err
+	}
+	if !dup {
+		stored.SourceFormat = format
+		if payload != nil {
+			if err := attachPrivatePayload(cfg, stored, payload, encrypted); err != nil {
+				return "", err
+			}
+		}
+		if err := commitEvent(ctx, cfg, stored, pending); err != nil {
+			return "", err
+		}
+		if payload != nil {
+			if err := writePrivatePayload(ctx, stored, payload); err != nil {
+				return "", err
+			}
+		}
+		if err := emitWritten(ctx, stored); err != nil {
+			return "", err
+		}
+	} else {
+		idempotentHits.Inc()
+	}
+	return eventReceipt(ctx, stored, dup)
+}
+
+// Outcomes of a PutEvents item. Status carries the same outcome in its
+// older form: "written", "deduped" or "rejected".
+const (
+	outcomeCreated   = "CREATED"
+	outcomeDuplicate = "DUPLICATE_NOOP"
+	outcomeRejected  = "REJECTED"
+)
+
+// BatchItemResult is the receipt of one input event. PayloadHash is that of
+// the stored event, so it is empty for rejected ones.
+type BatchItemResult struct {
+	Index       int    `json:"index"`
+	EventID     string `json:"event_id,omitempty"`
+	Outcome     string `json:"outcome"`
+	Status      string `json:"status"`
+	PayloadHash string `json:"payload_hash_sha256,omitempty"`
+	// Code is the ErrorEnvelope code of Reason; see errors.go.
+	Code    string         `json:"code,omitempty"`
+	Reason  string         `json:"reason,omitempty"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+type BatchSummary struct {
+	Written  int `json:"written"`
+	Deduped  int `json:"deduped"`
+	Rejected int `json:"rejected"`
+}
+
+type BatchResult struct {
+	TxID    string            `json:"tx_id"`
+	Receipt Receipt           `json:"receipt"`
+	Results []BatchItemResult `json:"results"`
+	Summary BatchSummary      `json:"summary"`
+}
+
+// PutEvents validates and writes a batch of events in one transaction.
+// Invalid events are rejected individually and reported with a reason; the
+// rest are written (or deduped) atomically with the transaction.
+func (c *AuditLogContract) PutEvents(ctx contractapi.TransactionContextInterface, eventsJSON string) (string, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal([]byte(eventsJSON), &raw); err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+	if len(raw) == 0 || len(raw) > maxPutBatch {
+		return "", fmt.Errorf("batch must contain between 1 and %d events", maxPutBatch)
+	}
+	cfg, err := loadConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if limit := cfg.maxBatchEvents(); len(raw) > limit {
+		return "", fmt.Errorf("batch_too_large: batch has %d events, limit %d", len(raw), limit)
+	}
+
+	pending := newPendingWrites()
+	var notices []WrittenNotice
+	receipt, err := newReceipt(ctx)
+	if err != nil {
+		return "", err
+	}
+	res := BatchResult{TxID: receipt.TxID, Receipt: receipt, Results: make([]BatchItemResult, 0, len(raw))}
+	for i, item := range raw {
+		r := BatchItemResult{Index: i}
+		var e LedgerEvent
+		if err := json.Unmarshal(item, &e); err != nil {
+			r.Outcome, r.Status = outcomeRejected, "rejected"
+			r.Code, r.Reason = errCodeValidationJSON, fmt.Sprintf("invalid json: %v", err)
+			rejectedEvents.WithLabelValues(r.Code).Inc()
+			res.Summary.Rejected++
+			res.Results = append(res.Results, r)
+			continue
+		}
+		r.EventID = e.EventID
+		stored, dup, err := prepareEvent(ctx, cfg, e, pending)
+		if err == nil && e.Supersedes != "" {
+			err = errSupersedesViaAmend
+		}
+		switch {
+		case err != nil:
+			r.Outcome, r.Status, r.Code = outcomeRejected, "rejected", errorCode(err)
+			r.Reason, r.Details = splitDetails(err.Error())
+			rejectedEvents.WithLabelValues(r.Code).Inc()
+			res.Summary.Rejected++
+		case dup:
+			idempotentHits.Inc()
+			r.Outcome, r.Status, r.PayloadHash = outcomeDuplicate, "deduped", stored.PayloadHash
+			res.Summary.Deduped++
+		default:
+			// Write failures abort the whole transaction.
+			if err := commitEvent(ctx, cfg, stored, pending); err != nil {
+				return "", err
+			}
+			r.Outcome, r.Status, r.PayloadHash = outcomeCreated, "written", stored.PayloadHash
+			res.Summary.Written++
+			notices = append(notices, noticeFor(ctx, stored))
+		}
+		res.Results = append(res.Results, r)
+	}
+	if err := emitBatchWritten(ctx, notices); err != nil {
+		return "", err
+	}
+	return marshalString(res)
+}
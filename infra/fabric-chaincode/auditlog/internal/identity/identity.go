@@ -0,0 +1,44 @@
+// Package identity holds the caller checks shared by the chaincode's
+// contracts. Which MSPs and attributes a transaction needs is decided by
+// the contract; this package only answers whether the caller has them.
+package identity
+
+import "github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+
+// Contains reports whether v is in list.
+func Contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+// MSPIn reports whether the caller's MSP is one of msps.
+func MSPIn(id cid.ClientIdentity, msps []string) (bool, error) {
+	mspID, err := id.GetMSPID()
+	if err != nil {
+		return false, err
+	}
+	return Contains(msps, mspID), nil
+}
+
+// HasAttr reports whether the caller carries attr set to "true".
+func HasAttr(id cid.ClientIdentity, attr string) (bool, error) {
+	v, found, err := id.GetAttributeValue(attr)
+	if err != nil {
+		return false, err
+	}
+	return found && v == "true", nil
+}
+
+// Granted reports whether the caller's MSP is in msps or it carries
+// attr=true.
+func Granted(id cid.ClientIdentity, msps []string, attr string) (bool, error) {
+	ok, err := MSPIn(id, msps)
+	if err != nil || ok {
+		return ok, err
+	}
+	return HasAttr(id, attr)
+}
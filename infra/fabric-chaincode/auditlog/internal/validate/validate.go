@@ -0,0 +1,33 @@
+// Package validate holds the field format checks shared by the chaincode's
+// contracts. Errors follow the contract's "<field> must ..." form so they
+// map to ERR_VALIDATION_FIELD with the field named.
+package validate
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+)
+
+var (
+	// UUID matches an event_id in any case.
+	UUID = regexp.MustCompile("^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$")
+	// SHA256 matches a lower-case hex SHA-256 digest.
+	SHA256 = regexp.MustCompile("^[0-9a-f]{64}$")
+)
+
+const MaxStorageURILength = 2048
+
+var storageSchemes = map[string]bool{"s3": true, "gs": true, "az": true, "https": true, "ipfs": true}
+
+// StorageURI checks a storage_uri naming where bytes live off-chain.
+func StorageURI(uri string) error {
+	if len(uri) > MaxStorageURILength {
+		return fmt.Errorf("storage_uri must be at most %d characters", MaxStorageURILength)
+	}
+	u, err := url.Parse(uri)
+	if err != nil || !storageSchemes[u.Scheme] || (u.Host == "" && u.Opaque == "") {
+		return fmt.Errorf("storage_uri must be an s3, gs, az, https or ipfs URI")
+	}
+	return nil
+}
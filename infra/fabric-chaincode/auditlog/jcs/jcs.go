@@ -0,0 +1,230 @@
+// Package jcs implements the JSON Canonicalization Scheme of RFC 8785. It
+// is shared by the chaincode and the Go client so both hash events the same
+// way.
+package jcs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf16"
+)
+
+// Buffers for the intermediate encoding are reused; ones grown past
+// maxPooledBuffer by an unusually large value are left to the collector.
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// Marshal returns the RFC 8785 canonical JSON of v.
+func Marshal(v any) ([]byte, error) {
+	out, _, err := MarshalExact(v)
+	return out, err
+}
+
+// MarshalExact is Marshal that also reports whether every number kept the
+// text encoding/json gave it, so decoding the output into v's type gives v
+// back. Integers beyond 2^53 are where canonical numbers lose precision.
+func MarshalExact(v any) ([]byte, bool, error) {
+	raw := bufferPool.Get().(*bytes.Buffer)
+	raw.Reset()
+	defer func() {
+		if raw.Cap() <= maxPooledBuffer {
+			bufferPool.Put(raw)
+		}
+	}()
+	enc := json.NewEncoder(raw)
+	// Canonical output escapes no HTML characters, so skip escaping them
+	// only to decode them again.
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, false, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw.Bytes()))
+	dec.UseNumber()
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, false, err
+	}
+	// The canonical form is rarely longer than the input.
+	w := &writer{buf: bytes.NewBuffer(make([]byte, 0, raw.Len())), exact: true}
+	if err := w.value(generic); err != nil {
+		return nil, false, err
+	}
+	return w.buf.Bytes(), w.exact, nil
+}
+
+type writer struct {
+	buf   *bytes.Buffer
+	exact bool
+}
+
+func (w *writer) value(v any) error {
+	buf := w.buf
+	switch t := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(t))
+	case json.Number:
+		f, err := strconv.ParseFloat(string(t), 64)
+		if err != nil {
+			return err
+		}
+		s, err := jcsNumber(f)
+		if err != nil {
+			return err
+		}
+		if s != string(t) {
+			w.exact = false
+		}
+		buf.WriteString(s)
+	case string:
+		jcsString(buf, t)
+	case []any:
+		buf.WriteByte('[')
+		for i, elem := range t {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := w.value(elem); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]any:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		// Members sort by the UTF-16 code units of their names.
+		sort.Slice(keys, func(i, j int) bool { return utf16Less(keys[i], keys[j]) })
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			jcsString(buf, k)
+			buf.WriteByte(':')
+			if err := w.value(t[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unsupported JSON value %T", v)
+	}
+	return nil
+}
+
+func utf16Less(a, b string) bool {
+	// Without supplementary characters, UTF-16 order is code point order,
+	// which is UTF-8 byte order.
+	if !hasSupplementary(a) && !hasSupplementary(b) {
+		return a < b
+	}
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+// hasSupplementary reports whether s holds a character outside the Basic
+// Multilingual Plane, which UTF-8 starts with a byte of 0xF0 or more.
+func hasSupplementary(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0xF0 {
+			return true
+		}
+	}
+	return false
+}
+
+// jcsString escapes only what RFC 8785 requires: quote, backslash and
+// control characters. Decoded strings are valid UTF-8, so every other byte
+// is copied through in runs.
+func jcsString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' {
+			continue
+		}
+		buf.WriteString(s[start:i])
+		start = i + 1
+		switch c {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			fmt.Fprintf(buf, `\u%04x`, c)
+		}
+	}
+	buf.WriteString(s[start:])
+	buf.WriteByte('"')
+}
+
+// jcsNumber formats f as ECMAScript's Number.prototype.toString does, which
+// RFC 8785 adopts for all numbers.
+func jcsNumber(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("number not representable in JSON")
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	sign := ""
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	// Shortest round-trip digits d1.d2...dk and exponent e, so that the value
+	// is 0.d1d2...dk * 10^n with n = e+1.
+	mant, exp, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	digits := strings.Replace(mant, ".", "", 1)
+	e, err := strconv.Atoi(exp)
+	if err != nil {
+		return "", err
+	}
+	k, n := len(digits), e+1
+
+	var s string
+	switch {
+	case k <= n && n <= 21:
+		s = digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		s = digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		s = "0." + strings.Repeat("0", -n) + digits
+	default:
+		s = digits[:1]
+		if k > 1 {
+			s += "." + digits[1:]
+		}
+		if n-1 >= 0 {
+			s += "e+" + strconv.Itoa(n-1)
+		} else {
+			s += "e-" + strconv.Itoa(1-n)
+		}
+	}
+	return sign + s, nil
+}
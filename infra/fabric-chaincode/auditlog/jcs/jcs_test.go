@@ -0,0 +1,160 @@
+package jcs_test
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"payscope/auditlog/auditlogtest"
+	"payscope/auditlog/contract"
+	"payscope/auditlog/jcs"
+)
+
+// The number serializations of RFC 8785 Appendix B, by IEEE 754 bit
+// pattern.
+func TestMarshalAppendixBNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		bits uint64
+		want string
+	}{
+		{0x0000000000000000, "0"},
+		{0x8000000000000000, "0"},
+		{0x0000000000000001, "5e-324"},
+		{0x8000000000000001, "-5e-324"},
+		{0x7fefffffffffffff, "1.7976931348623157e+308"},
+		{0xffefffffffffffff, "-1.7976931348623157e+308"},
+		{0x4340000000000000, "9007199254740992"},
+		{0xc340000000000000, "-9007199254740992"},
+		{0x4430000000000000, "295147905179352830000"},
+		{0x44b52d02c7e14af5, "9.999999999999997e+22"},
+		{0x44b52d02c7e14af6, "1e+23"},
+		{0x44b52d02c7e14af7, "1.0000000000000001e+23"},
+		{0x444b1ae4d6e2ef4e, "999999999999999700000"},
+		{0x444b1ae4d6e2ef4f, "999999999999999900000"},
+		{0x444b1ae4d6e2ef50, "1e+21"},
+		{0x3eb0c6f7a0b5ed8c, "9.999999999999997e-7"},
+		{0x3eb0c6f7a0b5ed8d, "0.000001"},
+		{0x41b3de4355555553, "333333333.3333332"},
+		{0x41b3de4355555554, "333333333.33333325"},
+		{0x41b3de4355555555, "333333333.3333333"},
+		{0x41b3de4355555556, "333333333.3333334"},
+		{0x41b3de4355555557, "333333333.33333343"},
+		{0xbecbf647612f3696, "-0.0000033333333333333333"},
+		{0x43143ff3c1cb0959, "1424953923781206.2"},
+	} {
+		got, err := jcs.Marshal(math.Float64frombits(tc.bits))
+		if err != nil || string(got) != tc.want {
+			t.Errorf("%016x: got %s, %v; want %s", tc.bits, got, err, tc.want)
+		}
+	}
+	for _, bits := range []uint64{0x7fffffffffffffff, 0x7ff0000000000000} {
+		if got, err := jcs.Marshal(math.Float64frombits(bits)); err == nil {
+			t.Errorf("%016x: got %s, want an error", bits, got)
+		}
+	}
+}
+
+// Number texts as submitted, and whether MarshalExact keeps them.
+func TestMarshalNumberText(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+		exact    bool
+	}{
+		{"1e21", "1e+21", false},
+		{"1E30", "1e+30", false},
+		{"1e-7", "1e-7", true},
+		{"0.000001", "0.000001", true},
+		{"-0", "0", false},
+		{"-0.0", "0", false},
+		{"5e-324", "5e-324", true},
+		{"4.50", "4.5", false},
+		{"2e-3", "0.002", false},
+		{"100000000000000000000", "100000000000000000000", true},
+		{"9007199254740991", "9007199254740991", true},
+		{"9007199254740993", "9007199254740992", false},
+		{"18446744073709551615", "18446744073709552000", false},
+		{"333333333.33333329", "333333333.3333333", false},
+	} {
+		got, exact, err := jcs.MarshalExact(json.RawMessage(tc.in))
+		if err != nil || string(got) != tc.want || exact != tc.exact {
+			t.Errorf("%s: got %s, exact %v, %v; want %s, exact %v", tc.in, got, exact, err, tc.want, tc.exact)
+		}
+	}
+}
+
+func TestMarshalVectors(t *testing.T) {
+	for _, tc := range []struct {
+		name, in, want string
+	}{
+		{
+			// RFC 8785 section 3.2.2.
+			"rfc8785 values",
+			`{"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+			  "string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+			  "literals": [null, true, false]}`,
+			`{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`,
+		},
+		{
+			// RFC 8785 section 3.2.3: UTF-16 order puts the surrogate pair
+			// of U+1F600 before U+FB33.
+			"rfc8785 sorting",
+			`{"\u20ac": "Euro Sign", "\r": "Carriage Return", "\ufb33": "Hebrew Letter Dalet With Dagesh",
+			  "1": "One", "\ud83d\ude00": "Emoji: Grinning Face", "\u0080": "Control",
+			  "\u00f6": "Latin Small Letter O With Diaeresis"}`,
+			"{\"\\r\":\"Carriage Return\",\"1\":\"One\",\"\u0080\":\"Control\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\"," +
+				"\"\u20ac\":\"Euro Sign\",\"\U0001f600\":\"Emoji: Grinning Face\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			// cyberphone/json-canonicalization testdata weird.json.
+			"cyberphone weird",
+			`{"\u20ac": "Euro Sign", "\r": "Carriage Return", "\u000a": "Newline", "1": "One",
+			  "\u0080": "Control\u007f", "\ud83d\ude02": "Smiley", "\u00f6": "Latin Small Letter O With Diaeresis",
+			  "\ufb33": "Hebrew Letter Dalet With Dagesh", "</script>": "Browser Challenge"}`,
+			"{\"\\n\":\"Newline\",\"\\r\":\"Carriage Return\",\"1\":\"One\",\"</script>\":\"Browser Challenge\"," +
+				"\"\u0080\":\"Control\u007f\",\"\u00f6\":\"Latin Small Letter O With Diaeresis\",\"\u20ac\":\"Euro Sign\"," +
+				"\"\U0001f602\":\"Smiley\",\"\ufb33\":\"Hebrew Letter Dalet With Dagesh\"}",
+		},
+		{
+			// Supplementary keys order by their high surrogate, below
+			// U+E000 and above U+D7FF.
+			"surrogate order",
+			`{"\ue000": 3, "\ud800\udc00": 2, "\ud7ff": 1, "\udbff\udfff": 2.5}`,
+			"{\"\ud7ff\":1,\"\U00010000\":2,\"\U0010ffff\":2.5,\"\ue000\":3}",
+		},
+		{
+			// Only quote, backslash and C0 controls are escaped, the named
+			// ones by name; U+007F, U+2028 and U+2029 are written as is.
+			"escaping",
+			`["\u0000\u0001\u001f \b\f\n\r\t \u007f \u2028\u2029 \/ <&>"]`,
+			"[\"\\u0000\\u0001\\u001f \\b\\f\\n\\r\\t \u007f \u2028\u2029 / <&>\"]",
+		},
+	} {
+		got, err := jcs.Marshal(json.RawMessage(tc.in))
+		if err != nil || string(got) != tc.want {
+			t.Errorf("%s: got %s, %v; want %s", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+// hashSink keeps the compiler from dropping the benchmarked hash.
+var hashSink [sha256.Size]byte
+
+// BenchmarkCanonicalHash computes the unsalted payload hash of a schema v2
+// INGEST event per operation: its RFC 8785 encoding and that encoding's
+// SHA-256, as PutEvent takes it.
+func BenchmarkCanonicalHash(b *testing.B) {
+	events := make([]*contract.LedgerEvent, 1000)
+	for i := range events {
+		events[i] = auditlogtest.IngestEvent(i + 1)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		canon, err := jcs.Marshal(events[i%len(events)])
+		if err != nil {
+			b.Fatal(err)
+		}
+		hashSink = sha256.Sum256(canon)
+	}
+}
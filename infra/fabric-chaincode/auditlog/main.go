@@ -0,0 +1,13 @@
+package main
+
+import "payscope/auditlog/contract"
+
+func main() {
+	cc, err := contract.NewChaincode()
+	if err != nil {
+		panic(err)
+	}
+	if err := contract.Start(cc); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,41 @@
+-- Off-chain mirror of audit ledger events, maintained by the indexer service.
+-- The ledger stays the source of truth; rows here are rebuilt by replaying blocks.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS audit_events (
+  ref TEXT PRIMARY KEY, -- ledger key suffix: "<event_id>" or "<producer_msp>:<event_id>"
+  event_id UUID NOT NULL,
+  event_type TEXT NOT NULL,
+  artifact_hash TEXT NOT NULL,
+  schema_version TEXT NOT NULL,
+  event_ts TIMESTAMPTZ NOT NULL,
+  payload_hash_sha256 CHAR(64) NOT NULL,
+  producer TEXT NULL,
+  creator_msp TEXT NULL,
+  stored JSONB NOT NULL,
+  block_number BIGINT NOT NULL,
+  tx_id TEXT NOT NULL,
+  search TSVECTOR GENERATED ALWAYS AS (
+    to_tsvector('simple',
+      event_id::text || ' ' || event_type || ' ' || artifact_hash || ' ' ||
+      schema_version || ' ' || coalesce(creator_msp, ''))
+  ) STORED
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_ts_idx ON audit_events (event_ts);
+CREATE INDEX IF NOT EXISTS audit_events_type_ts_idx ON audit_events (event_type, event_ts);
+CREATE INDEX IF NOT EXISTS audit_events_artifact_idx ON audit_events (artifact_hash);
+CREATE INDEX IF NOT EXISTS audit_events_search_idx ON audit_events USING GIN (search);
+CREATE INDEX IF NOT EXISTS audit_events_stored_idx ON audit_events USING GIN (stored jsonb_path_ops);
+
+-- Next block to process, per channel and chaincode.
+CREATE TABLE IF NOT EXISTS audit_indexer_checkpoints (
+  channel TEXT NOT NULL,
+  chaincode TEXT NOT NULL,
+  next_block BIGINT NOT NULL,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+  PRIMARY KEY (channel, chaincode)
+);
+
+COMMIT;
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"payscope/client/auditlog"
+)
+
+// source is the consumer side of the bridge, a *kafka.Reader in a consumer
+// group.
+type source interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// deadLetterSink takes the messages the bridge gives up on, a *kafka.Writer
+// on the dead-letter topic.
+type deadLetterSink interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+// ledger is where events go, an *auditlog.Client.
+type ledger interface {
+	PutEvents(events []*auditlog.Event) (*auditlog.BatchResult, error)
+}
+
+// bridge moves events from Kafka to the ledger in batches. A batch's offsets
+// are committed only once its PutEvents transaction has committed on the
+// channel and its rejected messages are on the dead-letter topic, so a crash
+// at any point redelivers the batch. The chaincode recognises redelivered
+// events by event_id and payload hash and reports them as deduped, which
+// makes delivery effectively exactly once.
+type bridge struct {
+	src      source
+	dlq      deadLetterSink // nil drops rejected messages after logging them
+	ledger   ledger
+	maxBatch int
+	linger   time.Duration
+}
+
+// run consumes until ctx is cancelled or the source fails. A batch cut
+// short by either is left uncommitted, to be redelivered.
+func (b *bridge) run(ctx context.Context) error {
+	for {
+		batch, err := b.collect(ctx)
+		if err != nil {
+			return err
+		}
+		if err := b.deliver(ctx, batch); err != nil {
+			return err
+		}
+	}
+}
+
+// collect fetches up to maxBatch messages, returning early once linger has
+// passed since the first one.
+func (b *bridge) collect(ctx context.Context) ([]kafka.Message, error) {
+	first, err := b.src.FetchMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	batch := []kafka.Message{first}
+	lingerCtx, cancel := context.WithTimeout(ctx, b.linger)
+	defer cancel()
+	for len(batch) < b.maxBatch {
+		msg, err := b.src.FetchMessage(lingerCtx)
+		if err != nil {
+			if ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+				return batch, nil
+			}
+			return nil, err
+		}
+		batch = append(batch, msg)
+	}
+	return batch, nil
+}
+
+// deliver writes batch to the ledger, dead-letters what cannot be written
+// and commits the batch's offsets. Submit failures are retried with backoff
+// until they succeed or ctx ends; offsets stay uncommitted meanwhile.
+func (b *bridge) deliver(ctx context.Context, batch []kafka.Message) error {
+	var events []*auditlog.Event
+	var sources []kafka.Message
+	var dead []kafka.Message
+	for _, msg := range batch {
+		e, err := decodeEvent(msg.Value)
+		if err != nil {
+			dead = append(dead, deadLetter(msg, err.code, err.reason))
+			continue
+		}
+		events = append(events, e)
+		sources = append(sources, msg)
+	}
+
+	var written, deduped int
+	if len(events) > 0 {
+		res, err := b.submit(ctx, events)
+		if err != nil {
+			return err
+		}
+		for _, r := range res.Results {
+			if r.Status == "rejected" && r.Index >= 0 && r.Index < len(sources) {
+				dead = append(dead, deadLetter(sources[r.Index], r.Code, r.Reason))
+			}
+		}
+		written, deduped = res.Summary.Written, res.Summary.Deduped
+	}
+
+	if len(dead) > 0 {
+		if err := b.sendDeadLetters(ctx, dead); err != nil {
+			return err
+		}
+	}
+	if err := b.src.CommitMessages(ctx, batch...); err != nil {
+		return err
+	}
+	log.Printf("kafkabridge: batch of %d: %d written, %d deduped, %d dead-lettered", len(batch), written, deduped, len(dead))
+	return nil
+}
+
+func (b *bridge) submit(ctx context.Context, events []*auditlog.Event) (*auditlog.BatchResult, error) {
+	backoff := time.Second
+	for {
+		res, err := b.ledger.PutEvents(events)
+		if err == nil {
+			return res, nil
+		}
+		if ce, ok := auditlog.AsContractError(err); ok {
+			err = ce
+		}
+		log.Printf("kafkabridge: PutEvents of %d events failed: %v; retrying in %s", len(events), err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+}
+
+func (b *bridge) sendDeadLetters(ctx context.Context, dead []kafka.Message) error {
+	if b.dlq == nil {
+		for _, m := range dead {
+			log.Printf("kafkabridge: dropping %s: %s", headerValue(m, "auditlog-source"), headerValue(m, "auditlog-error"))
+		}
+		return nil
+	}
+	return b.dlq.WriteMessages(ctx, dead...)
+}
+
+// deadLetter copies msg for the dead-letter topic, with headers naming
+// where it came from and why it was refused.
+func deadLetter(msg kafka.Message, code, reason string) kafka.Message {
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: "auditlog-source", Value: []byte(msg.Topic + "/" + strconv.Itoa(msg.Partition) + "/" + strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: "auditlog-error-code", Value: []byte(code)},
+		kafka.Header{Key: "auditlog-error", Value: []byte(reason)},
+	)
+	return kafka.Message{Key: msg.Key, Value: msg.Value, Headers: headers}
+}
+
+func headerValue(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
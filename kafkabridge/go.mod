@@ -0,0 +1,35 @@
+module payscope/kafkabridge
+
+go 1.21
+
+require (
+	github.com/segmentio/kafka-go v0.4.47
+	payscope/client/auditlog v0.0.0
+)
+
+require (
+	github.com/go-logr/logr v1.2.4 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/hyperledger/fabric-gateway v1.4.0 // indirect
+	github.com/hyperledger/fabric-protos-go-apiv2 v0.2.1 // indirect
+	github.com/klauspost/compress v1.15.9 // indirect
+	github.com/miekg/pkcs11 v1.1.1 // indirect
+	github.com/pierrec/lz4/v4 v4.1.15 // indirect
+	go.opentelemetry.io/otel v1.19.0 // indirect
+	go.opentelemetry.io/otel/metric v1.19.0 // indirect
+	go.opentelemetry.io/otel/trace v1.19.0 // indirect
+	golang.org/x/crypto v0.17.0 // indirect
+	golang.org/x/net v0.17.0 // indirect
+	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20231030173426-d783a09b4405 // indirect
+	google.golang.org/grpc v1.59.0 // indirect
+	google.golang.org/protobuf v1.31.0 // indirect
+	payscope/auditlog v0.0.0 // indirect
+)
+
+replace (
+	payscope/auditlog => ../infra/fabric-chaincode/auditlog
+	payscope/client/auditlog => ../client/auditlog
+)
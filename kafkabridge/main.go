@@ -0,0 +1,102 @@
+// Command kafkabridge writes audit events published to a Kafka topic into
+// the ledger.
+//
+// It consumes the topic in a consumer group, batches messages, one event
+// JSON per message, and submits each batch with PutEvents. Offsets are
+// committed only after the transaction has committed, so a restart replays
+// at most the batches in flight, which the chaincode deduplicates by
+// event_id. Messages the bridge or the chaincode rejects go to a
+// dead-letter topic when one is configured.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"payscope/client/auditlog"
+)
+
+// maxPutBatch is the chaincode's limit on events per PutEvents call.
+const maxPutBatch = 500
+
+func main() {
+	profilePath := flag.String("profile", os.Getenv("KAFKA_BRIDGE_PROFILE"), "audit log connection profile JSON")
+	brokers := flag.String("brokers", os.Getenv("KAFKA_BRIDGE_BROKERS"), "comma-separated Kafka bootstrap brokers")
+	topic := flag.String("topic", os.Getenv("KAFKA_BRIDGE_TOPIC"), "topic of audit events")
+	group := flag.String("group", envOr("KAFKA_BRIDGE_GROUP", "auditlog-bridge"), "consumer group id")
+	dlqTopic := flag.String("dead-letter-topic", os.Getenv("KAFKA_BRIDGE_DEAD_LETTER_TOPIC"), "topic for rejected messages; empty logs and drops them")
+	batchSize := flag.Int("batch-size", maxPutBatch, "most events per PutEvents transaction")
+	linger := flag.Duration("linger", 2*time.Second, "longest wait to fill a batch after its first message")
+	flag.Parse()
+
+	if *profilePath == "" || *brokers == "" || *topic == "" {
+		log.Fatal("kafkabridge: -profile, -brokers and -topic (or KAFKA_BRIDGE_PROFILE, KAFKA_BRIDGE_BROKERS, KAFKA_BRIDGE_TOPIC) are required")
+	}
+	if *batchSize < 1 || *batchSize > maxPutBatch {
+		log.Fatalf("kafkabridge: -batch-size must be between 1 and %d", maxPutBatch)
+	}
+	if *linger <= 0 {
+		log.Fatal("kafkabridge: -linger must be positive")
+	}
+	profile, err := auditlog.LoadProfile(*profilePath)
+	if err != nil {
+		log.Fatalf("kafkabridge: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c, err := auditlog.Connect(profile)
+	if err != nil {
+		log.Fatalf("kafkabridge: %v", err)
+	}
+	defer c.Close()
+
+	addrs := strings.Split(*brokers, ",")
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: addrs,
+		GroupID: *group,
+		Topic:   *topic,
+		// Offsets are committed explicitly, after the ledger commit.
+		CommitInterval: 0,
+		// A batch can hold up to batch-size messages of up to 1 MiB.
+		QueueCapacity: *batchSize,
+	})
+	defer reader.Close()
+
+	b := &bridge{src: reader, ledger: c, maxBatch: *batchSize, linger: *linger}
+	if *dlqTopic != "" {
+		w := &kafka.Writer{
+			Addr:         kafka.TCP(addrs...),
+			Topic:        *dlqTopic,
+			RequiredAcks: kafka.RequireAll,
+		}
+		defer w.Close()
+		b.dlq = w
+	}
+
+	log.Printf("kafkabridge: consuming %s as group %s into %s/%s", *topic, *group, profile.Channel, profile.Chaincode)
+	err = b.run(ctx)
+	if ctx.Err() != nil || errors.Is(err, io.EOF) {
+		log.Printf("kafkabridge: stopped")
+		return
+	}
+	log.Fatalf("kafkabridge: %v", err)
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
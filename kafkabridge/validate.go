@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"time"
+
+	"payscope/client/auditlog"
+)
+
+// invalidEvent is why a message was refused before reaching the ledger, in
+// the chaincode's error codes.
+type invalidEvent struct {
+	code   string
+	reason string
+}
+
+var uuidRe = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// decodeEvent parses a message value as one event and checks what can be
+// checked without the ledger, so a malformed message is dead-lettered on
+// its own instead of costing a transaction. Unknown fields are refused
+// rather than dropped: the chaincode hashes the event the bridge submits,
+// which must be the event that was published.
+func decodeEvent(value []byte) (*auditlog.Event, *invalidEvent) {
+	dec := json.NewDecoder(bytes.NewReader(value))
+	dec.DisallowUnknownFields()
+	var e auditlog.Event
+	if err := dec.Decode(&e); err != nil {
+		return nil, &invalidEvent{"ERR_VALIDATION_JSON", "invalid json: " + err.Error()}
+	}
+	if dec.More() {
+		return nil, &invalidEvent{"ERR_VALIDATION_JSON", "invalid json: more than one value"}
+	}
+	switch {
+	case !uuidRe.MatchString(e.EventID):
+		return nil, &invalidEvent{"ERR_VALIDATION_FIELD", "invalid event_id"}
+	case e.EventType == "":
+		return nil, &invalidEvent{"ERR_VALIDATION_FIELD", "invalid event_type"}
+	case e.ArtifactHash == "":
+		return nil, &invalidEvent{"ERR_VALIDATION_FIELD", "artifact_hash required"}
+	case e.SchemaVersion == "":
+		return nil, &invalidEvent{"ERR_VALIDATION_FIELD", "schema_version required"}
+	}
+	if _, err := time.Parse(time.RFC3339Nano, e.Timestamp); err != nil {
+		return nil, &invalidEvent{"ERR_VALIDATION_TIMESTAMP", "timestamp must be RFC3339"}
+	}
+	return &e, nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// config lists the webhook endpoints and how deliveries are retried.
+type config struct {
+	Endpoints []endpoint `json:"endpoints"`
+	// Attempts per delivery, including the first; default 8.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// Delay before the first retry, doubled for each further one up to
+	// MaxBackoffSeconds; defaults 1 and 300.
+	InitialBackoffSeconds int `json:"initial_backoff_seconds,omitempty"`
+	MaxBackoffSeconds     int `json:"max_backoff_seconds,omitempty"`
+	// Per-attempt request timeout; default 10.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// endpoint is one registered webhook receiver. Its secret, read from the
+// environment variable SecretEnv or the file SecretFile so it stays out of
+// the config, keys the HMAC signature of each delivery.
+type endpoint struct {
+	Name       string `json:"name"`
+	URL        string `json:"url"`
+	SecretEnv  string `json:"secret_env,omitempty"`
+	SecretFile string `json:"secret_file,omitempty"`
+	// Delivered event types; empty means every type.
+	EventTypes []string `json:"event_types,omitempty"`
+
+	secret []byte
+	types  map[string]bool
+}
+
+func loadConfig(path string) (*config, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var c config
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("parse config %s: %w", path, err)
+	}
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = 8
+	}
+	if c.InitialBackoffSeconds == 0 {
+		c.InitialBackoffSeconds = 1
+	}
+	if c.MaxBackoffSeconds == 0 {
+		c.MaxBackoffSeconds = 300
+	}
+	if c.TimeoutSeconds == 0 {
+		c.TimeoutSeconds = 10
+	}
+	if len(c.Endpoints) == 0 {
+		return nil, fmt.Errorf("config: at least one endpoint required")
+	}
+	if c.MaxAttempts < 1 || c.InitialBackoffSeconds < 0 || c.MaxBackoffSeconds < c.InitialBackoffSeconds || c.TimeoutSeconds < 1 {
+		return nil, fmt.Errorf("config: invalid retry settings")
+	}
+	names := map[string]bool{}
+	for i := range c.Endpoints {
+		ep := &c.Endpoints[i]
+		if ep.Name == "" || names[ep.Name] {
+			return nil, fmt.Errorf("config: endpoints[%d]: name required and unique", i)
+		}
+		names[ep.Name] = true
+		u, err := url.Parse(ep.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("config: endpoint %s: url must be http(s)", ep.Name)
+		}
+		if ep.secret, err = ep.loadSecret(filepath.Dir(path)); err != nil {
+			return nil, fmt.Errorf("config: endpoint %s: %w", ep.Name, err)
+		}
+		if len(ep.EventTypes) > 0 {
+			ep.types = map[string]bool{}
+			for _, t := range ep.EventTypes {
+				ep.types[strings.ToUpper(strings.TrimSpace(t))] = true
+			}
+		}
+	}
+	return &c, nil
+}
+
+func (ep *endpoint) loadSecret(dir string) ([]byte, error) {
+	switch {
+	case ep.SecretEnv != "" && ep.SecretFile != "":
+		return nil, fmt.Errorf("use either secret_env or secret_file")
+	case ep.SecretEnv != "":
+		if v := os.Getenv(ep.SecretEnv); v != "" {
+			return []byte(v), nil
+		}
+		return nil, fmt.Errorf("%s is unset", ep.SecretEnv)
+	case ep.SecretFile != "":
+		p := ep.SecretFile
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if s := strings.TrimSpace(string(b)); s != "" {
+			return []byte(s), nil
+		}
+		return nil, fmt.Errorf("%s is empty", p)
+	default:
+		return nil, fmt.Errorf("secret_env or secret_file required")
+	}
+}
+
+// wants reports whether ep subscribes to eventType.
+func (ep *endpoint) wants(eventType string) bool {
+	return ep.types == nil || ep.types[eventType]
+}
+
+func (c *config) backoff(retry int) time.Duration {
+	d := time.Duration(c.InitialBackoffSeconds) * time.Second
+	for i := 1; i < retry && d < time.Duration(c.MaxBackoffSeconds)*time.Second; i++ {
+		d *= 2
+	}
+	if max := time.Duration(c.MaxBackoffSeconds) * time.Second; d > max {
+		d = max
+	}
+	return d
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"payscope/client/auditlog"
+)
+
+// delivery is the webhook body for one written event.
+type delivery struct {
+	DeliveryID  string `json:"delivery_id"`
+	ChannelID   string `json:"channel_id"`
+	Chaincode   string `json:"chaincode"`
+	BlockNumber uint64 `json:"block_number"`
+	auditlog.WrittenNotice
+}
+
+// Headers every delivery carries. The signature is the hex HMAC-SHA256,
+// under the endpoint's secret, of the timestamp header, a dot and the body.
+const (
+	headerDelivery  = "X-Auditlog-Delivery"
+	headerTimestamp = "X-Auditlog-Timestamp"
+	headerSignature = "X-Auditlog-Signature"
+)
+
+type notifier struct {
+	cfg       *config
+	client    *http.Client
+	channel   string
+	chaincode string
+	now       func() time.Time
+}
+
+// handle delivers batch to every endpoint subscribed to its event types,
+// endpoints in parallel and each endpoint's events in order. An endpoint
+// that still fails after its last attempt is logged and skipped, so one
+// broken receiver cannot hold back the others indefinitely. It returns an
+// error only when ctx ends first, leaving batch to be redelivered.
+func (n *notifier) handle(ctx context.Context, batch *auditlog.WrittenBatch) error {
+	var wg sync.WaitGroup
+	for i := range n.cfg.Endpoints {
+		ep := &n.cfg.Endpoints[i]
+		var due []delivery
+		for _, e := range batch.Events {
+			if ep.wants(e.EventType) {
+				due = append(due, delivery{
+					DeliveryID:    deliveryID(ep.Name, batch.TxID, e.EventID),
+					ChannelID:     n.channel,
+					Chaincode:     n.chaincode,
+					BlockNumber:   batch.BlockNumber,
+					WrittenNotice: e,
+				})
+			}
+		}
+		if len(due) == 0 {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, d := range due {
+				err := n.deliver(ctx, ep, d)
+				if ctx.Err() != nil {
+					return
+				}
+				if err != nil {
+					log.Printf("notifier: %s: giving up on %s (event %s): %v", ep.Name, d.DeliveryID, d.EventID, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// deliver posts d to ep, retrying transport failures, 408, 429 and 5xx
+// answers with backoff. Other answers end it: 2xx as delivered, the rest as
+// refused.
+func (n *notifier) deliver(ctx context.Context, ep *endpoint, d delivery) error {
+	body, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, ep, d.DeliveryID, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt == n.cfg.MaxAttempts {
+			return err
+		}
+		wait := n.cfg.backoff(attempt)
+		log.Printf("notifier: %s: attempt %d of %s failed: %v; retrying in %s", ep.Name, attempt, d.DeliveryID, err, wait)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func (n *notifier) post(ctx context.Context, ep *endpoint, id string, body []byte) (retry bool, err error) {
+	reqCtx, cancel := context.WithTimeout(ctx, time.Duration(n.cfg.TimeoutSeconds)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	ts := strconv.FormatInt(n.now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "payscope-auditlog-notifier")
+	req.Header.Set(headerDelivery, id)
+	req.Header.Set(headerTimestamp, ts)
+	req.Header.Set(headerSignature, "v1="+sign(ep.secret, ts, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint answered %s", resp.Status)
+	default:
+		return false, fmt.Errorf("endpoint refused delivery: %s", resp.Status)
+	}
+}
+
+func sign(secret []byte, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliveryID is stable across retries and restarts, so receivers can drop
+// repeats of a delivery they already processed.
+func deliveryID(endpoint, txID, eventID string) string {
+	sum := sha256.Sum256([]byte(endpoint + "\x00" + txID + "\x00" + eventID))
+	return hex.EncodeToString(sum[:16])
+}
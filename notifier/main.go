@@ -0,0 +1,118 @@
+// Command notifier delivers signed webhooks when audit events land on the
+// ledger.
+//
+// It follows the chaincode's EventWritten and EventsWritten events from
+// committed transactions and posts one webhook per written event to every
+// registered endpoint subscribed to its event type. Progress is
+// checkpointed per transaction once its deliveries are done, so a restart
+// resumes after the last handled transaction; deliveries in flight at a
+// crash are sent again with the same delivery id.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/hyperledger/fabric-gateway/pkg/client"
+
+	"payscope/client/auditlog"
+)
+
+func main() {
+	profilePath := flag.String("profile", os.Getenv("NOTIFIER_PROFILE"), "audit log connection profile JSON")
+	configPath := flag.String("config", os.Getenv("NOTIFIER_CONFIG"), "webhook endpoints config JSON")
+	checkpointPath := flag.String("checkpoint", envOr("NOTIFIER_CHECKPOINT", "notifier-checkpoint.json"), "file recording the last handled transaction")
+	fromBlock := flag.Uint64("from-block", 0, "block to start from when there is no checkpoint")
+	flag.Parse()
+
+	if *profilePath == "" || *configPath == "" {
+		log.Fatal("notifier: -profile and -config (or NOTIFIER_PROFILE, NOTIFIER_CONFIG) are required")
+	}
+	profile, err := auditlog.LoadProfile(*profilePath)
+	if err != nil {
+		log.Fatalf("notifier: %v", err)
+	}
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("notifier: %v", err)
+	}
+	checkpoint, err := client.NewFileCheckpointer(*checkpointPath)
+	if err != nil {
+		log.Fatalf("notifier: checkpoint: %v", err)
+	}
+	defer checkpoint.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	n := &notifier{
+		cfg:       cfg,
+		client:    &http.Client{},
+		channel:   profile.Channel,
+		chaincode: profile.Chaincode,
+		now:       time.Now,
+	}
+	backoff := time.Second
+	for ctx.Err() == nil {
+		handled, err := run(ctx, profile, n, checkpoint, *fromBlock)
+		if ctx.Err() != nil {
+			break
+		}
+		if handled > 0 {
+			backoff = time.Second
+		}
+		log.Printf("notifier: stream ended after block %d: %v; reconnecting in %s", checkpoint.BlockNumber(), err, backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+		}
+		if backoff < time.Minute {
+			backoff *= 2
+		}
+	}
+	log.Printf("notifier: stopped at block %d", checkpoint.BlockNumber())
+}
+
+// run follows written events from the checkpoint and delivers them in
+// order. It returns how many transactions were handled before the stream
+// ended.
+func run(ctx context.Context, profile *auditlog.Profile, n *notifier, checkpoint *client.FileCheckpointer, fromBlock uint64) (int, error) {
+	c, err := auditlog.Connect(profile)
+	if err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	batches, err := c.WrittenEvents(streamCtx, fromBlock, checkpoint)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("notifier: following %s/%s for %d endpoints", profile.Channel, profile.Chaincode, len(n.cfg.Endpoints))
+
+	handled := 0
+	for batch := range batches {
+		if err := n.handle(ctx, batch); err != nil {
+			return handled, err
+		}
+		if err := checkpoint.CheckpointTransaction(batch.BlockNumber, batch.TxID); err != nil {
+			return handled, err
+		}
+		handled++
+	}
+	return handled, ctx.Err()
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}